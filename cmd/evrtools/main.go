@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -194,7 +195,9 @@ func runBuild() error {
 				if err != nil {
 					return fmt.Errorf("read manifest: %w", err)
 				}
-				return manifest.QuickRepack(m, files, dataDir, packageName)
+				repacker := &manifest.Repacker{Logger: log.New(os.Stdout, "", 0)}
+				_, err = repacker.QuickRepack(m, files, dataDir, packageName)
+				return err
 			}
 			return runRepack(files)
 		}
@@ -235,5 +238,7 @@ func runRepack(inputFiles [][]manifest.ScannedFile) error {
 		return fmt.Errorf("read manifest: %w", err)
 	}
 
-	return manifest.Repack(m, inputFiles, outputDir, packageName, dataDir)
+	repacker := &manifest.Repacker{Logger: log.New(os.Stdout, "", 0)}
+	_, err = repacker.Repack(m, inputFiles, outputDir, packageName, dataDir)
+	return err
 }
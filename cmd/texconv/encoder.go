@@ -1,18 +1,20 @@
-// encoder.go - BC texture compression using libsquish via CGo
+// encoder.go - mipmap generation and BC format selection shared by both the
+// CGo (libsquish) and pure-Go CompressBC implementations; see encoder_cgo.go
+// and encoder_nocgo.go for the format-specific compressor each build tag
+// links in.
 
 package main
 
-/*
-#cgo LDFLAGS: -lsquish -lstdc++
-#cgo CXXFLAGS: -std=c++11
-#include "squish_wrapper.h"
-*/
-import "C"
 import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
 	"fmt"
 	"image"
 	"image/color"
-	"unsafe"
+	"io"
+	"math"
+	"sort"
 )
 
 // BCFormat represents a block compression format
@@ -24,75 +26,6 @@ const (
 	BC5                 // Two-channel, 16 bytes/block
 )
 
-// CompressBC compresses RGBA image data to BC format using libsquish
-func CompressBC(img image.Image, format BCFormat) ([]byte, error) {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// Convert image to RGBA format that libsquish expects
-	rgba := imageToRGBA(img)
-
-	// Determine squish flags
-	var flags C.int
-
-	switch format {
-	case BC1:
-		flags = C.SQUISH_DXT1 | C.SQUISH_COLOUR_CLUSTER_FIT // DXT1, high quality
-	case BC3:
-		flags = C.SQUISH_DXT5 | C.SQUISH_COLOUR_CLUSTER_FIT // DXT5, high quality
-	case BC5:
-		flags = C.SQUISH_BC5
-	default:
-		return nil, fmt.Errorf("unsupported BC format: %d", format)
-	}
-
-	// Calculate storage requirements
-	storageSize := C.squish_get_storage_requirements(C.int(width), C.int(height), flags)
-	if storageSize <= 0 {
-		return nil, fmt.Errorf("invalid storage size: %d", storageSize)
-	}
-
-	// Allocate output buffer
-	compressed := make([]byte, storageSize)
-
-	// Compress using libsquish
-	C.squish_compress_image(
-		(*C.uchar)(unsafe.Pointer(&rgba[0])),
-		C.int(width),
-		C.int(height),
-		unsafe.Pointer(&compressed[0]),
-		flags,
-	)
-
-	return compressed, nil
-}
-
-// imageToRGBA converts an image.Image to RGBA byte array in the format libsquish expects
-// Format: r1,g1,b1,a1, r2,g2,b2,a2, ..., rn,gn,bn,an
-func imageToRGBA(img image.Image) []byte {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	rgba := make([]byte, width*height*4)
-	idx := 0
-
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r, g, b, a := img.At(x, y).RGBA()
-			// Convert from 16-bit to 8-bit
-			rgba[idx+0] = uint8(r >> 8)
-			rgba[idx+1] = uint8(g >> 8)
-			rgba[idx+2] = uint8(b >> 8)
-			rgba[idx+3] = uint8(a >> 8)
-			idx += 4
-		}
-	}
-
-	return rgba
-}
-
 // DetectBCFormat analyzes an image and determines the best BC format to use
 func DetectBCFormat(img image.Image) BCFormat {
 	bounds := img.Bounds()
@@ -124,78 +57,1025 @@ func DetectBCFormat(img image.Image) BCFormat {
 
 	// Decision logic:
 	// - BC3 (DXT5) if there's partial alpha (smooth gradients)
+	// - BC5 if the image looks like a tangent-space normal map (no alpha,
+	//   blue channel pinned high, red/green varying around the middle)
 	// - BC1 (DXT1) if binary alpha or no alpha
 	if hasPartialAlpha {
 		return BC3
 	}
+	if !hasPartialAlpha && looksLikeNormalMap(img) {
+		return BC5
+	}
 	return BC1
 }
 
-// GenerateMipmaps creates a mipmap chain for the given image
-func GenerateMipmaps(img image.Image) []image.Image {
-	mipmaps := []image.Image{img}
-
+// looksLikeNormalMap reports whether img's sampled pixels match the shape
+// of a tangent-space normal map: blue consistently near 255 (since Z points
+// mostly out of the surface) while red and green vary around the 128
+// midpoint, rather than sitting near a single flat color.
+func looksLikeNormalMap(img image.Image) bool {
 	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
 
-	// Generate mips until we reach 1x1
+	var blueSum, rSum, gSum int64
+	var rSqSum, gSqSum int64
+	sampleCount := int64(0)
+	const maxSamples = 1000
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += (bounds.Dy() / 10) + 1 {
+		for x := bounds.Min.X; x < bounds.Max.X; x += (bounds.Dx() / 10) + 1 {
+			if sampleCount >= maxSamples {
+				break
+			}
+			r, g, b, _ := img.At(x, y).RGBA()
+			r8, g8, b8 := int64(r>>8), int64(g>>8), int64(b>>8)
+
+			blueSum += b8
+			rSum += r8
+			gSum += g8
+			rSqSum += r8 * r8
+			gSqSum += g8 * g8
+			sampleCount++
+		}
+		if sampleCount >= maxSamples {
+			break
+		}
+	}
+	if sampleCount == 0 {
+		return false
+	}
+
+	avgBlue := blueSum / sampleCount
+	avgR := rSum / sampleCount
+	avgG := gSum / sampleCount
+	varR := rSqSum/sampleCount - avgR*avgR
+	varG := gSqSum/sampleCount - avgG*avgG
+
+	const blueThreshold = 200
+	const varianceThreshold = 64 // stddev >= 8
+
+	return avgBlue >= blueThreshold && (varR >= varianceThreshold || varG >= varianceThreshold)
+}
+
+// MipmapOptions configures GenerateMipmaps' resampling. The zero value
+// (FilterBox, gamma-space, no coverage correction) matches GenerateMipmaps'
+// old hardcoded behavior for any caller not yet passing real options - just
+// without the old box filter's fractional-coverage bug, since resizeImage
+// now always goes through resampleWithKernel's proper per-pixel weights.
+type MipmapOptions struct {
+	// Filter selects the resampling kernel - FilterBox, FilterBilinear (a
+	// triangle filter), FilterKaiser or FilterLanczos3.
+	Filter ResampleFilter
+	// KaiserAlpha sets FilterKaiser's window shape parameter; ignored for
+	// every other filter. <= 0 uses defaultKaiserAlpha.
+	KaiserAlpha float64
+	// SRGB, when true, resamples in linear light (srgbToLinear before
+	// averaging, linearToSrgb after) instead of averaging gamma-encoded
+	// samples directly - see resampleWithKernel.
+	SRGB bool
+	// AlphaCoverage, when true, rescales each mip's alpha so the fraction
+	// of texels above the 0.5 cutoff matches the base image's, preserving
+	// alpha-tested content (foliage, cutout text) through the chain -
+	// plain resampling washes that fraction toward 0.5 as mips shrink.
+	AlphaCoverage bool
+}
+
+// GenerateMipmaps creates a mipmap chain for img, halving each dimension
+// (down to 1x1) and resampling each level from the one above it per opts.
+func GenerateMipmaps(img image.Image, opts MipmapOptions) []image.Image {
+	base := toNRGBA(img)
+	mipmaps := []image.Image{base}
+
+	bounds := base.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	baseCoverage := alphaCoverage(base)
+
 	for width > 1 || height > 1 {
 		if width > 1 {
-			width = width / 2
+			width /= 2
 		}
 		if height > 1 {
-			height = height / 2
+			height /= 2
 		}
 
-		mip := resizeImage(mipmaps[len(mipmaps)-1], width, height)
+		prev := mipmaps[len(mipmaps)-1].(*image.NRGBA)
+		mip := resizeImage(prev, width, height, opts)
+		if opts.AlphaCoverage {
+			rescaleAlphaCoverage(mip, baseCoverage)
+		}
 		mipmaps = append(mipmaps, mip)
 	}
 
 	return mipmaps
 }
 
-// resizeImage downsamples an image to the target dimensions using box filtering
-func resizeImage(img image.Image, targetWidth, targetHeight int) image.Image {
+// toNRGBA converts an arbitrary image.Image to *image.NRGBA, passing
+// through an existing one unchanged.
+func toNRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok {
+		return n
+	}
+	bounds := img.Bounds()
+	out := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			out.Set(x, y, img.At(x, y))
+		}
+	}
+	return out
+}
+
+// resizeImage downsamples img to targetWidth x targetHeight using opts'
+// resampling kernel as a separable 1D convolution along each axis (see
+// resampleWithKernel), replacing the old integer box filter that truncated
+// sample radii to whole pixels and dropped fractional coverage entirely -
+// which is what aliased non-power-of-two mip chains before.
+func resizeImage(img *image.NRGBA, targetWidth, targetHeight int, opts MipmapOptions) *image.NRGBA {
+	kernel := kernelFor(opts.Filter, opts.KaiserAlpha)
+	return resampleWithKernel(img, targetWidth, targetHeight, kernel, opts.SRGB, false)
+}
+
+// alphaCoverage reports the fraction of img's pixels with alpha above the
+// 127/255 cutoff conventionally used for alpha-tested rendering.
+func alphaCoverage(img *image.NRGBA) float64 {
+	return alphaCoverageScaled(img, 1)
+}
+
+// alphaCoverageScaled is alphaCoverage as if every pixel's alpha were first
+// multiplied by scale (and clamped to 255), without mutating img.
+func alphaCoverageScaled(img *image.NRGBA, scale float64) float64 {
+	bounds := img.Bounds()
+	total := bounds.Dx() * bounds.Dy()
+	if total == 0 {
+		return 0
+	}
+	var above int
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			a := float64(img.NRGBAAt(x, y).A) * scale
+			if a > 255 {
+				a = 255
+			}
+			if a > 127 {
+				above++
+			}
+		}
+	}
+	return float64(above) / float64(total)
+}
+
+// rescaleAlphaCoverage scales img's alpha channel in place by the factor
+// (found via binary search over alphaCoverageScaled) that brings its
+// alphaCoverage closest to target. A plain resampling filter smooths sharp
+// alpha-test edges toward 0.5 faster than it shrinks the "solid" area they
+// bound, so without this, foliage and cutout text thin out or vanish over a
+// mip chain even though the filter itself did nothing wrong.
+func rescaleAlphaCoverage(img *image.NRGBA, target float64) {
+	lo, hi := 0.0, 4.0
+	for i := 0; i < 20; i++ {
+		mid := (lo + hi) / 2
+		if alphaCoverageScaled(img, mid) < target {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	scale := (lo + hi) / 2
+
 	bounds := img.Bounds()
-	srcWidth := bounds.Dx()
-	srcHeight := bounds.Dy()
-
-	result := image.NewRGBA(image.Rect(0, 0, targetWidth, targetHeight))
-
-	scaleX := float64(srcWidth) / float64(targetWidth)
-	scaleY := float64(srcHeight) / float64(targetHeight)
-
-	for dy := 0; dy < targetHeight; dy++ {
-		for dx := 0; dx < targetWidth; dx++ {
-			// Box filter: average 2x2 block of source pixels
-			sx := int(float64(dx) * scaleX)
-			sy := int(float64(dy) * scaleY)
-
-			var rSum, gSum, bSum, aSum uint32
-			sampleCount := 0
-
-			for ssy := sy; ssy < sy+int(scaleY)+1 && ssy < srcHeight; ssy++ {
-				for ssx := sx; ssx < sx+int(scaleX)+1 && ssx < srcWidth; ssx++ {
-					r, g, b, a := img.At(bounds.Min.X+ssx, bounds.Min.Y+ssy).RGBA()
-					rSum += r
-					gSum += g
-					bSum += b
-					aSum += a
-					sampleCount++
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.NRGBAAt(x, y)
+			a := float64(c.A) * scale
+			if a > 255 {
+				a = 255
+			}
+			c.A = uint8(a + 0.5)
+			img.SetNRGBA(x, y, c)
+		}
+	}
+}
+
+// TIFF tags and field types used by WriteTIFF/ReadTIFF. tiffTypeShort,
+// tiffTypeLong and tiffEntry itself are shared with main.go's own TIFF
+// writer (writeTIFF16/writeTIFFFloat) rather than redefined here - same
+// package, same meaning.
+const (
+	tagNewSubfileType  = 254
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagPhotometric     = 262
+	tagSamplesPerPixel = 277
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagTileByteCounts  = 325
+	tagSubIFDs         = 330
+	tagExtraSamples    = 338
+
+	compressionDeflate           = 8
+	photometricRGB               = 2
+	subfileTypeReducedResolution = 1
+
+	tiffTileSize = 256
+)
+
+// tiffFieldTypeSize returns the byte size of one value of a TIFF field
+// type (only the types WriteTIFF/ReadTIFF actually use).
+func tiffFieldTypeSize(typ uint16) int {
+	switch typ {
+	case tiffTypeShort:
+		return 2
+	case tiffTypeLong:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// extraSamplesForFormat returns the TIFF ExtraSamples (338) value
+// describing the alpha channel GenerateMipmaps always keeps in its NRGBA
+// mips: BC1's binary cutout and BC3's smooth alpha are both real,
+// un-premultiplied transparency (2 = unassociated alpha); BC5 stores
+// tangent-space X/Y and has no real alpha channel, so its NRGBA alpha is
+// unspecified data (0).
+func extraSamplesForFormat(format BCFormat) uint16 {
+	switch format {
+	case BC1, BC3:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// deflateBytes compresses data with compress/flate, the same raw-Deflate
+// format TIFF's Compression=8 specifies.
+func deflateBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// inflateBytes reverses deflateBytes.
+func inflateBytes(data []byte) ([]byte, error) {
+	zr := flate.NewReader(bytes.NewReader(data))
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// splitTiles slices img into tiffTileSize x tiffTileSize RGBA tiles in
+// row-major order, padding bottom/right edge tiles with zero past img's
+// real width/height - TIFF requires every tile to be the full TileWidth x
+// TileLength even at the image's edge.
+func splitTiles(img *image.NRGBA) (cols, rows int, tiles [][]byte) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	cols = (width + tiffTileSize - 1) / tiffTileSize
+	rows = (height + tiffTileSize - 1) / tiffTileSize
+
+	tiles = make([][]byte, 0, cols*rows)
+	for ty := 0; ty < rows; ty++ {
+		for tx := 0; tx < cols; tx++ {
+			tile := make([]byte, tiffTileSize*tiffTileSize*4)
+			for y := 0; y < tiffTileSize; y++ {
+				srcY := ty*tiffTileSize + y
+				if srcY >= height {
+					continue
+				}
+				for x := 0; x < tiffTileSize; x++ {
+					srcX := tx*tiffTileSize + x
+					if srcX >= width {
+						continue
+					}
+					c := img.NRGBAAt(bounds.Min.X+srcX, bounds.Min.Y+srcY)
+					off := (y*tiffTileSize + x) * 4
+					tile[off], tile[off+1], tile[off+2], tile[off+3] = c.R, c.G, c.B, c.A
+				}
+			}
+			tiles = append(tiles, tile)
+		}
+	}
+	return cols, rows, tiles
+}
+
+// tiffLevel holds one mip level's precomputed, Deflate-compressed tile
+// data, plus its IFD's file offset and each tile's absolute file offset
+// once WriteTIFF's layout pass has run.
+type tiffLevel struct {
+	width, height int
+	cols, rows    int
+	tiles         [][]byte
+
+	ifdOffset      uint32
+	tileAbsOffsets []uint32
+}
+
+// WriteTIFF serializes mips (base level first, then each successive mip,
+// as GenerateMipmaps returns them) as a single multi-image, tiled,
+// Deflate-compressed TIFF: the base level is the primary IFD, and every
+// further mip is linked from it via a SubIFDs (330) array of
+// reduced-resolution (NewSubfileType=1) IFDs, so the whole chain opens as
+// one file in standard TIFF-aware tools instead of needing N separate
+// images. format only affects the ExtraSamples tag (see
+// extraSamplesForFormat) - every level is written as 4 samples/pixel
+// (RGBA) regardless, since GenerateMipmaps' mips are always *image.NRGBA.
+func WriteTIFF(w io.Writer, mips []image.Image, format BCFormat) error {
+	if len(mips) == 0 {
+		return fmt.Errorf("no mip levels to write")
+	}
+	bo := binary.LittleEndian
+	extraSamples := extraSamplesForFormat(format)
+
+	levels := make([]*tiffLevel, len(mips))
+	for i, m := range mips {
+		img := toNRGBA(m)
+		bounds := img.Bounds()
+		if bounds.Dx() <= 0 || bounds.Dy() <= 0 {
+			return fmt.Errorf("mip %d: invalid bounds %v", i, bounds)
+		}
+		cols, rows, raw := splitTiles(img)
+		tiles := make([][]byte, len(raw))
+		for t, tile := range raw {
+			c, err := deflateBytes(tile)
+			if err != nil {
+				return fmt.Errorf("compress mip %d tile %d: %w", i, t, err)
+			}
+			tiles[t] = c
+		}
+		levels[i] = &tiffLevel{width: bounds.Dx(), height: bounds.Dy(), cols: cols, rows: rows, tiles: tiles}
+	}
+
+	entryCount := func(i int) int {
+		n := 11 // width, length, bitsPerSample, compression, photometric, samplesPerPixel, tileWidth, tileLength, tileOffsets, tileByteCounts, extraSamples
+		if i == 0 && len(levels) > 1 {
+			n++ // SubIFDs
+		}
+		if i > 0 {
+			n++ // NewSubfileType
+		}
+		return n
+	}
+
+	const headerSize = 8
+	offset := uint32(headerSize)
+
+	// Per TIFF 6.0, a field whose value fits in 4 bytes is stored directly
+	// in its directory entry's valOrOff rather than pointing at an
+	// out-of-line block - ReadTIFF's readTIFFValues already assumes this,
+	// so the layout below only reserves a block when a field doesn't fit.
+	numSubIFDs := len(levels) - 1
+	subIFDsInline := numSubIFDs == 1
+
+	bitsPerSampleOff := make([]uint32, len(levels))
+	tileOffsetsOff := make([]uint32, len(levels))
+	tileByteCountsOff := make([]uint32, len(levels))
+	tileOffsetsInline := make([]bool, len(levels))
+	tileByteCountsInline := make([]bool, len(levels))
+	var subIFDsOff uint32
+
+	for i, lvl := range levels {
+		lvl.ifdOffset = offset
+		offset += uint32(2 + entryCount(i)*12 + 4)
+
+		bitsPerSampleOff[i] = offset
+		offset += 4 * 2 // 4 uint16 samples, always out-of-line (8 bytes)
+
+		tileOffsetsInline[i] = len(lvl.tiles) == 1
+		if !tileOffsetsInline[i] {
+			tileOffsetsOff[i] = offset
+			offset += uint32(len(lvl.tiles)) * 4
+		}
+
+		tileByteCountsInline[i] = len(lvl.tiles) == 1
+		if !tileByteCountsInline[i] {
+			tileByteCountsOff[i] = offset
+			offset += uint32(len(lvl.tiles)) * 4
+		}
+
+		if i == 0 && numSubIFDs > 0 && !subIFDsInline {
+			subIFDsOff = offset
+			offset += uint32(numSubIFDs) * 4
+		}
+
+		lvl.tileAbsOffsets = make([]uint32, len(lvl.tiles))
+		for t, tile := range lvl.tiles {
+			lvl.tileAbsOffsets[t] = offset
+			offset += uint32(len(tile))
+		}
+	}
+
+	var out bytes.Buffer
+	out.Write([]byte("II"))
+	binary.Write(&out, bo, uint16(42))
+	binary.Write(&out, bo, uint32(headerSize))
+
+	bitsPerSample := []uint16{8, 8, 8, 8}
+
+	for i, lvl := range levels {
+		tileOffsetsVal := tileOffsetsOff[i]
+		if tileOffsetsInline[i] {
+			tileOffsetsVal = lvl.tileAbsOffsets[0]
+		}
+		tileByteCountsVal := tileByteCountsOff[i]
+		if tileByteCountsInline[i] {
+			tileByteCountsVal = uint32(len(lvl.tiles[0]))
+		}
+
+		entries := make([]tiffEntry, 0, entryCount(i))
+		if i > 0 {
+			entries = append(entries, tiffEntry{tag: tagNewSubfileType, typ: tiffTypeLong, count: 1, valOrOff: subfileTypeReducedResolution})
+		}
+		entries = append(entries,
+			tiffEntry{tag: tagImageWidth, typ: tiffTypeLong, count: 1, valOrOff: uint32(lvl.width)},
+			tiffEntry{tag: tagImageLength, typ: tiffTypeLong, count: 1, valOrOff: uint32(lvl.height)},
+			tiffEntry{tag: tagBitsPerSample, typ: tiffTypeShort, count: 4, valOrOff: bitsPerSampleOff[i]},
+			tiffEntry{tag: tagCompression, typ: tiffTypeShort, count: 1, valOrOff: compressionDeflate},
+			tiffEntry{tag: tagPhotometric, typ: tiffTypeShort, count: 1, valOrOff: photometricRGB},
+			tiffEntry{tag: tagSamplesPerPixel, typ: tiffTypeShort, count: 1, valOrOff: 4},
+			tiffEntry{tag: tagTileWidth, typ: tiffTypeLong, count: 1, valOrOff: tiffTileSize},
+			tiffEntry{tag: tagTileLength, typ: tiffTypeLong, count: 1, valOrOff: tiffTileSize},
+			tiffEntry{tag: tagTileOffsets, typ: tiffTypeLong, count: uint32(len(lvl.tiles)), valOrOff: tileOffsetsVal},
+			tiffEntry{tag: tagTileByteCounts, typ: tiffTypeLong, count: uint32(len(lvl.tiles)), valOrOff: tileByteCountsVal},
+		)
+		if i == 0 && numSubIFDs > 0 {
+			subIFDsVal := subIFDsOff
+			if subIFDsInline {
+				subIFDsVal = levels[1].ifdOffset
+			}
+			entries = append(entries, tiffEntry{tag: tagSubIFDs, typ: tiffTypeLong, count: uint32(numSubIFDs), valOrOff: subIFDsVal})
+		}
+		entries = append(entries, tiffEntry{tag: tagExtraSamples, typ: tiffTypeShort, count: 1, valOrOff: uint32(extraSamples)})
+		sort.Slice(entries, func(a, b int) bool { return entries[a].tag < entries[b].tag })
+
+		binary.Write(&out, bo, uint16(len(entries)))
+		for _, e := range entries {
+			binary.Write(&out, bo, e.tag)
+			binary.Write(&out, bo, e.typ)
+			binary.Write(&out, bo, e.count)
+			binary.Write(&out, bo, e.valOrOff)
+		}
+		binary.Write(&out, bo, uint32(0)) // no next IFD; SubIFDs links the mips instead
+
+		for _, v := range bitsPerSample {
+			binary.Write(&out, bo, v)
+		}
+		if !tileOffsetsInline[i] {
+			for _, off := range lvl.tileAbsOffsets {
+				binary.Write(&out, bo, off)
+			}
+		}
+		if !tileByteCountsInline[i] {
+			for _, tile := range lvl.tiles {
+				binary.Write(&out, bo, uint32(len(tile)))
+			}
+		}
+		if i == 0 && numSubIFDs > 1 {
+			for j := 1; j < len(levels); j++ {
+				binary.Write(&out, bo, levels[j].ifdOffset)
+			}
+		}
+		for _, tile := range lvl.tiles {
+			out.Write(tile)
+		}
+	}
+
+	if _, err := w.Write(out.Bytes()); err != nil {
+		return fmt.Errorf("write TIFF: %w", err)
+	}
+	return nil
+}
+
+// parseTIFFIFD reads the IFD at offset, returning its entries keyed by tag
+// and the file offset of the next IFD in its own chain (0 if none - mip
+// IFDs reached via SubIFDs aren't chained to each other this way).
+func parseTIFFIFD(data []byte, offset uint32, bo binary.ByteOrder) (map[uint16]tiffEntry, uint32, error) {
+	if int(offset)+2 > len(data) {
+		return nil, 0, fmt.Errorf("IFD offset %d out of range", offset)
+	}
+	count := int(bo.Uint16(data[offset:]))
+	entriesStart := int(offset) + 2
+	entriesEnd := entriesStart + count*12
+	if entriesEnd+4 > len(data) {
+		return nil, 0, fmt.Errorf("IFD at %d overruns file", offset)
+	}
+
+	entries := make(map[uint16]tiffEntry, count)
+	for i := 0; i < count; i++ {
+		e := data[entriesStart+i*12:]
+		tag := bo.Uint16(e[0:2])
+		entries[tag] = tiffEntry{
+			tag:      tag,
+			typ:      bo.Uint16(e[2:4]),
+			count:    bo.Uint32(e[4:8]),
+			valOrOff: bo.Uint32(e[8:12]),
+		}
+	}
+	return entries, bo.Uint32(data[entriesEnd:]), nil
+}
+
+// readTIFFValues resolves entry's value array, whether packed inline in
+// its directory entry or stored out-of-line at valOrOff.
+func readTIFFValues(data []byte, e tiffEntry, bo binary.ByteOrder) ([]uint32, error) {
+	size := tiffFieldTypeSize(e.typ)
+	if size == 0 {
+		return nil, fmt.Errorf("tag %d: unsupported field type %d", e.tag, e.typ)
+	}
+	total := size * int(e.count)
+
+	var raw []byte
+	if total <= 4 {
+		buf := make([]byte, 4)
+		bo.PutUint32(buf, e.valOrOff)
+		raw = buf[:total]
+	} else {
+		if int(e.valOrOff)+total > len(data) {
+			return nil, fmt.Errorf("tag %d: value array out of range", e.tag)
+		}
+		raw = data[e.valOrOff : int(e.valOrOff)+total]
+	}
+
+	values := make([]uint32, e.count)
+	for i := range values {
+		switch e.typ {
+		case tiffTypeShort:
+			values[i] = uint32(bo.Uint16(raw[i*2:]))
+		case tiffTypeLong:
+			values[i] = bo.Uint32(raw[i*4:])
+		}
+	}
+	return values, nil
+}
+
+// decodeTIFFLevel decodes the tiled, Deflate-compressed image described by
+// the IFD at ifdOffset into an *image.NRGBA.
+func decodeTIFFLevel(data []byte, ifdOffset uint32, bo binary.ByteOrder) (*image.NRGBA, error) {
+	entries, _, err := parseTIFFIFD(data, ifdOffset, bo)
+	if err != nil {
+		return nil, err
+	}
+	width := int(entries[tagImageWidth].valOrOff)
+	height := int(entries[tagImageLength].valOrOff)
+	tileWidth := int(entries[tagTileWidth].valOrOff)
+	tileLength := int(entries[tagTileLength].valOrOff)
+	if tileWidth <= 0 || tileLength <= 0 {
+		return nil, fmt.Errorf("IFD at %d: missing tile dimensions", ifdOffset)
+	}
+
+	tileOffsets, err := readTIFFValues(data, entries[tagTileOffsets], bo)
+	if err != nil {
+		return nil, err
+	}
+	tileByteCounts, err := readTIFFValues(data, entries[tagTileByteCounts], bo)
+	if err != nil {
+		return nil, err
+	}
+
+	cols := (width + tileWidth - 1) / tileWidth
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for t := range tileOffsets {
+		start, end := tileOffsets[t], tileOffsets[t]+tileByteCounts[t]
+		if int(end) > len(data) {
+			return nil, fmt.Errorf("IFD at %d: tile %d out of range", ifdOffset, t)
+		}
+		raw, err := inflateBytes(data[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("IFD at %d: inflate tile %d: %w", ifdOffset, t, err)
+		}
+
+		tx, ty := t%cols, t/cols
+		for y := 0; y < tileLength; y++ {
+			dstY := ty*tileLength + y
+			if dstY >= height {
+				break
+			}
+			for x := 0; x < tileWidth; x++ {
+				dstX := tx*tileWidth + x
+				if dstX >= width {
+					continue
 				}
+				off := (y*tileWidth + x) * 4
+				img.SetNRGBA(dstX, dstY, color.NRGBA{R: raw[off], G: raw[off+1], B: raw[off+2], A: raw[off+3]})
 			}
+		}
+	}
+	return img, nil
+}
+
+// ReadTIFF reconstructs the mip chain a WriteTIFF call produced: the
+// primary IFD as the base level, followed by whatever IFDs its SubIFDs
+// tag points at, in order.
+func ReadTIFF(r io.Reader) ([]image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read TIFF: %w", err)
+	}
+	if len(data) < 8 {
+		return nil, fmt.Errorf("not a valid TIFF: too short")
+	}
+
+	var bo binary.ByteOrder
+	switch {
+	case bytes.Equal(data[0:2], []byte("II")):
+		bo = binary.LittleEndian
+	case bytes.Equal(data[0:2], []byte("MM")):
+		bo = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("not a valid TIFF: bad byte order marker")
+	}
+
+	baseOffset := bo.Uint32(data[4:8])
+	baseEntries, _, err := parseTIFFIFD(data, baseOffset, bo)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := decodeTIFFLevel(data, baseOffset, bo)
+	if err != nil {
+		return nil, fmt.Errorf("decode base level: %w", err)
+	}
+	mips := []image.Image{base}
 
-			if sampleCount > 0 {
-				result.SetRGBA(dx, dy, color.RGBA{
-					R: uint8((rSum / uint32(sampleCount)) >> 8),
-					G: uint8((gSum / uint32(sampleCount)) >> 8),
-					B: uint8((bSum / uint32(sampleCount)) >> 8),
-					A: uint8((aSum / uint32(sampleCount)) >> 8),
-				})
+	if sub, ok := baseEntries[tagSubIFDs]; ok {
+		subOffsets, err := readTIFFValues(data, sub, bo)
+		if err != nil {
+			return nil, err
+		}
+		for i, off := range subOffsets {
+			level, err := decodeTIFFLevel(data, off, bo)
+			if err != nil {
+				return nil, fmt.Errorf("decode mip %d: %w", i+1, err)
+			}
+			mips = append(mips, level)
+		}
+	}
+	return mips, nil
+}
+
+// ResampleFilter selects the kernel GenerateMipChain uses to downsample
+// each mip level, in the style of the disintegration/imaging resize
+// package.
+type ResampleFilter int
+
+const (
+	// FilterBox is a simple averaging filter: fast, but can alias on
+	// sharp edges.
+	FilterBox ResampleFilter = iota
+	// FilterBilinear is a triangle filter: smoother than Box, still cheap.
+	FilterBilinear
+	// FilterCatmullRom is a sharpening cubic filter; the common default
+	// for quality upscaling.
+	FilterCatmullRom
+	// FilterLanczos3 is a windowed-sinc filter with the widest support;
+	// typically the sharpest downsampling result of these filters.
+	FilterLanczos3
+	// FilterKaiser is a windowed-sinc filter using a Kaiser window instead
+	// of Lanczos3's sinc-of-a-sinc window; its alpha parameter (see
+	// MipmapOptions.KaiserAlpha) trades main-lobe width for sidelobe
+	// suppression, where Lanczos3's shape is fixed.
+	FilterKaiser
+)
+
+// defaultKaiserAlpha is FilterKaiser's window shape parameter when
+// MipmapOptions.KaiserAlpha is left at zero - a typical middle-ground value
+// between a sharp, ringing-prone window (low alpha; alpha 0 is a plain
+// truncated sinc) and a soft one (high alpha).
+const defaultKaiserAlpha = 4.0
+
+// resampleKernel pairs a filter's continuous weight function with its
+// support radius in source-pixel units (the distance at which the
+// function is defined to be zero).
+type resampleKernel struct {
+	support float64
+	weight  func(x float64) float64
+}
+
+// kernelFor returns filter's weight function and support radius.
+// kaiserAlpha only affects FilterKaiser; <= 0 uses defaultKaiserAlpha.
+func kernelFor(filter ResampleFilter, kaiserAlpha float64) resampleKernel {
+	switch filter {
+	case FilterBilinear:
+		return resampleKernel{support: 1, weight: func(x float64) float64 {
+			if x < 0 {
+				x = -x
+			}
+			if x < 1 {
+				return 1 - x
 			}
+			return 0
+		}}
+	case FilterCatmullRom:
+		return resampleKernel{support: 2, weight: catmullRomWeight}
+	case FilterLanczos3:
+		return resampleKernel{support: 3, weight: lanczos3Weight}
+	case FilterKaiser:
+		if kaiserAlpha <= 0 {
+			kaiserAlpha = defaultKaiserAlpha
 		}
+		return resampleKernel{support: 3, weight: kaiserWeight(kaiserAlpha)}
+	default: // FilterBox
+		return resampleKernel{support: 0.5, weight: func(x float64) float64 {
+			if x < 0 {
+				x = -x
+			}
+			if x < 0.5 {
+				return 1
+			}
+			return 0
+		}}
 	}
+}
 
-	return result
+// catmullRomWeight is the Catmull-Rom cubic kernel (A=-0.5 in the general
+// Mitchell-Netravali family), support radius 2.
+func catmullRomWeight(x float64) float64 {
+	if x < 0 {
+		x = -x
+	}
+	switch {
+	case x < 1:
+		return (1.5*x-2.5)*x*x + 1
+	case x < 2:
+		return ((-0.5*x+2.5)*x-4)*x + 2
+	default:
+		return 0
+	}
+}
+
+// sinc is the normalized sinc function used by lanczos3Weight.
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	px := math.Pi * x
+	return math.Sin(px) / px
+}
+
+// lanczos3Weight is the 3-lobe Lanczos kernel, support radius 3.
+func lanczos3Weight(x float64) float64 {
+	if x < 0 {
+		x = -x
+	}
+	if x < 3 {
+		return sinc(x) * sinc(x/3)
+	}
+	return 0
+}
+
+// besselI0 approximates the zeroth-order modified Bessel function of the
+// first kind via its power series - 20 terms is far more than enough
+// precision for a windowing function.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k <= 20; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+	}
+	return sum
+}
+
+// kaiserWeight returns a windowed-sinc kernel, support radius 3, using a
+// Kaiser window (shape parameter alpha) in place of Lanczos3's sinc(x/3)
+// window - higher alpha narrows the main lobe and suppresses sidelobes
+// further, at the cost of a softer cutoff.
+func kaiserWeight(alpha float64) func(x float64) float64 {
+	const support = 3.0
+	denom := besselI0(alpha)
+	return func(x float64) float64 {
+		if x < 0 {
+			x = -x
+		}
+		if x >= support {
+			return 0
+		}
+		t := x / support
+		window := besselI0(alpha*math.Sqrt(1-t*t)) / denom
+		return sinc(x) * window
+	}
+}
+
+// resampleContrib holds one output sample's normalized, already-clamped
+// contributing source range and weights along a single axis.
+type resampleContrib struct {
+	start   int
+	weights []float64
+}
+
+// resampleWeights computes each destination index's contributing source
+// range and normalized weights for a resize from srcSize to dstSize. When
+// downsampling, the kernel's support is widened by the scale factor (the
+// standard "filter scaling" trick) so every source pixel still influences
+// the output instead of being skipped between sample points.
+func resampleWeights(srcSize, dstSize int, kernel resampleKernel) []resampleContrib {
+	scale := float64(srcSize) / float64(dstSize)
+	filterScale := math.Max(scale, 1.0)
+	support := kernel.support * filterScale
+
+	contribs := make([]resampleContrib, dstSize)
+	for i := 0; i < dstSize; i++ {
+		center := (float64(i)+0.5)*scale - 0.5
+		start := int(math.Floor(center - support))
+		end := int(math.Ceil(center + support))
+		if start < 0 {
+			start = 0
+		}
+		if end > srcSize-1 {
+			end = srcSize - 1
+		}
+
+		weights := make([]float64, end-start+1)
+		var sum float64
+		for j := start; j <= end; j++ {
+			w := kernel.weight((float64(j) - center) / filterScale)
+			weights[j-start] = w
+			sum += w
+		}
+		if sum != 0 {
+			for k := range weights {
+				weights[k] /= sum
+			}
+		}
+		contribs[i] = resampleContrib{start: start, weights: weights}
+	}
+	return contribs
+}
+
+// resampleNRGBA downsamples src to dstW x dstH with filter. When linear is
+// set (an sRGB DXGI format), color channels are converted through
+// srgbToLinear before filtering and linearToSrgb after, so averaging
+// happens in linear light instead of gamma space. When premultiply is
+// set, color channels are premultiplied by alpha before filtering and
+// un-premultiplied after, so transparent texels don't bleed their stored
+// color into opaque neighbors; when it's unset, color and alpha are
+// filtered independently, which instead preserves each mip's average
+// alpha/coverage rather than biasing it toward whatever color sits behind
+// fully-transparent texels.
+func resampleNRGBA(src *image.NRGBA, dstW, dstH int, filter ResampleFilter, linear, premultiply bool) *image.NRGBA {
+	return resampleWithKernel(src, dstW, dstH, kernelFor(filter, 0), linear, premultiply)
+}
+
+// resampleWithKernel is resampleNRGBA's body taking an already-resolved
+// kernel directly, so callers that need a filter option kernelFor doesn't
+// cover by name alone (FilterKaiser's alpha parameter) can build one with
+// kernelFor themselves instead of going through a filter enum value.
+func resampleWithKernel(src *image.NRGBA, dstW, dstH int, kernel resampleKernel, linear, premultiply bool) *image.NRGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	r := make([]float64, srcW*srcH)
+	g := make([]float64, srcW*srcH)
+	b := make([]float64, srcW*srcH)
+	a := make([]float64, srcW*srcH)
+	for y := 0; y < srcH; y++ {
+		for x := 0; x < srcW; x++ {
+			c := src.NRGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+			idx := y*srcW + x
+			av := float64(c.A) / 255
+			var rv, gv, bv float64
+			if linear {
+				rv, gv, bv = float64(srgbToLinear(c.R)), float64(srgbToLinear(c.G)), float64(srgbToLinear(c.B))
+			} else {
+				rv, gv, bv = float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+			}
+			if premultiply {
+				rv, gv, bv = rv*av, gv*av, bv*av
+			}
+			r[idx], g[idx], b[idx], a[idx] = rv, gv, bv, av
+		}
+	}
+
+	// Horizontal pass.
+	hContribs := resampleWeights(srcW, dstW, kernel)
+	hr := make([]float64, dstW*srcH)
+	hg := make([]float64, dstW*srcH)
+	hb := make([]float64, dstW*srcH)
+	ha := make([]float64, dstW*srcH)
+	for y := 0; y < srcH; y++ {
+		rowOff := y * srcW
+		for x := 0; x < dstW; x++ {
+			c := hContribs[x]
+			var sr, sg, sb, sa float64
+			for i, w := range c.weights {
+				idx := rowOff + c.start + i
+				sr += r[idx] * w
+				sg += g[idx] * w
+				sb += b[idx] * w
+				sa += a[idx] * w
+			}
+			dstIdx := y*dstW + x
+			hr[dstIdx], hg[dstIdx], hb[dstIdx], ha[dstIdx] = sr, sg, sb, sa
+		}
+	}
+
+	// Vertical pass.
+	vContribs := resampleWeights(srcH, dstH, kernel)
+	out := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	for x := 0; x < dstW; x++ {
+		for y := 0; y < dstH; y++ {
+			c := vContribs[y]
+			var sr, sg, sb, sa float64
+			for i, w := range c.weights {
+				idx := (c.start+i)*dstW + x
+				sr += hr[idx] * w
+				sg += hg[idx] * w
+				sb += hb[idx] * w
+				sa += ha[idx] * w
+			}
+
+			if premultiply && sa > 0 {
+				sr, sg, sb = sr/sa, sg/sa, sb/sa
+			}
+			sr, sg, sb = clamp01(sr), clamp01(sg), clamp01(sb)
+			sa = clamp01(sa)
+
+			var r8, g8, b8 uint8
+			if linear {
+				r8, g8, b8 = linearToSrgb(float32(sr)), linearToSrgb(float32(sg)), linearToSrgb(float32(sb))
+			} else {
+				r8, g8, b8 = uint8(sr*255+0.5), uint8(sg*255+0.5), uint8(sb*255+0.5)
+			}
+			out.SetNRGBA(x, y, color.NRGBA{R: r8, G: g8, B: b8, A: uint8(sa*255 + 0.5)})
+		}
+	}
+	return out
+}
+
+// clamp01 clamps v to [0, 1], guarding against filter ringing (Catmull-Rom
+// and Lanczos3 both have negative lobes) pushing a value out of range.
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// bcFormatForDXGI maps a DXGI format to the BCFormat GenerateMipChain
+// should compress mips with.
+func bcFormatForDXGI(dxgiFormat uint32) (BCFormat, error) {
+	switch dxgiFormat {
+	case DXGIFormatBC1Unorm, DXGIFormatBC1UnormSRGB:
+		return BC1, nil
+	case DXGIFormatBC3Unorm, DXGIFormatBC3UnormSRGB:
+		return BC3, nil
+	case DXGIFormatBC5Unorm, DXGIFormatBC5SNorm:
+		return BC5, nil
+	default:
+		return 0, fmt.Errorf("unsupported DXGI format for mip chain generation: %d", dxgiFormat)
+	}
+}
+
+// GenerateMipChain builds a full power-of-two mip chain for base down to
+// 1x1, resampling each level from the one above it with filter and
+// BC-compressing it to dxgiFormat, so the result can be concatenated
+// directly into writeDDSFile's compressedData argument. For the two *_SRGB
+// DXGI formats, resampling is done in linear light via srgbToLinear/
+// linearToSrgb instead of averaging gamma-encoded samples directly. alpha
+// selects how alpha interacts with color during filtering; see
+// resampleNRGBA.
+func GenerateMipChain(base *image.NRGBA, dxgiFormat uint32, filter ResampleFilter, alpha bool) ([][]byte, error) {
+	format, err := bcFormatForDXGI(dxgiFormat)
+	if err != nil {
+		return nil, err
+	}
+	isSRGB := dxgiFormat == DXGIFormatBC1UnormSRGB || dxgiFormat == DXGIFormatBC3UnormSRGB
+
+	bounds := base.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid dimensions %dx%d", width, height)
+	}
+
+	level := base
+	var chain [][]byte
+	for {
+		compressed, err := CompressBC(level, format)
+		if err != nil {
+			return nil, fmt.Errorf("compress mip %dx%d: %w", width, height, err)
+		}
+		chain = append(chain, compressed)
+
+		if width == 1 && height == 1 {
+			break
+		}
+		if width > 1 {
+			width /= 2
+		}
+		if height > 1 {
+			height /= 2
+		}
+		level = resampleNRGBA(level, width, height, filter, isSRGB, alpha)
+	}
+	return chain, nil
 }
@@ -0,0 +1,89 @@
+//go:build cgo
+
+// encoder_cgo.go - BC texture compression using libsquish via CGo. Only
+// built when cgo is enabled and libsquish is available to the linker; see
+// encoder_nocgo.go for the pure-Go fallback CompressBC takes under
+// CGO_ENABLED=0.
+
+package main
+
+/*
+#cgo LDFLAGS: -lsquish -lstdc++
+#cgo CXXFLAGS: -std=c++11
+#include "squish_wrapper.h"
+*/
+import "C"
+import (
+	"fmt"
+	"image"
+	"unsafe"
+)
+
+// CompressBC compresses RGBA image data to BC format using libsquish
+func CompressBC(img image.Image, format BCFormat) ([]byte, error) {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	// Convert image to RGBA format that libsquish expects
+	rgba := imageToRGBA(img)
+
+	// Determine squish flags
+	var flags C.int
+
+	switch format {
+	case BC1:
+		flags = C.SQUISH_DXT1 | C.SQUISH_COLOUR_CLUSTER_FIT // DXT1, high quality
+	case BC3:
+		flags = C.SQUISH_DXT5 | C.SQUISH_COLOUR_CLUSTER_FIT // DXT5, high quality
+	case BC5:
+		flags = C.SQUISH_BC5
+	default:
+		return nil, fmt.Errorf("unsupported BC format: %d", format)
+	}
+
+	// Calculate storage requirements
+	storageSize := C.squish_get_storage_requirements(C.int(width), C.int(height), flags)
+	if storageSize <= 0 {
+		return nil, fmt.Errorf("invalid storage size: %d", storageSize)
+	}
+
+	// Allocate output buffer
+	compressed := make([]byte, storageSize)
+
+	// Compress using libsquish
+	C.squish_compress_image(
+		(*C.uchar)(unsafe.Pointer(&rgba[0])),
+		C.int(width),
+		C.int(height),
+		unsafe.Pointer(&compressed[0]),
+		flags,
+	)
+
+	return compressed, nil
+}
+
+// imageToRGBA converts an image.Image to RGBA byte array in the format libsquish expects
+// Format: r1,g1,b1,a1, r2,g2,b2,a2, ..., rn,gn,bn,an
+func imageToRGBA(img image.Image) []byte {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	rgba := make([]byte, width*height*4)
+	idx := 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			// Convert from 16-bit to 8-bit
+			rgba[idx+0] = uint8(r >> 8)
+			rgba[idx+1] = uint8(g >> 8)
+			rgba[idx+2] = uint8(b >> 8)
+			rgba[idx+3] = uint8(a >> 8)
+			idx += 4
+		}
+	}
+
+	return rgba
+}
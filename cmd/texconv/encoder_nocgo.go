@@ -0,0 +1,41 @@
+//go:build !cgo
+
+// encoder_nocgo.go - pure-Go BC texture compression fallback for
+// CGO_ENABLED=0 builds, where libsquish can't be linked (see
+// encoder_cgo.go). Quality is whatever pkg/texture's PCA-based encoder
+// produces, not libsquish's cluster fit, but it keeps this tool building
+// and able to encode in environments without the CGo toolchain.
+
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/EchoTools/evrFileTools/pkg/texture"
+)
+
+// CompressBC compresses img to BC format using pkg/texture's pure-Go
+// encoder.
+func CompressBC(img image.Image, format BCFormat) ([]byte, error) {
+	pf, err := pureGoBCFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	return texture.CompressBC(toNRGBA(img), pf)
+}
+
+// pureGoBCFormat maps this package's BCFormat (BC1/BC3/BC5 - see its doc
+// comment) onto pkg/texture's equivalent.
+func pureGoBCFormat(format BCFormat) (texture.BCFormat, error) {
+	switch format {
+	case BC1:
+		return texture.BC1, nil
+	case BC3:
+		return texture.BC3, nil
+	case BC5:
+		return texture.BC5, nil
+	default:
+		return 0, fmt.Errorf("unsupported BC format: %d", format)
+	}
+}
@@ -13,6 +13,8 @@
 //
 // Usage:
 //   texconv decode input.dds output.png    # DDS → PNG (lossless storage)
+//   texconv decode input.dds output.tif --format tifffloat
+//                                           # DDS → HDR TIFF (R11G11B10_FLOAT only)
 //   texconv encode input.png output.dds    # PNG → DDS (BC compression)
 //   texconv info input.dds                 # Show texture info
 //   texconv batch decode dir/ out/         # Batch convert directory
@@ -20,7 +22,13 @@
 package main
 
 import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"image"
 	"image/png"
@@ -28,7 +36,11 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -136,11 +148,25 @@ func main() {
 
 	switch command {
 	case "decode":
-		if len(os.Args) != 4 {
-			fmt.Fprintf(os.Stderr, "Usage: texconv decode input.dds output.png\n")
+		if len(os.Args) < 4 {
+			fmt.Fprintf(os.Stderr, "Usage: texconv decode input.dds output.png [--reconstruct-normal] [--format png|tiff16|tifffloat] [--compress]\n")
+			os.Exit(1)
+		}
+		fs := flag.NewFlagSet("decode", flag.ExitOnError)
+		reconstructNormal := fs.Bool("reconstruct-normal", false, "fill BC5 normal maps' unused blue channel with the reconstructed Z")
+		formatFlag := fs.String("format", "png", "output format: png, tiff16, or tifffloat")
+		compress := fs.Bool("compress", false, "zlib-compress TIFF output (ignored for png)")
+		if err := fs.Parse(os.Args[4:]); err != nil {
+			os.Exit(1)
+		}
+
+		format, err := parseExportFormat(*formatFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		if err := decodeDDS(os.Args[2], os.Args[3]); err != nil {
+
+		if err := decodeDDS(os.Args[2], os.Args[3], *reconstructNormal, format, *compress); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -168,11 +194,21 @@ func main() {
 		}
 
 	case "batch":
-		if len(os.Args) != 5 {
-			fmt.Fprintf(os.Stderr, "Usage: texconv batch decode|encode input_dir output_dir\n")
+		if len(os.Args) < 5 {
+			fmt.Fprintf(os.Stderr, "Usage: texconv batch decode|encode input_dir output_dir [--jobs N] [--manifest file.json] [--stop-on-error]\n")
 			os.Exit(1)
 		}
-		if err := batchConvert(os.Args[2], os.Args[3], os.Args[4]); err != nil {
+		mode, inputDir, outputDir := os.Args[2], os.Args[3], os.Args[4]
+
+		fs := flag.NewFlagSet("batch", flag.ExitOnError)
+		jobs := fs.Int("jobs", runtime.NumCPU(), "number of concurrent conversion workers")
+		manifestPath := fs.String("manifest", "", "JSON manifest recording completed conversions; re-running skips jobs it already covers")
+		stopOnError := fs.Bool("stop-on-error", false, "stop the batch on the first conversion error instead of continuing")
+		if err := fs.Parse(os.Args[5:]); err != nil {
+			os.Exit(1)
+		}
+
+		if err := batchConvert(mode, inputDir, outputDir, *jobs, *manifestPath, *stopOnError); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -188,10 +224,12 @@ func printUsage() {
 	fmt.Println("texconv - Lossless DDS texture converter for EchoVR")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  texconv decode <input.dds> <output.png>    # DDS → PNG")
+	fmt.Println("  texconv decode <input.dds> <output> [--reconstruct-normal] [--format png|tiff16|tifffloat] [--compress]")
+	fmt.Println("                                              # DDS → PNG/TIFF")
 	fmt.Println("  texconv encode <input.png> <output.dds>    # PNG → DDS")
 	fmt.Println("  texconv info <input.dds>                   # Show info")
-	fmt.Println("  texconv batch <decode|encode> <dir> <out>  # Batch convert")
+	fmt.Println("  texconv batch <decode|encode> <dir> <out> [--jobs N] [--manifest file.json] [--stop-on-error]")
+	fmt.Println("                                              # Batch convert")
 	fmt.Println()
 	fmt.Println("Supported formats:")
 	fmt.Println("  BC1 (DXT1)  - RGB + 1-bit alpha")
@@ -201,8 +239,56 @@ func printUsage() {
 	fmt.Println("  BC7         - High quality RGBA")
 }
 
-// decodeDDS reads a DDS file and converts it to PNG
-func decodeDDS(inputPath, outputPath string) error {
+// ExportFormat selects decodeDDS's output container.
+type ExportFormat int
+
+const (
+	// FormatPNG is the default 8-bit-per-channel PNG output.
+	FormatPNG ExportFormat = iota
+	// FormatTIFF16 writes a 16-bit-per-channel TIFF, preserving more
+	// precision than an 8-bit PNG can; values above 1.0 still clip.
+	FormatTIFF16
+	// FormatTIFFFloat writes a 32-bit IEEE-float-per-channel TIFF,
+	// preserving an HDR format's full decoded range losslessly.
+	FormatTIFFFloat
+)
+
+// parseExportFormat maps the --format flag's value to an ExportFormat.
+func parseExportFormat(s string) (ExportFormat, error) {
+	switch s {
+	case "png":
+		return FormatPNG, nil
+	case "tiff16":
+		return FormatTIFF16, nil
+	case "tifffloat":
+		return FormatTIFFFloat, nil
+	default:
+		return 0, fmt.Errorf("unknown format %q (want png, tiff16, or tifffloat)", s)
+	}
+}
+
+// exportFormatName returns --format's flag value for an ExportFormat, for
+// error messages.
+func exportFormatName(format ExportFormat) string {
+	switch format {
+	case FormatTIFF16:
+		return "tiff16"
+	case FormatTIFFFloat:
+		return "tifffloat"
+	default:
+		return "png"
+	}
+}
+
+// decodeDDS reads a DDS file and converts it to the requested format. When
+// reconstructNormal is set and the texture is BC5, the otherwise-unused
+// blue channel is filled in from the decoded red/green channels (treated
+// as the X/Y of a normal map) so the output previews as a normal map
+// instead of a flat red/green tile. FormatTIFF16 and FormatTIFFFloat are
+// only meaningful for R11G11B10_FLOAT, whose f11/f10 mantissa/exponent
+// decoders (f11ToF32/f10ToF32) recover more range than an 8-bit NRGBA can
+// hold; compress zlib-compresses the TIFF strip and is ignored for png.
+func decodeDDS(inputPath, outputPath string, reconstructNormal bool, format ExportFormat, compress bool) error {
 	// Read DDS file
 	f, err := os.Open(inputPath)
 	if err != nil {
@@ -223,19 +309,32 @@ func decodeDDS(inputPath, outputPath string) error {
 		return fmt.Errorf("read data: %w", err)
 	}
 
-	// Decompress to RGBA
-	img, err := decompressBC(compressedData, info)
-	if err != nil {
-		return fmt.Errorf("decompress: %w", err)
-	}
-
-	// Write PNG
 	outFile, err := os.Create(outputPath)
 	if err != nil {
 		return fmt.Errorf("create output: %w", err)
 	}
 	defer outFile.Close()
 
+	if format == FormatTIFF16 || format == FormatTIFFFloat {
+		if info.Format != DXGIFormatR11G11B10Float {
+			return fmt.Errorf("format %q is only supported for R11G11B10_FLOAT, got %s", exportFormatName(format), info.FormatName)
+		}
+		samples, err := decompressR11G11B10FloatHDR(compressedData, int(info.Width), int(info.Height))
+		if err != nil {
+			return fmt.Errorf("decompress: %w", err)
+		}
+		if format == FormatTIFF16 {
+			return writeTIFF16(outFile, int(info.Width), int(info.Height), samples, compress)
+		}
+		return writeTIFFFloat(outFile, int(info.Width), int(info.Height), samples, compress)
+	}
+
+	// Decompress to RGBA
+	img, err := decompressBC(compressedData, info, reconstructNormal)
+	if err != nil {
+		return fmt.Errorf("decompress: %w", err)
+	}
+
 	if err := png.Encode(outFile, img); err != nil {
 		return fmt.Errorf("encode png: %w", err)
 	}
@@ -265,12 +364,14 @@ func encodeDDS(inputPath, outputPath string) error {
 		dxgiFormat = DXGIFormatBC1Unorm
 	case BC3:
 		dxgiFormat = DXGIFormatBC3Unorm
+	case BC5:
+		dxgiFormat = DXGIFormatBC5Unorm
 	default:
 		return fmt.Errorf("unsupported format: %d", format)
 	}
 
 	// Generate mipmaps
-	mipmaps := GenerateMipmaps(img)
+	mipmaps := GenerateMipmaps(img, MipmapOptions{Filter: FilterLanczos3, AlphaCoverage: format == BC3})
 
 	// Compress all mip levels
 	var compressedData []byte
@@ -326,11 +427,158 @@ func showInfo(inputPath string) error {
 	return nil
 }
 
-// batchConvert processes a directory of files
-func batchConvert(mode, inputDir, outputDir string) error {
+// manifestEntry records one completed conversion so a later batchConvert
+// run over the same input can recognize and skip it.
+type manifestEntry struct {
+	InputPath   string `json:"input_path"`
+	OutputPath  string `json:"output_path"`
+	InputSHA256 string `json:"input_sha256"`
+	DXGIFormat  uint32 `json:"dxgi_format"`
+	MipLevels   uint32 `json:"mip_levels"`
+	OutputSize  int64  `json:"output_size"`
+}
+
+// conversionManifest is the on-disk --manifest file: completed conversions
+// keyed by input path, so a re-run can tell which jobs are already done.
+type conversionManifest struct {
+	Entries map[string]manifestEntry `json:"entries"`
+}
+
+func loadManifest(path string) (*conversionManifest, error) {
+	m := &conversionManifest{Entries: map[string]manifestEntry{}}
+	if path == "" {
+		return m, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	if m.Entries == nil {
+		m.Entries = map[string]manifestEntry{}
+	}
+	return m, nil
+}
+
+func saveManifest(path string, m *conversionManifest) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// fileSHA256 hashes the full contents of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// batchJob is one (input, output) pair queued for conversion.
+type batchJob struct {
+	inputPath  string
+	outputPath string
+}
+
+// batchResult is what a worker reports back for one batchJob.
+type batchResult struct {
+	job     batchJob
+	skipped bool
+	entry   manifestEntry
+	err     error
+}
+
+// convertOne runs the single-file decode or encode path for job and
+// returns the manifest entry describing what it produced.
+func convertOne(mode string, job batchJob) (manifestEntry, error) {
+	hash, err := fileSHA256(job.inputPath)
+	if err != nil {
+		return manifestEntry{}, fmt.Errorf("hash input: %w", err)
+	}
+	entry := manifestEntry{InputPath: job.inputPath, OutputPath: job.outputPath, InputSHA256: hash}
+
+	if mode == "decode" {
+		f, err := os.Open(job.inputPath)
+		if err != nil {
+			return manifestEntry{}, fmt.Errorf("open: %w", err)
+		}
+		info, err := parseDDSHeader(f)
+		f.Close()
+		if err != nil {
+			return manifestEntry{}, fmt.Errorf("parse header: %w", err)
+		}
+		entry.DXGIFormat, entry.MipLevels = info.Format, info.MipLevels
+
+		if err := decodeDDS(job.inputPath, job.outputPath, false, FormatPNG, false); err != nil {
+			return manifestEntry{}, err
+		}
+	} else {
+		if err := encodeDDS(job.inputPath, job.outputPath); err != nil {
+			return manifestEntry{}, err
+		}
+		if f, err := os.Open(job.outputPath); err == nil {
+			if info, err := parseDDSHeader(f); err == nil {
+				entry.DXGIFormat, entry.MipLevels = info.Format, info.MipLevels
+			}
+			f.Close()
+		}
+	}
+
+	if fi, err := os.Stat(job.outputPath); err == nil {
+		entry.OutputSize = fi.Size()
+	}
+	return entry, nil
+}
+
+// failureReason buckets a conversion error for the end-of-batch summary,
+// so large failing batches can be triaged without tailing stderr.
+func failureReason(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "unsupported"):
+		return "unsupported format"
+	case strings.Contains(msg, "truncated"):
+		return "truncated data"
+	case strings.Contains(msg, "decode png"):
+		return "PNG decode error"
+	case strings.Contains(msg, "parse header") || strings.Contains(msg, "invalid DDS magic"):
+		return "invalid DDS header"
+	default:
+		return "other"
+	}
+}
+
+// batchConvert walks inputDir converting every matching file into outputDir
+// using a pool of jobs workers. If manifestPath is set, conversions already
+// recorded there (matched by input path, input SHA-256, and output path)
+// are skipped, so an interrupted batch can resume without redoing work; the
+// manifest is rewritten with the run's results when it finishes. Batches
+// continue past per-file errors by default, reporting a summary grouped by
+// failure reason; stopOnError switches to aborting on the first one.
+func batchConvert(mode, inputDir, outputDir string, jobs int, manifestPath string, stopOnError bool) error {
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("create output dir: %w", err)
 	}
+	if jobs < 1 {
+		jobs = 1
+	}
 
 	var ext string
 	if mode == "decode" {
@@ -339,10 +587,13 @@ func batchConvert(mode, inputDir, outputDir string) error {
 		ext = ".png"
 	}
 
-	count := 0
-	errors := 0
+	man, err := loadManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("load manifest: %w", err)
+	}
 
-	err := filepath.Walk(inputDir, func(path string, info os.FileInfo, err error) error {
+	var allJobs []batchJob
+	err = filepath.Walk(inputDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -355,46 +606,102 @@ func batchConvert(mode, inputDir, outputDir string) error {
 
 		relPath, _ := filepath.Rel(inputDir, path)
 		outPath := filepath.Join(outputDir, relPath)
-
 		if mode == "decode" {
 			outPath = strings.TrimSuffix(outPath, ext) + ".png"
 		} else {
 			outPath = strings.TrimSuffix(outPath, ext) + ".dds"
 		}
 
-		// Create output directory
 		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
-			fmt.Fprintf(os.Stderr, "mkdir %s: %v\n", filepath.Dir(outPath), err)
-			errors++
-			return nil
-		}
-
-		// Convert
-		var convErr error
-		if mode == "decode" {
-			convErr = decodeDDS(path, outPath)
-		} else {
-			convErr = encodeDDS(path, outPath)
-		}
-
-		if convErr != nil {
-			fmt.Fprintf(os.Stderr, "convert %s: %v\n", path, convErr)
-			errors++
-		} else {
-			count++
-			if count%100 == 0 {
-				fmt.Printf("Processed %d files...\n", count)
-			}
+			return fmt.Errorf("mkdir %s: %w", filepath.Dir(outPath), err)
 		}
 
+		allJobs = append(allJobs, batchJob{inputPath: path, outputPath: outPath})
 		return nil
 	})
-
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("\nCompleted: %d files converted, %d errors\n", count, errors)
+	jobCh := make(chan batchJob)
+	resultCh := make(chan batchResult)
+	var stopped int32 // set to 1 once stopOnError sees a failure
+
+	var workers sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobCh {
+				if stopOnError && atomic.LoadInt32(&stopped) != 0 {
+					continue
+				}
+
+				if existing, ok := man.Entries[job.inputPath]; ok && existing.OutputPath == job.outputPath {
+					if hash, err := fileSHA256(job.inputPath); err == nil && hash == existing.InputSHA256 {
+						if _, err := os.Stat(job.outputPath); err == nil {
+							resultCh <- batchResult{job: job, skipped: true, entry: existing}
+							continue
+						}
+					}
+				}
+
+				entry, convErr := convertOne(mode, job)
+				if convErr != nil && stopOnError {
+					atomic.StoreInt32(&stopped, 1)
+				}
+				resultCh <- batchResult{job: job, entry: entry, err: convErr}
+			}
+		}()
+	}
+
+	go func() {
+		for _, job := range allJobs {
+			jobCh <- job
+		}
+		close(jobCh)
+	}()
+	go func() {
+		workers.Wait()
+		close(resultCh)
+	}()
+
+	converted, skipped := 0, 0
+	failuresByReason := map[string]int{}
+	for result := range resultCh {
+		switch {
+		case result.skipped:
+			skipped++
+		case result.err != nil:
+			reason := failureReason(result.err)
+			failuresByReason[reason]++
+			fmt.Fprintf(os.Stderr, "convert %s: %v\n", result.job.inputPath, result.err)
+		default:
+			converted++
+			man.Entries[result.job.inputPath] = result.entry
+			if total := converted + skipped; total%100 == 0 {
+				fmt.Printf("Processed %d files...\n", total)
+			}
+		}
+	}
+
+	if err := saveManifest(manifestPath, man); err != nil {
+		return fmt.Errorf("save manifest: %w", err)
+	}
+
+	totalErrors := 0
+	for _, n := range failuresByReason {
+		totalErrors += n
+	}
+	fmt.Printf("\nCompleted: %d files converted, %d skipped (up to date), %d errors\n", converted, skipped, totalErrors)
+	if totalErrors > 0 {
+		fmt.Println("Errors by reason:")
+		for _, reason := range []string{"unsupported format", "truncated data", "PNG decode error", "invalid DDS header", "other"} {
+			if n := failuresByReason[reason]; n > 0 {
+				fmt.Printf("  %-20s %d\n", reason, n)
+			}
+		}
+	}
 	return nil
 }
 
@@ -539,7 +846,7 @@ func calculateMipSize(width, height, format uint32) uint32 {
 }
 
 // decompressBC decompresses BC-compressed data to RGBA
-func decompressBC(data []byte, info *TextureInfo) (*image.NRGBA, error) {
+func decompressBC(data []byte, info *TextureInfo, reconstructNormal bool) (*image.NRGBA, error) {
 	nrgba := image.NewNRGBA(image.Rect(0, 0, int(info.Width), int(info.Height)))
 
 	isSRGB := info.Format == DXGIFormatBC1UnormSRGB ||
@@ -552,7 +859,10 @@ func decompressBC(data []byte, info *TextureInfo) (*image.NRGBA, error) {
 	case DXGIFormatBC3Unorm, DXGIFormatBC3UnormSRGB:
 		return decompressBC3(data, int(info.Width), int(info.Height), isSRGB)
 	case DXGIFormatBC5Unorm, DXGIFormatBC5SNorm:
-		return decompressBC5(data, int(info.Width), int(info.Height))
+		signed := info.Format == DXGIFormatBC5SNorm
+		return decompressBC5(data, int(info.Width), int(info.Height), signed, reconstructNormal)
+	case DXGIFormatBC7Unorm, DXGIFormatBC7UnormSRGB:
+		return decompressBC7(data, int(info.Width), int(info.Height))
 	case DXGIFormatR8Unorm:
 		return decompressR8(data, int(info.Width), int(info.Height))
 	case DXGIFormatR11G11B10Float:
@@ -807,11 +1117,400 @@ func decompressBC3(data []byte, width, height int, isSRGB bool) (*image.NRGBA, e
 	return nrgba, nil
 }
 
-// decompressBC5 decompresses BC5 (normal maps) to RGBA
-func decompressBC5(data []byte, width, height int) (*image.NRGBA, error) {
-	// BC5 stores two channels (RG for normal maps)
-	// We'll decode them and reconstruct Z = sqrt(1 - X^2 - Y^2)
-	return nil, fmt.Errorf("BC5 decompression not yet implemented")
+// decompressBC5 decompresses BC5 (normal maps) to RGBA. A BC5 block is two
+// independent 8-byte BC4 channel blocks back to back: red first, then
+// green. When reconstructNormal is set, R and G are treated as X and Y of
+// a unit normal in [-1,1] and B is filled with the reconstructed Z so the
+// output previews as a normal map instead of a flat red/green tile.
+func decompressBC5(data []byte, width, height int, signed, reconstructNormal bool) (*image.NRGBA, error) {
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	blockW := (width + 3) / 4
+	blockH := (height + 3) / 4
+
+	offset := 0
+	for by := 0; by < blockH; by++ {
+		for bx := 0; bx < blockW; bx++ {
+			if offset+16 > len(data) {
+				return nil, fmt.Errorf("data truncated")
+			}
+
+			red, err := decodeBC4ChannelBlock(data[offset:offset+8], signed)
+			if err != nil {
+				return nil, err
+			}
+			green, err := decodeBC4ChannelBlock(data[offset+8:offset+16], signed)
+			if err != nil {
+				return nil, err
+			}
+			offset += 16
+
+			for py := 0; py < 4; py++ {
+				for px := 0; px < 4; px++ {
+					x := bx*4 + px
+					y := by*4 + py
+					if x >= width || y >= height {
+						continue
+					}
+
+					r := red[py*4+px]
+					g := green[py*4+px]
+
+					var b uint8
+					if reconstructNormal {
+						nx := float64(r)/255.0*2 - 1
+						ny := float64(g)/255.0*2 - 1
+						nz := math.Sqrt(math.Max(0, 1-nx*nx-ny*ny))
+						b = uint8(math.Round(nz * 255))
+					}
+
+					pixOffset := nrgba.PixOffset(x, y)
+					nrgba.Pix[pixOffset+0] = r
+					nrgba.Pix[pixOffset+1] = g
+					nrgba.Pix[pixOffset+2] = b
+					nrgba.Pix[pixOffset+3] = 255
+				}
+			}
+		}
+	}
+
+	return nrgba, nil
+}
+
+// decodeBC4ChannelBlock decodes one 8-byte BC4 channel block (the same
+// alpha-block format BC3 uses for its alpha channel) into 16 single-channel
+// samples, the same interpolation scheme decompressBC3 already applies to
+// its alpha block.
+func decodeBC4ChannelBlock(block []byte, signed bool) ([16]uint8, error) {
+	var out [16]uint8
+	if len(block) != 8 {
+		return out, fmt.Errorf("BC4 channel block must be 8 bytes, got %d", len(block))
+	}
+
+	var v0, v1 uint8
+	if signed {
+		v0 = uint8(int(int8(block[0])) + 128)
+		v1 = uint8(int(int8(block[1])) + 128)
+	} else {
+		v0, v1 = block[0], block[1]
+	}
+
+	var values [8]uint8
+	values[0], values[1] = v0, v1
+	if v0 > v1 {
+		for i := 2; i < 8; i++ {
+			values[i] = uint8((int(v0)*(8-i) + int(v1)*(i-1)) / 7)
+		}
+	} else {
+		for i := 2; i < 6; i++ {
+			values[i] = uint8((int(v0)*(6-i) + int(v1)*(i-1)) / 5)
+		}
+		values[6] = 0
+		values[7] = 255
+	}
+
+	indexBits := uint64(0)
+	for i := 0; i < 6; i++ {
+		indexBits |= uint64(block[2+i]) << (8 * i)
+	}
+	for i := 0; i < 16; i++ {
+		idx := (indexBits >> (3 * i)) & 0x7
+		out[i] = values[idx]
+	}
+	return out, nil
+}
+
+// bc7Weights2/3/4 are BC7's fixed interpolation weight tables (scaled to
+// 0-64) for 2-, 3-, and 4-bit palette indices.
+var (
+	bc7Weights2 = [4]uint32{0, 21, 43, 64}
+	bc7Weights3 = [8]uint32{0, 9, 18, 27, 37, 46, 55, 64}
+	bc7Weights4 = [16]uint32{0, 4, 9, 13, 17, 21, 26, 30, 34, 38, 43, 47, 51, 55, 60, 64}
+)
+
+// bc7BitReader reads BC7's LSB-first packed bitstream.
+type bc7BitReader struct {
+	data []byte
+	pos  int
+}
+
+func (br *bc7BitReader) readBit() uint32 {
+	byteIdx := br.pos / 8
+	bitIdx := uint(br.pos % 8)
+	bit := (br.data[byteIdx] >> bitIdx) & 1
+	br.pos++
+	return uint32(bit)
+}
+
+func (br *bc7BitReader) readBits(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		v |= br.readBit() << uint(i)
+	}
+	return v
+}
+
+// bc7Interpolate blends two 0-255 endpoints by a 0-64 weight, as BC7 specifies.
+func bc7Interpolate(e0, e1, weight uint32) uint8 {
+	return uint8(((64-weight)*e0 + weight*e1 + 32) >> 6)
+}
+
+// bc7PartitionBits gives the width of the partition-index field that
+// immediately follows the mode bit for each multi-subset BC7 mode: 4
+// bits for mode 0 (3 subsets, so it needs fewer than the 64 shapes the
+// 2-subset modes draw from), 6 bits for modes 1, 2, 3, and 7 (2 or 3
+// subsets, 64 shapes).
+var bc7PartitionBits = map[int]int{0: 4, 1: 6, 2: 6, 3: 6, 7: 6}
+
+// decodeBC7Block decodes one 16-byte BC7 block into 16 RGBA pixels in
+// raster order. Only the single-subset modes (4, 5, and 6) are
+// implemented, since together they cover the vast majority of assets.
+// The multi-subset modes (0, 1, 2, 3, 7) each need one of BC7's 64-entry
+// partition-shape tables (plus, for every subset after the first, a
+// per-shape anchor-index table) to know which texel belongs to which
+// subset; this tool has no reference BC7 corpus to validate a
+// transcription of those against, so rather than guess at over a
+// thousand individual table entries, decodeBC7Block still reports these
+// modes as not decodable - but now after actually parsing the block's
+// partition field, so the error names the specific shape a caller would
+// need to add support for instead of a content-free "not implemented".
+func decodeBC7Block(block []byte) ([16][4]uint8, error) {
+	var pixels [16][4]uint8
+	if len(block) != 16 {
+		return pixels, fmt.Errorf("BC7 block must be 16 bytes, got %d", len(block))
+	}
+
+	br := &bc7BitReader{data: block}
+	mode := -1
+	for m := 0; m < 8; m++ {
+		if br.readBit() == 1 {
+			mode = m
+			break
+		}
+	}
+
+	switch mode {
+	case 6:
+		return decodeBC7Mode6(br)
+	case 5:
+		return decodeBC7Mode5(br)
+	case 4:
+		return decodeBC7Mode4(br)
+	case -1:
+		return pixels, fmt.Errorf("BC7 block has no mode bit set (reserved encoding)")
+	default:
+		if bits, ok := bc7PartitionBits[mode]; ok {
+			partition := br.readBits(bits)
+			return pixels, fmt.Errorf("BC7 mode %d (partition shape %d) not implemented: needs a partition-shape and anchor-index table this tool has no reference corpus to validate a transcription of", mode, partition)
+		}
+		return pixels, fmt.Errorf("BC7 mode %d not implemented", mode)
+	}
+}
+
+// decodeBC7Mode4 decodes a mode-4 block: one subset, 5-bit RGB endpoints
+// and 6-bit alpha endpoints (both bit-replicated to 8 bits, no P-bit), an
+// index-selection bit that swaps which of two differently-sized index
+// sets drives color vs. alpha, and an optional channel/alpha rotation.
+func decodeBC7Mode4(br *bc7BitReader) ([16][4]uint8, error) {
+	var pixels [16][4]uint8
+
+	rotation := br.readBits(2)
+	idxMode := br.readBit()
+
+	var r0, r1, g0, g1, b0, b1 uint32
+	r0, r1 = br.readBits(5), br.readBits(5)
+	g0, g1 = br.readBits(5), br.readBits(5)
+	b0, b1 = br.readBits(5), br.readBits(5)
+	a0, a1 := br.readBits(6), br.readBits(6)
+
+	expand5 := func(v uint32) uint32 { return v<<3 | v>>2 }
+	expand6 := func(v uint32) uint32 { return v<<2 | v>>4 }
+	e0 := [3]uint32{expand5(r0), expand5(g0), expand5(b0)}
+	e1 := [3]uint32{expand5(r1), expand5(g1), expand5(b1)}
+	ea0, ea1 := expand6(a0), expand6(a1)
+
+	readIndices := func(bits int) []uint32 {
+		idx := make([]uint32, 16)
+		for i := range idx {
+			n := bits
+			if i == 0 {
+				n = bits - 1
+			}
+			idx[i] = br.readBits(n)
+		}
+		return idx
+	}
+
+	var colorIdx, alphaIdx []uint32
+	var colorWeights, alphaWeights []uint32
+	if idxMode == 0 {
+		colorIdx, alphaIdx = readIndices(2), readIndices(3)
+		colorWeights, alphaWeights = bc7Weights2[:], bc7Weights3[:]
+	} else {
+		alphaIdx, colorIdx = readIndices(2), readIndices(3)
+		colorWeights, alphaWeights = bc7Weights3[:], bc7Weights2[:]
+	}
+
+	for i := range pixels {
+		cw, aw := colorWeights[colorIdx[i]], alphaWeights[alphaIdx[i]]
+		r := bc7Interpolate(e0[0], e1[0], cw)
+		g := bc7Interpolate(e0[1], e1[1], cw)
+		b := bc7Interpolate(e0[2], e1[2], cw)
+		a := bc7Interpolate(ea0, ea1, aw)
+
+		switch rotation {
+		case 1:
+			r, a = a, r
+		case 2:
+			g, a = a, g
+		case 3:
+			b, a = a, b
+		}
+		pixels[i] = [4]uint8{r, g, b, a}
+	}
+	return pixels, nil
+}
+
+// decodeBC7Mode6 decodes a mode-6 block: one subset, 7-bit RGBA endpoints
+// with a shared per-endpoint P-bit (giving full 8-bit precision) and
+// 4-bit indices.
+func decodeBC7Mode6(br *bc7BitReader) ([16][4]uint8, error) {
+	var pixels [16][4]uint8
+
+	var r0, r1, g0, g1, b0, b1, a0, a1 uint32
+	r0, r1 = br.readBits(7), br.readBits(7)
+	g0, g1 = br.readBits(7), br.readBits(7)
+	b0, b1 = br.readBits(7), br.readBits(7)
+	a0, a1 = br.readBits(7), br.readBits(7)
+	p0, p1 := br.readBit(), br.readBit()
+
+	e0 := [4]uint32{r0<<1 | p0, g0<<1 | p0, b0<<1 | p0, a0<<1 | p0}
+	e1 := [4]uint32{r1<<1 | p1, g1<<1 | p1, b1<<1 | p1, a1<<1 | p1}
+
+	indices := make([]uint32, 16)
+	for i := range indices {
+		bits := 4
+		if i == 0 {
+			bits = 3
+		}
+		indices[i] = br.readBits(bits)
+	}
+
+	for i, idx := range indices {
+		w := bc7Weights4[idx]
+		pixels[i] = [4]uint8{
+			bc7Interpolate(e0[0], e1[0], w),
+			bc7Interpolate(e0[1], e1[1], w),
+			bc7Interpolate(e0[2], e1[2], w),
+			bc7Interpolate(e0[3], e1[3], w),
+		}
+	}
+	return pixels, nil
+}
+
+// decodeBC7Mode5 decodes a mode-5 block: one subset, 7-bit RGB endpoints
+// (no P-bit, bit-replicated to 8 bits) with an independent 8-bit alpha
+// endpoint pair, each with its own 2-bit index set, and an optional
+// channel/alpha rotation.
+func decodeBC7Mode5(br *bc7BitReader) ([16][4]uint8, error) {
+	var pixels [16][4]uint8
+
+	rotation := br.readBits(2)
+
+	var r0, r1, g0, g1, b0, b1 uint32
+	r0, r1 = br.readBits(7), br.readBits(7)
+	g0, g1 = br.readBits(7), br.readBits(7)
+	b0, b1 = br.readBits(7), br.readBits(7)
+	a0, a1 := br.readBits(8), br.readBits(8)
+
+	expand := func(v uint32) uint32 { return v<<1 | v>>6 }
+	e0 := [3]uint32{expand(r0), expand(g0), expand(b0)}
+	e1 := [3]uint32{expand(r1), expand(g1), expand(b1)}
+
+	colorIdx := make([]uint32, 16)
+	for i := range colorIdx {
+		bits := 2
+		if i == 0 {
+			bits = 1
+		}
+		colorIdx[i] = br.readBits(bits)
+	}
+	alphaIdx := make([]uint32, 16)
+	for i := range alphaIdx {
+		bits := 2
+		if i == 0 {
+			bits = 1
+		}
+		alphaIdx[i] = br.readBits(bits)
+	}
+
+	for i := range pixels {
+		cw := bc7Weights2[colorIdx[i]]
+		aw := bc7Weights2[alphaIdx[i]]
+		r := bc7Interpolate(e0[0], e1[0], cw)
+		g := bc7Interpolate(e0[1], e1[1], cw)
+		b := bc7Interpolate(e0[2], e1[2], cw)
+		a := bc7Interpolate(a0, a1, aw)
+
+		switch rotation {
+		case 1:
+			r, a = a, r
+		case 2:
+			g, a = a, g
+		case 3:
+			b, a = a, b
+		}
+		pixels[i] = [4]uint8{r, g, b, a}
+	}
+	return pixels, nil
+}
+
+// decompressBC7 decompresses BC7 to RGBA, block by block via
+// decodeBC7Block. Only modes 4, 5, and 6 decode; modes 0, 1, 2, 3, and 7
+// each need their own 64-entry partition table (and, for the 3-subset
+// modes, a separate anchor-index table) this tool has no reference
+// corpus to validate a transcription of, so decodeBC7Block reports those
+// modes as errors (naming the partition shape found) rather than risk a
+// silently-wrong decode.
+func decompressBC7(data []byte, width, height int) (*image.NRGBA, error) {
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	blockW := (width + 3) / 4
+	blockH := (height + 3) / 4
+
+	offset := 0
+	for by := 0; by < blockH; by++ {
+		for bx := 0; bx < blockW; bx++ {
+			if offset+16 > len(data) {
+				return nil, fmt.Errorf("data truncated")
+			}
+
+			pixels, err := decodeBC7Block(data[offset : offset+16])
+			if err != nil {
+				return nil, err
+			}
+			offset += 16
+
+			for py := 0; py < 4; py++ {
+				for px := 0; px < 4; px++ {
+					x := bx*4 + px
+					y := by*4 + py
+					if x >= width || y >= height {
+						continue
+					}
+
+					p := pixels[py*4+px]
+					pixOffset := nrgba.PixOffset(x, y)
+					nrgba.Pix[pixOffset+0] = p[0]
+					nrgba.Pix[pixOffset+1] = p[1]
+					nrgba.Pix[pixOffset+2] = p[2]
+					nrgba.Pix[pixOffset+3] = p[3]
+				}
+			}
+		}
+	}
+
+	return nrgba, nil
 }
 
 // decompressR8 decompresses R8_UNORM (grayscale) to RGBA
@@ -901,6 +1600,33 @@ func decompressR11G11B10Float(data []byte, width, height int) (*image.NRGBA, err
 	return nrgba, nil
 }
 
+// decompressR11G11B10FloatHDR decompresses packed R11G11B10_FLOAT data the
+// same way decompressR11G11B10Float does, but returns the f11ToF32/
+// f10ToF32-recovered channel values as full-range float32 samples (R, G,
+// B, A=1 per pixel) instead of clamping them into an 8-bit NRGBA, so a
+// TIFF export can preserve the format's actual HDR range.
+func decompressR11G11B10FloatHDR(data []byte, width, height int) ([]float32, error) {
+	if len(data) < width*height*4 {
+		return nil, fmt.Errorf("data truncated")
+	}
+
+	samples := make([]float32, width*height*4)
+	offset := 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			packed := uint32(data[offset]) | uint32(data[offset+1])<<8 | uint32(data[offset+2])<<16 | uint32(data[offset+3])<<24
+			offset += 4
+
+			si := (y*width + x) * 4
+			samples[si+0] = f11ToF32(packed & 0x7FF)
+			samples[si+1] = f11ToF32((packed >> 11) & 0x7FF)
+			samples[si+2] = f10ToF32((packed >> 22) & 0x3FF)
+			samples[si+3] = 1.0
+		}
+	}
+	return samples, nil
+}
+
 func f11ToF32(u uint32) float32 {
 	exponent := (u >> 6) & 0x1F
 	mantissa := u & 0x3F
@@ -954,7 +1680,8 @@ func writeDDSFile(w io.Writer, width, height, mipCount, dxgiFormat uint32, compr
 	switch dxgiFormat {
 	case DXGIFormatBC1Unorm, DXGIFormatBC1UnormSRGB:
 		bytesPerBlock = 8
-	case DXGIFormatBC3Unorm, DXGIFormatBC3UnormSRGB, DXGIFormatBC5Unorm, DXGIFormatBC5SNorm:
+	case DXGIFormatBC3Unorm, DXGIFormatBC3UnormSRGB, DXGIFormatBC5Unorm, DXGIFormatBC5SNorm,
+		DXGIFormatBC7Unorm, DXGIFormatBC7UnormSRGB:
 		bytesPerBlock = 16
 	default:
 		return fmt.Errorf("unsupported DXGI format: %d", dxgiFormat)
@@ -1014,3 +1741,155 @@ func max(a, b uint32) uint32 {
 	}
 	return b
 }
+
+// tiffEntry is one 12-byte TIFF IFD directory entry.
+type tiffEntry struct {
+	tag, typ uint16
+	count    uint32
+	valOrOff uint32
+}
+
+// TIFF tags, field types, and constants used by writeTIFF16/writeTIFFFloat.
+const (
+	tiffTagImageWidth      = 256
+	tiffTagImageLength     = 257
+	tiffTagBitsPerSample   = 258
+	tiffTagCompression     = 259
+	tiffTagPhotometric     = 262
+	tiffTagStripOffsets    = 273
+	tiffTagSamplesPerPixel = 277
+	tiffTagRowsPerStrip    = 278
+	tiffTagStripByteCounts = 279
+	tiffTagSampleFormat    = 339
+
+	tiffTypeShort = 3
+	tiffTypeLong  = 4
+
+	tiffCompressionNone    = 1
+	tiffCompressionDeflate = 8
+	tiffPhotometricRGB     = 2
+
+	tiffSampleFormatUnsignedInt = 1
+	tiffSampleFormatIEEEFloat   = 3
+
+	tiffSamplesPerPixelRGBA = 4
+)
+
+// writeTIFF16 writes samples (R,G,B,A float32 quadruples in [0, +inf), as
+// produced by decompressR11G11B10FloatHDR) as a 16-bit-per-channel TIFF.
+// Values are scaled by 65535 and clamped to the uint16 range, so any HDR
+// value above 1.0 clips the same way an 8-bit PNG export would, just with
+// 256x the precision below that point.
+func writeTIFF16(w io.Writer, width, height int, samples []float32, compress bool) error {
+	pix := make([]uint16, len(samples))
+	for i, v := range samples {
+		pix[i] = uint16(math.Min(65535, math.Max(0, float64(v)*65535)))
+	}
+
+	strip := make([]byte, len(pix)*2)
+	for i, v := range pix {
+		binary.LittleEndian.PutUint16(strip[i*2:], v)
+	}
+
+	bitsPerSample := []uint16{16, 16, 16, 16}
+	sampleFormat := []uint16{tiffSampleFormatUnsignedInt, tiffSampleFormatUnsignedInt, tiffSampleFormatUnsignedInt, tiffSampleFormatUnsignedInt}
+	return writeTIFFIFD(w, width, height, bitsPerSample, sampleFormat, strip, compress)
+}
+
+// writeTIFFFloat writes samples as a 32-bit IEEE-float-per-channel TIFF,
+// losslessly preserving decompressR11G11B10FloatHDR's decoded range.
+func writeTIFFFloat(w io.Writer, width, height int, samples []float32, compress bool) error {
+	strip := make([]byte, len(samples)*4)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint32(strip[i*4:], math.Float32bits(v))
+	}
+
+	bitsPerSample := []uint16{32, 32, 32, 32}
+	sampleFormat := []uint16{tiffSampleFormatIEEEFloat, tiffSampleFormatIEEEFloat, tiffSampleFormatIEEEFloat, tiffSampleFormatIEEEFloat}
+	return writeTIFFIFD(w, width, height, bitsPerSample, sampleFormat, strip, compress)
+}
+
+// writeTIFFIFD writes a single-strip, single-IFD TIFF around a pre-encoded
+// pixel strip, modeled on golang.org/x/image/tiff's IFD/strip writer.
+// x/image/tiff's own encoder only ever writes 8-bit unsigned samples, so
+// BitsPerSample/SampleFormat combinations like 16-bit or float32 are built
+// by hand here instead. compress optionally zlib-compresses the strip,
+// recording Compression=8 (Adobe Deflate) so readers know to inflate it.
+func writeTIFFIFD(w io.Writer, width, height int, bitsPerSample, sampleFormat []uint16, strip []byte, compress bool) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid dimensions %dx%d", width, height)
+	}
+
+	compressionTag := uint32(tiffCompressionNone)
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		if _, err := zw.Write(strip); err != nil {
+			return fmt.Errorf("compress strip: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("flush zlib writer: %w", err)
+		}
+		strip = buf.Bytes()
+		compressionTag = tiffCompressionDeflate
+	}
+
+	const byteOrderHeaderSize = 8
+	bo := binary.LittleEndian
+
+	entries := []tiffEntry{
+		{tag: tiffTagImageWidth, typ: tiffTypeLong, count: 1, valOrOff: uint32(width)},
+		{tag: tiffTagImageLength, typ: tiffTypeLong, count: 1, valOrOff: uint32(height)},
+		{tag: tiffTagBitsPerSample, typ: tiffTypeShort, count: uint32(len(bitsPerSample))}, // valOrOff set below
+		{tag: tiffTagCompression, typ: tiffTypeShort, count: 1, valOrOff: compressionTag},
+		{tag: tiffTagPhotometric, typ: tiffTypeShort, count: 1, valOrOff: tiffPhotometricRGB},
+		{tag: tiffTagStripOffsets, typ: tiffTypeLong, count: 1}, // valOrOff set below
+		{tag: tiffTagSamplesPerPixel, typ: tiffTypeShort, count: 1, valOrOff: tiffSamplesPerPixelRGBA},
+		{tag: tiffTagRowsPerStrip, typ: tiffTypeLong, count: 1, valOrOff: uint32(height)},
+		{tag: tiffTagStripByteCounts, typ: tiffTypeLong, count: 1, valOrOff: uint32(len(strip))},
+		{tag: tiffTagSampleFormat, typ: tiffTypeShort, count: uint32(len(sampleFormat))}, // valOrOff set below
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	ifdSize := 2 + len(entries)*12 + 4 // count + entries + next-IFD offset
+	dataBase := uint32(byteOrderHeaderSize) + uint32(ifdSize)
+	bitsPerSampleOff := dataBase
+	sampleFormatOff := bitsPerSampleOff + uint32(len(bitsPerSample))*2
+	stripOff := sampleFormatOff + uint32(len(sampleFormat))*2
+
+	for i := range entries {
+		switch entries[i].tag {
+		case tiffTagBitsPerSample:
+			entries[i].valOrOff = bitsPerSampleOff
+		case tiffTagSampleFormat:
+			entries[i].valOrOff = sampleFormatOff
+		case tiffTagStripOffsets:
+			entries[i].valOrOff = stripOff
+		}
+	}
+
+	var out bytes.Buffer
+	out.Write([]byte("II"))
+	binary.Write(&out, bo, uint16(42))
+	binary.Write(&out, bo, uint32(byteOrderHeaderSize))
+	binary.Write(&out, bo, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&out, bo, e.tag)
+		binary.Write(&out, bo, e.typ)
+		binary.Write(&out, bo, e.count)
+		binary.Write(&out, bo, e.valOrOff)
+	}
+	binary.Write(&out, bo, uint32(0)) // no next IFD
+	for _, v := range bitsPerSample {
+		binary.Write(&out, bo, v)
+	}
+	for _, v := range sampleFormat {
+		binary.Write(&out, bo, v)
+	}
+	out.Write(strip)
+
+	if _, err := w.Write(out.Bytes()); err != nil {
+		return fmt.Errorf("write TIFF: %w", err)
+	}
+	return nil
+}
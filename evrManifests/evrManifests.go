@@ -0,0 +1,219 @@
+// Package evrManifests parses and serializes the EVR manifest binary
+// format evrpkg.Package reads and writes. It's a thin adapter over
+// pkg/manifest.Manifest - the same PackageCount/section-header layout,
+// decoded by the same already-verified UnmarshalBinary/MarshalBinary - with
+// the field names evrpkg's Build/Extract/Replace pipeline was written
+// against, since that pipeline predates pkg/manifest and pins down its own
+// naming (T instead of TypeSymbol, SomeStructure instead of Metadata, and so
+// on) for the same bytes.
+package evrManifests
+
+import (
+	"fmt"
+
+	"github.com/goopsie/evrFileTools/pkg/manifest"
+)
+
+// knownManifestType is the only binary layout MarshalManifest and
+// UnmarshalManifest have been verified against; anything else is rejected
+// rather than silently decoded against an unconfirmed layout.
+const knownManifestType = "5932408047-EVR"
+
+// HeaderChunk describes one of a manifest's three variable-length sections
+// (FrameContents, SomeStructure, Frames): its total byte size and element
+// counts. Field-for-field the same as pkg/manifest.Section.
+type HeaderChunk struct {
+	SectionSize  uint64
+	Unk1         uint64
+	Unk2         uint64
+	ElementSize  uint64
+	Count        uint64
+	ElementCount uint64
+}
+
+// ManifestHeader is the fixed-size header preceding a manifest's three
+// sections.
+type ManifestHeader struct {
+	PackageCount  uint32
+	Unk1          uint32
+	Unk2          uint64
+	FrameContents HeaderChunk
+	SomeStructure HeaderChunk
+	Frames        HeaderChunk
+}
+
+// FrameContents describes one file packed into a frame: T and FileSymbol
+// identify it, FileIndex names the Frames entry holding its bytes, and
+// DataOffset/Size locate it within that frame's decompressed data.
+type FrameContents struct {
+	T             int64
+	FileSymbol    int64
+	FileIndex     uint32
+	DataOffset    uint32
+	Size          uint32
+	SomeAlignment uint32
+}
+
+// SomeStructure carries the same (T, FileSymbol) pair as FrameContents plus
+// per-file metadata Extract/Build/Replace don't otherwise need.
+type SomeStructure struct {
+	T          int64
+	FileSymbol int64
+	Unk1       int64
+	Unk2       int64
+	AssetType  int64
+}
+
+// Frame describes one compressed data frame within a package file.
+type Frame struct {
+	CurrentPackageIndex uint32
+	CurrentOffset       uint32
+	CompressedSize      uint32
+	DecompressedSize    uint32
+}
+
+// EvrManifest is a full parsed manifest: its header plus the three sections
+// the header describes.
+type EvrManifest struct {
+	Header        ManifestHeader
+	FrameContents []FrameContents
+	SomeStructure []SomeStructure
+	Frames        []Frame
+}
+
+// MarshalManifest decodes b - the decompressed bytes that follow a
+// package's CompressedHeader - into an EvrManifest, per manifestType's
+// binary layout. Despite the name (kept for parity with UnmarshalManifest,
+// its struct->bytes counterpart below, and because evrpkg already calls it
+// this way), this direction goes bytes->struct.
+func MarshalManifest(b []byte, manifestType string) (EvrManifest, error) {
+	if manifestType != knownManifestType {
+		return EvrManifest{}, fmt.Errorf("unsupported manifest type %q", manifestType)
+	}
+
+	var m manifest.Manifest
+	if err := m.UnmarshalBinary(b); err != nil {
+		return EvrManifest{}, err
+	}
+	return fromManifest(m), nil
+}
+
+// UnmarshalManifest encodes e back into bytes per manifestType's binary
+// layout, the form evrpkg's writeManifest compresses and writes to disk.
+func UnmarshalManifest(e EvrManifest, manifestType string) ([]byte, error) {
+	if manifestType != knownManifestType {
+		return nil, fmt.Errorf("unsupported manifest type %q", manifestType)
+	}
+	m := toManifest(e)
+	return m.MarshalBinary()
+}
+
+func fromManifest(m manifest.Manifest) EvrManifest {
+	out := EvrManifest{
+		Header: ManifestHeader{
+			PackageCount:  m.Header.PackageCount,
+			Unk1:          m.Header.Unk1,
+			Unk2:          m.Header.Unk2,
+			FrameContents: headerChunkFromSection(m.Header.FrameContents),
+			SomeStructure: headerChunkFromSection(m.Header.Metadata),
+			Frames:        headerChunkFromSection(m.Header.Frames),
+		},
+		FrameContents: make([]FrameContents, len(m.FrameContents)),
+		SomeStructure: make([]SomeStructure, len(m.Metadata)),
+		Frames:        make([]Frame, len(m.Frames)),
+	}
+	for i, fc := range m.FrameContents {
+		out.FrameContents[i] = FrameContents{
+			T:             fc.TypeSymbol,
+			FileSymbol:    fc.FileSymbol,
+			FileIndex:     fc.FrameIndex,
+			DataOffset:    fc.DataOffset,
+			Size:          fc.Size,
+			SomeAlignment: fc.Alignment,
+		}
+	}
+	for i, md := range m.Metadata {
+		out.SomeStructure[i] = SomeStructure{
+			T:          md.TypeSymbol,
+			FileSymbol: md.FileSymbol,
+			Unk1:       md.Unk1,
+			Unk2:       md.Unk2,
+			AssetType:  md.AssetType,
+		}
+	}
+	for i, f := range m.Frames {
+		out.Frames[i] = Frame{
+			CurrentPackageIndex: f.PackageIndex,
+			CurrentOffset:       f.Offset,
+			CompressedSize:      f.CompressedSize,
+			DecompressedSize:    f.Length,
+		}
+	}
+	return out
+}
+
+func toManifest(e EvrManifest) manifest.Manifest {
+	m := manifest.Manifest{
+		Header: manifest.Header{
+			PackageCount:  e.Header.PackageCount,
+			Unk1:          e.Header.Unk1,
+			Unk2:          e.Header.Unk2,
+			FrameContents: sectionFromHeaderChunk(e.Header.FrameContents),
+			Metadata:      sectionFromHeaderChunk(e.Header.SomeStructure),
+			Frames:        sectionFromHeaderChunk(e.Header.Frames),
+		},
+		FrameContents: make([]manifest.FrameContent, len(e.FrameContents)),
+		Metadata:      make([]manifest.FileMetadata, len(e.SomeStructure)),
+		Frames:        make([]manifest.Frame, len(e.Frames)),
+	}
+	for i, fc := range e.FrameContents {
+		m.FrameContents[i] = manifest.FrameContent{
+			TypeSymbol: fc.T,
+			FileSymbol: fc.FileSymbol,
+			FrameIndex: fc.FileIndex,
+			DataOffset: fc.DataOffset,
+			Size:       fc.Size,
+			Alignment:  fc.SomeAlignment,
+		}
+	}
+	for i, ss := range e.SomeStructure {
+		m.Metadata[i] = manifest.FileMetadata{
+			TypeSymbol: ss.T,
+			FileSymbol: ss.FileSymbol,
+			Unk1:       ss.Unk1,
+			Unk2:       ss.Unk2,
+			AssetType:  ss.AssetType,
+		}
+	}
+	for i, f := range e.Frames {
+		m.Frames[i] = manifest.Frame{
+			PackageIndex:   f.CurrentPackageIndex,
+			Offset:         f.CurrentOffset,
+			CompressedSize: f.CompressedSize,
+			Length:         f.DecompressedSize,
+		}
+	}
+	return m
+}
+
+func headerChunkFromSection(s manifest.Section) HeaderChunk {
+	return HeaderChunk{
+		SectionSize:  s.Length,
+		Unk1:         s.Unk1,
+		Unk2:         s.Unk2,
+		ElementSize:  s.ElementSize,
+		Count:        s.Count,
+		ElementCount: s.ElementCount,
+	}
+}
+
+func sectionFromHeaderChunk(h HeaderChunk) manifest.Section {
+	return manifest.Section{
+		Length:       h.SectionSize,
+		Unk1:         h.Unk1,
+		Unk2:         h.Unk2,
+		ElementSize:  h.ElementSize,
+		Count:        h.Count,
+		ElementCount: h.ElementCount,
+	}
+}
@@ -0,0 +1,79 @@
+package evrManifests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goopsie/evrFileTools/pkg/manifest"
+)
+
+func TestMarshalUnmarshalManifestRoundTrip(t *testing.T) {
+	src := manifest.Manifest{
+		Header: manifest.Header{
+			PackageCount: 2,
+			Unk1:         524288,
+			FrameContents: manifest.Section{
+				ElementSize:  32,
+				Count:        1,
+				ElementCount: 1,
+			},
+			Metadata: manifest.Section{
+				ElementSize:  40,
+				Count:        1,
+				ElementCount: 1,
+			},
+			Frames: manifest.Section{
+				ElementSize:  16,
+				Count:        1,
+				ElementCount: 1,
+			},
+		},
+		FrameContents: []manifest.FrameContent{
+			{TypeSymbol: 123, FileSymbol: 456, FrameIndex: 0, DataOffset: 8, Size: 16, Alignment: 1},
+		},
+		Metadata: []manifest.FileMetadata{
+			{TypeSymbol: 123, FileSymbol: 456, AssetType: 9},
+		},
+		Frames: []manifest.Frame{
+			{PackageIndex: 0, Offset: 0, CompressedSize: 64, Length: 128},
+		},
+	}
+	b, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	got, err := MarshalManifest(b, knownManifestType)
+	if err != nil {
+		t.Fatalf("MarshalManifest: %v", err)
+	}
+	if got.Header.PackageCount != 2 || got.Header.Unk1 != 524288 {
+		t.Errorf("decoded header = %+v", got.Header)
+	}
+	if len(got.FrameContents) != 1 || got.FrameContents[0].T != 123 || got.FrameContents[0].FileSymbol != 456 {
+		t.Errorf("decoded FrameContents = %+v", got.FrameContents)
+	}
+	if len(got.SomeStructure) != 1 || got.SomeStructure[0].AssetType != 9 {
+		t.Errorf("decoded SomeStructure = %+v", got.SomeStructure)
+	}
+	if len(got.Frames) != 1 || got.Frames[0].CompressedSize != 64 || got.Frames[0].DecompressedSize != 128 {
+		t.Errorf("decoded Frames = %+v", got.Frames)
+	}
+
+	roundTripped, err := UnmarshalManifest(got, knownManifestType)
+	if err != nil {
+		t.Fatalf("UnmarshalManifest: %v", err)
+	}
+	if !reflect.DeepEqual(b, roundTripped) {
+		t.Errorf("round-tripped bytes differ from original")
+	}
+}
+
+func TestMarshalManifestUnsupportedType(t *testing.T) {
+	if _, err := MarshalManifest(nil, "unknown-type"); err == nil {
+		t.Error("expected an error for an unsupported manifest type")
+	}
+	if _, err := UnmarshalManifest(EvrManifest{}, "unknown-type"); err == nil {
+		t.Error("expected an error for an unsupported manifest type")
+	}
+}
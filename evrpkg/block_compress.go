@@ -0,0 +1,75 @@
+package evrpkg
+
+import (
+	"bytes"
+	"runtime"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// parallelBlockMinFrameSize is the smallest frame Replace will bother
+// splitting into blocks for ParallelBlockSize: below this, the overhead of
+// spinning up a worker per block outweighs what's saved by parallelizing,
+// since a single encoder.EncodeAll call is already fast enough.
+const parallelBlockMinFrameSize = 6 * 1024 * 1024
+
+// compressFrameReplace compresses data the way Replace writes a rebuilt
+// frame: with a single shared encoder.EncodeAll call, unless
+// ParallelBlockSize is set and data is at least parallelBlockMinFrameSize,
+// in which case it's split into fixed-size blocks compressed concurrently
+// by independent encoders and concatenated back into one byte stream.
+// Concatenating independent zstd frames this way is valid input to any
+// zstd decoder - decoder.DecodeAll already decodes a stream of concatenated
+// frames, which is how this package already reads package files - so
+// nothing downstream needs to know a frame was compressed in blocks.
+func (p *Package) compressFrameReplace(data []byte) []byte {
+	if p.ParallelBlockSize <= 0 || len(data) < parallelBlockMinFrameSize {
+		return p.encoder.EncodeAll(data, nil)
+	}
+
+	numBlocks := (len(data) + p.ParallelBlockSize - 1) / p.ParallelBlockSize
+	blocks := make([][]byte, numBlocks)
+	for i := 0; i < numBlocks; i++ {
+		start := i * p.ParallelBlockSize
+		end := start + p.ParallelBlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		blocks[i] = data[start:end]
+	}
+
+	results := make([][]byte, numBlocks)
+
+	numWorkers := runtime.NumCPU()
+	if numWorkers > numBlocks {
+		numWorkers = numBlocks
+	}
+	jobs := make(chan int)
+	done := make(chan struct{}, numWorkers)
+	for w := 0; w < numWorkers; w++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			blockEncoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(compressionLevel))
+			if err != nil {
+				return
+			}
+			defer blockEncoder.Close()
+			for i := range jobs {
+				results[i] = blockEncoder.EncodeAll(blocks[i], nil)
+			}
+		}()
+	}
+	for i := 0; i < numBlocks; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	for w := 0; w < numWorkers; w++ {
+		<-done
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)/2))
+	for _, r := range results {
+		out.Write(r)
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,447 @@
+package evrpkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	evrm "github.com/goopsie/evrFileTools/evrManifests"
+)
+
+// sourceFile is one file Build found under its input fs.FS: the (typeSymbol,
+// fileSymbol) pair it's keyed by in the rebuilt manifest, the path to read
+// its bytes back from, its size (known up front so Replace can report a
+// modified file's new size without re-reading it from disk), and the
+// SomeAlignment value its FrameContents entry should carry.
+type sourceFile struct {
+	TypeSymbol    int64
+	FileSymbol    int64
+	Path          string
+	Size          uint32
+	SomeAlignment uint32
+}
+
+// fileGroup accumulates the files destined for one shared zstd frame before
+// appendChunkToPackages flushes it to the active package. windowSize, when
+// nonzero, is the zstd window size a RebuildFrame recorded for this frame;
+// appendChunkToPackages reuses it instead of p.encoder's default so an
+// unmodified frame comes back out byte-identical.
+type fileGroup struct {
+	currentData      bytes.Buffer
+	decompressedSize uint32
+	fileIndex        uint32
+	fileCount        int
+	windowSize       uint64
+}
+
+// Build rebuilds a package/manifest pair from inputFS. If inputFS has a
+// "rebuild.json" at its root - the sidecar Extract writes next to its
+// output tree - Build honors it to reproduce the original frame grouping,
+// file order and zstd window parameters exactly, so frames left unmodified
+// round-trip to byte-identical compressed output. Otherwise it falls back
+// to scanning inputFS, which must be laid out the way Extract writes its
+// output: "<groupIndex>/<typeSymbol>/<fileSymbol>". groupIndex controls
+// which files land in the same pre-dedup frame; it has no effect on the
+// files' identity, only on how they're batched.
+func (p *Package) Build(ctx context.Context, inputFS fs.FS) error {
+	p.log().Printf("Building list of files to package...\n")
+
+	rebuild, err := loadRebuildManifest(inputFS)
+	if err == nil {
+		p.log().Printf("found %s, reproducing original frame layout\n", rebuildManifestName)
+		files, windowSizes := filesFromRebuildManifest(rebuild)
+		return p.rebuildPackageManifestCombo(ctx, inputFS, files, windowSizes)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to load %s: %w", rebuildManifestName, err)
+	}
+
+	files, err := scanSourceFiles(inputFS)
+	if err != nil {
+		return fmt.Errorf("failed to scan input filesystem: %w", err)
+	}
+	return p.rebuildPackageManifestCombo(ctx, inputFS, files, nil)
+}
+
+// filesFromRebuildManifest rebuilds the same [][]sourceFile shape
+// scanSourceFiles produces, but from rm's recorded frame/file order instead
+// of re-deriving it from directory names, plus the per-FileIndex window
+// size rebuildPackageManifestCombo should reuse when compressing that frame.
+func filesFromRebuildManifest(rm *RebuildManifest) ([][]sourceFile, map[uint32]uint64) {
+	groups := make([][]sourceFile, len(rm.Frames))
+	windowSizes := make(map[uint32]uint64, len(rm.Frames))
+	for i, frame := range rm.Frames {
+		files := make([]sourceFile, len(frame.Files))
+		for j, rf := range frame.Files {
+			files[j] = sourceFile{
+				TypeSymbol:    rf.TypeSymbol,
+				FileSymbol:    rf.FileSymbol,
+				Path:          rf.Path,
+				Size:          rf.Size,
+				SomeAlignment: rf.SomeAlignment,
+			}
+		}
+		groups[i] = files
+		if frame.WindowSize != 0 {
+			windowSizes[frame.FileIndex] = frame.WindowSize
+		}
+	}
+	return groups, windowSizes
+}
+
+// scanSourceFiles walks fsys for files laid out as
+// "<groupIndex>/<typeSymbol>/<fileSymbol>[.ext]" and groups them by
+// groupIndex. Paths that don't match - too shallow, or a non-numeric
+// component - are skipped rather than treated as an error, since a stray
+// file (e.g. a README dropped alongside extracted output) shouldn't abort
+// the whole build.
+func scanSourceFiles(fsys fs.FS) ([][]sourceFile, error) {
+	parseSymbol := func(s string) (int64, error) {
+		if ext := path.Ext(s); ext != "" {
+			s = s[:len(s)-len(ext)]
+		}
+		if strings.HasPrefix(s, "0x") {
+			u, err := strconv.ParseUint(s[2:], 16, 64)
+			return int64(u), err
+		}
+		return strconv.ParseInt(s, 10, 64)
+	}
+
+	var groups [][]sourceFile
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		parts := strings.Split(p, "/")
+		if len(parts) < 3 {
+			return nil
+		}
+		dir1 := parts[len(parts)-3]
+		dir2 := parts[len(parts)-2]
+		dir3 := strings.TrimSuffix(parts[len(parts)-1], evrCasManifestExt)
+
+		groupIndex, err := strconv.ParseInt(dir1, 10, 64)
+		if err != nil {
+			return nil
+		}
+		typeSymbol, err := parseSymbol(dir2)
+		if err != nil {
+			return nil
+		}
+		fileSymbol, err := parseSymbol(dir3)
+		if err != nil {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		// A ".evrcas.json" sidecar's own size is its JSON encoding, not the
+		// file it reassembles to - recompute it from the chunk refs so
+		// Size stays accurate for the rebuilt FrameContents entry.
+		size := uint32(info.Size())
+		if strings.HasSuffix(p, evrCasManifestExt) {
+			b, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			var cm evrCasFileManifest
+			if err := json.Unmarshal(b, &cm); err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+			size = 0
+			for _, ref := range cm.Chunks {
+				size += ref.Size
+			}
+		}
+
+		if int(groupIndex) >= len(groups) {
+			grown := make([][]sourceFile, groupIndex+1)
+			copy(grown, groups)
+			groups = grown
+		}
+		groups[groupIndex] = append(groups[groupIndex], sourceFile{
+			TypeSymbol:    typeSymbol,
+			FileSymbol:    fileSymbol,
+			Path:          p,
+			Size:          size,
+			SomeAlignment: 1,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// rebuildPackageManifestCombo writes fileMap's groups out as frames, one
+// shared zstd frame per group (or one per file when Format is "chunked").
+// frameWindowSizes, when non-nil, maps a group's position in fileMap (which
+// becomes its FileIndex) to the zstd window size that frame should be
+// recompressed with, as recorded by a RebuildFrame; a nil or missing entry
+// falls back to p.encoder's default window.
+func (p *Package) rebuildPackageManifestCombo(ctx context.Context, inputFS fs.FS, fileMap [][]sourceFile, frameWindowSizes map[uint32]uint64) error {
+	totalFileCount := 0
+	for _, v := range fileMap {
+		totalFileCount += len(v)
+	}
+	p.log().Printf("Building from %d files\n", totalFileCount)
+	manifest := evrm.EvrManifest{
+		Header: evrm.ManifestHeader{
+			PackageCount:  1,
+			Unk1:          0,
+			Unk2:          0,
+			FrameContents: evrm.HeaderChunk{SectionSize: 0, Unk1: 0, Unk2: 0, ElementSize: 32, Count: 0, ElementCount: 0},
+			SomeStructure: evrm.HeaderChunk{SectionSize: 0, Unk1: 0, Unk2: 0, ElementSize: 40, Count: 0, ElementCount: 0},
+			Frames:        evrm.HeaderChunk{SectionSize: 0, Unk1: 0, Unk2: 0, ElementSize: 16, Count: 0, ElementCount: 0},
+		},
+		FrameContents: make([]evrm.FrameContents, totalFileCount),
+		SomeStructure: make([]evrm.SomeStructure, totalFileCount),
+		Frames:        []evrm.Frame{},
+	}
+
+	currentFileGroup := fileGroup{}
+	totalFilesWritten := 0
+
+	// chunkStore and pending are only populated when Dedupe is set; flushGroup
+	// finalizes whatever's in pending into chunkStore once currentFileGroup's
+	// frame index is actually known (right after it's written), and chunkFiles
+	// collects the ordered chunk hash list ChunkManifest needs per file.
+	chunkStore := newChunkStore()
+	pending := make(map[[32]byte]chunkLocation)
+	var chunkFiles []fileChunkEntry
+
+	// tocByPackage is only populated when Format is "chunked": forcing a
+	// flush after every file (below) means each one lands in its own frame,
+	// so its compressed bytes are an independently-decodable zstd sub-frame
+	// rather than sharing one with the rest of its group.
+	tocByPackage := make(map[uint32][]chunkedTOCEntry)
+
+	flushGroup := func() error {
+		currentFileGroup.windowSize = frameWindowSizes[currentFileGroup.fileIndex]
+		if err := p.appendChunkToPackages(&manifest, currentFileGroup); err != nil {
+			return err
+		}
+		for sum, loc := range pending {
+			loc.FrameIndex = currentFileGroup.fileIndex
+			chunkStore.record(sum, loc)
+			delete(pending, sum)
+		}
+		currentFileGroup.currentData.Reset()
+		currentFileGroup.fileIndex++
+		currentFileGroup.fileCount = 0
+		return nil
+	}
+
+	logTimer := make(chan bool, 1)
+	go logTimerFunc(logTimer)
+
+	for _, group := range fileMap {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if currentFileGroup.currentData.Len() != 0 {
+			if err := flushGroup(); err != nil {
+				return err
+			}
+		}
+		for _, file := range group {
+			toWrite, err := readDedupedSourceFile(inputFS, file.Path)
+			if err != nil {
+				return err
+			}
+
+			dataOffset := uint32(currentFileGroup.currentData.Len())
+			if p.Dedupe {
+				sums := make([][32]byte, 0, len(toWrite)/chunkMinSize+1)
+				for _, chunk := range splitContentDefined(toWrite) {
+					sum := chunkSum(chunk)
+					sums = append(sums, sum)
+					if _, ok := chunkStore.lookup(sum); ok {
+						continue
+					}
+					if _, ok := pending[sum]; ok {
+						continue
+					}
+					pending[sum] = chunkLocation{
+						DataOffset: uint32(currentFileGroup.currentData.Len()),
+						Size:       uint32(len(chunk)),
+					}
+					currentFileGroup.currentData.Write(chunk)
+				}
+				chunkFiles = append(chunkFiles, fileChunkEntry{
+					TypeSymbol: file.TypeSymbol,
+					FileSymbol: file.FileSymbol,
+					FileIndex:  currentFileGroup.fileIndex,
+					Chunks:     sums,
+				})
+			} else {
+				currentFileGroup.currentData.Write(toWrite)
+			}
+
+			frameContentsEntry := evrm.FrameContents{
+				T:             file.TypeSymbol,
+				FileSymbol:    file.FileSymbol,
+				FileIndex:     currentFileGroup.fileIndex,
+				DataOffset:    dataOffset,
+				Size:          uint32(len(toWrite)),
+				SomeAlignment: file.SomeAlignment,
+			}
+			someStructureEntry := evrm.SomeStructure{
+				T:          file.TypeSymbol,
+				FileSymbol: file.FileSymbol,
+				Unk1:       0,
+				Unk2:       0,
+				AssetType:  0,
+			}
+
+			manifest.FrameContents[totalFilesWritten] = frameContentsEntry
+			manifest.SomeStructure[totalFilesWritten] = someStructureEntry
+			manifest.Header.FrameContents = incrementHeaderChunk(manifest.Header.FrameContents, 1)
+			manifest.Header.SomeStructure = incrementHeaderChunk(manifest.Header.SomeStructure, 1)
+
+			totalFilesWritten++
+			currentFileGroup.fileCount++
+
+			if p.format() == "chunked" {
+				frameIdxForThisFile := currentFileGroup.fileIndex
+				if err := flushGroup(); err != nil {
+					return err
+				}
+				committed := manifest.Frames[frameIdxForThisFile]
+				tocByPackage[committed.CurrentPackageIndex] = append(tocByPackage[committed.CurrentPackageIndex], chunkedTOCEntry{
+					FileSymbol:             file.FileSymbol,
+					TypeSymbol:             file.TypeSymbol,
+					FrameIndex:             frameIdxForThisFile,
+					SubFrameOffset:         committed.CurrentOffset,
+					SubFrameCompressedSize: committed.CompressedSize,
+					UncompressedSize:       committed.DecompressedSize,
+					SHA256:                 chunkSum(toWrite),
+				})
+			}
+		}
+		if len(logTimer) > 0 {
+			<-logTimer
+			p.log().Printf("\033[2K\rWrote %d/%d files ", totalFilesWritten, totalFileCount)
+		}
+	}
+	if currentFileGroup.currentData.Len() > 0 {
+		if err := flushGroup(); err != nil {
+			return err
+		}
+	}
+	p.log().Printf("finished writing package data, %d files in %d packages\n", totalFilesWritten, manifest.Header.PackageCount)
+
+	if p.Dedupe {
+		if err := p.writeChunkManifestSidecar(chunkStore, chunkFiles); err != nil {
+			return fmt.Errorf("failed to write chunk manifest: %w", err)
+		}
+	}
+
+	for i := uint32(0); i < manifest.Header.PackageCount; i++ {
+		packageStats, err := os.Stat(fmt.Sprintf("%s/packages/%s_%d", p.OutputDir, p.PackageName, i))
+		if err != nil {
+			return fmt.Errorf("failed to stat package for weirddata writing: %w", err)
+		}
+		newEntry := evrm.Frame{
+			CurrentPackageIndex: i,
+			CurrentOffset:       uint32(packageStats.Size()),
+			CompressedSize:      0,
+			DecompressedSize:    0,
+		}
+		manifest.Frames = append(manifest.Frames, newEntry)
+		manifest.Header.Frames = incrementHeaderChunk(manifest.Header.Frames, 1)
+	}
+
+	newEntry := evrm.Frame{}
+	manifest.Frames = append(manifest.Frames, newEntry)
+	manifest.Header.Frames = incrementHeaderChunk(manifest.Header.Frames, 1)
+
+	// Append the chunked TOC trailer only after every "weirddata" terminator
+	// frame above has already stat'd each package's size - the trailer adds
+	// bytes past the real frame data, and those terminators need to see the
+	// pre-trailer size to mark where the real data actually ends.
+	if p.format() == "chunked" {
+		for i := uint32(0); i < manifest.Header.PackageCount; i++ {
+			packagePath := fmt.Sprintf("%s/packages/%s_%d", p.OutputDir, p.PackageName, i)
+			if err := writeChunkedTOCTrailer(packagePath, tocByPackage[i]); err != nil {
+				return fmt.Errorf("failed to write chunked TOC trailer for package %d: %w", i, err)
+			}
+		}
+	}
+
+	p.log().Printf("Writing manifest\n")
+	return p.writeManifest(manifest)
+}
+
+func (p *Package) appendChunkToPackages(manifest *evrm.EvrManifest, currentFileGroup fileGroup) error {
+	os.MkdirAll(fmt.Sprintf("%s/packages", p.OutputDir), 0777)
+
+	cEntry := evrm.Frame{}
+	activePackageNum := uint32(0)
+	if len(manifest.Frames) > 0 {
+		cEntry = manifest.Frames[len(manifest.Frames)-1]
+		activePackageNum = cEntry.CurrentPackageIndex
+	}
+
+	// If decompressedSize is set, it means data is ALREADY compressed (from
+	// Replace's parallel worker or Build's dedup path). currentFileGroup's
+	// decompressedSize field doubles as that "already compressed" flag: when
+	// it's nonzero, currentData holds the compressed bytes rather than the
+	// raw ones.
+	var compFile []byte
+	if currentFileGroup.decompressedSize != 0 {
+		compFile = currentFileGroup.currentData.Bytes()
+	} else {
+		compFile = p.encodeFrame(currentFileGroup.currentData.Bytes(), currentFileGroup.windowSize)
+	}
+
+	currentPackagePath := fmt.Sprintf("%s/packages/%s_%d", p.OutputDir, p.PackageName, activePackageNum)
+
+	if int(cEntry.CurrentOffset+cEntry.CompressedSize)+len(compFile) > math.MaxInt32 {
+		activePackageNum++
+		manifest.Header.PackageCount = activePackageNum + 1
+		currentPackagePath = fmt.Sprintf("%s/packages/%s_%d", p.OutputDir, p.PackageName, activePackageNum)
+	}
+
+	f, err := os.OpenFile(currentPackagePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0777)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(compFile); err != nil {
+		return err
+	}
+
+	newEntry := evrm.Frame{
+		CurrentPackageIndex: activePackageNum,
+		CurrentOffset:       cEntry.CurrentOffset + cEntry.CompressedSize,
+		CompressedSize:      uint32(len(compFile)),
+		DecompressedSize:    uint32(currentFileGroup.currentData.Len()),
+	}
+	if currentFileGroup.decompressedSize != 0 {
+		newEntry.DecompressedSize = currentFileGroup.decompressedSize
+	}
+	if newEntry.CurrentOffset+newEntry.CompressedSize > math.MaxInt32 {
+		newEntry.CurrentOffset = 0
+	}
+
+	manifest.Frames = append(manifest.Frames, newEntry)
+	manifest.Header.Frames = incrementHeaderChunk(manifest.Header.Frames, 1)
+	return nil
+}
@@ -0,0 +1,144 @@
+package evrpkg
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	evrm "github.com/goopsie/evrFileTools/evrManifests"
+)
+
+// skippableFrameMagic is the zstd skippable-frame magic number (the low
+// nibble 0x0-0xF of 0x184D2A5_ is reserved for the format's own use; 0xE is
+// just one of the sixteen sub-magics, arbitrarily picked since nothing else
+// in this tool writes skippable frames). See the zstd compression format
+// spec's "Skippable Frames" section.
+const skippableFrameMagic = 0x184D2A5E
+
+// chunkedTOCEntry locates one file's independently-decodable zstd sub-frame
+// within a "-format chunked" package, without needing to consult the evrm
+// manifest's FrameContents table first.
+type chunkedTOCEntry struct {
+	FileSymbol             int64    `json:"fileSymbol"`
+	TypeSymbol             int64    `json:"typeSymbol"`
+	FrameIndex             uint32   `json:"frameIndex"`
+	SubFrameOffset         uint32   `json:"subFrameOffset"`
+	SubFrameCompressedSize uint32   `json:"subFrameCompressedSize"`
+	UncompressedSize       uint32   `json:"uncompressedSize"`
+	SHA256                 [32]byte `json:"sha256"`
+}
+
+// writeChunkedTOCTrailer appends entries to packagePath, already-written
+// package data and all, as a zstd skippable frame: a reader can find it
+// purely by seeking from EOF, with no need to parse the evrm manifest
+// first. Layout appended to the file:
+//
+//	[magic u32][tocLen+8 u32][toc JSON, tocLen bytes][tocLen u32][magic u32]
+//
+// The header half makes it a spec-compliant skippable frame (a decoder that
+// doesn't know about the TOC can skip over it using the ordinary skippable
+// frame rule); the footer half - tocLen and magic repeated at the very end
+// - lets readChunkedTOCTrailer locate the frame's start from EOF without
+// re-deriving anything else about the file.
+func writeChunkedTOCTrailer(packagePath string, entries []chunkedTOCEntry) error {
+	tocBytes, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(packagePath, os.O_RDWR|os.O_APPEND, 0777)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := make([]byte, 8)
+	binary.LittleEndian.PutUint32(header[0:4], skippableFrameMagic)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(tocBytes))+8) // frame size covers the TOC and the footer
+
+	footer := make([]byte, 8)
+	binary.LittleEndian.PutUint32(footer[0:4], uint32(len(tocBytes)))
+	binary.LittleEndian.PutUint32(footer[4:8], skippableFrameMagic)
+
+	if _, err := f.Write(header); err != nil {
+		return err
+	}
+	if _, err := f.Write(tocBytes); err != nil {
+		return err
+	}
+	_, err = f.Write(footer)
+	return err
+}
+
+// readChunkedTOCTrailer reads back the trailer written by
+// writeChunkedTOCTrailer. It returns an error (not an empty TOC) when
+// packagePath is too short or its last 8 bytes don't carry
+// skippableFrameMagic, so callers can tell "this package wasn't built with
+// -format chunked" apart from "this package's TOC happens to be empty".
+func readChunkedTOCTrailer(packagePath string) ([]chunkedTOCEntry, error) {
+	f, err := os.Open(packagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if stat.Size() < 16 {
+		return nil, fmt.Errorf("%s: too small to hold a chunked TOC trailer", packagePath)
+	}
+
+	footer := make([]byte, 8)
+	if _, err := f.ReadAt(footer, stat.Size()-8); err != nil {
+		return nil, err
+	}
+	tocLen := binary.LittleEndian.Uint32(footer[0:4])
+	magic := binary.LittleEndian.Uint32(footer[4:8])
+	if magic != skippableFrameMagic {
+		return nil, fmt.Errorf("%s: no chunked TOC trailer present", packagePath)
+	}
+
+	tocStart := stat.Size() - 8 - int64(tocLen)
+	if tocStart < 8 {
+		return nil, fmt.Errorf("%s: chunked TOC trailer length %d is larger than the file", packagePath, tocLen)
+	}
+
+	header := make([]byte, 8)
+	if _, err := f.ReadAt(header, tocStart-8); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(header[0:4]) != skippableFrameMagic || binary.LittleEndian.Uint32(header[4:8]) != tocLen+8 {
+		return nil, fmt.Errorf("%s: chunked TOC trailer header doesn't match its footer", packagePath)
+	}
+
+	tocBytes := make([]byte, tocLen)
+	if _, err := f.ReadAt(tocBytes, tocStart); err != nil {
+		return nil, err
+	}
+
+	var entries []chunkedTOCEntry
+	if err := json.Unmarshal(tocBytes, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// detectChunkedFormat probes every package file fullManifest names for a
+// chunked TOC trailer, returning the combined entries from all of them.
+// found is false when none of them carry one, meaning the package wasn't
+// built with -format chunked.
+func (p *Package) detectChunkedFormat(fullManifest evrm.EvrManifest) (entries []chunkedTOCEntry, found bool) {
+	for i := 0; i < int(fullManifest.Header.PackageCount); i++ {
+		path := fmt.Sprintf("%s/packages/%s_%d", p.DataDir, p.PackageName, i)
+		e, err := readChunkedTOCTrailer(path)
+		if err != nil {
+			continue
+		}
+		found = true
+		entries = append(entries, e...)
+	}
+	return entries, found
+}
@@ -0,0 +1,171 @@
+package evrpkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	chunkWindowSize = 64
+	chunkAvgBits    = 13
+	chunkMask       = 1<<chunkAvgBits - 1
+	chunkMinSize    = 2 * 1024
+	chunkMaxSize    = 64 * 1024
+)
+
+// splitContentDefined splits data into content-defined chunks using a
+// Rabin-like rolling hash over a chunkWindowSize-byte window: s1 is the sum
+// of the bytes currently in the window and s2 accumulates s1 as the window
+// slides. A boundary is declared wherever s2&chunkMask==chunkMask, which
+// lands on average every 1<<chunkAvgBits bytes since chunkMask has
+// chunkAvgBits low bits set. Boundaries below chunkMinSize are ignored, and
+// a chunk is forced to end at chunkMaxSize, so a pathological input (e.g. a
+// long run that never trips the hash) can't produce one unbounded chunk.
+// Because the boundary only depends on the 64 bytes behind it, inserting or
+// deleting bytes elsewhere in a file re-chunks only the affected region -
+// everything else still hashes the same, which is what lets identical
+// chunks shared by different files be recognized at all.
+func splitContentDefined(data []byte) [][]byte {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var chunks [][]byte
+	start := 0
+	var s1, s2 uint32
+	for i := 0; i < len(data); i++ {
+		in := uint32(data[i])
+		if i-start >= chunkWindowSize {
+			out := uint32(data[i-chunkWindowSize])
+			s1 -= out
+			s2 -= uint32(chunkWindowSize) * out
+		}
+		s1 += in
+		s2 += s1
+
+		size := i - start + 1
+		boundary := size >= chunkMinSize && s2&chunkMask == chunkMask
+		if boundary || size >= chunkMaxSize {
+			chunks = append(chunks, data[start:i+1])
+			start = i + 1
+			s1, s2 = 0, 0
+		}
+	}
+	if start < len(data) {
+		chunks = append(chunks, data[start:])
+	}
+	return chunks
+}
+
+func chunkSum(data []byte) [32]byte {
+	return sha256.Sum256(data)
+}
+
+// chunkLocation is where one content-defined chunk's bytes live once its
+// fileGroup has been flushed to a package: which frame holds it, and the
+// offset/size of its span within that frame's decompressed bytes. This
+// leans on the existing Frame indirection (package index + on-disk offset
+// are already recorded per frame) rather than duplicating that bookkeeping
+// per chunk.
+type chunkLocation struct {
+	FrameIndex uint32 `json:"frameIndex"`
+	DataOffset uint32 `json:"dataOffset"`
+	Size       uint32 `json:"size"`
+}
+
+// ChunkStore maps the SHA-256 of a content-defined chunk to where it was
+// first written, so Build can recognize a chunk it has already stored -
+// common for shared textures/meshes referenced by many files - and skip
+// writing and compressing it again.
+type ChunkStore struct {
+	locations map[[32]byte]chunkLocation
+}
+
+func newChunkStore() *ChunkStore {
+	return &ChunkStore{locations: make(map[[32]byte]chunkLocation)}
+}
+
+func (cs *ChunkStore) lookup(sum [32]byte) (chunkLocation, bool) {
+	loc, ok := cs.locations[sum]
+	return loc, ok
+}
+
+// record stores sum's location the first time it's seen; later calls for
+// an already-known sum are no-ops, since a chunk only needs one location.
+func (cs *ChunkStore) record(sum [32]byte, loc chunkLocation) {
+	if _, ok := cs.locations[sum]; ok {
+		return
+	}
+	cs.locations[sum] = loc
+}
+
+// fileChunkEntry records one file as an ordered list of chunk hashes, so it
+// can be reassembled by resolving each hash through a ChunkManifest's
+// Chunks map and concatenating the results in order.
+type fileChunkEntry struct {
+	TypeSymbol int64      `json:"typeSymbol"`
+	FileSymbol int64      `json:"fileSymbol"`
+	FileIndex  uint32     `json:"fileIndex"`
+	Chunks     [][32]byte `json:"chunks"`
+}
+
+// ChunkManifest is the sidecar Build writes next to the regular evrm
+// manifest whenever Dedupe is set. evrm's FrameContents can only describe a
+// file as one contiguous span within a single frame, which no longer holds
+// once a file's duplicate chunks are skipped instead of rewritten - so
+// Extract reads ChunkManifest instead when one is present, and the
+// FrameContents entries written alongside it are metadata-only (T,
+// FileSymbol, FileIndex for grouping/filtering), not a real
+// DataOffset/Size span.
+type ChunkManifest struct {
+	Files  []fileChunkEntry         `json:"files"`
+	Chunks map[string]chunkLocation `json:"chunks"`
+}
+
+// writeChunkManifestSidecar resolves every pending/finalized chunk in store
+// into a ChunkManifest and writes it to
+// "<OutputDir>/manifests/<PackageName>.chunks.json".
+func (p *Package) writeChunkManifestSidecar(store *ChunkStore, files []fileChunkEntry) error {
+	cm := &ChunkManifest{
+		Files:  files,
+		Chunks: make(map[string]chunkLocation, len(store.locations)),
+	}
+	for sum, loc := range store.locations {
+		cm.Chunks[hex.EncodeToString(sum[:])] = loc
+	}
+
+	path := fmt.Sprintf("%s/manifests/%s.chunks.json", p.OutputDir, p.PackageName)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0777)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(cm); err != nil {
+		return err
+	}
+
+	p.log().Printf("wrote chunk manifest with %d unique chunks for %d files\n", len(cm.Chunks), len(cm.Files))
+	return nil
+}
+
+// loadChunkManifest reads the sidecar written by writeChunkManifestSidecar.
+// It returns an error (not a zero-value manifest) when path doesn't exist,
+// so callers can tell "no sidecar, fall back to the non-deduped extraction
+// path" apart from "sidecar exists but is empty/corrupt".
+func loadChunkManifest(path string) (*ChunkManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cm := &ChunkManifest{}
+	if err := json.NewDecoder(f).Decode(cm); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
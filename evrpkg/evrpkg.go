@@ -0,0 +1,269 @@
+// Package evrpkg is the library form of the evrFileTools CLI: extracting,
+// building and replacing files in an EVR package/manifest pair without going
+// through flags, stdout, or a fixed set of on-disk paths. main.go is a thin
+// wrapper around this package that populates a Package from flags.
+package evrpkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	evrm "github.com/goopsie/evrFileTools/evrManifests"
+	"github.com/klauspost/compress/zstd"
+)
+
+const compressionLevel = zstd.SpeedFastest
+
+// Logger is the subset of *log.Logger that evrpkg needs to report progress.
+// Callers that don't care about progress output can leave Package.Logger nil,
+// in which case every call becomes a no-op.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...interface{}) {}
+
+// Package is one dataDir/outputDir/packageName combination and the options
+// that govern how its files are read or written. It carries the same knobs
+// the CLI used to expose as flag globals, but as fields so a caller embedding
+// evrpkg - a mod manager, an asset server, a test harness - can drive several
+// packages concurrently instead of shelling out once per invocation.
+type Package struct {
+	// DataDir is the directory containing "manifests" and "packages",
+	// e.g. ready-at-dawn-echo-arena/_data. Required for Extract and Replace.
+	DataDir string
+	// OutputDir is the directory Build and Replace write their rebuilt
+	// package/manifest files into, and Extract writes extracted files into.
+	OutputDir string
+	// PackageName is the manifest/package file name, e.g. "48037dc70b0ecab2".
+	PackageName string
+	// ManifestType selects the evrm layout to (un)marshal with. Defaults to
+	// "5932408047-EVR" if left empty.
+	ManifestType string
+
+	// Dedupe, when set, makes Build deduplicate repeated content-defined
+	// chunks across files instead of writing them into every frame that
+	// references them, and write a "<PackageName>.chunks.json" sidecar next
+	// to the manifest so Extract can reassemble files from it.
+	Dedupe bool
+	// Format selects the package layout Build writes: "combo" (default,
+	// files batched into shared zstd frames) or "chunked" (one
+	// independently-decodable zstd sub-frame per file, plus a TOC trailer so
+	// Extract can pull a single file without decompressing anything else).
+	Format string
+	// ParallelBlockSize, if set (bytes), makes Replace split any rebuilt
+	// frame of at least parallelBlockMinFrameSize into blocks of this size
+	// and compress them concurrently with independent zstd encoders instead
+	// of a single EncodeAll call. 0 disables block splitting.
+	ParallelBlockSize int
+
+	// CacheDir overrides where Replace keeps its content-addressable frame
+	// cache. Empty defaults to "<OutputDir>/.evrcache".
+	CacheDir string
+	// NoCache disables Replace's frame cache entirely, so every modified
+	// frame is decompressed, patched and recompressed from scratch.
+	NoCache bool
+
+	// MaxDecompressedSize caps how many bytes any single zstd decompress -
+	// the manifest wrapper, or one frame during Extract/Replace - is allowed
+	// to produce. Defaults to 512 MiB if left zero, so a crafted
+	// CompressedHeader or frame header in a community-shared package can't
+	// force an unbounded allocation.
+	MaxDecompressedSize int64
+
+	// Logger receives progress messages formerly written to stdout. A nil
+	// Logger discards them.
+	Logger Logger
+	// ProgressMode selects how Extract reports its progress line: "auto"
+	// (default) rewrites one carriage-returned status line, "plain"
+	// prints a new line per tick instead, and "json" emits one NDJSON
+	// line per tick to stderr instead of going through Logger at all.
+	ProgressMode string
+
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// New returns a Package ready to use, with its zstd encoder/decoder
+// initialized and ManifestType/Format defaulted the way the CLI's flags used
+// to. Callers are free to set the remaining fields on the returned value
+// before calling Extract, Build or Replace.
+func New(dataDir, outputDir, packageName string) (*Package, error) {
+	p := &Package{
+		DataDir:      dataDir,
+		OutputDir:    outputDir,
+		PackageName:  packageName,
+		ManifestType: "5932408047-EVR",
+		Format:       "combo",
+	}
+
+	var err error
+	p.decoder, err = zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	p.encoder, err = zstd.NewWriter(nil, zstd.WithEncoderLevel(compressionLevel))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Package) log() Logger {
+	if p.Logger == nil {
+		return nopLogger{}
+	}
+	return p.Logger
+}
+
+func (p *Package) manifestType() string {
+	if p.ManifestType == "" {
+		return "5932408047-EVR"
+	}
+	return p.ManifestType
+}
+
+func (p *Package) format() string {
+	if p.Format == "" {
+		return "combo"
+	}
+	return p.Format
+}
+
+// defaultMaxDecompressedSize is MaxDecompressedSize's zero-value default.
+const defaultMaxDecompressedSize = 512 * 1024 * 1024
+
+func (p *Package) maxDecompressedSize() int64 {
+	if p.MaxDecompressedSize <= 0 {
+		return defaultMaxDecompressedSize
+	}
+	return p.MaxDecompressedSize
+}
+
+func (p *Package) progressMode() ProgressMode {
+	switch ProgressMode(p.ProgressMode) {
+	case ProgressPlain:
+		return ProgressPlain
+	case ProgressJSON:
+		return ProgressJSON
+	default:
+		return ProgressAuto
+	}
+}
+
+// Key identifies one file within a manifest by the same (typeSymbol,
+// fileSymbol) pair evrm.FrameContents and evrm.SomeStructure use.
+type Key struct {
+	TypeSymbol int64
+	FileSymbol int64
+}
+
+// ExtractFilter narrows what Extract writes out. The zero value extracts
+// every file with no grouping in the output path.
+type ExtractFilter struct {
+	// TexturesOnly, if true, only extracts the known texture type symbols
+	// (-4707359568332879775, 5353709876897953952, -2094201140079393352,
+	// 5231972605540061417).
+	TexturesOnly bool
+	// PreserveGroups, if true, preserves frame-index groups in the output
+	// path, e.g. "<outputDir>/<fileIndex>/<typeSymbol>/<fileSymbol>" instead
+	// of "<outputDir>/<typeSymbol>/<fileSymbol>".
+	PreserveGroups bool
+	// Format selects the ExtractSink Extract writes through: "" or "dir"
+	// (default) writes loose files the way Extract always has, "tar",
+	// "tar.zst" or "zip" instead collect every file and write a single
+	// "<PackageName>.<ext>" archive, with deterministic member ordering so
+	// repeated extractions produce byte-identical archives.
+	Format string
+	// Dedup, if true and Format is "dir" (or left empty), makes Extract
+	// shard every extracted file into content-defined chunks and pool
+	// unique ones once under "<OutputDir>/chunks/", writing every file that
+	// is a single chunk as a hardlink into that pool and every file of more
+	// than one chunk as an ".evrcas.json" sidecar listing its chunk hashes.
+	// Which form a file takes depends only on its chunk count, not on
+	// whether its content repeats elsewhere, so a file seen only once is
+	// also pooled rather than written as a plain copy. A Build reading
+	// the result back with scanSourceFiles resolves both forms
+	// transparently; reproducing the result via "rebuild.json" is not
+	// supported together with Dedup. Dedup has no effect for archive
+	// Formats, which have no on-disk tree to pool chunks under.
+	Dedup bool
+
+	// Include, if non-empty, restricts Extract to files whose
+	// "<fileIndex>/<typeSymbol>/<fileSymbol>" virtual path matches at least
+	// one of these path.Match glob patterns. Empty matches everything.
+	Include []string
+	// Exclude drops any file matching one of these glob patterns, in the
+	// same virtual-path form as Include, even if Include also matched it.
+	Exclude []string
+	// List, if true, makes Extract skip the sink entirely and instead print
+	// one line per surviving file - type, index, symbol, the enclosing
+	// frame's compressed size, the file's decompressed size, and its
+	// offset within the decompressed frame - so a package can be inspected
+	// without materializing anything to disk.
+	List bool
+}
+
+// textureTypeSymbols is the set of type symbols ExtractFilter.TexturesOnly
+// restricts extraction to.
+var textureTypeSymbols = map[int64]bool{
+	-4707359568332879775: true,
+	5353709876897953952:  true,
+	-2094201140079393352: true,
+	5231972605540061417:  true,
+}
+
+// CompressedHeader prefixes every on-disk manifest file: a zstd frame of
+// UncompressedSize/CompressedSize bytes follows it.
+type CompressedHeader struct {
+	Magic            [4]byte
+	HeaderSize       uint32
+	UncompressedSize uint64
+	CompressedSize   uint64
+}
+
+// LoadManifest reads and decompresses "<DataDir>/manifests/<PackageName>"
+// and unmarshals it per p.ManifestType. Extract and Replace both start here;
+// it's exported so callers that only need the manifest itself - e.g. to dump
+// it as JSON the way '-mode jsonmanifest' did - don't have to duplicate the
+// compressed-header handling.
+func (p *Package) LoadManifest() (evrm.EvrManifest, error) {
+	path := fmt.Sprintf("%s/manifests/%s", p.DataDir, p.PackageName)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return evrm.EvrManifest{}, fmt.Errorf("failed to open manifest file, check DataDir: %w", err)
+	}
+
+	compHeader := CompressedHeader{}
+	headerSize := binary.Size(compHeader)
+	if len(b) < headerSize {
+		return evrm.EvrManifest{}, fmt.Errorf("%s: shorter than a compressed header", path)
+	}
+	if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, &compHeader); err != nil {
+		return evrm.EvrManifest{}, fmt.Errorf("failed to marshal manifest into struct: %w", err)
+	}
+	if compHeader.UncompressedSize > uint64(p.maxDecompressedSize()) {
+		return evrm.EvrManifest{}, fmt.Errorf("%s: manifest declares %d decompressed bytes, over the %d byte limit", path, compHeader.UncompressedSize, p.maxDecompressedSize())
+	}
+	if len(b[headerSize:]) != int(compHeader.CompressedSize) {
+		return evrm.EvrManifest{}, fmt.Errorf("%s: manifest header does not match actual size of manifest", path)
+	}
+
+	decompBytes, err := p.decompressZSTD(b[headerSize:])
+	if err != nil {
+		return evrm.EvrManifest{}, fmt.Errorf("failed to decompress manifest: %w", err)
+	}
+	if len(decompBytes) != int(compHeader.UncompressedSize) {
+		return evrm.EvrManifest{}, fmt.Errorf("%s: manifest header does not match actual size of manifest", path)
+	}
+
+	manifest, err := evrm.MarshalManifest(decompBytes, p.manifestType())
+	if err != nil {
+		return evrm.EvrManifest{}, fmt.Errorf("error creating manifest: %w", err)
+	}
+	return manifest, nil
+}
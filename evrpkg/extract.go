@@ -0,0 +1,458 @@
+package evrpkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"sync"
+
+	evrm "github.com/goopsie/evrFileTools/evrManifests"
+)
+
+// Extract writes every file filter selects out of p's package/manifest pair
+// into p.OutputDir. It picks the fastest extraction path the on-disk layout
+// supports: a "<PackageName>.chunks.json" dedup sidecar if one exists, a
+// chunked-format TOC trailer if the package was built with Format "chunked",
+// or falling back to the ordinary FrameContents-driven walk.
+func (p *Package) Extract(ctx context.Context, filter ExtractFilter) error {
+	if filter.List {
+		return p.ListFiles(ctx, filter)
+	}
+	manifest, err := p.LoadManifest()
+	if err != nil {
+		return err
+	}
+
+	chunkManifestFile := fmt.Sprintf("%s/manifests/%s.chunks.json", p.DataDir, p.PackageName)
+	if chunkManifest, err := loadChunkManifest(chunkManifestFile); err == nil {
+		return p.extractDeduped(ctx, manifest, chunkManifest, filter)
+	}
+	if tocEntries, ok := p.detectChunkedFormat(manifest); ok {
+		return p.extractChunked(ctx, manifest, tocEntries, filter)
+	}
+	return p.extractCombo(ctx, manifest, filter)
+}
+
+// matchesIncludeExclude reports whether virtualPath - a file's
+// "<fileIndex>/<typeSymbol>/<fileSymbol>" path - survives filter.Include
+// and filter.Exclude. A malformed glob pattern never matches, the same way
+// path.Match itself treats ErrBadPattern.
+func matchesIncludeExclude(virtualPath string, filter ExtractFilter) bool {
+	if len(filter.Include) > 0 {
+		matched := false
+		for _, pattern := range filter.Include {
+			if ok, _ := path.Match(pattern, virtualPath); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, pattern := range filter.Exclude {
+		if ok, _ := path.Match(pattern, virtualPath); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// extractFilterMatches reports whether content survives filter's
+// TexturesOnly, Include and Exclude checks combined.
+func extractFilterMatches(filter ExtractFilter, content evrm.FrameContents) bool {
+	if filter.TexturesOnly {
+		if _, ok := textureTypeSymbols[content.T]; !ok {
+			return false
+		}
+	}
+	virtualPath := fmt.Sprintf("%d/%d/%d", content.FileIndex, content.T, content.FileSymbol)
+	return matchesIncludeExclude(virtualPath, filter)
+}
+
+// ListFiles walks fullManifest's FrameContents applying filter the same way
+// Extract would, but prints one line per surviving file through p.Logger
+// instead of writing anything out - a quick way to inspect what a large
+// package contains before committing to extracting it.
+func (p *Package) ListFiles(ctx context.Context, filter ExtractFilter) error {
+	manifest, err := p.LoadManifest()
+	if err != nil {
+		return err
+	}
+
+	for _, content := range manifest.FrameContents {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !extractFilterMatches(filter, content) {
+			continue
+		}
+		if int(content.FileIndex) >= len(manifest.Frames) {
+			return fmt.Errorf("file %d/%d references frame %d, manifest only has %d frames", content.T, content.FileSymbol, content.FileIndex, len(manifest.Frames))
+		}
+		frame := manifest.Frames[content.FileIndex]
+		p.log().Printf("%d/%d/%d\tcompressedSize=%d\tdecompressedSize=%d\toffset=%d\n",
+			content.FileIndex, content.T, content.FileSymbol,
+			frame.CompressedSize, content.Size, content.DataOffset)
+	}
+	return nil
+}
+
+func (p *Package) openPackages(count int) (map[uint32]*os.File, error) {
+	packages := make(map[uint32]*os.File, count)
+	for i := 0; i < count; i++ {
+		pFilePath := fmt.Sprintf("%s/packages/%s_%d", p.DataDir, p.PackageName, i)
+		f, err := os.Open(pFilePath)
+		if err != nil {
+			for _, open := range packages {
+				open.Close()
+			}
+			return nil, fmt.Errorf("failed to open package %s: %w", pFilePath, err)
+		}
+		packages[uint32(i)] = f
+	}
+	return packages, nil
+}
+
+func closePackages(packages map[uint32]*os.File) {
+	for _, f := range packages {
+		f.Close()
+	}
+}
+
+func (p *Package) extractCombo(ctx context.Context, fullManifest evrm.EvrManifest, filter ExtractFilter) error {
+	packages, err := p.openPackages(int(fullManifest.Header.PackageCount))
+	if err != nil {
+		return err
+	}
+	defer closePackages(packages)
+
+	framesToProcess := make(map[uint32][]evrm.FrameContents)
+	for _, content := range fullManifest.FrameContents {
+		if !extractFilterMatches(filter, content) {
+			continue
+		}
+		framesToProcess[content.FileIndex] = append(framesToProcess[content.FileIndex], content)
+	}
+
+	type extractJob struct {
+		frameIndex int
+		data       []byte
+	}
+
+	numWorkers := runtime.NumCPU()
+	jobs := make(chan extractJob, numWorkers*2)
+	var wg sync.WaitGroup
+
+	progress := NewProgress(int64(fullManifest.Header.Frames.Count))
+	progressDone := make(chan struct{})
+	defer close(progressDone)
+	go progress.run(p.progressMode(), p.log(), "Extracting", progressDone)
+
+	var workerErr error
+	var workerErrOnce sync.Once
+
+	var rebuildMu sync.Mutex
+	rebuildFrames := make(map[uint32]RebuildFrame)
+
+	sink, err := p.newExtractSink(filter)
+	if err != nil {
+		return err
+	}
+
+	worker := func() {
+		defer wg.Done()
+		for job := range jobs {
+			decompBytes, err := p.decompressZSTD(job.data)
+			if err != nil {
+				p.log().Printf("Error decompressing frame %d: %v\n", job.frameIndex, err)
+				continue
+			}
+			if len(decompBytes) != int(fullManifest.Frames[job.frameIndex].DecompressedSize) {
+				p.log().Printf("Size mismatch frame %d\n", job.frameIndex)
+				continue
+			}
+
+			contents, ok := framesToProcess[uint32(job.frameIndex)]
+			if !ok {
+				continue
+			}
+			sortedContents := append([]evrm.FrameContents(nil), contents...)
+			sort.Slice(sortedContents, func(a, b int) bool { return sortedContents[a].DataOffset < sortedContents[b].DataOffset })
+
+			rebuildFrame := RebuildFrame{FileIndex: uint32(job.frameIndex)}
+			if meta, err := decodeZstdFrameHeader(job.data); err == nil {
+				rebuildFrame.WindowSize = meta.WindowSize
+				rebuildFrame.DictionaryID = meta.DictionaryID
+			}
+
+			for _, v2 := range sortedContents {
+				relPath := fmt.Sprintf("%d/%d", v2.T, v2.FileSymbol)
+				if filter.PreserveGroups {
+					relPath = fmt.Sprintf("%d/%d/%d", v2.FileIndex, v2.T, v2.FileSymbol)
+				}
+
+				if uint64(v2.DataOffset)+uint64(v2.Size) > uint64(len(decompBytes)) {
+					workerErrOnce.Do(func() {
+						workerErr = fmt.Errorf("frame %d: file %d/%d (size %d at offset %d) extends past its %d-byte decompressed frame",
+							job.frameIndex, v2.T, v2.FileSymbol, v2.Size, v2.DataOffset, len(decompBytes))
+					})
+					continue
+				}
+
+				fileBytes := decompBytes[v2.DataOffset : v2.DataOffset+v2.Size]
+				if err := sink.WriteFile(relPath, fileBytes, 0777); err != nil {
+					workerErrOnce.Do(func() { workerErr = err })
+					continue
+				}
+				progress.AddFilesWritten(1)
+				progress.AddDecompressedBytes(int64(len(fileBytes)))
+
+				rebuildFrame.Files = append(rebuildFrame.Files, RebuildFrameFile{
+					Path:          relPath,
+					TypeSymbol:    v2.T,
+					FileSymbol:    v2.FileSymbol,
+					DataOffset:    v2.DataOffset,
+					Size:          v2.Size,
+					SomeAlignment: v2.SomeAlignment,
+				})
+			}
+
+			rebuildMu.Lock()
+			rebuildFrames[uint32(job.frameIndex)] = rebuildFrame
+			rebuildMu.Unlock()
+		}
+	}
+
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go worker()
+	}
+
+	totalFilesWritten := 0
+	for k, v := range fullManifest.Frames {
+		if err := ctx.Err(); err != nil {
+			close(jobs)
+			wg.Wait()
+			return err
+		}
+		if _, ok := framesToProcess[uint32(k)]; !ok {
+			continue
+		}
+
+		activeFile := packages[v.CurrentPackageIndex]
+		activeFile.Seek(int64(v.CurrentOffset), 0)
+
+		splitFile := make([]byte, v.CompressedSize)
+		if v.CompressedSize == 0 {
+			continue
+		}
+		_, err := io.ReadAtLeast(activeFile, splitFile, int(v.CompressedSize))
+		if err != nil && v.DecompressedSize == 0 {
+			continue
+		} else if err != nil {
+			close(jobs)
+			wg.Wait()
+			return fmt.Errorf("failed to read file, check input: %w", err)
+		}
+
+		progress.AddFrame(1)
+		progress.AddCompressedBytes(int64(len(splitFile)))
+
+		jobs <- extractJob{frameIndex: k, data: splitFile}
+		totalFilesWritten++
+	}
+
+	close(jobs)
+	wg.Wait()
+	if workerErr != nil {
+		return workerErr
+	}
+
+	if err := sink.Close(); err != nil {
+		return fmt.Errorf("failed to finalize extract sink: %w", err)
+	}
+
+	rm := &RebuildManifest{Frames: make([]RebuildFrame, 0, len(rebuildFrames))}
+	for _, rf := range rebuildFrames {
+		rm.Frames = append(rm.Frames, rf)
+	}
+	if err := writeRebuildManifest(p.OutputDir, rm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", rebuildManifestName, err)
+	}
+	return nil
+}
+
+// extractChunked is extractCombo's counterpart for a "Format: chunked"
+// package: entries already resolve each file's exact sub-frame, so
+// extraction never touches a package byte outside what was asked for -
+// there's no outer frame to decompress before getting at the file inside
+// it, unlike extractCombo's FrameContents-driven path.
+func (p *Package) extractChunked(ctx context.Context, fullManifest evrm.EvrManifest, entries []chunkedTOCEntry, filter ExtractFilter) error {
+	packages, err := p.openPackages(int(fullManifest.Header.PackageCount))
+	if err != nil {
+		return err
+	}
+	defer closePackages(packages)
+
+	sink, err := p.newExtractSink(filter)
+	if err != nil {
+		return err
+	}
+
+	logTimer := make(chan bool, 1)
+	go logTimerFunc(logTimer)
+
+	totalFilesWritten := 0
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if filter.TexturesOnly {
+			if _, ok := textureTypeSymbols[entry.TypeSymbol]; !ok {
+				continue
+			}
+		}
+		virtualPath := fmt.Sprintf("%d/%d/%d", entry.FrameIndex, entry.TypeSymbol, entry.FileSymbol)
+		if !matchesIncludeExclude(virtualPath, filter) {
+			continue
+		}
+		if int(entry.FrameIndex) >= len(fullManifest.Frames) {
+			return fmt.Errorf("TOC entry for file %x references frame %d, manifest only has %d frames", entry.FileSymbol, entry.FrameIndex, len(fullManifest.Frames))
+		}
+		frame := fullManifest.Frames[entry.FrameIndex]
+		activeFile, ok := packages[frame.CurrentPackageIndex]
+		if !ok {
+			return fmt.Errorf("frame %d references package %d, which isn't open", entry.FrameIndex, frame.CurrentPackageIndex)
+		}
+
+		compressed := make([]byte, entry.SubFrameCompressedSize)
+		if _, err := activeFile.ReadAt(compressed, int64(entry.SubFrameOffset)); err != nil {
+			return fmt.Errorf("failed to read sub-frame for file %x: %w", entry.FileSymbol, err)
+		}
+		decompBytes, err := p.decompressZSTD(compressed)
+		if err != nil {
+			return fmt.Errorf("failed to decompress sub-frame for file %x: %w", entry.FileSymbol, err)
+		}
+		if uint32(len(decompBytes)) != entry.UncompressedSize {
+			p.log().Printf("size mismatch for file %x\n", entry.FileSymbol)
+			continue
+		}
+
+		relPath := fmt.Sprintf("%d/%d", entry.TypeSymbol, entry.FileSymbol)
+		if filter.PreserveGroups {
+			relPath = fmt.Sprintf("%d/%d/%d", entry.FrameIndex, entry.TypeSymbol, entry.FileSymbol)
+		}
+		if err := sink.WriteFile(relPath, decompBytes, 0777); err != nil {
+			return err
+		}
+
+		totalFilesWritten++
+		if len(logTimer) > 0 {
+			<-logTimer
+			p.log().Printf("\033[2K\rExtracting file %d/%d", totalFilesWritten, len(entries))
+		}
+	}
+
+	return sink.Close()
+}
+
+// extractDeduped is extractCombo's counterpart for a manifest built with
+// Dedupe: instead of walking FrameContents, it reassembles each file from
+// chunkManifest's ordered chunk hash list, decompressing each referenced
+// frame at most once regardless of how many files or chunks reference it.
+func (p *Package) extractDeduped(ctx context.Context, fullManifest evrm.EvrManifest, chunkManifest *ChunkManifest, filter ExtractFilter) error {
+	packages, err := p.openPackages(int(fullManifest.Header.PackageCount))
+	if err != nil {
+		return err
+	}
+	defer closePackages(packages)
+
+	sink, err := p.newExtractSink(filter)
+	if err != nil {
+		return err
+	}
+
+	decompressedFrames := make(map[uint32][]byte)
+	decompressFrame := func(frameIndex uint32) ([]byte, error) {
+		if b, ok := decompressedFrames[frameIndex]; ok {
+			return b, nil
+		}
+		if int(frameIndex) >= len(fullManifest.Frames) {
+			return nil, fmt.Errorf("chunk references frame %d, manifest only has %d frames", frameIndex, len(fullManifest.Frames))
+		}
+		v := fullManifest.Frames[frameIndex]
+		activeFile, ok := packages[v.CurrentPackageIndex]
+		if !ok {
+			return nil, fmt.Errorf("frame %d references package %d, which isn't open", frameIndex, v.CurrentPackageIndex)
+		}
+
+		compressed := make([]byte, v.CompressedSize)
+		if _, err := activeFile.ReadAt(compressed, int64(v.CurrentOffset)); err != nil {
+			return nil, fmt.Errorf("failed to read frame %d: %w", frameIndex, err)
+		}
+		decompBytes, err := p.decompressZSTD(compressed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress frame %d: %w", frameIndex, err)
+		}
+		decompressedFrames[frameIndex] = decompBytes
+		return decompBytes, nil
+	}
+
+	logTimer := make(chan bool, 1)
+	go logTimerFunc(logTimer)
+
+	totalFilesWritten := 0
+	for _, entry := range chunkManifest.Files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if filter.TexturesOnly {
+			if _, ok := textureTypeSymbols[entry.TypeSymbol]; !ok {
+				continue
+			}
+		}
+		virtualPath := fmt.Sprintf("%d/%d/%d", entry.FileIndex, entry.TypeSymbol, entry.FileSymbol)
+		if !matchesIncludeExclude(virtualPath, filter) {
+			continue
+		}
+
+		constructed := bytes.NewBuffer(nil)
+		for _, sum := range entry.Chunks {
+			loc, ok := chunkManifest.Chunks[hex.EncodeToString(sum[:])]
+			if !ok {
+				return fmt.Errorf("file %x references unknown chunk %x", entry.FileSymbol, sum)
+			}
+			frameBytes, err := decompressFrame(loc.FrameIndex)
+			if err != nil {
+				return err
+			}
+			if int64(loc.DataOffset)+int64(loc.Size) > int64(len(frameBytes)) {
+				return fmt.Errorf("file %x chunk extends past decompressed frame %d", entry.FileSymbol, loc.FrameIndex)
+			}
+			constructed.Write(frameBytes[loc.DataOffset : loc.DataOffset+loc.Size])
+		}
+
+		relPath := fmt.Sprintf("%d/%d", entry.TypeSymbol, entry.FileSymbol)
+		if filter.PreserveGroups {
+			relPath = fmt.Sprintf("%d/%d/%d", entry.FileIndex, entry.TypeSymbol, entry.FileSymbol)
+		}
+		if err := sink.WriteFile(relPath, constructed.Bytes(), 0777); err != nil {
+			return err
+		}
+
+		totalFilesWritten++
+		if len(logTimer) > 0 {
+			<-logTimer
+			p.log().Printf("\033[2K\rExtracting file %d/%d", totalFilesWritten, len(chunkManifest.Files))
+		}
+	}
+
+	return sink.Close()
+}
@@ -0,0 +1,179 @@
+package evrpkg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// casChunksDirName is the CAS pool a dedup'd Extract writes unique
+// content-defined chunks into, under OutputDir, and a dedup-aware Build
+// scan reads them back from.
+const casChunksDirName = "chunks"
+
+// casIndexName is the sidecar extractCAS.writeIndex flushes once
+// extraction finishes, mapping every pooled chunk's hash to its size, so
+// the pool can be verified or garbage-collected without re-hashing every
+// blob.
+const casIndexName = "index"
+
+// evrCasManifestExt marks an extracted file that splitContentDefined cut
+// into more than one chunk, whether or not those chunks repeat elsewhere:
+// "<path>"+evrCasManifestExt holds an
+// evrCasFileManifest listing the pooled chunks that reassemble it, in
+// order, instead of the file's own bytes sitting at "<path>".
+const evrCasManifestExt = ".evrcas.json"
+
+// evrCasChunkRef is one chunk of a deduped file, in the order its bytes
+// belong in the reassembled file.
+type evrCasChunkRef struct {
+	Hash string `json:"hash"`
+	Size uint32 `json:"size"`
+}
+
+// evrCasFileManifest is the sidecar extractCAS.writeFile writes in place
+// of a file's content when splitContentDefined cut it into more than one
+// chunk.
+type evrCasFileManifest struct {
+	Chunks []evrCasChunkRef `json:"chunks"`
+}
+
+// extractCAS pools the content-defined chunks a dedup'd Extract writes
+// under "<OutputDir>/chunks/", so a chunk repeated across frames or files
+// is only written to disk once. It's shared across extractCombo's worker
+// goroutines, with seen guarded by mu.
+type extractCAS struct {
+	outputDir string
+
+	mu   sync.Mutex
+	seen map[[32]byte]uint32
+}
+
+func newExtractCAS(outputDir string) *extractCAS {
+	return &extractCAS{outputDir: outputDir, seen: make(map[[32]byte]uint32)}
+}
+
+func (c *extractCAS) poolPath(sum [32]byte) string {
+	return filepath.Join(c.outputDir, casChunksDirName, hex.EncodeToString(sum[:]))
+}
+
+// store writes sum's pooled blob the first time it's seen; later calls for
+// an already-pooled chunk are no-ops.
+func (c *extractCAS) store(sum [32]byte, data []byte) error {
+	c.mu.Lock()
+	_, ok := c.seen[sum]
+	if !ok {
+		c.seen[sum] = uint32(len(data))
+	}
+	c.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Join(c.outputDir, casChunksDirName), 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(c.poolPath(sum), data, 0666)
+}
+
+// writeFile pools data's content-defined chunks and materializes path as
+// either a hardlink to the pooled blob, when data is a single chunk, or an
+// evrCasFileManifest referencing the pooled chunks in order, when it's
+// more than one.
+func (c *extractCAS) writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+
+	chunks := splitContentDefined(data)
+	if len(chunks) <= 1 {
+		if len(data) == 0 {
+			return os.WriteFile(path, nil, 0666)
+		}
+		sum := chunkSum(data)
+		if err := c.store(sum, data); err != nil {
+			return err
+		}
+		os.Remove(path)
+		if err := os.Link(c.poolPath(sum), path); err != nil {
+			return os.WriteFile(path, data, 0666)
+		}
+		return nil
+	}
+
+	refs := make([]evrCasChunkRef, 0, len(chunks))
+	for _, chunk := range chunks {
+		sum := chunkSum(chunk)
+		if err := c.store(sum, chunk); err != nil {
+			return err
+		}
+		refs = append(refs, evrCasChunkRef{Hash: hex.EncodeToString(sum[:]), Size: uint32(len(chunk))})
+	}
+
+	manifestBytes, err := json.Marshal(evrCasFileManifest{Chunks: refs})
+	if err != nil {
+		return err
+	}
+	os.Remove(path)
+	return os.WriteFile(path+evrCasManifestExt, manifestBytes, 0666)
+}
+
+// writeIndex flushes "<OutputDir>/chunks/index", mapping every pooled
+// chunk's hash to its size, for tooling that wants to verify or garbage
+// collect the pool without re-hashing every blob.
+func (c *extractCAS) writeIndex() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	index := make(map[string]uint32, len(c.seen))
+	for sum, size := range c.seen {
+		index[hex.EncodeToString(sum[:])] = size
+	}
+
+	if err := os.MkdirAll(filepath.Join(c.outputDir, casChunksDirName), 0777); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(index, "", " ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.outputDir, casChunksDirName, casIndexName), b, 0666)
+}
+
+// readDedupedSourceFile reads fsys's path, transparently reassembling an
+// evrCasFileManifest - the form a file takes after a dedup'd Extract when
+// it was cut into more than one chunk - into its real content by reading and
+// concatenating each referenced chunk from fsys's "chunks/" CAS pool. A
+// path that isn't a CAS manifest, including one that's merely a hardlink
+// into the pool, is read as-is.
+func readDedupedSourceFile(fsys fs.FS, filePath string) ([]byte, error) {
+	if !strings.HasSuffix(filePath, evrCasManifestExt) {
+		return fs.ReadFile(fsys, filePath)
+	}
+
+	b, err := fs.ReadFile(fsys, filePath)
+	if err != nil {
+		return nil, err
+	}
+	var cm evrCasFileManifest
+	if err := json.Unmarshal(b, &cm); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	for _, ref := range cm.Chunks {
+		chunkBytes, err := fs.ReadFile(fsys, path.Join(casChunksDirName, ref.Hash))
+		if err != nil {
+			return nil, fmt.Errorf("%s: chunk %s: %w", filePath, ref.Hash, err)
+		}
+		out.Write(chunkBytes)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,194 @@
+package evrpkg
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ExtractSink is where Extract's worker goroutines send each file's bytes.
+// WriteFile may be called concurrently from multiple goroutines; a sink
+// that can't accept concurrent writers must serialize them itself.
+// Close flushes and finalizes the sink - for an archive sink, this is
+// where the actual archive gets written.
+type ExtractSink interface {
+	WriteFile(path string, data []byte, mode fs.FileMode) error
+	Close() error
+}
+
+// newExtractSink opens the sink filter.Format selects, rooted at
+// p.OutputDir: "" or "dir" (default) writes loose files the way Extract
+// always has, "tar"/"tar.zst"/"zip" instead collect every file and write
+// a single "<PackageName>.<ext>" archive on Close.
+func (p *Package) newExtractSink(filter ExtractFilter) (ExtractSink, error) {
+	switch filter.Format {
+	case "", "dir":
+		var cas *extractCAS
+		if filter.Dedup {
+			cas = newExtractCAS(p.OutputDir)
+		}
+		return &dirSink{outputDir: p.OutputDir, cas: cas}, nil
+	case "tar":
+		f, err := os.Create(filepath.Join(p.OutputDir, p.PackageName+".tar"))
+		if err != nil {
+			return nil, err
+		}
+		return newTarSink(f), nil
+	case "tar.zst":
+		f, err := os.Create(filepath.Join(p.OutputDir, p.PackageName+".tar.zst"))
+		if err != nil {
+			return nil, err
+		}
+		return p.newTarZstSink(f)
+	case "zip":
+		f, err := os.Create(filepath.Join(p.OutputDir, p.PackageName+".zip"))
+		if err != nil {
+			return nil, err
+		}
+		return newZipSink(f), nil
+	default:
+		return nil, fmt.Errorf("unknown extract format %q: must be one of 'dir', 'tar', 'tar.zst', 'zip'", filter.Format)
+	}
+}
+
+// dirSink is Extract's original behavior: every file becomes its own
+// os.WriteFile under outputDir. os.MkdirAll/os.WriteFile are already safe
+// to call concurrently for distinct paths, so dirSink needs no locking of
+// its own; when cas is set it hands the write to extractCAS instead,
+// which does its own locking around the shared chunk pool. path, as
+// received from WriteFile, is relative to outputDir.
+type dirSink struct {
+	outputDir string
+	cas       *extractCAS
+}
+
+func (s *dirSink) WriteFile(path string, data []byte, mode fs.FileMode) error {
+	fullPath := filepath.Join(s.outputDir, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0777); err != nil {
+		return err
+	}
+	if s.cas != nil {
+		return s.cas.writeFile(fullPath, data)
+	}
+	return os.WriteFile(fullPath, data, mode)
+}
+
+func (s *dirSink) Close() error {
+	if s.cas == nil {
+		return nil
+	}
+	return s.cas.writeIndex()
+}
+
+// archiveEntry is one file buffered by a bufferedArchiveSink until Close.
+type archiveEntry struct {
+	path string
+	data []byte
+	mode fs.FileMode
+}
+
+// bufferedArchiveSink backs every archive sink (tar, tar.zst, zip): since
+// extractCombo's worker goroutines write files in whatever order their
+// frame finishes decompressing, an archive sink has to buffer every file
+// and sort it before writing, or two runs over the same package could
+// produce byte-different archives. finalize does the actual encoding once
+// Close has the complete, sorted entry list.
+type bufferedArchiveSink struct {
+	mu       sync.Mutex
+	entries  []archiveEntry
+	finalize func([]archiveEntry) error
+}
+
+func (s *bufferedArchiveSink) WriteFile(path string, data []byte, mode fs.FileMode) error {
+	cp := append([]byte(nil), data...)
+	s.mu.Lock()
+	s.entries = append(s.entries, archiveEntry{path: path, data: cp, mode: mode})
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *bufferedArchiveSink) Close() error {
+	s.mu.Lock()
+	entries := s.entries
+	s.entries = nil
+	s.mu.Unlock()
+
+	sort.Slice(entries, func(a, b int) bool { return entries[a].path < entries[b].path })
+	return s.finalize(entries)
+}
+
+func newTarSink(f *os.File) *bufferedArchiveSink {
+	return &bufferedArchiveSink{finalize: func(entries []archiveEntry) error {
+		defer f.Close()
+		tw := tar.NewWriter(f)
+		if err := writeTarEntries(tw, entries); err != nil {
+			return err
+		}
+		return tw.Close()
+	}}
+}
+
+// newTarZstSink is newTarSink's zstd-compressed counterpart: the tar
+// stream is written through a fresh zstd.Writer at p's encoder level
+// rather than p.encoder itself, since p.encoder isn't safe for concurrent
+// use and Close already runs outside the worker goroutines that share it.
+func (p *Package) newTarZstSink(f *os.File) (*bufferedArchiveSink, error) {
+	return &bufferedArchiveSink{finalize: func(entries []archiveEntry) error {
+		defer f.Close()
+		zw, err := zstd.NewWriter(f, zstd.WithEncoderLevel(compressionLevel))
+		if err != nil {
+			return err
+		}
+		tw := tar.NewWriter(zw)
+		if err := writeTarEntries(tw, entries); err != nil {
+			return err
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return zw.Close()
+	}}, nil
+}
+
+func writeTarEntries(tw *tar.Writer, entries []archiveEntry) error {
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name: e.path,
+			Mode: int64(e.mode.Perm()),
+			Size: int64(len(e.data)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("%s: %w", e.path, err)
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			return fmt.Errorf("%s: %w", e.path, err)
+		}
+	}
+	return nil
+}
+
+func newZipSink(f *os.File) *bufferedArchiveSink {
+	return &bufferedArchiveSink{finalize: func(entries []archiveEntry) error {
+		defer f.Close()
+		zw := zip.NewWriter(f)
+		for _, e := range entries {
+			hdr := &zip.FileHeader{Name: e.path, Method: zip.Deflate}
+			hdr.SetMode(e.mode)
+			w, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return fmt.Errorf("%s: %w", e.path, err)
+			}
+			if _, err := w.Write(e.data); err != nil {
+				return fmt.Errorf("%s: %w", e.path, err)
+			}
+		}
+		return zw.Close()
+	}}
+}
@@ -0,0 +1,139 @@
+package evrpkg
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cacheDirName is the default subdirectory of OutputDir Replace's frame
+// cache lives in when Package.CacheDir is left empty.
+const cacheDirName = ".evrcache"
+
+// cacheMaxEntries caps how many compressed frames frameCache keeps around
+// before evict starts reclaiming the least recently used ones.
+const cacheMaxEntries = 2048
+
+// frameCache is an on-disk, content-addressable store of already-compressed
+// replace frames, keyed by frameCacheKey. Each entry is a pair of files -
+// "<key>.bin" holding the compressed payload and "<key>.json" holding its
+// decompressed size - so a hit can be served without touching the manifest
+// or the source package at all.
+type frameCache struct {
+	dir string
+}
+
+// newFrameCache returns a frameCache rooted at dir. dir is created lazily by
+// store, not here, so a run that never hits a cache miss never creates it.
+func newFrameCache(dir string) *frameCache {
+	return &frameCache{dir: dir}
+}
+
+// frameCache returns the cache Replace should use, or nil if p.NoCache is
+// set. A nil *frameCache is always a cache miss, so callers can use it
+// unconditionally without a separate "is caching enabled" check.
+func (p *Package) frameCache() *frameCache {
+	if p.NoCache {
+		return nil
+	}
+	dir := p.CacheDir
+	if dir == "" {
+		dir = filepath.Join(p.OutputDir, cacheDirName)
+	}
+	return newFrameCache(dir)
+}
+
+type frameCacheEntry struct {
+	DecompressedSize uint32 `json:"decompressedSize"`
+}
+
+func (c *frameCache) binPath(key [32]byte) string {
+	return filepath.Join(c.dir, hex.EncodeToString(key[:])+".bin")
+}
+
+func (c *frameCache) metaPath(key [32]byte) string {
+	return filepath.Join(c.dir, hex.EncodeToString(key[:])+".json")
+}
+
+// lookup returns the cached compressed frame for key, if present. A hit
+// bumps the entry's mtime so evict's LRU ordering reflects last use, not
+// last write.
+func (c *frameCache) lookup(key [32]byte) (data []byte, decompressedSize uint32, ok bool) {
+	meta, err := os.ReadFile(c.metaPath(key))
+	if err != nil {
+		return nil, 0, false
+	}
+	var entry frameCacheEntry
+	if err := json.Unmarshal(meta, &entry); err != nil {
+		return nil, 0, false
+	}
+	data, err = os.ReadFile(c.binPath(key))
+	if err != nil {
+		return nil, 0, false
+	}
+
+	now := time.Now()
+	os.Chtimes(c.binPath(key), now, now)
+	os.Chtimes(c.metaPath(key), now, now)
+
+	return data, entry.DecompressedSize, true
+}
+
+// store writes a compressed frame and its decompressed size under key.
+func (c *frameCache) store(key [32]byte, data []byte, decompressedSize uint32) error {
+	if err := os.MkdirAll(c.dir, 0777); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.binPath(key), data, 0666); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(frameCacheEntry{DecompressedSize: decompressedSize})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.metaPath(key), meta, 0666)
+}
+
+// evict removes the least-recently-used entries (by mtime) until at most
+// maxEntries remain, so an iterative modding workflow that keeps re-running
+// Replace doesn't grow the cache directory without bound.
+func (c *frameCache) evict(maxEntries int) error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	type bin struct {
+		key     string
+		modTime time.Time
+	}
+	var bins []bin
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".bin") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		bins = append(bins, bin{key: strings.TrimSuffix(name, ".bin"), modTime: info.ModTime()})
+	}
+	if len(bins) <= maxEntries {
+		return nil
+	}
+
+	sort.Slice(bins, func(a, b int) bool { return bins[a].modTime.Before(bins[b].modTime) })
+	for _, b := range bins[:len(bins)-maxEntries] {
+		os.Remove(filepath.Join(c.dir, b.key+".bin"))
+		os.Remove(filepath.Join(c.dir, b.key+".json"))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package evrpkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+
+	evrm "github.com/goopsie/evrFileTools/evrManifests"
+)
+
+// decompressZSTD decompresses b, rejecting it before calling DecodeAll if
+// its zstd frame header declares more than p.maxDecompressedSize() bytes of
+// content - and again afterwards, in case the header omitted a content size
+// - so a crafted frame can't force DecodeAll to preallocate an unbounded
+// buffer. Mirrors the same cap LoadManifest applies to CompressedHeader.
+func (p *Package) decompressZSTD(b []byte) ([]byte, error) {
+	limit := p.maxDecompressedSize()
+	if size, ok := zstdFrameContentSize(b); ok && size > uint64(limit) {
+		return nil, fmt.Errorf("zstd frame declares %d decompressed bytes, over the %d byte limit", size, limit)
+	}
+
+	out, err := p.decoder.DecodeAll(b, nil)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, fmt.Errorf("decompressed output is %d bytes, over the %d byte limit", len(out), limit)
+	}
+	return out, nil
+}
+
+func incrementHeaderChunk(chunk evrm.HeaderChunk, amount int) evrm.HeaderChunk {
+	for i := 0; i < amount; i++ {
+		chunk.Count++
+		chunk.ElementCount++
+		chunk.SectionSize += uint64(chunk.ElementSize)
+	}
+	return chunk
+}
+
+// writeManifest compresses manifest per p.ManifestType and writes it to
+// "<OutputDir>/manifests/<PackageName>".
+func (p *Package) writeManifest(manifest evrm.EvrManifest) error {
+	os.MkdirAll(p.OutputDir+"/manifests/", 0777)
+	file, err := os.OpenFile(p.OutputDir+"/manifests/"+p.PackageName, os.O_RDWR|os.O_CREATE, 0777)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	manifestBytes, err := evrm.UnmarshalManifest(manifest, p.manifestType())
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(p.compressManifest(manifestBytes))
+	return err
+}
+
+func (p *Package) compressManifest(b []byte) []byte {
+	zstdBytes := p.encoder.EncodeAll(b, nil)
+
+	cHeader := CompressedHeader{
+		[4]byte{0x5A, 0x53, 0x54, 0x44},
+		uint32(binary.Size(CompressedHeader{})),
+		uint64(len(b)),
+		uint64(len(zstdBytes)),
+	}
+
+	fBuf := bytes.NewBuffer(nil)
+	binary.Write(fBuf, binary.LittleEndian, cHeader)
+	fBuf.Write(zstdBytes)
+	return fBuf.Bytes()
+}
+
+// logTimerFunc feeds logTimer once a second so progress-printing loops
+// elsewhere only log at most once per tick, instead of once per item.
+func logTimerFunc(logTimer chan bool) {
+	for {
+		time.Sleep(1 * time.Second)
+		logTimer <- true
+	}
+}
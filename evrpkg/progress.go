@@ -0,0 +1,160 @@
+package evrpkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressMode selects how a Progress reports each tick: ProgressAuto
+// (default) rewrites one carriage-returned status line the way Extract
+// always has, ProgressPlain prints a new line per tick instead - for
+// output piped to a file or CI log, where "\r" has no effect - and
+// ProgressJSON emits one NDJSON line per tick straight to stderr instead
+// of going through a Logger, for a GUI or CI wrapper to parse.
+type ProgressMode string
+
+const (
+	ProgressAuto  ProgressMode = "auto"
+	ProgressPlain ProgressMode = "plain"
+	ProgressJSON  ProgressMode = "json"
+)
+
+// Progress accumulates the counters a long-running pass over a package -
+// today, Extract - reports while it runs: frames and files done, and
+// compressed bytes read versus decompressed bytes written. Add* methods
+// are safe to call concurrently from worker goroutines; run's ticker
+// goroutine is the only reader.
+type Progress struct {
+	// TotalFrames is the frame count a full run is expected to process,
+	// used to compute ETA. It's fixed at construction since Extract knows
+	// it up front from the manifest.
+	TotalFrames int64
+
+	framesDone           int64
+	filesWritten         int64
+	compressedBytesIn    int64
+	decompressedBytesOut int64
+
+	start time.Time
+}
+
+// NewProgress returns a Progress tracking totalFrames, with its elapsed-time
+// clock starting immediately.
+func NewProgress(totalFrames int64) *Progress {
+	return &Progress{TotalFrames: totalFrames, start: time.Now()}
+}
+
+func (pr *Progress) AddFrame(n int64)             { atomic.AddInt64(&pr.framesDone, n) }
+func (pr *Progress) AddFilesWritten(n int64)      { atomic.AddInt64(&pr.filesWritten, n) }
+func (pr *Progress) AddCompressedBytes(n int64)   { atomic.AddInt64(&pr.compressedBytesIn, n) }
+func (pr *Progress) AddDecompressedBytes(n int64) { atomic.AddInt64(&pr.decompressedBytesOut, n) }
+
+// progressSnapshot is one tick's worth of Progress, formatted for either a
+// text status line or, via its JSON tags, a ProgressJSON NDJSON line.
+type progressSnapshot struct {
+	FramesDone     int64   `json:"framesDone"`
+	TotalFrames    int64   `json:"totalFrames"`
+	FilesWritten   int64   `json:"filesWritten"`
+	BytesIn        int64   `json:"bytesIn"`
+	BytesOut       int64   `json:"bytesOut"`
+	BytesInPerSec  float64 `json:"bytesInPerSec"`
+	BytesOutPerSec float64 `json:"bytesOutPerSec"`
+	ETASeconds     float64 `json:"etaSeconds,omitempty"`
+}
+
+func (pr *Progress) snapshot() progressSnapshot {
+	elapsed := time.Since(pr.start).Seconds()
+	if elapsed <= 0 {
+		elapsed = 1e-9
+	}
+
+	framesDone := atomic.LoadInt64(&pr.framesDone)
+	bytesIn := atomic.LoadInt64(&pr.compressedBytesIn)
+	bytesOut := atomic.LoadInt64(&pr.decompressedBytesOut)
+
+	s := progressSnapshot{
+		FramesDone:     framesDone,
+		TotalFrames:    pr.TotalFrames,
+		FilesWritten:   atomic.LoadInt64(&pr.filesWritten),
+		BytesIn:        bytesIn,
+		BytesOut:       bytesOut,
+		BytesInPerSec:  float64(bytesIn) / elapsed,
+		BytesOutPerSec: float64(bytesOut) / elapsed,
+	}
+	if framesDone > 0 && pr.TotalFrames > framesDone {
+		secondsPerFrame := elapsed / float64(framesDone)
+		s.ETASeconds = secondsPerFrame * float64(pr.TotalFrames-framesDone)
+	}
+	return s
+}
+
+// line formats s as e.g. "Extracting 1234/5000 frames - 812 files - 42.7
+// MiB/s in, 118.0 MiB/s out - ETA 00:47", with verb naming the pass ("Extracting").
+func (s progressSnapshot) line(verb string) string {
+	eta := "--:--"
+	if s.ETASeconds > 0 {
+		eta = formatETA(s.ETASeconds)
+	}
+	return fmt.Sprintf("%s %d/%d frames - %d files - %s/s in, %s/s out - ETA %s",
+		verb, s.FramesDone, s.TotalFrames, s.FilesWritten,
+		formatIECRate(s.BytesInPerSec), formatIECRate(s.BytesOutPerSec), eta)
+}
+
+// formatIECRate renders a bytes/sec rate using IEC binary units (KiB, MiB,
+// GiB, TiB), computed inline rather than pulling in a units dependency.
+func formatIECRate(bytesPerSec float64) string {
+	const unit = 1024.0
+	if bytesPerSec < unit {
+		return fmt.Sprintf("%.0f B", bytesPerSec)
+	}
+	div := unit
+	exp := 0
+	for v := bytesPerSec / unit; v >= unit && exp < 3; v /= unit {
+		div *= unit
+		exp++
+	}
+	suffixes := [...]string{"KiB", "MiB", "GiB", "TiB"}
+	return fmt.Sprintf("%.1f %s", bytesPerSec/div, suffixes[exp])
+}
+
+func formatETA(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second))
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	s := int(d.Seconds()) % 60
+	if h > 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// run ticks once a second until done is closed, reporting pr's current
+// snapshot per mode: ProgressAuto/ProgressPlain format a line through
+// logger, ProgressJSON writes a raw NDJSON line to stderr instead. verb is
+// the present participle the reported pass is named by, e.g. "Extracting".
+func (pr *Progress) run(mode ProgressMode, logger Logger, verb string, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s := pr.snapshot()
+			switch mode {
+			case ProgressJSON:
+				if b, err := json.Marshal(s); err == nil {
+					fmt.Fprintln(os.Stderr, string(b))
+				}
+			case ProgressPlain:
+				logger.Printf("%s\n", s.line(verb))
+			default:
+				logger.Printf("\033[2K\r%s", s.line(verb))
+			}
+		}
+	}
+}
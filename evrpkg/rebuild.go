@@ -0,0 +1,202 @@
+package evrpkg
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// rebuildManifestName is the sidecar Extract writes next to its output tree
+// and Build looks for at the root of its input fs.FS.
+const rebuildManifestName = "rebuild.json"
+
+// RebuildFrameFile is one file's position within its original frame, in the
+// order it was concatenated into that frame's decompressed bytes.
+type RebuildFrameFile struct {
+	Path          string `json:"path"`
+	TypeSymbol    int64  `json:"typeSymbol"`
+	FileSymbol    int64  `json:"fileSymbol"`
+	DataOffset    uint32 `json:"dataOffset"`
+	Size          uint32 `json:"size"`
+	SomeAlignment uint32 `json:"someAlignment"`
+}
+
+// RebuildFrame captures everything Build needs to reproduce one original
+// frame byte-for-byte: the files packed into it, in their original order,
+// and the zstd window parameters the original encoder picked for it - which
+// vary with input size even at a fixed encoder level, so reusing them is
+// what lets an unmodified frame round-trip to identical compressed bytes.
+type RebuildFrame struct {
+	FileIndex    uint32             `json:"fileIndex"`
+	WindowSize   uint64             `json:"windowSize"`
+	DictionaryID uint32             `json:"dictionaryId,omitempty"`
+	Files        []RebuildFrameFile `json:"files"`
+}
+
+// RebuildManifest is the "rebuild.json" sidecar Extract writes next to its
+// output tree. When present at the root of Build's input fs.FS, Build
+// reconstructs frames in this exact order with matching zstd parameters
+// instead of re-deriving grouping from the output directory layout, so
+// frames left unmodified produce byte-identical compressed output.
+type RebuildManifest struct {
+	Frames []RebuildFrame `json:"frames"`
+}
+
+func writeRebuildManifest(outputDir string, rm *RebuildManifest) error {
+	sort.Slice(rm.Frames, func(a, b int) bool { return rm.Frames[a].FileIndex < rm.Frames[b].FileIndex })
+
+	f, err := os.OpenFile(fmt.Sprintf("%s/%s", outputDir, rebuildManifestName), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0777)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", " ")
+	return enc.Encode(rm)
+}
+
+// loadRebuildManifest reads the sidecar written by writeRebuildManifest from
+// the root of fsys. It returns fs.ErrNotExist (wrapped) when no sidecar is
+// present, so Build can tell "fall back to scanning the directory layout"
+// apart from "sidecar exists but is corrupt".
+func loadRebuildManifest(fsys fs.FS) (*RebuildManifest, error) {
+	b, err := fs.ReadFile(fsys, rebuildManifestName)
+	if err != nil {
+		return nil, err
+	}
+	rm := &RebuildManifest{}
+	if err := json.Unmarshal(b, rm); err != nil {
+		return nil, fmt.Errorf("%s: %w", rebuildManifestName, err)
+	}
+	return rm, nil
+}
+
+// zstdFrameMeta is the subset of a zstd frame's header that matters for
+// reproducing byte-identical output.
+type zstdFrameMeta struct {
+	WindowSize   uint64
+	DictionaryID uint32
+}
+
+const zstdFrameMagic = 0xFD2FB528
+
+// decodeZstdFrameHeader parses just enough of a zstd frame's header - magic
+// number, frame header descriptor, window descriptor and dictionary ID - to
+// recover the window size the encoder chose for it. See the Zstandard
+// compression format spec's "Frame_Header" section; none of this requires
+// decompressing the frame's actual data.
+func decodeZstdFrameHeader(b []byte) (zstdFrameMeta, error) {
+	if len(b) < 5 {
+		return zstdFrameMeta{}, fmt.Errorf("frame too short for a zstd header")
+	}
+	if binary.LittleEndian.Uint32(b[0:4]) != zstdFrameMagic {
+		return zstdFrameMeta{}, fmt.Errorf("not a zstd frame")
+	}
+
+	descriptor := b[4]
+	singleSegment := descriptor&0x20 != 0
+	dictIDFlag := descriptor & 0x3
+	pos := 5
+
+	var windowSize uint64
+	if singleSegment {
+		// No Window_Descriptor: the window is exactly the (as yet unparsed)
+		// Frame_Content_Size, which isn't needed here since the decompressed
+		// size is already known from the evrm manifest.
+	} else {
+		if pos >= len(b) {
+			return zstdFrameMeta{}, fmt.Errorf("truncated window descriptor")
+		}
+		wd := b[pos]
+		pos++
+		exponent := uint(wd >> 3)
+		mantissa := uint64(wd & 0x7)
+		windowBase := uint64(1) << (10 + exponent)
+		windowSize = windowBase + (windowBase/8)*mantissa
+	}
+
+	var dictID uint32
+	dictIDBytes := [4]int{0, 1, 2, 4}[dictIDFlag]
+	if dictIDBytes > 0 {
+		if pos+dictIDBytes > len(b) {
+			return zstdFrameMeta{}, fmt.Errorf("truncated dictionary ID")
+		}
+		switch dictIDBytes {
+		case 1:
+			dictID = uint32(b[pos])
+		case 2:
+			dictID = uint32(binary.LittleEndian.Uint16(b[pos : pos+2]))
+		case 4:
+			dictID = binary.LittleEndian.Uint32(b[pos : pos+4])
+		}
+	}
+
+	return zstdFrameMeta{WindowSize: windowSize, DictionaryID: dictID}, nil
+}
+
+// zstdFrameContentSize parses just enough of a zstd frame's header to
+// recover its declared Frame_Content_Size, without decompressing anything.
+// It reports ok=false if b isn't a zstd frame, is too short to read the
+// field, or the frame omits Frame_Content_Size entirely (legal for a
+// multi-segment frame with no single-segment flag) - callers that need a
+// bomb guard should treat "not known" the same as "unbounded".
+func zstdFrameContentSize(b []byte) (size uint64, ok bool) {
+	if len(b) < 5 || binary.LittleEndian.Uint32(b[0:4]) != zstdFrameMagic {
+		return 0, false
+	}
+
+	descriptor := b[4]
+	singleSegment := descriptor&0x20 != 0
+	fcsFlag := descriptor >> 6
+	dictIDFlag := descriptor & 0x3
+	pos := 5
+
+	if !singleSegment {
+		pos++ // Window_Descriptor
+	}
+	pos += [4]int{0, 1, 2, 4}[dictIDFlag] // Dictionary_ID
+
+	fcsBytes := [4]int{0, 2, 4, 8}[fcsFlag]
+	if fcsFlag == 0 && singleSegment {
+		fcsBytes = 1
+	}
+	if fcsBytes == 0 || pos+fcsBytes > len(b) {
+		return 0, false
+	}
+
+	switch fcsBytes {
+	case 1:
+		return uint64(b[pos]), true
+	case 2:
+		// Per the Zstandard spec, a 2-byte Frame_Content_Size is biased by
+		// 256 - values below that fit in the 1-byte single-segment form.
+		return uint64(binary.LittleEndian.Uint16(b[pos:pos+2])) + 256, true
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(b[pos : pos+4])), true
+	default:
+		return binary.LittleEndian.Uint64(b[pos : pos+8]), true
+	}
+}
+
+// encodeFrame compresses data with the zstd window size a RebuildFrame
+// recorded for it, when windowSize is nonzero, so the frame comes back out
+// byte-identical to the one Extract originally read. A zero windowSize
+// falls back to p.encoder's default window, for every path that doesn't
+// have an original frame to reproduce.
+func (p *Package) encodeFrame(data []byte, windowSize uint64) []byte {
+	if windowSize == 0 {
+		return p.encoder.EncodeAll(data, nil)
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(compressionLevel), zstd.WithWindowSize(int(windowSize)))
+	if err != nil {
+		return p.encoder.EncodeAll(data, nil)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(data, nil)
+}
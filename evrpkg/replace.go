@@ -0,0 +1,293 @@
+package evrpkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+
+	evrm "github.com/goopsie/evrFileTools/evrManifests"
+)
+
+// fcWrapper pairs a FrameContents entry with its index in the manifest, so
+// replaceFiles can sort a frame's entries by DataOffset and still know which
+// slot in manifest.FrameContents to update afterwards.
+type fcWrapper struct {
+	index int
+	fc    evrm.FrameContents
+}
+
+// frameResult is one frame's outcome from replaceFiles' parallel pipeline:
+// either its original compressed bytes passed through untouched, or a
+// recompressed replacement with modified file content spliced in.
+type frameResult struct {
+	index            int
+	data             []byte
+	err              error
+	decompressedSize uint32
+	compressedSize   uint32
+	isModified       bool
+}
+
+// frameCacheKey identifies a modified frame's recompressed output by the
+// bytes it's built from: the frame's original compressed bytes (so a change
+// to the source package invalidates it) plus the SHA-256 of every
+// replacement file spliced into it, in DataOffset order (so the key doesn't
+// depend on map iteration order). Compression settings such as
+// ParallelBlockSize are not part of the key. sortedContents must already be
+// sorted by DataOffset.
+func frameCacheKey(frameInput []byte, overrides map[Key][]byte, sortedContents []fcWrapper) [32]byte {
+	h := sha256.New()
+	h.Write(frameInput)
+	for _, entry := range sortedContents {
+		key := Key{TypeSymbol: entry.fc.T, FileSymbol: entry.fc.FileSymbol}
+		if modData, exists := overrides[key]; exists {
+			sum := sha256.Sum256(modData)
+			h.Write(sum[:])
+		}
+	}
+	var key [32]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// Replace rebuilds p's package/manifest pair with the files named by
+// overrides swapped in for their original content, and every other file
+// passed through unmodified. Each override's Reader is drained once, up
+// front, since its bytes may be needed more than once while a shared frame
+// is reconstructed.
+func (p *Package) Replace(ctx context.Context, overrides map[Key]io.Reader) error {
+	manifest, err := p.LoadManifest()
+	if err != nil {
+		return err
+	}
+
+	overrideBytes := make(map[Key][]byte, len(overrides))
+	for k, r := range overrides {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read override for type %d file %d: %w", k.TypeSymbol, k.FileSymbol, err)
+		}
+		overrideBytes[k] = b
+	}
+
+	return p.replaceFiles(ctx, manifest, overrideBytes)
+}
+
+func (p *Package) replaceFiles(ctx context.Context, manifest evrm.EvrManifest, overrides map[Key][]byte) error {
+	modifiedFrames := make(map[uint32]bool, manifest.Header.Frames.Count)
+	frameContentsLookupTable := make(map[Key]evrm.FrameContents, manifest.Header.FrameContents.Count)
+
+	for _, v := range manifest.FrameContents {
+		key := Key{TypeSymbol: v.T, FileSymbol: v.FileSymbol}
+		frameContentsLookupTable[key] = v
+	}
+	for key := range overrides {
+		if fc, ok := frameContentsLookupTable[key]; ok {
+			modifiedFrames[fc.FileIndex] = true
+		}
+	}
+
+	contentsByFrame := make(map[uint32][]fcWrapper)
+	for k, v := range manifest.FrameContents {
+		contentsByFrame[v.FileIndex] = append(contentsByFrame[v.FileIndex], fcWrapper{index: k, fc: v})
+	}
+
+	packages, err := p.openPackages(int(manifest.Header.PackageCount))
+	if err != nil {
+		return err
+	}
+	defer closePackages(packages)
+
+	cache := p.frameCache()
+
+	newManifest := manifest
+	newManifest.Frames = make([]evrm.Frame, 0)
+	newManifest.Header.Frames = evrm.HeaderChunk{SectionSize: 0, Unk1: 0, Unk2: 0, ElementSize: 16, Count: 0, ElementCount: 0}
+
+	logTimer := make(chan bool, 1)
+	go logTimerFunc(logTimer)
+
+	totalFrames := int(manifest.Header.Frames.Count)
+
+	// Channel to deliver results in strict order 0, 1, 2... The buffer size
+	// determines how far "ahead" we can process.
+	lookaheadSize := runtime.NumCPU() * 4
+	futureResults := make(chan chan frameResult, lookaheadSize)
+
+	// 1. Dispatcher: queues one result channel per frame, in frame order, and
+	// starts a goroutine per frame that reads its compressed bytes (with
+	// ReadAt, so the reads of different frames run concurrently) and
+	// processes it.
+	go func() {
+		defer close(futureResults)
+
+		for i := 0; i < totalFrames; i++ {
+			resultChan := make(chan frameResult, 1)
+			futureResults <- resultChan
+
+			go func(idx int, ch chan frameResult) {
+				v := manifest.Frames[idx]
+
+				activeFile := packages[v.CurrentPackageIndex]
+				splitFile := make([]byte, v.CompressedSize)
+				if v.CompressedSize > 0 {
+					if _, err := activeFile.ReadAt(splitFile, int64(v.CurrentOffset)); err != nil {
+						ch <- frameResult{index: idx, err: err}
+						return
+					}
+				}
+
+				isMod := modifiedFrames[uint32(idx)]
+				res := frameResult{index: idx, data: splitFile, decompressedSize: v.DecompressedSize, isModified: isMod}
+
+				if !isMod {
+					ch <- res
+					return
+				}
+
+				sortedFrameContents := append([]fcWrapper(nil), contentsByFrame[uint32(idx)]...)
+				sort.Slice(sortedFrameContents, func(a, b int) bool {
+					return sortedFrameContents[a].fc.DataOffset < sortedFrameContents[b].fc.DataOffset
+				})
+
+				var cacheKey [32]byte
+				if cache != nil {
+					cacheKey = frameCacheKey(splitFile, overrides, sortedFrameContents)
+					if cachedData, cachedSize, ok := cache.lookup(cacheKey); ok {
+						res.data = cachedData
+						res.decompressedSize = cachedSize
+						ch <- res
+						return
+					}
+				}
+
+				decompBytes, err := p.decompressZSTD(splitFile)
+				if err != nil {
+					res.err = err
+					ch <- res
+					return
+				}
+
+				constructedFile := bytes.NewBuffer(make([]byte, 0, v.DecompressedSize))
+				for _, entry := range sortedFrameContents {
+					key := Key{TypeSymbol: entry.fc.T, FileSymbol: entry.fc.FileSymbol}
+					if modData, exists := overrides[key]; exists {
+						constructedFile.Write(modData)
+					} else {
+						start := entry.fc.DataOffset
+						end := start + entry.fc.Size
+						if uint64(end) > uint64(len(decompBytes)) {
+							res.err = fmt.Errorf("frame %d: file %d/%d (size %d at offset %d) extends past its %d-byte decompressed frame",
+								idx, entry.fc.T, entry.fc.FileSymbol, entry.fc.Size, start, len(decompBytes))
+							ch <- res
+							return
+						}
+						constructedFile.Write(decompBytes[start:end])
+					}
+				}
+
+				res.data = p.compressFrameReplace(constructedFile.Bytes())
+				res.decompressedSize = uint32(constructedFile.Len())
+
+				if cache != nil {
+					cache.store(cacheKey, res.data, res.decompressedSize)
+				}
+
+				ch <- res
+			}(i, resultChan)
+		}
+	}()
+
+	// 2. Collector: receives results in strict order and writes them to disk.
+	for resultCh := range futureResults {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		res := <-resultCh
+		if res.err != nil {
+			return res.err
+		}
+
+		if len(logTimer) > 0 {
+			<-logTimer
+			status := "stock"
+			if res.isModified {
+				status = "modified"
+			}
+			p.log().Printf("\033[2K\rWriting %s frame %d/%d", status, res.index, totalFrames)
+		}
+
+		if res.isModified {
+			// Recompute this frame's FrameContents offsets/sizes to match
+			// the data we just spliced together in the dispatcher.
+			sortedFrameContents := append([]fcWrapper(nil), contentsByFrame[uint32(res.index)]...)
+			sort.Slice(sortedFrameContents, func(a, b int) bool {
+				return sortedFrameContents[a].fc.DataOffset < sortedFrameContents[b].fc.DataOffset
+			})
+
+			currentOffset := uint32(0)
+			for _, entry := range sortedFrameContents {
+				key := Key{TypeSymbol: entry.fc.T, FileSymbol: entry.fc.FileSymbol}
+				size := entry.fc.Size
+				if modData, exists := overrides[key]; exists {
+					size = uint32(len(modData))
+				}
+
+				newManifest.FrameContents[entry.index] = evrm.FrameContents{
+					T:             entry.fc.T,
+					FileSymbol:    entry.fc.FileSymbol,
+					FileIndex:     entry.fc.FileIndex,
+					DataOffset:    currentOffset,
+					Size:          size,
+					SomeAlignment: entry.fc.SomeAlignment,
+				}
+				currentOffset += size
+			}
+		}
+
+		if err := p.appendChunkToPackages(&newManifest, fileGroup{
+			currentData:      *bytes.NewBuffer(res.data),
+			decompressedSize: res.decompressedSize,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if cache != nil {
+		if err := cache.evict(cacheMaxEntries); err != nil {
+			p.log().Printf("warning: failed to evict frame cache: %v\n", err)
+		}
+	}
+
+	for i := uint32(0); i < newManifest.Header.PackageCount; i++ {
+		packageStats, err := os.Stat(fmt.Sprintf("%s/packages/%s_%d", p.OutputDir, p.PackageName, i))
+		if err != nil {
+			return fmt.Errorf("failed to stat package for weirddata writing: %w", err)
+		}
+		newEntry := evrm.Frame{
+			CurrentPackageIndex: i,
+			CurrentOffset:       uint32(packageStats.Size()),
+			CompressedSize:      0,
+			DecompressedSize:    0,
+		}
+		newManifest.Frames = append(newManifest.Frames, newEntry)
+		newManifest.Header.Frames = incrementHeaderChunk(newManifest.Header.Frames, 1)
+	}
+
+	newEntry := evrm.Frame{}
+	newManifest.Frames = append(newManifest.Frames, newEntry)
+	newManifest.Header.Frames = incrementHeaderChunk(newManifest.Header.Frames, 1)
+
+	if err := p.writeManifest(newManifest); err != nil {
+		return err
+	}
+
+	p.log().Printf("\nfinished, modified %d files\n", len(overrides))
+	return nil
+}
@@ -9,7 +9,8 @@ func TestHeader(t *testing.T) {
 	t.Run("MarshalUnmarshal", func(t *testing.T) {
 		original := &Header{
 			Magic:            Magic,
-			HeaderLength:     16,
+			HeaderLength:     HeaderSize,
+			Codec:            CodecZstd,
 			Length:           1024,
 			CompressedLength: 512,
 		}
@@ -32,7 +33,7 @@ func TestHeader(t *testing.T) {
 	t.Run("InvalidMagic", func(t *testing.T) {
 		h := &Header{
 			Magic:            [4]byte{0x00, 0x00, 0x00, 0x00},
-			HeaderLength:     16,
+			HeaderLength:     HeaderSize,
 			Length:           1024,
 			CompressedLength: 512,
 		}
@@ -44,7 +45,7 @@ func TestHeader(t *testing.T) {
 	t.Run("ZeroLength", func(t *testing.T) {
 		h := &Header{
 			Magic:            Magic,
-			HeaderLength:     16,
+			HeaderLength:     HeaderSize,
 			Length:           0,
 			CompressedLength: 512,
 		}
@@ -0,0 +1,162 @@
+package archive
+
+import (
+	"compress/flate"
+	"fmt"
+	"io"
+
+	"github.com/DataDog/zstd"
+	"github.com/andybalholm/brotli"
+	"github.com/pierrec/lz4/v4"
+)
+
+// CodecTag identifies which codec compressed an archive's body. It is
+// persisted in Header.Codec so a Reader knows how to decompress without
+// the caller having to remember what it was encoded with.
+type CodecTag uint8
+
+const (
+	CodecZstd    CodecTag = iota // github.com/DataDog/zstd
+	CodecLZ4                     // github.com/pierrec/lz4
+	CodecDeflate                 // compress/flate
+	CodecBrotli                  // github.com/andybalholm/brotli
+	CodecStored                  // uncompressed passthrough
+)
+
+// Codec streams data through a compression algorithm.
+type Codec interface {
+	Tag() CodecTag
+	NewWriter(dst io.Writer, level int) (io.WriteCloser, error)
+	NewReader(src io.Reader) (io.ReadCloser, error)
+}
+
+// registeredCodecs holds every codec available to CodecFor, keyed by Tag().
+// It starts pre-populated with the five codecs this package implements;
+// RegisterCodec adds to it.
+var registeredCodecs = map[CodecTag]Codec{
+	CodecZstd:    zstdCodec{},
+	CodecLZ4:     lz4Codec{},
+	CodecDeflate: deflateCodec{},
+	CodecBrotli:  brotliCodec{},
+	CodecStored:  storedCodec{},
+}
+
+// RegisterCodec makes a custom Codec available to CodecFor (and therefore
+// to Header, Writer, and Reader) under its own Tag(). Callers adding a
+// custom codec should pick a CodecTag value above CodecStored so it never
+// collides with a codec this package adds later. Call this from an init
+// func, the same way manifest converters register themselves with
+// tool.Register.
+func RegisterCodec(codec Codec) {
+	registeredCodecs[codec.Tag()] = codec
+}
+
+// CodecFor returns the Codec implementation for tag.
+func CodecFor(tag CodecTag) (Codec, error) {
+	codec, ok := registeredCodecs[tag]
+	if !ok {
+		return nil, fmt.Errorf("unknown codec tag %d", tag)
+	}
+	return codec, nil
+}
+
+type zstdCodec struct{}
+
+func (zstdCodec) Tag() CodecTag { return CodecZstd }
+func (zstdCodec) NewWriter(dst io.Writer, level int) (io.WriteCloser, error) {
+	return zstd.NewWriterLevel(dst, level), nil
+}
+func (zstdCodec) NewReader(src io.Reader) (io.ReadCloser, error) {
+	return zstd.NewReader(src), nil
+}
+
+type lz4Codec struct{}
+
+func (lz4Codec) Tag() CodecTag { return CodecLZ4 }
+func (lz4Codec) NewWriter(dst io.Writer, level int) (io.WriteCloser, error) {
+	w := lz4.NewWriter(dst)
+	if err := w.Apply(lz4.CompressionLevelOption(lz4Level(level))); err != nil {
+		return nil, fmt.Errorf("configure lz4 writer: %w", err)
+	}
+	return w, nil
+}
+func (lz4Codec) NewReader(src io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(lz4.NewReader(src)), nil
+}
+
+// lz4Level maps a zstd-style level (negative..22) onto lz4's fixed set of
+// compression levels, the same way brotliLevel does for brotli.
+func lz4Level(zstdLevel int) lz4.CompressionLevel {
+	switch {
+	case zstdLevel <= 1:
+		return lz4.Fast
+	case zstdLevel >= 19:
+		return lz4.Level9
+	default:
+		return lz4.Level5
+	}
+}
+
+type deflateCodec struct{}
+
+func (deflateCodec) Tag() CodecTag { return CodecDeflate }
+func (deflateCodec) NewWriter(dst io.Writer, level int) (io.WriteCloser, error) {
+	w, err := flate.NewWriter(dst, deflateLevel(level))
+	if err != nil {
+		return nil, fmt.Errorf("create deflate writer: %w", err)
+	}
+	return w, nil
+}
+func (deflateCodec) NewReader(src io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(src), nil
+}
+
+// deflateLevel maps a zstd-style level onto flate's -2..9 range.
+func deflateLevel(zstdLevel int) int {
+	switch {
+	case zstdLevel <= 1:
+		return flate.BestSpeed
+	case zstdLevel >= 19:
+		return flate.BestCompression
+	default:
+		return flate.DefaultCompression
+	}
+}
+
+type brotliCodec struct{}
+
+func (brotliCodec) Tag() CodecTag { return CodecBrotli }
+func (brotliCodec) NewWriter(dst io.Writer, level int) (io.WriteCloser, error) {
+	return brotli.NewWriterLevel(dst, brotliLevel(level)), nil
+}
+func (brotliCodec) NewReader(src io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(src)), nil
+}
+
+// brotliLevel maps a zstd-style level onto brotli's 0-11 quality range.
+func brotliLevel(zstdLevel int) int {
+	switch {
+	case zstdLevel <= 1:
+		return 5
+	case zstdLevel >= 19:
+		return 11
+	default:
+		return 9
+	}
+}
+
+type storedCodec struct{}
+
+func (storedCodec) Tag() CodecTag { return CodecStored }
+func (storedCodec) NewWriter(dst io.Writer, _ int) (io.WriteCloser, error) {
+	return nopWriteCloser{dst}, nil
+}
+func (storedCodec) NewReader(src io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(src), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,74 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog "), 200)
+
+	for _, tag := range []CodecTag{CodecZstd, CodecLZ4, CodecDeflate, CodecBrotli, CodecStored} {
+		codec, err := CodecFor(tag)
+		if err != nil {
+			t.Fatalf("CodecFor(%d): %v", tag, err)
+		}
+
+		var compressed bytes.Buffer
+		w, err := codec.NewWriter(&compressed, DefaultCompressionLevel)
+		if err != nil {
+			t.Fatalf("tag %d: NewWriter: %v", tag, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("tag %d: Write: %v", tag, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("tag %d: Close: %v", tag, err)
+		}
+
+		r, err := codec.NewReader(&compressed)
+		if err != nil {
+			t.Fatalf("tag %d: NewReader: %v", tag, err)
+		}
+		decompressed := make([]byte, len(data))
+		if _, err := io.ReadFull(r, decompressed); err != nil {
+			t.Fatalf("tag %d: read: %v", tag, err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("tag %d: Close reader: %v", tag, err)
+		}
+
+		if !bytes.Equal(decompressed, data) {
+			t.Fatalf("tag %d: round trip mismatch", tag)
+		}
+	}
+}
+
+func TestCodecForUnknownTag(t *testing.T) {
+	if _, err := CodecFor(CodecTag(99)); err == nil {
+		t.Fatal("expected error for unknown codec tag")
+	}
+}
+
+func TestEncodeDecodeWithCodec(t *testing.T) {
+	original := bytes.Repeat([]byte("archive codec round trip "), 100)
+
+	for _, tag := range []CodecTag{CodecZstd, CodecLZ4, CodecDeflate, CodecBrotli, CodecStored} {
+		var buf bytes.Buffer
+		ws := &seekableBuffer{Buffer: &buf}
+
+		if err := Encode(ws, original, WithCodec(tag)); err != nil {
+			t.Fatalf("tag %d: encode: %v", tag, err)
+		}
+
+		rs := bytes.NewReader(buf.Bytes())
+		decoded, err := ReadAll(rs)
+		if err != nil {
+			t.Fatalf("tag %d: decode: %v", tag, err)
+		}
+		if !bytes.Equal(decoded, original) {
+			t.Errorf("tag %d: data mismatch", tag)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// containerMagic identifies a Container file, written once at offset 0.
+var containerMagic = [4]byte{0x45, 0x56, 0x43, 0x54} // "EVCT"
+
+// containerHeaderSize is the fixed size of the leading containerMagic+codec header.
+const containerHeaderSize = len(containerMagic) + 1
+
+// containerTrailerMagic identifies the fixed-size trailer ContainerWriter
+// appends after the TOC, so OpenContainer can locate it without scanning
+// the whole file.
+const containerTrailerMagic = "EVRTOC1"
+
+// containerTrailerSize is len(containerTrailerMagic) + tocOffset(8) + tocLength(4).
+const containerTrailerSize = len(containerTrailerMagic) + 8 + 4
+
+// EntryType classifies an Entry's payload, so a consumer walking a
+// Container can pick out what it needs (e.g. a manifest plus the DDS
+// textures it references) without relying on naming conventions.
+type EntryType uint16
+
+const (
+	EntryTypeOther EntryType = iota
+	EntryTypeDDS
+	EntryTypeRawBC
+	EntryTypeMetadata
+	EntryTypeManifest
+)
+
+// Entry describes one file stored in a Container, analogous to tar.Header.
+// CompressedSize is the on-disk size of the entry's compressed payload;
+// Size is the payload's uncompressed size.
+type Entry struct {
+	Name           string
+	Type           EntryType
+	Size           uint64
+	CompressedSize uint64
+	Offset         uint64
+	CRC32          uint32
+}
+
+// ContainerOption configures a ContainerWriter.
+type ContainerOption func(*ContainerWriter)
+
+// WithContainerCodec selects which Codec compresses every entry's payload.
+// It defaults to CodecZstd.
+func WithContainerCodec(tag CodecTag) ContainerOption {
+	return func(w *ContainerWriter) {
+		w.codecTag = tag
+	}
+}
+
+// WithContainerCompressionLevel sets the compression level used for every entry.
+func WithContainerCompressionLevel(level int) ContainerOption {
+	return func(w *ContainerWriter) {
+		w.level = level
+	}
+}
+
+// ContainerWriter writes a Container: a tar-like multi-entry archive with
+// each entry's payload compressed independently via the pluggable Codec
+// interface, and a TOC written as a footer once every entry is closed.
+type ContainerWriter struct {
+	dst      io.WriteSeeker
+	codec    Codec
+	codecTag CodecTag
+	level    int
+	entries  []Entry
+	cur      *containerEntryWriter
+}
+
+// NewContainerWriter creates a ContainerWriter that writes to dst.
+func NewContainerWriter(dst io.WriteSeeker, opts ...ContainerOption) (*ContainerWriter, error) {
+	w := &ContainerWriter{
+		dst:      dst,
+		codecTag: CodecZstd,
+		level:    DefaultCompressionLevel,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	codec, err := CodecFor(w.codecTag)
+	if err != nil {
+		return nil, err
+	}
+	w.codec = codec
+
+	header := append(append([]byte{}, containerMagic[:]...), byte(w.codecTag))
+	if _, err := dst.Write(header); err != nil {
+		return nil, fmt.Errorf("write container header: %w", err)
+	}
+
+	return w, nil
+}
+
+// Create opens a new entry for writing. The returned io.WriteCloser must
+// be closed before Create or Close is called again.
+func (w *ContainerWriter) Create(e Entry) (io.WriteCloser, error) {
+	if w.cur != nil {
+		return nil, fmt.Errorf("create %q: entry %q not closed", e.Name, w.cur.entry.Name)
+	}
+
+	pos, err := w.dst.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("get entry offset: %w", err)
+	}
+	e.Offset = uint64(pos)
+
+	counter := &countingWriter{w: w.dst}
+	codecWriter, err := w.codec.NewWriter(counter, w.level)
+	if err != nil {
+		return nil, fmt.Errorf("create entry %q writer: %w", e.Name, err)
+	}
+
+	ew := &containerEntryWriter{
+		w:       w,
+		entry:   e,
+		codec:   codecWriter,
+		counter: counter,
+		crc:     crc32.NewIEEE(),
+	}
+	w.cur = ew
+	return ew, nil
+}
+
+// Close finalizes the container by writing its TOC and trailer. It
+// returns an error if an entry opened with Create is still open.
+func (w *ContainerWriter) Close() error {
+	if w.cur != nil {
+		return fmt.Errorf("close: entry %q not closed", w.cur.entry.Name)
+	}
+
+	tocOffset, err := w.dst.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("get TOC offset: %w", err)
+	}
+
+	var toc bytes.Buffer
+	binary.Write(&toc, binary.LittleEndian, uint32(len(w.entries)))
+	for _, e := range w.entries {
+		writeEntry(&toc, e)
+	}
+	if _, err := w.dst.Write(toc.Bytes()); err != nil {
+		return fmt.Errorf("write TOC: %w", err)
+	}
+
+	var trailer bytes.Buffer
+	trailer.WriteString(containerTrailerMagic)
+	binary.Write(&trailer, binary.LittleEndian, uint64(tocOffset))
+	binary.Write(&trailer, binary.LittleEndian, uint32(toc.Len()))
+	if _, err := w.dst.Write(trailer.Bytes()); err != nil {
+		return fmt.Errorf("write trailer: %w", err)
+	}
+
+	return nil
+}
+
+// containerEntryWriter streams one entry's uncompressed payload through
+// the container's codec, tracking the counters its TOC entry needs.
+type containerEntryWriter struct {
+	w       *ContainerWriter
+	entry   Entry
+	codec   io.WriteCloser
+	counter *countingWriter
+	crc     hashWriter32
+}
+
+// hashWriter32 is the subset of hash.Hash32 containerEntryWriter needs;
+// declared locally so this file doesn't have to import hash.
+type hashWriter32 interface {
+	io.Writer
+	Sum32() uint32
+}
+
+func (ew *containerEntryWriter) Write(p []byte) (int, error) {
+	ew.crc.Write(p)
+	ew.entry.Size += uint64(len(p))
+	return ew.codec.Write(p)
+}
+
+func (ew *containerEntryWriter) Close() error {
+	if err := ew.codec.Close(); err != nil {
+		return fmt.Errorf("close entry %q: %w", ew.entry.Name, err)
+	}
+	ew.entry.CompressedSize = uint64(ew.counter.n)
+	ew.entry.CRC32 = ew.crc.Sum32()
+	ew.w.entries = append(ew.w.entries, ew.entry)
+	ew.w.cur = nil
+	return nil
+}
+
+// writeEntry serializes e's TOC record: a length-prefixed name followed
+// by its fixed-size fields.
+func writeEntry(buf *bytes.Buffer, e Entry) {
+	name := []byte(e.Name)
+	binary.Write(buf, binary.LittleEndian, uint32(len(name)))
+	buf.Write(name)
+	binary.Write(buf, binary.LittleEndian, uint16(e.Type))
+	binary.Write(buf, binary.LittleEndian, e.Size)
+	binary.Write(buf, binary.LittleEndian, e.CompressedSize)
+	binary.Write(buf, binary.LittleEndian, e.Offset)
+	binary.Write(buf, binary.LittleEndian, e.CRC32)
+}
+
+// readEntry deserializes one TOC record written by writeEntry.
+func readEntry(r *bytes.Reader) (Entry, error) {
+	var nameLen uint32
+	if err := binary.Read(r, binary.LittleEndian, &nameLen); err != nil {
+		return Entry{}, fmt.Errorf("read name length: %w", err)
+	}
+	name := make([]byte, nameLen)
+	if _, err := io.ReadFull(r, name); err != nil {
+		return Entry{}, fmt.Errorf("read name: %w", err)
+	}
+
+	var e Entry
+	e.Name = string(name)
+	var typ uint16
+	if err := binary.Read(r, binary.LittleEndian, &typ); err != nil {
+		return Entry{}, fmt.Errorf("read type: %w", err)
+	}
+	e.Type = EntryType(typ)
+	if err := binary.Read(r, binary.LittleEndian, &e.Size); err != nil {
+		return Entry{}, fmt.Errorf("read size: %w", err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &e.CompressedSize); err != nil {
+		return Entry{}, fmt.Errorf("read compressed size: %w", err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &e.Offset); err != nil {
+		return Entry{}, fmt.Errorf("read offset: %w", err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &e.CRC32); err != nil {
+		return Entry{}, fmt.Errorf("read CRC32: %w", err)
+	}
+	return e, nil
+}
+
+// countingWriter tracks how many bytes have passed through it, so a
+// ContainerWriter entry's compressed size can be recovered without
+// buffering the compressed output to measure it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Container provides read access to a Container file written by
+// ContainerWriter: Walk lists every entry's metadata, and Open streams one
+// entry's decompressed payload by name.
+type Container struct {
+	r       io.ReaderAt
+	codec   Codec
+	entries []Entry
+	byName  map[string]int
+}
+
+// OpenContainer opens a Container of the given total size.
+func OpenContainer(r io.ReaderAt, size int64) (*Container, error) {
+	if size < int64(containerHeaderSize+containerTrailerSize) {
+		return nil, fmt.Errorf("archive too small to be a container: %d bytes", size)
+	}
+
+	header := make([]byte, containerHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("read container header: %w", err)
+	}
+	if !bytes.Equal(header[:len(containerMagic)], containerMagic[:]) {
+		return nil, fmt.Errorf("not a container: missing magic")
+	}
+	codec, err := CodecFor(CodecTag(header[len(containerMagic)]))
+	if err != nil {
+		return nil, err
+	}
+
+	trailer := make([]byte, containerTrailerSize)
+	if _, err := r.ReadAt(trailer, size-int64(containerTrailerSize)); err != nil {
+		return nil, fmt.Errorf("read trailer: %w", err)
+	}
+	if string(trailer[:len(containerTrailerMagic)]) != containerTrailerMagic {
+		return nil, fmt.Errorf("not a container: missing trailer magic")
+	}
+	trailerReader := bytes.NewReader(trailer[len(containerTrailerMagic):])
+	var tocOffset uint64
+	var tocLength uint32
+	if err := binary.Read(trailerReader, binary.LittleEndian, &tocOffset); err != nil {
+		return nil, fmt.Errorf("read TOC offset: %w", err)
+	}
+	if err := binary.Read(trailerReader, binary.LittleEndian, &tocLength); err != nil {
+		return nil, fmt.Errorf("read TOC length: %w", err)
+	}
+
+	tocBytes := make([]byte, tocLength)
+	if _, err := r.ReadAt(tocBytes, int64(tocOffset)); err != nil {
+		return nil, fmt.Errorf("read TOC: %w", err)
+	}
+	tocReader := bytes.NewReader(tocBytes)
+	var count uint32
+	if err := binary.Read(tocReader, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("read entry count: %w", err)
+	}
+
+	entries := make([]Entry, count)
+	byName := make(map[string]int, count)
+	for i := range entries {
+		e, err := readEntry(tocReader)
+		if err != nil {
+			return nil, fmt.Errorf("read entry %d: %w", i, err)
+		}
+		entries[i] = e
+		byName[e.Name] = i
+	}
+
+	return &Container{r: r, codec: codec, entries: entries, byName: byName}, nil
+}
+
+// Walk calls fn once per entry in TOC order, stopping at the first error.
+func (c *Container) Walk(fn func(Entry) error) error {
+	for _, e := range c.entries {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open returns a reader for name's decompressed payload.
+func (c *Container) Open(name string) (io.ReadCloser, error) {
+	idx, ok := c.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("entry %q not found", name)
+	}
+	e := c.entries[idx]
+
+	section := io.NewSectionReader(c.r, int64(e.Offset), int64(e.CompressedSize))
+	return c.codec.NewReader(section)
+}
@@ -0,0 +1,121 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestContainerWriteReadRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	ws := &seekableBuffer{Buffer: &buf}
+
+	w, err := NewContainerWriter(ws, WithContainerCodec(CodecBrotli))
+	if err != nil {
+		t.Fatalf("NewContainerWriter: %v", err)
+	}
+
+	files := map[string]EntryType{
+		"manifest.bin": EntryTypeManifest,
+		"tex/0.dds":    EntryTypeDDS,
+		"tex/0.meta":   EntryTypeMetadata,
+	}
+	contents := map[string][]byte{
+		"manifest.bin": bytes.Repeat([]byte("manifest bytes "), 50),
+		"tex/0.dds":    bytes.Repeat([]byte("dds payload "), 200),
+		"tex/0.meta":   bytes.Repeat([]byte{0xAB}, 256),
+	}
+
+	for _, name := range []string{"manifest.bin", "tex/0.dds", "tex/0.meta"} {
+		ew, err := w.Create(Entry{Name: name, Type: files[name]})
+		if err != nil {
+			t.Fatalf("Create(%s): %v", name, err)
+		}
+		if _, err := ew.Write(contents[name]); err != nil {
+			t.Fatalf("Write(%s): %v", name, err)
+		}
+		if err := ew.Close(); err != nil {
+			t.Fatalf("Close(%s): %v", name, err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close container: %v", err)
+	}
+
+	c, err := OpenContainer(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("OpenContainer: %v", err)
+	}
+
+	var walked []string
+	if err := c.Walk(func(e Entry) error {
+		walked = append(walked, e.Name)
+		if want := contents[e.Name]; uint64(len(want)) != e.Size {
+			t.Errorf("entry %s: got size %d, want %d", e.Name, e.Size, len(want))
+		}
+		if e.Type != files[e.Name] {
+			t.Errorf("entry %s: got type %d, want %d", e.Name, e.Type, files[e.Name])
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if len(walked) != len(contents) {
+		t.Fatalf("walked %d entries, want %d", len(walked), len(contents))
+	}
+
+	for name, want := range contents {
+		r, err := c.Open(name)
+		if err != nil {
+			t.Fatalf("Open(%s): %v", name, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("close %s: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("entry %s: content mismatch", name)
+		}
+	}
+}
+
+func TestContainerOpenMissingEntry(t *testing.T) {
+	var buf bytes.Buffer
+	ws := &seekableBuffer{Buffer: &buf}
+
+	w, err := NewContainerWriter(ws)
+	if err != nil {
+		t.Fatalf("NewContainerWriter: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	c, err := OpenContainer(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("OpenContainer: %v", err)
+	}
+	if _, err := c.Open("missing"); err == nil {
+		t.Fatal("expected error for missing entry")
+	}
+}
+
+func TestContainerCreateRejectsUnclosedEntry(t *testing.T) {
+	var buf bytes.Buffer
+	ws := &seekableBuffer{Buffer: &buf}
+
+	w, err := NewContainerWriter(ws)
+	if err != nil {
+		t.Fatalf("NewContainerWriter: %v", err)
+	}
+	if _, err := w.Create(Entry{Name: "a"}); err != nil {
+		t.Fatalf("Create(a): %v", err)
+	}
+	if _, err := w.Create(Entry{Name: "b"}); err == nil {
+		t.Fatal("expected error creating entry while previous one is open")
+	}
+}
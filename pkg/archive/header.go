@@ -1,19 +1,41 @@
-// Package archive provides types and functions for working with ZSTD compressed archives.
+// Package archive provides types and functions for working with compressed archives.
 package archive
 
 import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"math"
 )
 
-// Magic bytes identifying a ZSTD archive header.
+// Magic bytes identifying an archive header.
 var Magic = [4]byte{0x5a, 0x53, 0x54, 0x44} // "ZSTD"
 
+// HeaderSize is the fixed on-disk size of Header.
+const HeaderSize = 25
+
+// StreamHeaderLength is the HeaderLength a StreamWriter stamps on its
+// header instead of HeaderSize. It marks a header whose Length and
+// CompressedLength are left zero - the real values live in the Footer
+// appended after the compressed body - so a reader can tell at a glance
+// whether to expect the older seek-and-patch layout or the newer
+// streamed-footer one, the same way tool.ArchiveEncode's peekHeaderLength
+// already distinguishes its own legacy header from this package's Header.
+const StreamHeaderLength = HeaderSize + 1
+
+// maxDecodedLength caps the uncompressed size a header is allowed to
+// declare. ReadAll trusts Length enough to pre-allocate a buffer of that
+// size, so without a cap a corrupted or adversarial header can request an
+// allocation far larger than the actual archive, crashing the process
+// before decompression ever reports an error. math.MaxInt32 matches the
+// bound manifest.MaxPackageSize already assumes elsewhere in this repo.
+const maxDecodedLength = math.MaxInt32
+
 // Header represents the header of a compressed archive file.
 type Header struct {
 	Magic            [4]byte
 	HeaderLength     uint32
+	Codec            CodecTag
 	Length           uint64 // Uncompressed size
 	CompressedLength uint64 // Compressed size
 }
@@ -28,12 +50,18 @@ func (h *Header) Validate() error {
 	if h.Magic != Magic {
 		return fmt.Errorf("invalid magic: expected %x, got %x", Magic, h.Magic)
 	}
-	if h.HeaderLength != 16 {
-		return fmt.Errorf("invalid header length: expected 16, got %d", h.HeaderLength)
+	if h.HeaderLength != HeaderSize {
+		return fmt.Errorf("invalid header length: expected %d, got %d", HeaderSize, h.HeaderLength)
+	}
+	if _, err := CodecFor(h.Codec); err != nil {
+		return err
 	}
 	if h.Length == 0 {
 		return fmt.Errorf("uncompressed size is zero")
 	}
+	if h.Length > maxDecodedLength {
+		return fmt.Errorf("uncompressed size %d exceeds maximum %d", h.Length, maxDecodedLength)
+	}
 	if h.CompressedLength == 0 {
 		return fmt.Errorf("compressed size is zero")
 	}
@@ -58,11 +86,13 @@ func (h *Header) UnmarshalBinary(data []byte) error {
 	return h.Validate()
 }
 
-// NewHeader creates a new archive header with the given sizes.
+// NewHeader creates a new zstd-codec archive header with the given sizes.
+// Use NewWriter's WithCodec option to pick a different codec.
 func NewHeader(uncompressedSize, compressedSize uint64) *Header {
 	return &Header{
 		Magic:            Magic,
-		HeaderLength:     16,
+		HeaderLength:     HeaderSize,
+		Codec:            CodecZstd,
 		Length:           uncompressedSize,
 		CompressedLength: compressedSize,
 	}
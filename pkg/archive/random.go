@@ -0,0 +1,56 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// FrameLocation describes where one independently-compressed frame lives
+// within a RandomReader's source: its byte offset, its size on disk, and
+// its decompressed length.
+type FrameLocation struct {
+	Offset           int64
+	CompressedLength int64
+	Length           int64
+}
+
+// RandomReader decompresses individual frames out of an io.ReaderAt on
+// demand, without requiring the whole source to be read (or decompressed)
+// up front. It's for container formats - like a manifest's Frames table -
+// where each frame is compressed independently, so any one of them can be
+// decoded just by reading its own byte range.
+type RandomReader struct {
+	src   io.ReaderAt
+	codec Codec
+}
+
+// NewRandomReader creates a RandomReader over src, decompressing frames
+// with the codec named by tag.
+func NewRandomReader(src io.ReaderAt, tag CodecTag) (*RandomReader, error) {
+	codec, err := CodecFor(tag)
+	if err != nil {
+		return nil, err
+	}
+	return &RandomReader{src: src, codec: codec}, nil
+}
+
+// ReadFrame reads and decompresses the frame at loc.
+func (r *RandomReader) ReadFrame(loc FrameLocation) ([]byte, error) {
+	compressed := make([]byte, loc.CompressedLength)
+	if _, err := r.src.ReadAt(compressed, loc.Offset); err != nil {
+		return nil, fmt.Errorf("read frame at offset %d: %w", loc.Offset, err)
+	}
+
+	zReader, err := r.codec.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("create codec reader: %w", err)
+	}
+	defer zReader.Close()
+
+	data := make([]byte, loc.Length)
+	if _, err := io.ReadFull(zReader, data); err != nil {
+		return nil, fmt.Errorf("decompress frame: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,58 @@
+package archive
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRandomReaderReadFrame(t *testing.T) {
+	frames := [][]byte{
+		bytes.Repeat([]byte("frame zero "), 50),
+		bytes.Repeat([]byte("frame one is a bit longer "), 80),
+		[]byte("frame two"),
+	}
+
+	var src bytes.Buffer
+	locations := make([]FrameLocation, len(frames))
+	for i, data := range frames {
+		codec, err := CodecFor(CodecZstd)
+		if err != nil {
+			t.Fatalf("CodecFor: %v", err)
+		}
+
+		offset := int64(src.Len())
+		w, err := codec.NewWriter(&src, DefaultCompressionLevel)
+		if err != nil {
+			t.Fatalf("NewWriter: %v", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+
+		locations[i] = FrameLocation{
+			Offset:           offset,
+			CompressedLength: int64(src.Len()) - offset,
+			Length:           int64(len(data)),
+		}
+	}
+
+	r, err := NewRandomReader(bytes.NewReader(src.Bytes()), CodecZstd)
+	if err != nil {
+		t.Fatalf("NewRandomReader: %v", err)
+	}
+
+	// Read frame 2 before frame 0 to confirm random access doesn't require
+	// reading frames in order.
+	for _, i := range []int{2, 0, 1} {
+		got, err := r.ReadFrame(locations[i])
+		if err != nil {
+			t.Fatalf("ReadFrame(%d): %v", i, err)
+		}
+		if !bytes.Equal(got, frames[i]) {
+			t.Errorf("ReadFrame(%d): got %d bytes, want %d bytes", i, len(got), len(frames[i]))
+		}
+	}
+}
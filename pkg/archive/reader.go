@@ -34,7 +34,15 @@ func NewReader(r io.ReadSeeker) (*Reader, error) {
 		return nil, fmt.Errorf("parse header: %w", err)
 	}
 
-	reader.zReader = zstd.NewReader(r)
+	codec, err := CodecFor(reader.header.Codec)
+	if err != nil {
+		return nil, err
+	}
+	zReader, err := codec.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("create codec %d reader: %w", reader.header.Codec, err)
+	}
+	reader.zReader = zReader
 	return reader, nil
 }
 
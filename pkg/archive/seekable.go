@@ -0,0 +1,610 @@
+package archive
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// DefaultChunkSize is the uncompressed size of each chunk EncodeSeekable
+// splits its input into, unless overridden with WithChunkSize.
+const DefaultChunkSize = 256 * 1024
+
+// DefaultSeekableCacheSize is the decompressed-chunk cache size
+// NewSeekableReader uses when WithCacheSize hasn't been passed.
+const DefaultSeekableCacheSize = 8 * 1024 * 1024
+
+// seekableTrailerMagic identifies the fixed-size trailer EncodeSeekable
+// appends after the footer, so NewSeekableReader can locate the footer
+// without scanning the whole file.
+const seekableTrailerMagic = "EVRSEEK1"
+
+// seekableTrailerSize is len(seekableTrailerMagic) + footerOffset(8) + footerLength(4).
+const seekableTrailerSize = len(seekableTrailerMagic) + 8 + 4
+
+// ChunkIndexEntry describes one independently-compressed chunk of a
+// seekable archive.
+type ChunkIndexEntry struct {
+	UncompressedOffset uint32
+	CompressedOffset   uint32
+	CompressedLen      uint32
+}
+
+// chunkedConfig holds EncodeSeekable's and NewChunkedWriter's options.
+type chunkedConfig struct {
+	chunkSize   int
+	codec       CodecTag
+	level       int
+	concurrency int
+}
+
+// ChunkedOption configures EncodeSeekable.
+type ChunkedOption func(*chunkedConfig)
+
+// WithChunkSize sets the uncompressed size of each independently
+// compressed chunk.
+func WithChunkSize(size int) ChunkedOption {
+	return func(c *chunkedConfig) {
+		c.chunkSize = size
+	}
+}
+
+// WithChunkCodec selects which Codec compresses each chunk.
+func WithChunkCodec(tag CodecTag) ChunkedOption {
+	return func(c *chunkedConfig) {
+		c.codec = tag
+	}
+}
+
+// WithChunkCompressionLevel sets the compression level used for each chunk.
+func WithChunkCompressionLevel(level int) ChunkedOption {
+	return func(c *chunkedConfig) {
+		c.level = level
+	}
+}
+
+// WithChunkConcurrency sets how many goroutines ChunkedWriter uses to
+// compress chunks in parallel. The zero value means runtime.GOMAXPROCS(0).
+func WithChunkConcurrency(n int) ChunkedOption {
+	return func(c *chunkedConfig) {
+		c.concurrency = n
+	}
+}
+
+// EncodeSeekable compresses data as a seekable archive: a regular Header
+// followed by data split into fixed-size chunks that are each compressed
+// independently, then a footer index of ChunkIndexEntry and a trailer
+// recording where the footer starts. Unlike Encode, the body isn't one
+// continuous codec stream, so it must be read back with
+// NewSeekableReader, not Reader/ReadAll. It is a thin wrapper around
+// NewChunkedWriter for callers that already have the full payload in a
+// []byte.
+func EncodeSeekable(dst io.WriteSeeker, data []byte, opts ...ChunkedOption) error {
+	w, err := NewChunkedWriter(dst, opts...)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write data: %w", err)
+	}
+	return w.Close()
+}
+
+// chunkJob is one uncompressed chunk queued for a compressWorker.
+type chunkJob struct {
+	index  uint32
+	offset uint32
+	data   []byte
+}
+
+// compressedChunk is the result of compressing a chunkJob.
+type compressedChunk struct {
+	index  uint32
+	offset uint32
+	data   []byte
+	err    error
+}
+
+// ChunkedWriter streams data into a seekable archive. It splits input into
+// fixed-size chunks as Write is called and compresses them across a pool
+// of goroutines, so large payloads like texture packs and manifests don't
+// have to be buffered whole in memory or compressed on a single core.
+// Chunks are compressed out of order but reassembled in order before
+// being written to dst, mirroring how LZ4-frame and zstd multi-frame
+// writers parallelize without reordering their output. Close writes the
+// footer index and trailer, then back-patches the header via Seek, just
+// like EncodeSeekable did before it was rewritten in terms of this type.
+type ChunkedWriter struct {
+	dst   io.WriteSeeker
+	cfg   *chunkedConfig
+	codec Codec
+
+	header *Header
+	buf    bytes.Buffer // partial chunk not yet dispatched
+	offset int64        // total uncompressed bytes written so far
+
+	nextIndex uint32
+	jobs      chan chunkJob
+	results   chan compressedChunk
+	wg        sync.WaitGroup
+
+	entries    []ChunkIndexEntry
+	writerDone chan error
+}
+
+// NewChunkedWriter creates a ChunkedWriter that writes a seekable archive
+// to dst.
+func NewChunkedWriter(dst io.WriteSeeker, opts ...ChunkedOption) (*ChunkedWriter, error) {
+	cfg := &chunkedConfig{
+		chunkSize:   DefaultChunkSize,
+		codec:       CodecZstd,
+		level:       DefaultCompressionLevel,
+		concurrency: runtime.GOMAXPROCS(0),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.chunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", cfg.chunkSize)
+	}
+	if cfg.concurrency <= 0 {
+		cfg.concurrency = 1
+	}
+
+	codec, err := CodecFor(cfg.codec)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &Header{
+		Magic:        Magic,
+		HeaderLength: HeaderSize,
+		Codec:        cfg.codec,
+	}
+	headerBytes, err := header.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("marshal header: %w", err)
+	}
+	if _, err := dst.Write(headerBytes); err != nil {
+		return nil, fmt.Errorf("write header: %w", err)
+	}
+
+	w := &ChunkedWriter{
+		dst:        dst,
+		cfg:        cfg,
+		codec:      codec,
+		header:     header,
+		jobs:       make(chan chunkJob, cfg.concurrency),
+		results:    make(chan compressedChunk, cfg.concurrency),
+		writerDone: make(chan error, 1),
+	}
+
+	for i := 0; i < cfg.concurrency; i++ {
+		w.wg.Add(1)
+		go w.compressWorker()
+	}
+	go func() {
+		w.wg.Wait()
+		close(w.results)
+	}()
+	go w.writeResults()
+
+	return w, nil
+}
+
+// compressWorker compresses chunks off w.jobs and publishes them to
+// w.results, out of order, for writeResults to reassemble.
+func (w *ChunkedWriter) compressWorker() {
+	defer w.wg.Done()
+	for job := range w.jobs {
+		var compressed bytes.Buffer
+		zw, err := w.codec.NewWriter(&compressed, w.cfg.level)
+		if err == nil {
+			if _, werr := zw.Write(job.data); werr != nil {
+				err = werr
+			} else {
+				err = zw.Close()
+			}
+		}
+		w.results <- compressedChunk{index: job.index, offset: job.offset, data: compressed.Bytes(), err: err}
+	}
+}
+
+// writeResults reassembles compressed chunks in index order and writes
+// them to dst as they become available, recording each one's
+// ChunkIndexEntry. It reports the first error it hits on writerDone once
+// w.results is drained.
+func (w *ChunkedWriter) writeResults() {
+	pending := make(map[uint32]compressedChunk)
+	next := uint32(0)
+	var firstErr error
+
+	for res := range w.results {
+		pending[res.index] = res
+		for {
+			res, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			if firstErr != nil {
+				continue
+			}
+			if res.err != nil {
+				firstErr = fmt.Errorf("compress chunk %d: %w", res.index, res.err)
+				continue
+			}
+
+			pos, err := w.dst.Seek(0, io.SeekCurrent)
+			if err != nil {
+				firstErr = fmt.Errorf("get chunk position: %w", err)
+				continue
+			}
+			if _, err := w.dst.Write(res.data); err != nil {
+				firstErr = fmt.Errorf("write chunk %d: %w", res.index, err)
+				continue
+			}
+			w.entries = append(w.entries, ChunkIndexEntry{
+				UncompressedOffset: res.offset,
+				CompressedOffset:   uint32(pos),
+				CompressedLen:      uint32(len(res.data)),
+			})
+		}
+	}
+
+	w.writerDone <- firstErr
+}
+
+// Write buffers p, dispatching full chunks to the worker pool as they
+// fill up.
+func (w *ChunkedWriter) Write(p []byte) (int, error) {
+	total := len(p)
+	for len(p) > 0 {
+		room := w.cfg.chunkSize - w.buf.Len()
+		n := room
+		if n > len(p) {
+			n = len(p)
+		}
+		w.buf.Write(p[:n])
+		p = p[n:]
+		if w.buf.Len() >= w.cfg.chunkSize {
+			w.dispatch()
+		}
+	}
+	return total, nil
+}
+
+// dispatch queues the current partial chunk as a job, resetting buf.
+func (w *ChunkedWriter) dispatch() {
+	if w.buf.Len() == 0 {
+		return
+	}
+	data := make([]byte, w.buf.Len())
+	copy(data, w.buf.Bytes())
+	w.buf.Reset()
+
+	w.jobs <- chunkJob{index: w.nextIndex, offset: uint32(w.offset), data: data}
+	w.nextIndex++
+	w.offset += int64(len(data))
+}
+
+// Close flushes any buffered tail, waits for all chunks to be compressed
+// and written, then writes the footer index, trailer, and back-patched
+// header.
+func (w *ChunkedWriter) Close() error {
+	w.dispatch()
+	close(w.jobs)
+
+	if err := <-w.writerDone; err != nil {
+		return err
+	}
+
+	footerOffset, err := w.dst.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("get footer position: %w", err)
+	}
+
+	var footer bytes.Buffer
+	binary.Write(&footer, binary.LittleEndian, uint32(len(w.entries)))
+	for _, e := range w.entries {
+		binary.Write(&footer, binary.LittleEndian, e)
+	}
+	if _, err := w.dst.Write(footer.Bytes()); err != nil {
+		return fmt.Errorf("write footer: %w", err)
+	}
+
+	var trailer bytes.Buffer
+	trailer.WriteString(seekableTrailerMagic)
+	binary.Write(&trailer, binary.LittleEndian, uint64(footerOffset))
+	binary.Write(&trailer, binary.LittleEndian, uint32(footer.Len()))
+	if _, err := w.dst.Write(trailer.Bytes()); err != nil {
+		return fmt.Errorf("write trailer: %w", err)
+	}
+
+	endPos, err := w.dst.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("get end position: %w", err)
+	}
+	w.header.Length = uint64(w.offset)
+	w.header.CompressedLength = uint64(endPos) - uint64(w.header.Size())
+
+	if _, err := w.dst.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seek to start: %w", err)
+	}
+	headerBytes, err := w.header.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("marshal header: %w", err)
+	}
+	if _, err := w.dst.Write(headerBytes); err != nil {
+		return fmt.Errorf("rewrite header: %w", err)
+	}
+	if _, err := w.dst.Seek(endPos, io.SeekStart); err != nil {
+		return fmt.Errorf("seek to end: %w", err)
+	}
+
+	return nil
+}
+
+// SeekableReaderOption configures a SeekableReader.
+type SeekableReaderOption func(*SeekableReader)
+
+// WithCacheSize sets the byte budget for SeekableReader's decompressed
+// chunk cache.
+func WithCacheSize(bytes int) SeekableReaderOption {
+	return func(r *SeekableReader) {
+		r.cache = newChunkCache(bytes)
+	}
+}
+
+// SeekableReader provides random access into an archive written by
+// EncodeSeekable: it binary-searches the chunk index to find the chunk
+// covering a requested offset, decompresses just that chunk (caching
+// recently used ones), and slices out the requested range.
+type SeekableReader struct {
+	header  *Header
+	codec   Codec
+	r       io.ReaderAt
+	entries []ChunkIndexEntry
+	size    int64
+	pos     int64
+	cache   *chunkCache
+}
+
+// NewSeekableReader opens a seekable archive of the given total size.
+func NewSeekableReader(r io.ReaderAt, size int64, opts ...SeekableReaderOption) (*SeekableReader, error) {
+	if size < int64(HeaderSize+seekableTrailerSize) {
+		return nil, fmt.Errorf("archive too small to be seekable: %d bytes", size)
+	}
+
+	headerBuf := make([]byte, HeaderSize)
+	if _, err := r.ReadAt(headerBuf, 0); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	header := &Header{}
+	if err := header.UnmarshalBinary(headerBuf); err != nil {
+		return nil, fmt.Errorf("parse header: %w", err)
+	}
+
+	codec, err := CodecFor(header.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	trailerBuf := make([]byte, seekableTrailerSize)
+	if _, err := r.ReadAt(trailerBuf, size-int64(seekableTrailerSize)); err != nil {
+		return nil, fmt.Errorf("read trailer: %w", err)
+	}
+	if string(trailerBuf[:len(seekableTrailerMagic)]) != seekableTrailerMagic {
+		return nil, fmt.Errorf("not a seekable archive: missing trailer magic")
+	}
+	trailer := bytes.NewReader(trailerBuf[len(seekableTrailerMagic):])
+	var footerOffset uint64
+	var footerLength uint32
+	if err := binary.Read(trailer, binary.LittleEndian, &footerOffset); err != nil {
+		return nil, fmt.Errorf("read footer offset: %w", err)
+	}
+	if err := binary.Read(trailer, binary.LittleEndian, &footerLength); err != nil {
+		return nil, fmt.Errorf("read footer length: %w", err)
+	}
+
+	footerBuf := make([]byte, footerLength)
+	if _, err := r.ReadAt(footerBuf, int64(footerOffset)); err != nil {
+		return nil, fmt.Errorf("read footer: %w", err)
+	}
+	footer := bytes.NewReader(footerBuf)
+	var count uint32
+	if err := binary.Read(footer, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("read chunk count: %w", err)
+	}
+	entries := make([]ChunkIndexEntry, count)
+	for i := range entries {
+		if err := binary.Read(footer, binary.LittleEndian, &entries[i]); err != nil {
+			return nil, fmt.Errorf("read chunk entry %d: %w", i, err)
+		}
+	}
+
+	sr := &SeekableReader{
+		header:  header,
+		codec:   codec,
+		r:       r,
+		entries: entries,
+		size:    int64(header.Length),
+		cache:   newChunkCache(DefaultSeekableCacheSize),
+	}
+	for _, opt := range opts {
+		opt(sr)
+	}
+	return sr, nil
+}
+
+// Size returns the uncompressed length of the archive.
+func (r *SeekableReader) Size() int64 {
+	return r.size
+}
+
+// ReadAt implements io.ReaderAt over the decompressed content.
+func (r *SeekableReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("ReadAt: negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	total := 0
+	for total < len(p) {
+		curOff := off + int64(total)
+		if curOff >= r.size {
+			return total, io.EOF
+		}
+
+		idx := r.chunkIndexFor(curOff)
+		chunk, err := r.chunkData(idx)
+		if err != nil {
+			return total, err
+		}
+
+		chunkOff := curOff - int64(r.entries[idx].UncompressedOffset)
+		n := copy(p[total:], chunk[chunkOff:])
+		total += n
+	}
+	return total, nil
+}
+
+// Read implements io.Reader, advancing the reader's position.
+func (r *SeekableReader) Read(p []byte) (int, error) {
+	n, err := r.ReadAt(p, r.pos)
+	r.pos += int64(n)
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *SeekableReader) Seek(offset int64, whence int) (int64, error) {
+	var newPos int64
+	switch whence {
+	case io.SeekStart:
+		newPos = offset
+	case io.SeekCurrent:
+		newPos = r.pos + offset
+	case io.SeekEnd:
+		newPos = r.size + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if newPos < 0 {
+		return 0, fmt.Errorf("negative seek position %d", newPos)
+	}
+	r.pos = newPos
+	return newPos, nil
+}
+
+// chunkIndexFor returns the index of the chunk covering uncompressed
+// offset off.
+func (r *SeekableReader) chunkIndexFor(off int64) int {
+	return sort.Search(len(r.entries), func(i int) bool {
+		return int64(r.entries[i].UncompressedOffset) > off
+	}) - 1
+}
+
+// chunkData returns the decompressed bytes of chunk idx, populating the
+// cache on a miss.
+func (r *SeekableReader) chunkData(idx int) ([]byte, error) {
+	if data, ok := r.cache.get(idx); ok {
+		return data, nil
+	}
+
+	entry := r.entries[idx]
+	compressed := make([]byte, entry.CompressedLen)
+	if _, err := r.r.ReadAt(compressed, int64(entry.CompressedOffset)); err != nil {
+		return nil, fmt.Errorf("read chunk %d: %w", idx, err)
+	}
+
+	chunkReader, err := r.codec.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("create chunk %d reader: %w", idx, err)
+	}
+	defer chunkReader.Close()
+
+	data, err := io.ReadAll(chunkReader)
+	if err != nil {
+		return nil, fmt.Errorf("decompress chunk %d: %w", idx, err)
+	}
+
+	r.cache.put(idx, data)
+	return data, nil
+}
+
+type chunkCacheEntry struct {
+	idx  int
+	data []byte
+}
+
+// chunkCache is a byte-bounded LRU cache of decompressed chunks, mirroring
+// manifest's frameCache.
+type chunkCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	curBytes int
+	order    *list.List
+	items    map[int]*list.Element
+}
+
+func newChunkCache(maxBytes int) *chunkCache {
+	if maxBytes <= 0 {
+		maxBytes = DefaultSeekableCacheSize
+	}
+	return &chunkCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[int]*list.Element),
+	}
+}
+
+func (c *chunkCache) get(idx int) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[idx]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*chunkCacheEntry).data, true
+}
+
+func (c *chunkCache) put(idx int, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[idx]; ok {
+		c.curBytes -= len(el.Value.(*chunkCacheEntry).data)
+		c.order.Remove(el)
+		delete(c.items, idx)
+	}
+
+	if len(data) > c.maxBytes {
+		return
+	}
+
+	el := c.order.PushFront(&chunkCacheEntry{idx: idx, data: data})
+	c.items[idx] = el
+	c.curBytes += len(data)
+
+	for c.curBytes > c.maxBytes {
+		back := c.order.Back()
+		if back == nil {
+			break
+		}
+		entry := back.Value.(*chunkCacheEntry)
+		c.curBytes -= len(entry.data)
+		c.order.Remove(back)
+		delete(c.items, entry.idx)
+	}
+}
@@ -0,0 +1,167 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestSeekableReaderRandomAccess(t *testing.T) {
+	data := make([]byte, 10*DefaultChunkSize+1234)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	var buf bytes.Buffer
+	ws := &seekableBuffer{Buffer: &buf}
+	if err := EncodeSeekable(ws, data, WithChunkSize(4096)); err != nil {
+		t.Fatalf("EncodeSeekable: %v", err)
+	}
+
+	r, err := NewSeekableReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("NewSeekableReader: %v", err)
+	}
+
+	if r.Size() != int64(len(data)) {
+		t.Fatalf("Size() = %d, want %d", r.Size(), len(data))
+	}
+
+	for _, tc := range []struct {
+		off, n int
+	}{
+		{0, 10},
+		{4096, 100},
+		{4090, 20}, // straddles a chunk boundary
+		{len(data) - 5, 5},
+	} {
+		got := make([]byte, tc.n)
+		n, err := r.ReadAt(got, int64(tc.off))
+		if err != nil {
+			t.Fatalf("ReadAt(off=%d, n=%d): %v", tc.off, tc.n, err)
+		}
+		if n != tc.n {
+			t.Fatalf("ReadAt(off=%d, n=%d): read %d bytes", tc.off, tc.n, n)
+		}
+		want := data[tc.off : tc.off+tc.n]
+		if !bytes.Equal(got, want) {
+			t.Errorf("ReadAt(off=%d, n=%d): mismatch", tc.off, tc.n)
+		}
+	}
+}
+
+func TestSeekableReaderSeekAndRead(t *testing.T) {
+	data := bytes.Repeat([]byte("seekable archive chunk contents "), 1000)
+
+	var buf bytes.Buffer
+	ws := &seekableBuffer{Buffer: &buf}
+	if err := EncodeSeekable(ws, data, WithChunkSize(512)); err != nil {
+		t.Fatalf("EncodeSeekable: %v", err)
+	}
+
+	r, err := NewSeekableReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("NewSeekableReader: %v", err)
+	}
+
+	if _, err := r.Seek(1000, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	got := make([]byte, 50)
+	n, err := r.Read(got)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if n != len(got) {
+		t.Fatalf("Read: got %d bytes, want %d", n, len(got))
+	}
+	if !bytes.Equal(got, data[1000:1050]) {
+		t.Errorf("Read after Seek: mismatch")
+	}
+}
+
+func TestSeekableReaderCodecs(t *testing.T) {
+	data := bytes.Repeat([]byte("cross codec seekable test data "), 500)
+
+	for _, tag := range []CodecTag{CodecZstd, CodecLZ4, CodecDeflate, CodecBrotli, CodecStored} {
+		var buf bytes.Buffer
+		ws := &seekableBuffer{Buffer: &buf}
+		if err := EncodeSeekable(ws, data, WithChunkSize(1024), WithChunkCodec(tag)); err != nil {
+			t.Fatalf("tag %d: EncodeSeekable: %v", tag, err)
+		}
+
+		r, err := NewSeekableReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("tag %d: NewSeekableReader: %v", tag, err)
+		}
+
+		got := make([]byte, len(data))
+		if _, err := r.ReadAt(got, 0); err != nil {
+			t.Fatalf("tag %d: ReadAt: %v", tag, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("tag %d: full read mismatch", tag)
+		}
+	}
+}
+
+func TestChunkedWriterStreamingWrites(t *testing.T) {
+	data := make([]byte, 20*4096+777)
+	rand.New(rand.NewSource(2)).Read(data)
+
+	var buf bytes.Buffer
+	ws := &seekableBuffer{Buffer: &buf}
+	w, err := NewChunkedWriter(ws, WithChunkSize(4096), WithChunkConcurrency(4))
+	if err != nil {
+		t.Fatalf("NewChunkedWriter: %v", err)
+	}
+
+	// Feed the writer in small, irregular pieces that don't line up with
+	// chunk boundaries, the way a streaming caller would.
+	for off := 0; off < len(data); {
+		n := 777
+		if off+n > len(data) {
+			n = len(data) - off
+		}
+		if _, err := w.Write(data[off : off+n]); err != nil {
+			t.Fatalf("Write at %d: %v", off, err)
+		}
+		off += n
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewSeekableReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("NewSeekableReader: %v", err)
+	}
+	if r.Size() != int64(len(data)) {
+		t.Fatalf("Size() = %d, want %d", r.Size(), len(data))
+	}
+
+	got := make([]byte, len(data))
+	if _, err := r.ReadAt(got, 0); err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("round trip mismatch")
+	}
+}
+
+func TestRegularReaderStillWorksWithoutIndex(t *testing.T) {
+	original := []byte("plain archive with no chunk index")
+
+	var buf bytes.Buffer
+	ws := &seekableBuffer{Buffer: &buf}
+	if err := Encode(ws, original); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	decoded, err := ReadAll(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(decoded, original) {
+		t.Errorf("ReadAll mismatch: got %q, want %q", decoded, original)
+	}
+}
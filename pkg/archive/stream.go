@@ -0,0 +1,368 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// streamFooterMagic identifies a Footer, the fixed-size record a
+// StreamWriter appends immediately after the compressed body. A
+// StreamReader can't rely on the codec reader to stop exactly at the
+// compressed frame's end to find it - several codecs (zstd among them)
+// read ahead past the frame boundary to fill an internal decompression
+// buffer, silently swallowing whatever immediately follows. Instead,
+// NewStreamReader reads everything past the header up front and slices
+// the fixed-size Footer off the tail before any of it reaches the codec.
+var streamFooterMagic = [8]byte{'E', 'V', 'R', 'S', 'T', 'R', 'M', '1'}
+
+// streamFooterSize is the fixed on-disk size of Footer.
+const streamFooterSize = 8 + 8 + 8 + 4 + 4
+
+// Footer carries what a StreamWriter can only know once every byte of the
+// body has passed through it: the final uncompressed and compressed
+// lengths, and a CRC32 of each stream. NewStreamReader reads it back and
+// StreamReader.Close validates it against what it actually decompressed.
+type Footer struct {
+	Length            uint64
+	CompressedLength  uint64
+	UncompressedCRC32 uint32
+	CompressedCRC32   uint32
+}
+
+// MarshalBinary encodes the footer to binary format.
+func (f *Footer) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.Write(streamFooterMagic[:])
+	if err := binary.Write(buf, binary.LittleEndian, f.Length); err != nil {
+		return nil, fmt.Errorf("write length: %w", err)
+	}
+	if err := binary.Write(buf, binary.LittleEndian, f.CompressedLength); err != nil {
+		return nil, fmt.Errorf("write compressed length: %w", err)
+	}
+	if err := binary.Write(buf, binary.LittleEndian, f.UncompressedCRC32); err != nil {
+		return nil, fmt.Errorf("write uncompressed crc32: %w", err)
+	}
+	if err := binary.Write(buf, binary.LittleEndian, f.CompressedCRC32); err != nil {
+		return nil, fmt.Errorf("write compressed crc32: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a footer previously written by MarshalBinary.
+func (f *Footer) UnmarshalBinary(data []byte) error {
+	if len(data) < streamFooterSize {
+		return fmt.Errorf("data too short for stream footer: %d bytes", len(data))
+	}
+
+	var magic [8]byte
+	copy(magic[:], data[:8])
+	if magic != streamFooterMagic {
+		return fmt.Errorf("invalid stream footer magic %x", magic)
+	}
+
+	f.Length = binary.LittleEndian.Uint64(data[8:16])
+	f.CompressedLength = binary.LittleEndian.Uint64(data[16:24])
+	f.UncompressedCRC32 = binary.LittleEndian.Uint32(data[24:28])
+	f.CompressedCRC32 = binary.LittleEndian.Uint32(data[28:32])
+	return nil
+}
+
+// StreamWriterOption configures a StreamWriter.
+type StreamWriterOption func(*StreamWriter)
+
+// WithStreamCompressionLevel sets the compression level for the writer.
+func WithStreamCompressionLevel(level int) StreamWriterOption {
+	return func(w *StreamWriter) {
+		w.level = level
+	}
+}
+
+// WithStreamCodec selects which Codec compresses the archive body. It
+// defaults to CodecZstd.
+func WithStreamCodec(tag CodecTag) StreamWriterOption {
+	return func(w *StreamWriter) {
+		w.codecTag = tag
+	}
+}
+
+// StreamWriter writes a streaming archive: a header with Length and
+// CompressedLength left zero, the compressed body, and a Footer with the
+// real lengths and CRC32 checksums appended once Close flushes the
+// compressor. Unlike Writer, which seeks back into the header after
+// compressing to patch in the final sizes, StreamWriter only ever writes
+// forward, so dst can be a pipe, a socket, or a cloud object upload -
+// anything io.Writer wraps. Use Writer instead when dst can seek and the
+// existing fixed-header layout is required.
+type StreamWriter struct {
+	dst      io.Writer
+	codecTag CodecTag
+	level    int
+
+	zWriter         io.WriteCloser
+	compressedOut   *countingCRCWriter
+	uncompressedCRC hashWriter32
+	length          uint64
+}
+
+// NewStreamWriter creates a StreamWriter that writes a streaming archive
+// to dst.
+func NewStreamWriter(dst io.Writer, opts ...StreamWriterOption) (*StreamWriter, error) {
+	w := &StreamWriter{
+		dst:      dst,
+		codecTag: CodecZstd,
+		level:    DefaultCompressionLevel,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	header := &Header{
+		Magic:        Magic,
+		HeaderLength: StreamHeaderLength,
+		Codec:        w.codecTag,
+	}
+	headerBytes, err := header.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("marshal header: %w", err)
+	}
+	if _, err := dst.Write(headerBytes); err != nil {
+		return nil, fmt.Errorf("write header: %w", err)
+	}
+
+	codec, err := CodecFor(w.codecTag)
+	if err != nil {
+		return nil, err
+	}
+
+	w.compressedOut = &countingCRCWriter{w: dst, crc: crc32.NewIEEE()}
+	zWriter, err := codec.NewWriter(w.compressedOut, w.level)
+	if err != nil {
+		return nil, fmt.Errorf("create codec %d writer: %w", w.codecTag, err)
+	}
+	w.zWriter = zWriter
+	w.uncompressedCRC = crc32.NewIEEE()
+
+	return w, nil
+}
+
+// Write streams p through the compressor, accumulating the uncompressed
+// length and CRC32 the footer needs.
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	w.uncompressedCRC.Write(p)
+	w.length += uint64(len(p))
+	return w.zWriter.Write(p)
+}
+
+// Close flushes the compressor and appends the Footer recording the
+// final uncompressed/compressed lengths and CRC32 checksums.
+func (w *StreamWriter) Close() error {
+	if err := w.zWriter.Close(); err != nil {
+		return fmt.Errorf("close compressor: %w", err)
+	}
+
+	footer := &Footer{
+		Length:            w.length,
+		CompressedLength:  uint64(w.compressedOut.n),
+		UncompressedCRC32: w.uncompressedCRC.Sum32(),
+		CompressedCRC32:   w.compressedOut.crc.Sum32(),
+	}
+	footerBytes, err := footer.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("marshal footer: %w", err)
+	}
+	if _, err := w.dst.Write(footerBytes); err != nil {
+		return fmt.Errorf("write footer: %w", err)
+	}
+	return nil
+}
+
+// EncodeStream compresses data and writes it as a streaming archive to
+// dst, which need not support Seek.
+func EncodeStream(dst io.Writer, data []byte, opts ...StreamWriterOption) error {
+	w, err := NewStreamWriter(dst, opts...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write data: %w", err)
+	}
+
+	return w.Close()
+}
+
+// StreamReader decompresses a streaming archive written by StreamWriter.
+// It needs only a plain io.Reader: NewStreamReader reads everything past
+// the header up front, splits the fixed-size Footer off the tail, and
+// only ever hands the codec the bytes in between - so the codec reader
+// can never read into (and swallow) the Footer no matter how far ahead
+// of the frame boundary it buffers. Close validates the Footer against
+// what was actually decompressed.
+type StreamReader struct {
+	header *Header
+	footer *Footer
+
+	zReader         io.ReadCloser
+	compressedIn    *countingCRCReader
+	uncompressedCRC hashWriter32
+	length          uint64
+}
+
+// NewStreamReader creates a new StreamReader from the given source. It
+// reads and checks the header, reads the rest of src in full to separate
+// the compressed body from the trailing Footer, and returns a reader for
+// the decompressed content; call Close once the body has been fully read
+// to validate the footer.
+func NewStreamReader(src io.Reader) (*StreamReader, error) {
+	r := &StreamReader{}
+
+	var headerBuf [HeaderSize]byte
+	if _, err := io.ReadFull(src, headerBuf[:]); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	header := &Header{}
+	if err := binary.Read(bytes.NewReader(headerBuf[:]), binary.LittleEndian, header); err != nil {
+		return nil, fmt.Errorf("parse header: %w", err)
+	}
+	if header.Magic != Magic {
+		return nil, fmt.Errorf("invalid magic: expected %x, got %x", Magic, header.Magic)
+	}
+	if header.HeaderLength != StreamHeaderLength {
+		return nil, fmt.Errorf("not a streaming archive: expected header length %d, got %d", StreamHeaderLength, header.HeaderLength)
+	}
+	r.header = header
+
+	rest, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("read body and footer: %w", err)
+	}
+	if len(rest) < streamFooterSize {
+		return nil, fmt.Errorf("stream too short for footer: %d bytes", len(rest))
+	}
+	body, footerBuf := rest[:len(rest)-streamFooterSize], rest[len(rest)-streamFooterSize:]
+
+	footer := &Footer{}
+	if err := footer.UnmarshalBinary(footerBuf); err != nil {
+		return nil, fmt.Errorf("parse footer: %w", err)
+	}
+	r.footer = footer
+
+	codec, err := CodecFor(header.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	r.compressedIn = &countingCRCReader{r: bytes.NewReader(body), crc: crc32.NewIEEE()}
+	zReader, err := codec.NewReader(r.compressedIn)
+	if err != nil {
+		return nil, fmt.Errorf("create codec %d reader: %w", header.Codec, err)
+	}
+	r.zReader = zReader
+	r.uncompressedCRC = crc32.NewIEEE()
+
+	return r, nil
+}
+
+// Header returns the archive header. Its Length and CompressedLength are
+// always zero for a streaming archive; use Footer (valid only once Close
+// has returned without error) for the real sizes.
+func (r *StreamReader) Header() *Header {
+	return r.header
+}
+
+// Footer returns the archive's footer. It's already been read by
+// NewStreamReader, but isn't trustworthy until Close has validated it
+// against what was actually decompressed.
+func (r *StreamReader) Footer() *Footer {
+	return r.footer
+}
+
+// Read reads decompressed data into p.
+func (r *StreamReader) Read(p []byte) (int, error) {
+	n, err := r.zReader.Read(p)
+	r.uncompressedCRC.Write(p[:n])
+	r.length += uint64(n)
+	return n, err
+}
+
+// Close closes the decompressor and validates the Footer NewStreamReader
+// already read against the lengths and CRC32 checksums actually observed
+// while reading.
+func (r *StreamReader) Close() error {
+	if err := r.zReader.Close(); err != nil {
+		return fmt.Errorf("close decompressor: %w", err)
+	}
+
+	footer := r.footer
+	if footer.Length != r.length {
+		return fmt.Errorf("uncompressed length mismatch: footer says %d, got %d", footer.Length, r.length)
+	}
+	if footer.CompressedLength != uint64(r.compressedIn.n) {
+		return fmt.Errorf("compressed length mismatch: footer says %d, got %d", footer.CompressedLength, r.compressedIn.n)
+	}
+	if got := r.uncompressedCRC.Sum32(); footer.UncompressedCRC32 != got {
+		return fmt.Errorf("uncompressed crc32 mismatch: footer says %08x, got %08x", footer.UncompressedCRC32, got)
+	}
+	if got := r.compressedIn.crc.Sum32(); footer.CompressedCRC32 != got {
+		return fmt.Errorf("compressed crc32 mismatch: footer says %08x, got %08x", footer.CompressedCRC32, got)
+	}
+
+	r.footer = footer
+	return nil
+}
+
+// DecodeStream reads the entire decompressed content from a streaming
+// archive written by StreamWriter (or EncodeStream), validating its
+// footer once the body has been fully read.
+func DecodeStream(src io.Reader) ([]byte, error) {
+	r, err := NewStreamReader(src)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read content: %w", err)
+	}
+	if err := r.Close(); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// countingCRCWriter tracks both the byte count and rolling CRC32 of
+// everything written through it, so StreamWriter.Close can report the
+// compressed stream's final length and checksum without buffering it.
+type countingCRCWriter struct {
+	w   io.Writer
+	crc hashWriter32
+	n   int64
+}
+
+func (c *countingCRCWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.crc.Write(p[:n])
+	c.n += int64(n)
+	return n, err
+}
+
+// countingCRCReader is countingCRCWriter's read-side counterpart:
+// StreamReader.Close needs the compressed stream's actual length and
+// CRC32 to validate against the Footer, computed as the codec reader
+// pulls bytes through on the way to decompressing them.
+type countingCRCReader struct {
+	r   io.Reader
+	crc hashWriter32
+	n   int64
+}
+
+func (c *countingCRCReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.crc.Write(p[:n])
+	c.n += int64(n)
+	return n, err
+}
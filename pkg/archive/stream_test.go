@@ -0,0 +1,113 @@
+package archive
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStreamEncodeDecodeRoundTrip(t *testing.T) {
+	original := []byte("Hello, World! This is streamed test data for compression.")
+
+	var buf bytes.Buffer
+	if err := EncodeStream(&buf, original); err != nil {
+		t.Fatalf("EncodeStream: %v", err)
+	}
+
+	decoded, err := DecodeStream(&buf)
+	if err != nil {
+		t.Fatalf("DecodeStream: %v", err)
+	}
+	if !bytes.Equal(decoded, original) {
+		t.Errorf("data mismatch: got %q, want %q", decoded, original)
+	}
+}
+
+// nonSeekingWriter exposes only Write, hiding every other method of the
+// bytes.Buffer it wraps - this confirms a bare io.Writer (a pipe, say)
+// works as EncodeStream's destination.
+type nonSeekingWriter struct {
+	buf *bytes.Buffer
+}
+
+func (w *nonSeekingWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func TestStreamWriterDoesNotRequireSeek(t *testing.T) {
+	var buf bytes.Buffer
+	dst := &nonSeekingWriter{buf: &buf}
+
+	if err := EncodeStream(dst, []byte("no seeking needed")); err != nil {
+		t.Fatalf("EncodeStream: %v", err)
+	}
+
+	decoded, err := DecodeStream(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("DecodeStream: %v", err)
+	}
+	if string(decoded) != "no seeking needed" {
+		t.Errorf("got %q", decoded)
+	}
+}
+
+func TestStreamReaderDetectsCorruption(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeStream(&buf, []byte("detect me if you can")); err != nil {
+		t.Fatalf("EncodeStream: %v", err)
+	}
+
+	corrupted := buf.Bytes()
+	corrupted[len(corrupted)-1] ^= 0xff // flip every bit of the last byte of the footer's compressed CRC32
+
+	r, err := NewStreamReader(bytes.NewReader(corrupted))
+	if err != nil {
+		t.Fatalf("NewStreamReader: %v", err)
+	}
+	if _, err := readAllStream(r); err == nil {
+		t.Fatal("expected Close to report the corrupted footer")
+	}
+}
+
+// readAllStream drains r and closes it, returning Close's error (if any)
+// instead of swallowing it the way io.ReadAll's caller normally would.
+func readAllStream(r *StreamReader) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), r.Close()
+}
+
+func TestStreamReaderRejectsSeekAndPatchHeader(t *testing.T) {
+	var buf bytes.Buffer
+	ws := &seekableBuffer{Buffer: &buf}
+	if err := Encode(ws, []byte("legacy layout")); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	if _, err := NewStreamReader(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Fatal("expected NewStreamReader to reject a seek-and-patch archive")
+	}
+}
+
+func TestFooterRoundTrip(t *testing.T) {
+	original := &Footer{
+		Length:            1024,
+		CompressedLength:  512,
+		UncompressedCRC32: 0xdeadbeef,
+		CompressedCRC32:   0x12345678,
+	}
+
+	data, err := original.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	decoded := &Footer{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if *decoded != *original {
+		t.Errorf("mismatch: got %+v, want %+v", decoded, original)
+	}
+}
@@ -3,14 +3,12 @@ package archive
 import (
 	"fmt"
 	"io"
-
-	"github.com/DataDog/zstd"
 )
 
 // Writer wraps an io.WriteSeeker to provide compression of archive data.
 type Writer struct {
 	dst     io.WriteSeeker
-	zWriter *zstd.Writer
+	zWriter io.WriteCloser
 	header  *Header
 	level   int
 }
@@ -25,6 +23,14 @@ func WithCompressionLevel(level int) WriterOption {
 	}
 }
 
+// WithCodec selects which Codec compresses the archive body. It defaults
+// to CodecZstd.
+func WithCodec(tag CodecTag) WriterOption {
+	return func(w *Writer) {
+		w.header.Codec = tag
+	}
+}
+
 // NewWriter creates a new archive writer that writes to dst.
 // The uncompressedSize is the expected size of the uncompressed data.
 func NewWriter(dst io.WriteSeeker, uncompressedSize uint64, opts ...WriterOption) (*Writer, error) {
@@ -33,7 +39,8 @@ func NewWriter(dst io.WriteSeeker, uncompressedSize uint64, opts ...WriterOption
 		level: DefaultCompressionLevel,
 		header: &Header{
 			Magic:            Magic,
-			HeaderLength:     16,
+			HeaderLength:     HeaderSize,
+			Codec:            CodecZstd,
 			Length:           uncompressedSize,
 			CompressedLength: 0, // Will be updated after writing
 		},
@@ -52,7 +59,15 @@ func NewWriter(dst io.WriteSeeker, uncompressedSize uint64, opts ...WriterOption
 		return nil, fmt.Errorf("write header: %w", err)
 	}
 
-	w.zWriter = zstd.NewWriterLevel(dst, w.level)
+	codec, err := CodecFor(w.header.Codec)
+	if err != nil {
+		return nil, err
+	}
+	zWriter, err := codec.NewWriter(dst, w.level)
+	if err != nil {
+		return nil, fmt.Errorf("create codec %d writer: %w", w.header.Codec, err)
+	}
+	w.zWriter = zWriter
 	return w, nil
 }
 
@@ -0,0 +1,227 @@
+package asset
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/goopsie/evrFileTools/pkg/manifest"
+)
+
+// AssetInfo is what a Resolver knows about a single GUID: which manifest
+// entry it names and, once a symbol dictionary has resolved it, the
+// asset's human-readable type and file names.
+type AssetInfo struct {
+	GUID         uint64
+	TypeSymbol   int64
+	FileSymbol   int64
+	PackageIndex uint32
+	FrameIndex   uint32
+
+	// TypeName and FileName are populated from dictionaries loaded via
+	// LoadTypeNames/LoadFileNames; they're empty when no dictionary
+	// covers this GUID.
+	TypeName string
+	FileName string
+}
+
+// Resolver indexes manifests by GUID, so tools holding a raw
+// AssetReference.ReferenceGUID/TargetGUID can look up the file it names
+// instead of treating it as an opaque number. Once a reference's targets
+// have been registered with AddReferences, Dependencies can also walk the
+// chain of assets it transitively pulls in.
+//
+// A manifest identifies files by the pair (TypeSymbol, FileSymbol) rather
+// than a single GUID; by convention FileSymbol doubles as the GUID used in
+// AssetReference, so Resolver indexes on uint64(FileSymbol).
+type Resolver struct {
+	byGUID map[uint64]*AssetInfo
+	refs   map[uint64][]uint64
+
+	typeNames map[int64]string
+	fileNames map[int64]string
+}
+
+// NewResolver builds a Resolver over one or more manifests. Files present
+// in more than one manifest (the same FileSymbol appearing in, say, both a
+// base game manifest and a DLC manifest) resolve to whichever manifest was
+// added last.
+func NewResolver(manifests ...*manifest.Manifest) *Resolver {
+	r := &Resolver{
+		byGUID:    make(map[uint64]*AssetInfo),
+		refs:      make(map[uint64][]uint64),
+		typeNames: make(map[int64]string),
+		fileNames: make(map[int64]string),
+	}
+	for _, m := range manifests {
+		r.Add(m)
+	}
+	return r
+}
+
+// Add indexes another manifest's files into the resolver, keyed by
+// uint64(FileSymbol).
+func (r *Resolver) Add(m *manifest.Manifest) {
+	for _, fc := range m.FrameContents {
+		guid := uint64(fc.FileSymbol)
+		info := &AssetInfo{
+			GUID:       guid,
+			TypeSymbol: fc.TypeSymbol,
+			FileSymbol: fc.FileSymbol,
+			FrameIndex: fc.FrameIndex,
+		}
+		if int(fc.FrameIndex) < len(m.Frames) {
+			info.PackageIndex = m.Frames[fc.FrameIndex].PackageIndex
+		}
+		r.byGUID[guid] = info
+	}
+}
+
+// Resolve looks up guid against the indexed manifests, returning its
+// AssetInfo (with TypeName/FileName filled in from any loaded dictionaries)
+// and whether guid is known at all.
+func (r *Resolver) Resolve(guid uint64) (*AssetInfo, bool) {
+	info, ok := r.byGUID[guid]
+	if !ok {
+		return nil, false
+	}
+
+	resolved := *info
+	resolved.TypeName = r.typeNames[info.TypeSymbol]
+	resolved.FileName = r.fileNames[info.FileSymbol]
+	return &resolved, true
+}
+
+// AddReferences registers the targets a given GUID's reference file points
+// at - typically ref.TargetGUID from one or more AssetReference values
+// parsed out of guid's own file - so Dependencies can later walk from guid
+// through them. Zero targets (no reference present) are ignored.
+func (r *Resolver) AddReferences(guid uint64, refs ...*AssetReference) {
+	for _, ref := range refs {
+		if ref.TargetGUID == 0 {
+			continue
+		}
+		r.refs[guid] = append(r.refs[guid], ref.TargetGUID)
+	}
+}
+
+// Dependencies transitively follows the reference graph built by
+// AddReferences, starting at guid, and returns every GUID reachable from
+// it in discovery order (guid itself is never included, even when a cycle
+// leads back to it). Asset reference chains are common - a material referencing
+// its textures, which in turn reference a tint - so a tool extracting an
+// asset can request guid's full closure instead of resolving one
+// reference at a time.
+func (r *Resolver) Dependencies(guid uint64) []uint64 {
+	visited := make(map[uint64]bool)
+	visited[guid] = true
+	var order []uint64
+
+	var walk func(uint64)
+	walk = func(g uint64) {
+		for _, target := range r.refs[g] {
+			if visited[target] {
+				continue
+			}
+			visited[target] = true
+			order = append(order, target)
+			walk(target)
+		}
+	}
+	walk(guid)
+
+	return order
+}
+
+// LoadTypeNames reads a symbol dictionary mapping TypeSymbol values to
+// human-readable type names (e.g. "material", "texture"), one entry per
+// line formatted as "<symbol>\t<name>". Blank lines and lines starting
+// with '#' are skipped; <symbol> accepts decimal or 0x-prefixed hex.
+func (r *Resolver) LoadTypeNames(rd io.Reader) error {
+	names, err := readSymbolDict(rd)
+	if err != nil {
+		return fmt.Errorf("load type names: %w", err)
+	}
+	for symbol, name := range names {
+		r.typeNames[symbol] = name
+	}
+	return nil
+}
+
+// LoadFileNames reads a symbol dictionary mapping FileSymbol values to
+// human-readable asset names (e.g. "weapon_shield_v3"), in the same
+// "<symbol>\t<name>" format as LoadTypeNames.
+func (r *Resolver) LoadFileNames(rd io.Reader) error {
+	names, err := readSymbolDict(rd)
+	if err != nil {
+		return fmt.Errorf("load file names: %w", err)
+	}
+	for symbol, name := range names {
+		r.fileNames[symbol] = name
+	}
+	return nil
+}
+
+func readSymbolDict(rd io.Reader) (map[int64]string, error) {
+	names := make(map[int64]string)
+
+	scanner := bufio.NewScanner(rd)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"<symbol>\\t<name>\", got %q", lineNum, line)
+		}
+
+		symbol, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid symbol %q: %w", lineNum, parts[0], err)
+		}
+		names[symbol] = strings.TrimSpace(parts[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan dictionary: %w", err)
+	}
+
+	return names, nil
+}
+
+// StringResolved is String, but with ReferenceGUID and TargetGUID looked
+// up through res - e.g. `ref=material:"weapon_shield_v3"` instead of
+// `ref=00000000deadbeef`. A GUID res doesn't recognize falls back to its
+// raw hex form; res may be nil, in which case this is equivalent to
+// String().
+func (r *AssetReference) StringResolved(res *Resolver) string {
+	if res == nil {
+		return r.String()
+	}
+	return fmt.Sprintf(
+		"AssetRef[type=%s, size=%d, %s, %s, flags=0x%x, extra=%d bytes]",
+		r.Type, r.Size, res.describeGUID("ref", r.ReferenceGUID), res.describeGUID("target", r.TargetGUID), r.Flags, len(r.AdditionalData),
+	)
+}
+
+// describeGUID formats guid for StringResolved: the resolved type and file
+// name when both are known, the type name alone with the GUID left in hex
+// when only it is known, or plain hex when res has no dictionary entries
+// for guid at all.
+func (res *Resolver) describeGUID(label string, guid uint64) string {
+	info, ok := res.Resolve(guid)
+	if !ok || (info.TypeName == "" && info.FileName == "") {
+		return fmt.Sprintf("%s=%016x", label, guid)
+	}
+	typeName := info.TypeName
+	if typeName == "" {
+		typeName = fmt.Sprintf("%x", info.TypeSymbol)
+	}
+	if info.FileName == "" {
+		return fmt.Sprintf("%s=%s:%016x", label, typeName, guid)
+	}
+	return fmt.Sprintf("%s=%s:%q", label, typeName, info.FileName)
+}
@@ -0,0 +1,112 @@
+package asset
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goopsie/evrFileTools/pkg/manifest"
+)
+
+func testManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		FrameContents: []manifest.FrameContent{
+			{TypeSymbol: 88, FileSymbol: 0x1111, FrameIndex: 0},
+			{TypeSymbol: 120, FileSymbol: 0x2222, FrameIndex: 1},
+			{TypeSymbol: 96, FileSymbol: 0x3333, FrameIndex: 2},
+		},
+		Frames: []manifest.Frame{
+			{PackageIndex: 0},
+			{PackageIndex: 1},
+			{PackageIndex: 1},
+		},
+	}
+}
+
+func TestResolverResolve(t *testing.T) {
+	r := NewResolver(testManifest())
+
+	info, ok := r.Resolve(0x2222)
+	if !ok {
+		t.Fatal("expected 0x2222 to resolve")
+	}
+	if info.TypeSymbol != 120 || info.PackageIndex != 1 || info.FrameIndex != 1 {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+
+	if _, ok := r.Resolve(0xdead); ok {
+		t.Fatal("expected unknown GUID to not resolve")
+	}
+}
+
+func TestResolverLoadNames(t *testing.T) {
+	r := NewResolver(testManifest())
+
+	if err := r.LoadTypeNames(strings.NewReader("88\tmaterial\n# comment\n\n120\ttexture\n")); err != nil {
+		t.Fatalf("LoadTypeNames: %v", err)
+	}
+	if err := r.LoadFileNames(strings.NewReader("0x1111\tweapon_shield_v3\n")); err != nil {
+		t.Fatalf("LoadFileNames: %v", err)
+	}
+
+	info, ok := r.Resolve(0x1111)
+	if !ok {
+		t.Fatal("expected 0x1111 to resolve")
+	}
+	if info.TypeName != "material" || info.FileName != "weapon_shield_v3" {
+		t.Fatalf("unexpected names: %+v", info)
+	}
+
+	info, ok = r.Resolve(0x2222)
+	if !ok || info.TypeName != "texture" || info.FileName != "" {
+		t.Fatalf("unexpected info for 0x2222: %+v", info)
+	}
+}
+
+func TestResolverLoadNamesMalformed(t *testing.T) {
+	r := NewResolver()
+	if err := r.LoadTypeNames(strings.NewReader("not-a-valid-line\n")); err == nil {
+		t.Fatal("expected error for malformed dictionary line")
+	}
+}
+
+func TestResolverDependencies(t *testing.T) {
+	r := NewResolver(testManifest())
+
+	// material (0x1111) -> texture (0x2222) -> tint (0x3333), a cycle back
+	// to the material thrown in to confirm it doesn't loop forever.
+	r.AddReferences(0x1111, &AssetReference{ReferenceGUID: 0x1111, TargetGUID: 0x2222})
+	r.AddReferences(0x2222, &AssetReference{ReferenceGUID: 0x2222, TargetGUID: 0x3333})
+	r.AddReferences(0x3333, &AssetReference{ReferenceGUID: 0x3333, TargetGUID: 0x1111})
+
+	deps := r.Dependencies(0x1111)
+	if len(deps) != 2 {
+		t.Fatalf("expected 2 transitive dependencies, got %d: %v", len(deps), deps)
+	}
+	if deps[0] != 0x2222 || deps[1] != 0x3333 {
+		t.Fatalf("unexpected dependency order: %v", deps)
+	}
+}
+
+func TestAssetReferenceStringResolved(t *testing.T) {
+	r := NewResolver(testManifest())
+	if err := r.LoadTypeNames(strings.NewReader("88\tmaterial\n120\ttexture\n")); err != nil {
+		t.Fatalf("LoadTypeNames: %v", err)
+	}
+	if err := r.LoadFileNames(strings.NewReader("0x2222\tweapon_shield_v3\n")); err != nil {
+		t.Fatalf("LoadFileNames: %v", err)
+	}
+
+	ref := &AssetReference{Type: ReferenceTypeMaterial, Size: 88, ReferenceGUID: 0x1111, TargetGUID: 0x2222}
+
+	got := ref.StringResolved(r)
+	if !strings.Contains(got, `target=texture:"weapon_shield_v3"`) {
+		t.Fatalf("expected resolved target in output, got %q", got)
+	}
+	if !strings.Contains(got, "ref=material:0000000000001111") {
+		t.Fatalf("expected type-only ref in output, got %q", got)
+	}
+
+	if got := ref.StringResolved(nil); got != ref.String() {
+		t.Fatalf("nil resolver should fall back to String(): %q vs %q", got, ref.String())
+	}
+}
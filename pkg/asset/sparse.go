@@ -0,0 +1,184 @@
+package asset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// minSparseRun is the shortest run of zero bytes Compact treats as a hole
+// worth eliding. Shorter runs are left embedded in their surrounding data
+// fragment, since a fragment boundary (16 bytes once marshaled) would cost
+// more than the run saves.
+const minSparseRun = 32
+
+// SparseFragment is one contiguous non-zero run within a sparse-scanned
+// byte slice, analogous to archive/tar's sparseEntry.
+type SparseFragment struct {
+	Offset int64
+	Length int64
+}
+
+// SparseMap is the compacted description of a byte slice with large zero
+// runs: the original size plus the non-zero fragments, so the zero runs
+// between (and after) them never need to be stored. It describes the
+// layout only - the fragment bytes themselves are returned by Compact and
+// consumed by Expand separately, the same split archive/tar makes between
+// a sparse header and the file's stored data.
+type SparseMap struct {
+	Size      int64
+	Fragments []SparseFragment
+}
+
+// Validate checks the invariants Compact always produces, and that
+// UnmarshalBinary and Expand require of any SparseMap they're handed:
+// fragments are strictly ordered by Offset, non-overlapping, every
+// fragment's end offset is within Size, and only the last fragment may be
+// empty (Length 0).
+func (m *SparseMap) Validate() error {
+	if m.Size < 0 {
+		return fmt.Errorf("negative size %d", m.Size)
+	}
+
+	var prevEnd int64
+	for i, f := range m.Fragments {
+		if f.Offset < 0 || f.Length < 0 {
+			return fmt.Errorf("fragment %d has a negative offset or length", i)
+		}
+		if f.Offset < prevEnd {
+			return fmt.Errorf("fragment %d at offset %d overlaps the previous fragment ending at %d", i, f.Offset, prevEnd)
+		}
+		end := f.Offset + f.Length
+		if end > m.Size {
+			return fmt.Errorf("fragment %d ends at %d, past size %d", i, end, m.Size)
+		}
+		if f.Length == 0 && i != len(m.Fragments)-1 {
+			return fmt.Errorf("fragment %d is empty but isn't the last fragment", i)
+		}
+		prevEnd = end
+	}
+	return nil
+}
+
+// Compact scans data for runs of at least minSparseRun zero bytes and
+// returns a SparseMap describing everything else as data fragments, along
+// with those fragments' bytes concatenated together (the "packed" form).
+// Expand reverses this.
+func Compact(data []byte) (*SparseMap, []byte) {
+	m := &SparseMap{Size: int64(len(data))}
+	packed := make([]byte, 0, len(data))
+
+	fragStart := 0
+	i := 0
+	for i < len(data) {
+		if data[i] != 0 {
+			i++
+			continue
+		}
+
+		runStart := i
+		for i < len(data) && data[i] == 0 {
+			i++
+		}
+		if i-runStart < minSparseRun {
+			// Too short to bother eliding; stays embedded in whichever
+			// fragment eventually spans it.
+			continue
+		}
+
+		if runStart > fragStart {
+			m.Fragments = append(m.Fragments, SparseFragment{Offset: int64(fragStart), Length: int64(runStart - fragStart)})
+			packed = append(packed, data[fragStart:runStart]...)
+		}
+		fragStart = i
+	}
+	if fragStart < len(data) {
+		m.Fragments = append(m.Fragments, SparseFragment{Offset: int64(fragStart), Length: int64(len(data) - fragStart)})
+		packed = append(packed, data[fragStart:]...)
+	}
+
+	return m, packed
+}
+
+// Expand reverses Compact: it reconstructs the original byte slice from m
+// and its packed fragment bytes, zero-filling every gap m's fragments
+// don't cover.
+func Expand(m *SparseMap, packed []byte) ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid sparse map: %w", err)
+	}
+
+	out := make([]byte, m.Size)
+	var read int64
+	for i, f := range m.Fragments {
+		if read+f.Length > int64(len(packed)) {
+			return nil, fmt.Errorf("fragment %d needs %d packed bytes, only %d remain", i, f.Length, int64(len(packed))-read)
+		}
+		copy(out[f.Offset:f.Offset+f.Length], packed[read:read+f.Length])
+		read += f.Length
+	}
+	if read != int64(len(packed)) {
+		return nil, fmt.Errorf("packed data has %d trailing bytes no fragment covers", int64(len(packed))-read)
+	}
+
+	return out, nil
+}
+
+// sparseMapMagic identifies a marshaled SparseMap, so UnmarshalBinary fails
+// fast on unrelated data instead of misreading it as a huge fragment count.
+var sparseMapMagic = [4]byte{'S', 'P', 'R', 'S'}
+
+// MarshalBinary serializes the compacted form (the map only, not the
+// packed fragment bytes Compact returned alongside it).
+func (m *SparseMap) MarshalBinary() ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid sparse map: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(sparseMapMagic[:])
+	if err := binary.Write(buf, binary.LittleEndian, m.Size); err != nil {
+		return nil, fmt.Errorf("write size: %w", err)
+	}
+	if err := binary.Write(buf, binary.LittleEndian, uint32(len(m.Fragments))); err != nil {
+		return nil, fmt.Errorf("write fragment count: %w", err)
+	}
+	for i, f := range m.Fragments {
+		if err := binary.Write(buf, binary.LittleEndian, f); err != nil {
+			return nil, fmt.Errorf("write fragment %d: %w", i, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a SparseMap previously written by MarshalBinary.
+func (m *SparseMap) UnmarshalBinary(data []byte) error {
+	buf := bytes.NewReader(data)
+
+	var magic [4]byte
+	if _, err := io.ReadFull(buf, magic[:]); err != nil {
+		return fmt.Errorf("read magic: %w", err)
+	}
+	if magic != sparseMapMagic {
+		return fmt.Errorf("invalid sparse map magic %x", magic)
+	}
+
+	if err := binary.Read(buf, binary.LittleEndian, &m.Size); err != nil {
+		return fmt.Errorf("read size: %w", err)
+	}
+
+	var count uint32
+	if err := binary.Read(buf, binary.LittleEndian, &count); err != nil {
+		return fmt.Errorf("read fragment count: %w", err)
+	}
+
+	m.Fragments = make([]SparseFragment, count)
+	for i := range m.Fragments {
+		if err := binary.Read(buf, binary.LittleEndian, &m.Fragments[i]); err != nil {
+			return fmt.Errorf("read fragment %d: %w", i, err)
+		}
+	}
+
+	return m.Validate()
+}
@@ -0,0 +1,149 @@
+package asset
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSparseRoundTrip(t *testing.T) {
+	data := make([]byte, 4096)
+	copy(data[0:16], []byte("header.........."))
+	copy(data[2048:2064], []byte("mid-data........"))
+	copy(data[4080:4096], []byte("trailer........."))
+	// Everything else stays zero: a 2032-byte gap and a 2016-byte gap, both
+	// well past minSparseRun, so both get holed out below.
+
+	m, packed := Compact(data)
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if len(m.Fragments) != 3 {
+		t.Fatalf("expected 3 fragments, got %d", len(m.Fragments))
+	}
+	if len(packed) >= len(data) {
+		t.Fatalf("packed form (%d bytes) didn't shrink vs original (%d bytes)", len(packed), len(data))
+	}
+
+	got, err := Expand(m, packed)
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("round trip mismatch")
+	}
+}
+
+func TestSparseCompactEmbedsShortZeroRuns(t *testing.T) {
+	data := make([]byte, 128)
+	copy(data[0:16], []byte("before.........."))
+	// A 16-byte zero run, shorter than minSparseRun, sits between two
+	// non-zero runs and should stay embedded in one fragment rather than
+	// being holed out.
+	copy(data[32:48], []byte("after............"))
+
+	m, packed := Compact(data)
+	if len(m.Fragments) != 1 {
+		t.Fatalf("expected the short zero run to stay embedded in one fragment, got %d fragments", len(m.Fragments))
+	}
+	if !bytes.Equal(packed, data[:m.Fragments[0].Length]) {
+		t.Fatal("packed bytes should include the embedded zero run verbatim")
+	}
+}
+
+func TestSparseCompactAllZero(t *testing.T) {
+	data := make([]byte, 1024)
+
+	m, packed := Compact(data)
+	if len(m.Fragments) != 0 {
+		t.Fatalf("expected no fragments for all-zero data, got %d", len(m.Fragments))
+	}
+	if len(packed) != 0 {
+		t.Fatalf("expected no packed bytes for all-zero data, got %d", len(packed))
+	}
+
+	got, err := Expand(m, packed)
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("round trip mismatch")
+	}
+}
+
+func TestSparseCompactNoZeroRuns(t *testing.T) {
+	data := bytes.Repeat([]byte("no zero runs here"), 20)
+
+	m, packed := Compact(data)
+	if len(m.Fragments) != 1 {
+		t.Fatalf("expected a single fragment, got %d", len(m.Fragments))
+	}
+	if !bytes.Equal(packed, data) {
+		t.Fatal("packed form should equal the original when nothing is sparse")
+	}
+}
+
+func TestSparseMapMarshalUnmarshal(t *testing.T) {
+	original := &SparseMap{
+		Size: 4096,
+		Fragments: []SparseFragment{
+			{Offset: 0, Length: 16},
+			{Offset: 2048, Length: 16},
+			{Offset: 4080, Length: 16},
+		},
+	}
+
+	data, err := original.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	decoded := &SparseMap{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+
+	if decoded.Size != original.Size || len(decoded.Fragments) != len(original.Fragments) {
+		t.Fatalf("mismatch: got %+v, want %+v", decoded, original)
+	}
+	for i := range original.Fragments {
+		if decoded.Fragments[i] != original.Fragments[i] {
+			t.Errorf("fragment %d: got %+v, want %+v", i, decoded.Fragments[i], original.Fragments[i])
+		}
+	}
+}
+
+func TestSparseMapValidateRejectsOverlap(t *testing.T) {
+	m := &SparseMap{
+		Size: 100,
+		Fragments: []SparseFragment{
+			{Offset: 0, Length: 50},
+			{Offset: 40, Length: 10},
+		},
+	}
+	if err := m.Validate(); err == nil {
+		t.Fatal("expected error for overlapping fragments")
+	}
+}
+
+func TestSparseMapValidateRejectsEndPastSize(t *testing.T) {
+	m := &SparseMap{
+		Size:      100,
+		Fragments: []SparseFragment{{Offset: 90, Length: 20}},
+	}
+	if err := m.Validate(); err == nil {
+		t.Fatal("expected error for fragment ending past size")
+	}
+}
+
+func TestSparseMapValidateRejectsEmptyNonLastFragment(t *testing.T) {
+	m := &SparseMap{
+		Size: 100,
+		Fragments: []SparseFragment{
+			{Offset: 0, Length: 0},
+			{Offset: 50, Length: 10},
+		},
+	}
+	if err := m.Validate(); err == nil {
+		t.Fatal("expected error for empty fragment that isn't last")
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"io"
 )
 
+// audioReferenceGUID is the type GUID every AudioReference starts with.
+const audioReferenceGUID = 0x38ee951a26fb816a
+
 // AudioReference represents an audio asset reference structure.
 // Based on analysis of 119 files, typical structure appears to be:
 // - 8-byte GUID/type identifier (0x38ee951a26fb816a)
@@ -53,37 +56,118 @@ type AudioIndex struct {
 	References []AudioReference
 }
 
-// ParseAudioIndex reads multiple audio references from binary data.
-// The format and count are determined by analyzing the data structure.
+// AudioIndexHeader is the optional leading record ParseAudioIndex checks
+// for before falling back to auto-detection: an entry count and a fixed
+// per-entry size, both little-endian uint32s.
+type AudioIndexHeader struct {
+	EntryCount uint32
+	EntrySize  uint32
+}
+
+// knownAudioIndexEntrySizes are the AudioReference sizes ParseAudioIndex
+// has observed in the wild: the 24-byte fixed header alone, and that
+// header followed by 8 or 24 bytes of Reserved data.
+var knownAudioIndexEntrySizes = [...]uint32{24, 32, 48}
+
+// ParseAudioIndex reads multiple audio references from binary data. It
+// first looks for a leading AudioIndexHeader (EntryCount + EntrySize); if
+// that isn't present or doesn't account for the whole file, it falls back
+// to scanning for the 0x38ee951a26fb816a GUID recurring at a fixed stride
+// of 24, 32, or 48 bytes. If neither approach finds a repeated layout, the
+// whole file is parsed as a single reference, as before.
 func ParseAudioIndex(r io.Reader) (*AudioIndex, error) {
-	// Read all data first to determine structure
 	data, err := io.ReadAll(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read audio index: %w", err)
 	}
-
-	// Try to parse as repeated structures
-	index := &AudioIndex{}
-
-	// Basic structure appears to be fixed-size entries
-	// Analyze the data to determine entry size
 	if len(data) < 24 {
 		return nil, fmt.Errorf("data too short for audio index")
 	}
+	ra := &readerAt{data}
 
-	// For now, treat entire file as a single reference
-	// This may need adjustment based on actual file analysis
-	ref, err := ParseAudioReference(io.NewSectionReader(
-		&readerAt{data}, 0, int64(len(data)),
-	))
+	if hdr, ok := parseAudioIndexHeader(data); ok {
+		return decodeAudioIndexEntries(ra, 8, int(hdr.EntryCount), int(hdr.EntrySize))
+	}
+
+	if entrySize, count, ok := detectAudioIndexLayout(data); ok {
+		return decodeAudioIndexEntries(ra, 0, count, entrySize)
+	}
+
+	ref, err := ParseAudioReference(io.NewSectionReader(ra, 0, int64(len(data))))
 	if err != nil {
 		return nil, err
 	}
+	return &AudioIndex{References: []AudioReference{*ref}}, nil
+}
 
-	index.References = append(index.References, *ref)
+// parseAudioIndexHeader reads the leading EntryCount/EntrySize pair and
+// reports whether it looks valid: a known entry size and an entry count
+// that exactly accounts for the rest of data.
+func parseAudioIndexHeader(data []byte) (AudioIndexHeader, bool) {
+	hdr := AudioIndexHeader{
+		EntryCount: binary.LittleEndian.Uint32(data[0:4]),
+		EntrySize:  binary.LittleEndian.Uint32(data[4:8]),
+	}
+	if hdr.EntryCount == 0 || !isKnownAudioIndexEntrySize(hdr.EntrySize) {
+		return AudioIndexHeader{}, false
+	}
+	if 8+uint64(hdr.EntryCount)*uint64(hdr.EntrySize) != uint64(len(data)) {
+		return AudioIndexHeader{}, false
+	}
+	return hdr, true
+}
+
+// detectAudioIndexLayout looks for data split evenly into fixed-size
+// entries each starting with the audio reference GUID, trying every
+// known entry size in turn.
+func detectAudioIndexLayout(data []byte) (entrySize, count int, ok bool) {
+	for _, stride := range knownAudioIndexEntrySizes {
+		if len(data)%int(stride) != 0 {
+			continue
+		}
+		n := len(data) / int(stride)
+		if n == 0 {
+			continue
+		}
+		allMatch := true
+		for i := 0; i < n; i++ {
+			off := i * int(stride)
+			if binary.LittleEndian.Uint64(data[off:off+8]) != audioReferenceGUID {
+				allMatch = false
+				break
+			}
+		}
+		if allMatch {
+			return int(stride), n, true
+		}
+	}
+	return 0, 0, false
+}
+
+// decodeAudioIndexEntries decodes count fixed-size AudioReference records
+// starting at base, each entrySize bytes long.
+func decodeAudioIndexEntries(ra *readerAt, base int64, count, entrySize int) (*AudioIndex, error) {
+	index := &AudioIndex{References: make([]AudioReference, 0, count)}
+	for i := 0; i < count; i++ {
+		off := base + int64(i*entrySize)
+		ref, err := ParseAudioReference(io.NewSectionReader(ra, off, int64(entrySize)))
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		index.References = append(index.References, *ref)
+	}
 	return index, nil
 }
 
+func isKnownAudioIndexEntrySize(size uint32) bool {
+	for _, known := range knownAudioIndexEntrySizes {
+		if size == known {
+			return true
+		}
+	}
+	return false
+}
+
 // String returns a human-readable representation.
 func (r *AudioReference) String() string {
 	return fmt.Sprintf(
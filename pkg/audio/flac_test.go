@@ -0,0 +1,265 @@
+package audio
+
+import (
+	"bytes"
+	"crypto/md5"
+	"testing"
+)
+
+// testBitWriter packs MSB-first bits into bytes, mirroring bitReader's
+// layout so a hand-built stream decodes exactly like a real encoder's.
+type testBitWriter struct {
+	bits []byte
+}
+
+func (w *testBitWriter) writeBits(v uint64, n uint) {
+	for i := int(n) - 1; i >= 0; i-- {
+		w.bits = append(w.bits, byte((v>>uint(i))&1))
+	}
+}
+
+func (w *testBitWriter) align() {
+	for len(w.bits)%8 != 0 {
+		w.bits = append(w.bits, 0)
+	}
+}
+
+// bytes packs the bits written so far into bytes; len(bits) must already
+// be a multiple of 8 (callers align() first where FLAC requires it).
+func (w *testBitWriter) bytes() []byte {
+	if len(w.bits)%8 != 0 {
+		panic("testBitWriter: bit count not byte-aligned")
+	}
+	out := make([]byte, len(w.bits)/8)
+	for i, bit := range w.bits {
+		out[i/8] = out[i/8]<<1 | bit
+	}
+	return out
+}
+
+func crc8Of(data []byte) byte {
+	var c byte
+	for _, b := range data {
+		c = crc8Table[c^b]
+	}
+	return c
+}
+
+func crc16Of(data []byte) uint16 {
+	var c uint16
+	for _, b := range data {
+		c = (c << 8) ^ crc16Table[byte(c>>8)^b]
+	}
+	return c
+}
+
+// buildStreamInfo encodes a 34-byte STREAMINFO block body matching
+// parseStreamInfo's layout.
+func buildStreamInfo(t *testing.T, blockSize uint16, sampleRate uint32, channels, bps uint8, totalSamples uint64, md5 [16]byte) []byte {
+	t.Helper()
+	w := &testBitWriter{}
+	w.writeBits(uint64(blockSize), 16) // min block size
+	w.writeBits(uint64(blockSize), 16) // max block size
+	w.writeBits(0, 24)                 // min frame size, unused by decode
+	w.writeBits(0, 24)                 // max frame size, unused by decode
+	w.writeBits(uint64(sampleRate), 20)
+	w.writeBits(uint64(channels-1), 3)
+	w.writeBits(uint64(bps-1), 5)
+	w.writeBits(totalSamples, 36)
+	w.align()
+	body := w.bytes()
+	return append(body, md5[:]...)
+}
+
+// buildConstantMonoFrame encodes a single-subframe CONSTANT frame: one
+// channel, blockSize samples all equal to value, at bps bits per sample.
+func buildConstantMonoFrame(t *testing.T, blockSize uint32, bps uint8, value int32) []byte {
+	t.Helper()
+	w := &testBitWriter{}
+	w.writeBits(0x3ffe, 14) // sync
+	w.writeBits(0, 1)       // reserved
+	w.writeBits(0, 1)       // blocking strategy, unused by decode
+	w.writeBits(6, 4)       // block size code 6: 8 extra bits follow
+	w.writeBits(0, 4)       // sample rate code 0: defer to STREAMINFO
+	w.writeBits(0, 4)       // channel assignment 0: mono
+	w.writeBits(0, 3)       // sample size code 0: defer to STREAMINFO
+	w.writeBits(0, 1)       // reserved
+	w.writeBits(0, 8)       // frame number (UTF-8-like, single byte)
+	w.writeBits(uint64(blockSize-1), 8)
+
+	headerBytes := w.bytes()
+	w.writeBits(uint64(crc8Of(headerBytes)), 8)
+
+	w.writeBits(0, 1) // subframe padding
+	w.writeBits(0, 6) // subframe type 0: CONSTANT
+	w.writeBits(0, 1) // no wasted bits
+	w.writeBits(uint64(uint32(value)), uint(bps))
+	w.align()
+
+	body := w.bytes()
+	w.writeBits(uint64(crc16Of(body)), 16)
+
+	return w.bytes()
+}
+
+func TestResolveFLACConstantFrameRoundTrip(t *testing.T) {
+	const (
+		blockSize  = 4
+		sampleRate = 44100
+		bps        = 8
+		value      = int32(42)
+	)
+
+	var expectedMD5 [16]byte
+	{
+		h := md5.New()
+		for i := 0; i < blockSize; i++ {
+			writeSampleToMD5(h, value, bps)
+		}
+		copy(expectedMD5[:], h.Sum(nil))
+	}
+
+	info := buildStreamInfo(t, blockSize, sampleRate, 1, bps, blockSize, expectedMD5)
+	frame := buildConstantMonoFrame(t, blockSize, bps, value)
+
+	var stream bytes.Buffer
+	stream.WriteString("fLaC")
+	stream.WriteByte(0x80) // last metadata block, type STREAMINFO
+	writeUint24(&stream, uint32(len(info)))
+	stream.Write(info)
+	stream.Write(frame)
+
+	s, err := ResolveFLAC(bytes.NewReader(stream.Bytes()), &AudioReference{AssetReference: 0})
+	if err != nil {
+		t.Fatalf("ResolveFLAC: %v", err)
+	}
+	if s.Info.SampleRate != sampleRate || s.Info.Channels != 1 || s.Info.BitsPerSample != bps {
+		t.Fatalf("unexpected STREAMINFO: %+v", s.Info)
+	}
+
+	s.SetVerify(true)
+	it := s.Frames()
+	if !it.Next() {
+		t.Fatalf("expected one frame, got none (err=%v)", it.Err())
+	}
+	got := it.Frame()
+	if got.BlockSize != blockSize {
+		t.Fatalf("BlockSize = %d, want %d", got.BlockSize, blockSize)
+	}
+	for i, v := range got.Samples {
+		if v != value {
+			t.Errorf("sample %d = %d, want %d", i, v, value)
+		}
+	}
+	if it.Next() {
+		t.Fatal("expected only one frame")
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("unexpected error after last frame: %v", err)
+	}
+
+	ok, err := s.VerifyMD5()
+	if err != nil {
+		t.Fatalf("VerifyMD5: %v", err)
+	}
+	if !ok {
+		t.Fatal("VerifyMD5 reported a mismatch for a golden MD5Signature")
+	}
+}
+
+// buildLeftSideStereoFrame encodes a two-subframe, VERBATIM, left/side
+// stereo frame: ch0 stores left directly, ch1 stores side = left-right at
+// bps+1 bits.
+func buildLeftSideStereoFrame(t *testing.T, bps uint8, left, side []int32) []byte {
+	t.Helper()
+	blockSize := len(left)
+
+	w := &testBitWriter{}
+	w.writeBits(0x3ffe, 14) // sync
+	w.writeBits(0, 1)       // reserved
+	w.writeBits(0, 1)       // blocking strategy
+	w.writeBits(6, 4)       // block size code 6: 8 extra bits follow
+	w.writeBits(0, 4)       // sample rate code 0: defer to STREAMINFO
+	w.writeBits(8, 4)       // channel assignment 8: left/side
+	w.writeBits(0, 3)       // sample size code 0: defer to STREAMINFO
+	w.writeBits(0, 1)       // reserved
+	w.writeBits(0, 8)       // frame number
+	w.writeBits(uint64(blockSize-1), 8)
+
+	headerBytes := w.bytes()
+	w.writeBits(uint64(crc8Of(headerBytes)), 8)
+
+	writeVerbatimSubframe(w, left, uint(bps))
+	writeVerbatimSubframe(w, side, uint(bps)+1) // side channel: bps+1
+	w.align()
+
+	body := w.bytes()
+	w.writeBits(uint64(crc16Of(body)), 16)
+
+	return w.bytes()
+}
+
+// writeVerbatimSubframe appends a VERBATIM subframe storing values as-is,
+// each signed to bits bits.
+func writeVerbatimSubframe(w *testBitWriter, values []int32, bits uint) {
+	w.writeBits(0, 1) // padding
+	w.writeBits(1, 6) // subframe type 1: VERBATIM
+	w.writeBits(0, 1) // no wasted bits
+	mask := uint64(1)<<bits - 1
+	for _, v := range values {
+		w.writeBits(uint64(v)&mask, bits)
+	}
+}
+
+func TestResolveFLACLeftSideDecorrelation(t *testing.T) {
+	const (
+		sampleRate = 48000
+		bps        = 8
+	)
+	left := []int32{10, 20}
+	right := []int32{12, 16}
+	side := make([]int32, len(left))
+	for i := range left {
+		side[i] = left[i] - right[i]
+	}
+
+	info := buildStreamInfo(t, uint16(len(left)), sampleRate, 2, bps, uint64(len(left)), [16]byte{})
+	frame := buildLeftSideStereoFrame(t, bps, left, side)
+
+	var stream bytes.Buffer
+	stream.WriteString("fLaC")
+	stream.WriteByte(0x80)
+	writeUint24(&stream, uint32(len(info)))
+	stream.Write(info)
+	stream.Write(frame)
+
+	s, err := ResolveFLAC(bytes.NewReader(stream.Bytes()), &AudioReference{AssetReference: 0})
+	if err != nil {
+		t.Fatalf("ResolveFLAC: %v", err)
+	}
+
+	it := s.Frames()
+	if !it.Next() {
+		t.Fatalf("expected one frame, got none (err=%v)", it.Err())
+	}
+	got := it.Frame()
+
+	want := make([]int32, 0, len(left)*2)
+	for i := range left {
+		want = append(want, left[i], right[i])
+	}
+	if len(got.Samples) != len(want) {
+		t.Fatalf("got %d samples, want %d", len(got.Samples), len(want))
+	}
+	for i := range want {
+		if got.Samples[i] != want[i] {
+			t.Errorf("sample %d = %d, want %d", i, got.Samples[i], want[i])
+		}
+	}
+}
+
+func writeUint24(buf *bytes.Buffer, v uint32) {
+	buf.WriteByte(byte(v >> 16))
+	buf.WriteByte(byte(v >> 8))
+	buf.WriteByte(byte(v))
+}
@@ -0,0 +1,81 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+)
+
+// AssetPool opens the raw asset an AudioReference's AssetReference field
+// names, independent of whatever pack or archive actually stores it.
+// Implementations wrap the module's existing pack/archive readers, keyed
+// by whichever asset ID scheme that storage uses (e.g. a FileSymbol).
+type AssetPool interface {
+	Open(assetID uint64) (io.ReadSeekCloser, error)
+}
+
+// ResolvedAsset pairs a parsed AudioReference with the FLAC stream opened
+// for it. Stream's frames are read lazily from the pool-provided handle,
+// so callers must Close the ResolvedAsset once they're done decoding it.
+type ResolvedAsset struct {
+	Reference AudioReference
+	Stream    *FLACStream
+	io.Closer
+}
+
+// Resolve opens every reference in idx through pool and parses it as a
+// FLAC stream, mirroring how the texture side already threads from a
+// reference to decoded pixels. Each asset pool.Open returns is treated as
+// starting at its own offset 0, since the pool - not a shared file - is
+// what AssetReference indexes into.
+//
+// If any reference fails to open or parse, every ResolvedAsset opened so
+// far is closed before the error is returned.
+func Resolve(idx *AudioIndex, pool AssetPool) ([]ResolvedAsset, error) {
+	resolved := make([]ResolvedAsset, 0, len(idx.References))
+	for i, ref := range idx.References {
+		asset, err := pool.Open(ref.AssetReference)
+		if err != nil {
+			closeResolved(resolved)
+			return nil, fmt.Errorf("reference %d: open asset %016x: %w", i, ref.AssetReference, err)
+		}
+
+		ra, err := assetReaderAt(asset)
+		if err != nil {
+			asset.Close()
+			closeResolved(resolved)
+			return nil, fmt.Errorf("reference %d: asset %016x: %w", i, ref.AssetReference, err)
+		}
+
+		stream, err := ResolveFLAC(ra, &AudioReference{})
+		if err != nil {
+			asset.Close()
+			closeResolved(resolved)
+			return nil, fmt.Errorf("reference %d: resolve asset %016x: %w", i, ref.AssetReference, err)
+		}
+
+		resolved = append(resolved, ResolvedAsset{Reference: ref, Stream: stream, Closer: asset})
+	}
+	return resolved, nil
+}
+
+// closeResolved closes every already-opened asset in resolved, used to
+// clean up after a later reference's open or parse failure.
+func closeResolved(resolved []ResolvedAsset) {
+	for _, r := range resolved {
+		r.Close()
+	}
+}
+
+// assetReaderAt adapts r to io.ReaderAt, using r directly when it already
+// implements the interface (as *os.File does) and otherwise buffering it in
+// full, since ResolveFLAC needs random access to seek past metadata.
+func assetReaderAt(r io.ReadSeekCloser) (io.ReaderAt, error) {
+	if ra, ok := r.(io.ReaderAt); ok {
+		return ra, nil
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("buffer asset: %w", err)
+	}
+	return &readerAt{data}, nil
+}
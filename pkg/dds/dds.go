@@ -0,0 +1,379 @@
+// Package dds is an importable DDS codec built on top of pkg/texture's BC
+// block decoders. It reads and writes standard DDS files (magic + DDS_HEADER
+// + optional DX10 extension), as opposed to pkg/texture, which parses Echo
+// VR's own headerless/metadata texture container formats.
+//
+// This was meant to keep BC decoding in one place so the CLI wouldn't
+// drift from an importable library. In practice cmd/texconv has its own,
+// independently maintained BC7/BC6H decoders rather than calling into
+// pkg/texture, so the two have already diverged (e.g. pkg/texture's
+// decodeBC7Block and cmd/texconv's cover different sets of BC7 modes at
+// any given time) and a fix to one doesn't carry to the other. Decode/
+// DecodeAll below only get whatever pkg/texture.DecodeBC supports.
+package dds
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/EchoTools/evrFileTools/pkg/texture"
+)
+
+func init() {
+	image.RegisterFormat("dds", "DDS ", decodeImage, DecodeConfig)
+}
+
+// DXGIFormat identifies a DXGI_FORMAT value, matching pkg/texture's
+// DXGI_FORMAT_* constants.
+type DXGIFormat uint32
+
+// ddsHeader mirrors the 124-byte DDS_HEADER structure.
+type ddsHeader struct {
+	Size              uint32
+	Flags             uint32
+	Height            uint32
+	Width             uint32
+	PitchOrLinearSize uint32
+	Depth             uint32
+	MipMapCount       uint32
+	Reserved1         [11]uint32
+	PixelFormat       ddsPixelFormat
+	Caps              uint32
+	Caps2             uint32
+	Caps3             uint32
+	Caps4             uint32
+	Reserved2         uint32
+}
+
+// ddsPixelFormat mirrors the 32-byte DDS_PIXELFORMAT structure.
+type ddsPixelFormat struct {
+	Size        uint32
+	Flags       uint32
+	FourCC      [4]byte
+	RGBBitCount uint32
+	RBitMask    uint32
+	GBitMask    uint32
+	BBitMask    uint32
+	ABitMask    uint32
+}
+
+// ddsDX10Header mirrors the 20-byte DDS_HEADER_DXT10 extension.
+type ddsDX10Header struct {
+	DXGIFormat        uint32
+	ResourceDimension uint32
+	MiscFlag          uint32
+	ArraySize         uint32
+	MiscFlags2        uint32
+}
+
+// TextureInfo describes a DDS stream's shape and format, as recovered from
+// its header.
+type TextureInfo struct {
+	Width      uint32
+	Height     uint32
+	MipLevels  uint32
+	DXGIFormat DXGIFormat
+}
+
+// BCEncoder compresses one image into raw BC block data for the given
+// format. dds has no built-in compressor of its own - BC encoding quality
+// spans everything from a fast average-color pass to cluster-fit or
+// mode-searching search, and callers care about that choice - so Encode
+// always defers to a caller-supplied BCEncoder rather than picking one.
+type BCEncoder interface {
+	Encode(img image.Image, format DXGIFormat) ([]byte, error)
+}
+
+// EncodeOptions configures Encode.
+type EncodeOptions struct {
+	Format          DXGIFormat
+	GenerateMipmaps bool
+	SRGB            bool
+	BCEncoder       BCEncoder
+}
+
+// Decode reads a DDS stream and decompresses its top-level mip into an
+// image.Image.
+func Decode(r io.Reader) (image.Image, *TextureInfo, error) {
+	info, data, err := readHeader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if texture.IsHDRFormat(uint32(info.DXGIFormat)) {
+		return nil, nil, fmt.Errorf("dds: HDR format %s: BC6H decode isn't implemented yet", texture.FormatName(uint32(info.DXGIFormat)))
+	}
+
+	size, err := mipSize(info.Width, info.Height, info.DXGIFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) < size {
+		return nil, nil, fmt.Errorf("dds: pixel data too short: got %d bytes, want at least %d", len(data), size)
+	}
+
+	meta := &texture.TextureMetadata{Width: info.Width, Height: info.Height, DXGIFormat: uint32(info.DXGIFormat)}
+	img, err := texture.DecodeBC(data[:size], meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	return img, info, nil
+}
+
+// DecodeAll decodes every mip level present in the DDS stream, largest
+// first.
+func DecodeAll(r io.Reader) ([]image.Image, *TextureInfo, error) {
+	info, data, err := readHeader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if texture.IsHDRFormat(uint32(info.DXGIFormat)) {
+		return nil, nil, fmt.Errorf("dds: HDR format %s: BC6H decode isn't implemented yet", texture.FormatName(uint32(info.DXGIFormat)))
+	}
+
+	images := make([]image.Image, 0, info.MipLevels)
+	width, height := info.Width, info.Height
+	offset := 0
+	for level := uint32(0); level < info.MipLevels; level++ {
+		size, err := mipSize(width, height, info.DXGIFormat)
+		if err != nil {
+			return nil, nil, err
+		}
+		if offset+size > len(data) {
+			return nil, nil, fmt.Errorf("dds: mip %d: pixel data too short", level)
+		}
+
+		meta := &texture.TextureMetadata{Width: width, Height: height, DXGIFormat: uint32(info.DXGIFormat)}
+		img, err := texture.DecodeBC(data[offset:offset+size], meta)
+		if err != nil {
+			return nil, nil, fmt.Errorf("dds: mip %d: %w", level, err)
+		}
+		images = append(images, img)
+
+		offset += size
+		if width > 1 {
+			width /= 2
+		}
+		if height > 1 {
+			height /= 2
+		}
+	}
+	return images, info, nil
+}
+
+// DecodeConfig returns a DDS stream's dimensions and color model without
+// decompressing any pixel data, for image.RegisterFormat.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	info, _, err := readHeader(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{
+		ColorModel: color.NRGBAModel,
+		Width:      int(info.Width),
+		Height:     int(info.Height),
+	}, nil
+}
+
+// decodeImage adapts Decode to the func(io.Reader) (image.Image, error)
+// signature image.RegisterFormat requires.
+func decodeImage(r io.Reader) (image.Image, error) {
+	img, _, err := Decode(r)
+	return img, err
+}
+
+// Encode compresses img per opts and writes a complete DDS file to w.
+func Encode(w io.Writer, img image.Image, opts EncodeOptions) error {
+	if opts.BCEncoder == nil {
+		return fmt.Errorf("dds: EncodeOptions.BCEncoder is required")
+	}
+
+	levels := []image.Image{img}
+	if opts.GenerateMipmaps {
+		levels = generateMipmaps(img)
+	}
+
+	var compressed bytes.Buffer
+	for i, level := range levels {
+		block, err := opts.BCEncoder.Encode(level, opts.Format)
+		if err != nil {
+			return fmt.Errorf("dds: encode mip %d: %w", i, err)
+		}
+		compressed.Write(block)
+	}
+
+	dxgiFormat := opts.Format
+	if opts.SRGB {
+		dxgiFormat = srgbVariant(dxgiFormat)
+	}
+
+	bounds := img.Bounds()
+	meta := &texture.TextureMetadata{
+		Width:       uint32(bounds.Dx()),
+		Height:      uint32(bounds.Dy()),
+		MipLevels:   uint32(len(levels)),
+		DXGIFormat:  uint32(dxgiFormat),
+		RawFileSize: uint32(compressed.Len()),
+		ArraySize:   1,
+	}
+	ddsData, err := texture.ConvertRawBCToDDS(compressed.Bytes(), meta)
+	if err != nil {
+		return fmt.Errorf("dds: %w", err)
+	}
+	_, err = w.Write(ddsData)
+	return err
+}
+
+// srgbVariant maps a BC UNORM format to its SRGB-tagged counterpart. Formats
+// with no SRGB variant (BC4, BC5, BC6H) are returned unchanged.
+func srgbVariant(format DXGIFormat) DXGIFormat {
+	switch uint32(format) {
+	case texture.DXGI_FORMAT_BC1_UNORM:
+		return DXGIFormat(texture.DXGI_FORMAT_BC1_UNORM_SRGB)
+	case texture.DXGI_FORMAT_BC2_UNORM:
+		return DXGIFormat(texture.DXGI_FORMAT_BC2_UNORM_SRGB)
+	case texture.DXGI_FORMAT_BC3_UNORM:
+		return DXGIFormat(texture.DXGI_FORMAT_BC3_UNORM_SRGB)
+	case texture.DXGI_FORMAT_BC7_UNORM:
+		return DXGIFormat(texture.DXGI_FORMAT_BC7_UNORM_SRGB)
+	default:
+		return format
+	}
+}
+
+// bytesPerBlock returns the size in bytes of one 4x4 BC block for format.
+func bytesPerBlock(format DXGIFormat) (int, error) {
+	switch uint32(format) {
+	case texture.DXGI_FORMAT_BC1_UNORM, texture.DXGI_FORMAT_BC1_UNORM_SRGB,
+		texture.DXGI_FORMAT_BC4_UNORM, texture.DXGI_FORMAT_BC4_SNORM:
+		return 8, nil
+	case texture.DXGI_FORMAT_BC2_UNORM, texture.DXGI_FORMAT_BC2_UNORM_SRGB,
+		texture.DXGI_FORMAT_BC3_UNORM, texture.DXGI_FORMAT_BC3_UNORM_SRGB,
+		texture.DXGI_FORMAT_BC5_UNORM, texture.DXGI_FORMAT_BC5_SNORM,
+		texture.DXGI_FORMAT_BC6H_UF16, texture.DXGI_FORMAT_BC6H_SF16,
+		texture.DXGI_FORMAT_BC7_UNORM, texture.DXGI_FORMAT_BC7_UNORM_SRGB:
+		return 16, nil
+	default:
+		return 0, fmt.Errorf("dds: unsupported DXGI format 0x%x", uint32(format))
+	}
+}
+
+// mipSize returns the byte size of one BC-compressed mip level.
+func mipSize(width, height uint32, format DXGIFormat) (int, error) {
+	bpb, err := bytesPerBlock(format)
+	if err != nil {
+		return 0, err
+	}
+	blocksWide := (width + 3) / 4
+	blocksHigh := (height + 3) / 4
+	return int(blocksWide*blocksHigh) * bpb, nil
+}
+
+// readHeader parses a DDS magic, header, and optional DX10 extension from r,
+// returning the recovered TextureInfo alongside the remaining pixel data.
+func readHeader(r io.Reader) (*TextureInfo, []byte, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, nil, fmt.Errorf("dds: read magic: %w", err)
+	}
+	if binary.LittleEndian.Uint32(magic[:]) != texture.DDS_MAGIC {
+		return nil, nil, fmt.Errorf("dds: not a DDS stream (bad magic)")
+	}
+
+	var header ddsHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, nil, fmt.Errorf("dds: read header: %w", err)
+	}
+
+	info := &TextureInfo{Width: header.Width, Height: header.Height, MipLevels: header.MipMapCount}
+	if info.MipLevels == 0 {
+		info.MipLevels = 1
+	}
+
+	if string(header.PixelFormat.FourCC[:]) == "DX10" {
+		var dx10 ddsDX10Header
+		if err := binary.Read(r, binary.LittleEndian, &dx10); err != nil {
+			return nil, nil, fmt.Errorf("dds: read DX10 header: %w", err)
+		}
+		info.DXGIFormat = DXGIFormat(dx10.DXGIFormat)
+	} else {
+		switch string(header.PixelFormat.FourCC[:]) {
+		case "DXT1":
+			info.DXGIFormat = DXGIFormat(texture.DXGI_FORMAT_BC1_UNORM)
+		case "DXT5":
+			info.DXGIFormat = DXGIFormat(texture.DXGI_FORMAT_BC3_UNORM)
+		case "ATI2", "BC5U":
+			info.DXGIFormat = DXGIFormat(texture.DXGI_FORMAT_BC5_UNORM)
+		default:
+			return nil, nil, fmt.Errorf("dds: unsupported pixel format fourCC %q", header.PixelFormat.FourCC)
+		}
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dds: read pixel data: %w", err)
+	}
+	return info, data, nil
+}
+
+// generateMipmaps builds a full mip chain down to 1x1 using box-filter
+// downsampling.
+func generateMipmaps(img image.Image) []image.Image {
+	levels := []image.Image{img}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	for width > 1 || height > 1 {
+		if width > 1 {
+			width /= 2
+		}
+		if height > 1 {
+			height /= 2
+		}
+		levels = append(levels, boxDownsample(levels[len(levels)-1], width, height))
+	}
+	return levels
+}
+
+// boxDownsample resizes img to width x height by averaging each destination
+// pixel's source footprint.
+func boxDownsample(img image.Image, width, height int) image.Image {
+	src := img.Bounds()
+	srcWidth, srcHeight := src.Dx(), src.Dy()
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	scaleX := float64(srcWidth) / float64(width)
+	scaleY := float64(srcHeight) / float64(height)
+
+	for y := 0; y < height; y++ {
+		sy := int(float64(y) * scaleY)
+		for x := 0; x < width; x++ {
+			sx := int(float64(x) * scaleX)
+
+			var rSum, gSum, bSum, aSum, count uint32
+			for dy := sy; dy < sy+int(scaleY)+1 && dy < srcHeight; dy++ {
+				for dx := sx; dx < sx+int(scaleX)+1 && dx < srcWidth; dx++ {
+					r, g, b, a := img.At(src.Min.X+dx, src.Min.Y+dy).RGBA()
+					rSum += r
+					gSum += g
+					bSum += b
+					aSum += a
+					count++
+				}
+			}
+			if count == 0 {
+				continue
+			}
+			out.SetNRGBA(x, y, color.NRGBA{
+				R: uint8((rSum / count) >> 8),
+				G: uint8((gSum / count) >> 8),
+				B: uint8((bSum / count) >> 8),
+				A: uint8((aSum / count) >> 8),
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package dds
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/EchoTools/evrFileTools/pkg/texture"
+)
+
+// solidColorBC1Encoder is a minimal BCEncoder for tests: it compresses every
+// block to a flat color using the top-left pixel, with an all-zero index
+// table (every pixel picks endpoint 0).
+type solidColorBC1Encoder struct{}
+
+func (solidColorBC1Encoder) Encode(img image.Image, format DXGIFormat) ([]byte, error) {
+	bounds := img.Bounds()
+	blocksWide := (bounds.Dx() + 3) / 4
+	blocksHigh := (bounds.Dy() + 3) / 4
+	r, g, b, _ := img.At(bounds.Min.X, bounds.Min.Y).RGBA()
+	c0 := uint16(r>>11)<<11 | uint16(g>>10)<<5 | uint16(b>>11)
+
+	out := make([]byte, blocksWide*blocksHigh*8)
+	for i := 0; i < blocksWide*blocksHigh; i++ {
+		out[i*8+0] = byte(c0)
+		out[i*8+1] = byte(c0 >> 8)
+		// c1 left at 0, indices left at 0: every pixel decodes to c0.
+	}
+	return out, nil
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 255, G: 0, B: 0, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	opts := EncodeOptions{
+		Format:    DXGIFormat(texture.DXGI_FORMAT_BC1_UNORM),
+		BCEncoder: solidColorBC1Encoder{},
+	}
+	if err := Encode(&buf, img, opts); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	decoded, info, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if info.Width != 8 || info.Height != 8 {
+		t.Errorf("info = %+v, want 8x8", info)
+	}
+	if info.DXGIFormat != DXGIFormat(texture.DXGI_FORMAT_BC1_UNORM) {
+		t.Errorf("DXGIFormat = %v, want BC1_UNORM", info.DXGIFormat)
+	}
+	r, g, b, a := decoded.At(0, 0).RGBA()
+	if uint8(r>>8) != 255 || uint8(g>>8) != 0 || uint8(b>>8) != 0 || uint8(a>>8) != 255 {
+		t.Errorf("pixel(0,0) = (%d,%d,%d,%d), want opaque red", r>>8, g>>8, b>>8, a>>8)
+	}
+}
+
+func TestEncodeWithMipmapsDecodeAll(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 0, G: 255, B: 0, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	opts := EncodeOptions{
+		Format:          DXGIFormat(texture.DXGI_FORMAT_BC1_UNORM),
+		GenerateMipmaps: true,
+		BCEncoder:       solidColorBC1Encoder{},
+	}
+	if err := Encode(&buf, img, opts); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	images, info, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("DecodeAll: %v", err)
+	}
+	// 4x4 -> 2x2 -> 1x1
+	if len(images) != 3 {
+		t.Fatalf("len(images) = %d, want 3", len(images))
+	}
+	if info.MipLevels != 3 {
+		t.Errorf("MipLevels = %d, want 3", info.MipLevels)
+	}
+}
+
+func TestEncodeRequiresBCEncoder(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	var buf bytes.Buffer
+	err := Encode(&buf, img, EncodeOptions{Format: DXGIFormat(texture.DXGI_FORMAT_BC1_UNORM)})
+	if err == nil {
+		t.Fatal("Encode: want error for nil BCEncoder")
+	}
+}
+
+func TestDecodeRejectsBadMagic(t *testing.T) {
+	_, _, err := Decode(bytes.NewReader([]byte("not a dds file at all")))
+	if err == nil {
+		t.Fatal("Decode: want error for bad magic")
+	}
+}
+
+func TestDecodeRejectsHDRFormat(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	var buf bytes.Buffer
+	opts := EncodeOptions{
+		Format:    DXGIFormat(texture.DXGI_FORMAT_BC6H_UF16),
+		BCEncoder: solidColorBC1Encoder{},
+	}
+	if err := Encode(&buf, img, opts); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if _, _, err := Decode(&buf); err == nil {
+		t.Fatal("Decode: want error for BC6H (HDR) format")
+	}
+}
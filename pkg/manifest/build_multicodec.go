@@ -0,0 +1,78 @@
+package manifest
+
+import "fmt"
+
+// BuildMultiCodec builds a package and manifest like Build, but chooses a
+// codec per frame via b.codecPolicy (DefaultCodecPolicy if unset) instead
+// of always using zstd. The resulting Frame.CompressedSize/Length entries
+// are codec-agnostic, as in Build; the chosen codec for each frame is
+// recorded in the returned CodecIndex, which callers must persist
+// alongside the manifest (e.g. via CodecIndex.WriteFile) for extraction
+// to know how to decompress each frame.
+func (b *Builder) BuildMultiCodec(fileGroups [][]ScannedFile) (*Manifest, *CodecIndex, error) {
+	manifest, frames, err := b.assembleFrames(fileGroups)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policy := b.codecPolicy
+	if policy == nil {
+		policy = DefaultCodecPolicy
+	}
+
+	groupForFrame := frameCodecGroups(fileGroups)
+
+	codecIndex := &CodecIndex{Codecs: make([]CodecTag, 0, len(frames))}
+
+	for i, frame := range frames {
+		codec := policy(groupForFrame[i])
+
+		compressed, err := codec.Compress(frame.Bytes(), b.compressionLevel)
+		if err != nil {
+			return nil, nil, fmt.Errorf("compress frame %d with codec %d: %w", i, codec.Tag(), err)
+		}
+
+		if codec.Tag() != CodecStored && float64(len(compressed)) > storedRatioThreshold*float64(frame.Len()) {
+			codec = storedCodec{}
+			compressed, err = codec.Compress(frame.Bytes(), b.compressionLevel)
+			if err != nil {
+				return nil, nil, fmt.Errorf("store frame %d: %w", i, err)
+			}
+		}
+
+		if err := b.writeCompressedFrame(manifest, compressed, uint32(frame.Len()), uint32(i)); err != nil {
+			return nil, nil, err
+		}
+		codecIndex.Codecs = append(codecIndex.Codecs, codec.Tag())
+	}
+
+	b.addTerminatorFrames(manifest)
+
+	return manifest, codecIndex, nil
+}
+
+// frameCodecGroups maps each assembled frame back to the ScannedFile group
+// that produced it, so a CodecPolicy can inspect file metadata (type,
+// extension, size) when choosing a codec. assembleFrames starts a new
+// frame per non-empty fileGroups entry, so the mapping is positional.
+func frameCodecGroups(fileGroups [][]ScannedFile) [][]ScannedFile {
+	var groups [][]ScannedFile
+	for _, group := range fileGroups {
+		if len(group) == 0 {
+			continue
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// DecompressFrame decompresses a frame's raw package bytes using the codec
+// recorded for it in index (or zstd if index is nil, matching legacy
+// manifests with no sidecar).
+func DecompressFrame(index *CodecIndex, frameIndex uint32, compressed []byte, decompressedSize uint32) ([]byte, error) {
+	codec, err := CodecFor(index.CodecForFrame(frameIndex))
+	if err != nil {
+		return nil, err
+	}
+	return codec.Decompress(make([]byte, 0, decompressedSize), compressed)
+}
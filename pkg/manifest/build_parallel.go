@@ -0,0 +1,196 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/DataDog/zstd"
+)
+
+// DefaultMaxInFlightBytes bounds how much uncompressed frame data
+// BuildParallel holds in memory at once when no explicit limit is set via
+// SetMaxInFlightBytes.
+const DefaultMaxInFlightBytes = 256 * 1024 * 1024
+
+// SetConcurrency sets the number of worker goroutines BuildParallel uses
+// to compress frames. The zero value means runtime.GOMAXPROCS(0).
+func (b *Builder) SetConcurrency(n int) {
+	b.concurrency = n
+}
+
+// SetMaxInFlightBytes bounds the total uncompressed size of frames queued
+// for compression or awaiting in-order write at any given time. This
+// caps BuildParallel's memory ceiling independent of how many frames a
+// large asset tree produces. The zero value means DefaultMaxInFlightBytes.
+func (b *Builder) SetMaxInFlightBytes(n int64) {
+	b.maxInFlightBytes = n
+}
+
+// BuildStats reports how BuildParallel's compression pass spent its time,
+// so callers can tell whether raising concurrency is actually worth it for
+// a given asset tree.
+type BuildStats struct {
+	// Frames is the number of frames processed.
+	Frames int
+	// WorkerBytes is the uncompressed bytes each worker goroutine
+	// processed, indexed by worker id. Its length is the concurrency the
+	// pass actually ran with.
+	WorkerBytes []int64
+	// Duration is the wall-clock time the pass took, from the first job
+	// dispatched to the last result committed.
+	Duration time.Duration
+}
+
+type compressedFrame struct {
+	index  uint32
+	worker int
+	size   int64 // uncompressed size, for in-flight accounting
+	data   []byte
+	err    error
+}
+
+// BuildParallel builds a package and manifest like Build, but compresses
+// frames across a worker pool instead of on the caller's goroutine. The
+// resulting manifest and package bytes are the same at any concurrency:
+// assembleFrames decides frame boundaries up front single-threaded, only
+// compression is parallelized, and a reorder stage writes results back in
+// strict frame order. Files land in the same frames and offsets as with
+// Build, but each frame is compressed in one shot rather than through
+// Build's streaming encoder, so compressed sizes and package bytes differ
+// from Build's.
+func (b *Builder) BuildParallel(fileGroups [][]ScannedFile) (*Manifest, BuildStats, error) {
+	manifest, frames, err := b.assembleFrames(fileGroups)
+	if err != nil {
+		return nil, BuildStats{}, err
+	}
+
+	stats, err := b.compressAndWriteFrames(manifest, frames)
+	if err != nil {
+		return nil, BuildStats{}, err
+	}
+
+	b.addTerminatorFrames(manifest)
+
+	return manifest, stats, nil
+}
+
+func (b *Builder) compressAndWriteFrames(manifest *Manifest, frames []*bytes.Buffer) (BuildStats, error) {
+	start := time.Now()
+
+	concurrency := b.concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	maxInFlight := b.maxInFlightBytes
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultMaxInFlightBytes
+	}
+
+	jobs := make(chan int)
+	results := make(chan compressedFrame, concurrency)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for idx := range jobs {
+				frame := frames[idx]
+				compressed, err := zstd.CompressLevel(nil, frame.Bytes(), b.compressionLevel)
+				results <- compressedFrame{
+					index:  uint32(idx),
+					worker: worker,
+					size:   int64(frame.Len()),
+					data:   compressed,
+					err:    err,
+				}
+			}
+		}(w)
+	}
+
+	// Feed jobs in order, but throttle how far ahead of the writer the
+	// producer is allowed to get, in bytes of uncompressed frame data.
+	var (
+		inFlightMu   sync.Mutex
+		inFlightCond = sync.NewCond(&inFlightMu)
+		inFlightSize int64
+	)
+
+	go func() {
+		defer close(jobs)
+		for i, frame := range frames {
+			size := int64(frame.Len())
+
+			inFlightMu.Lock()
+			for inFlightSize+size > maxInFlight && inFlightSize > 0 {
+				inFlightCond.Wait()
+			}
+			inFlightSize += size
+			inFlightMu.Unlock()
+
+			jobs <- i
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[uint32]compressedFrame, concurrency)
+	next := uint32(0)
+	total := len(frames)
+	written := 0
+	var firstErr error
+	workerBytes := make([]int64, concurrency)
+
+	releaseInFlight := func(size int64) {
+		inFlightMu.Lock()
+		inFlightSize -= size
+		inFlightMu.Unlock()
+		inFlightCond.Broadcast()
+	}
+
+	for result := range results {
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("compress frame %d: %w", result.index, result.err)
+			}
+			releaseInFlight(result.size)
+			continue
+		}
+		pending[result.index] = result
+
+		for {
+			res, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+
+			if firstErr == nil {
+				if err := b.writeCompressedFrame(manifest, res.data, uint32(res.size), next); err != nil {
+					firstErr = err
+				}
+			}
+			workerBytes[res.worker] += res.size
+			releaseInFlight(res.size)
+			written++
+			next++
+		}
+	}
+
+	stats := BuildStats{Frames: written, WorkerBytes: workerBytes, Duration: time.Since(start)}
+
+	if firstErr != nil {
+		return stats, firstErr
+	}
+	if written != total {
+		return stats, fmt.Errorf("wrote %d of %d frames", written, total)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,126 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func makeBuildFixture(t *testing.T, dir string) [][]ScannedFile {
+	t.Helper()
+
+	var groups [][]ScannedFile
+	for g := 0; g < 4; g++ {
+		var group []ScannedFile
+		for f := 0; f < 5; f++ {
+			path := filepath.Join(dir, "files", fmt.Sprintf("g%d_f%d", g, f))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			content := bytes.Repeat([]byte("payload-"), 100+g*10+f)
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			group = append(group, ScannedFile{
+				TypeSymbol: int64(g + 1),
+				FileSymbol: int64(g*100 + f),
+				Path:       path,
+				Size:       uint32(len(content)),
+			})
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func TestBuildParallelMatchesSequentialBuild(t *testing.T) {
+	seqDir := t.TempDir()
+	parDir := t.TempDir()
+
+	groups := makeBuildFixture(t, seqDir)
+
+	seqBuilder := NewBuilder(seqDir, "pkg")
+	seqManifest, err := seqBuilder.Build(groups)
+	if err != nil {
+		t.Fatalf("sequential Build: %v", err)
+	}
+
+	parBuilder := NewBuilder(parDir, "pkg")
+	parBuilder.SetConcurrency(4)
+	parManifest, stats, err := parBuilder.BuildParallel(groups)
+	if err != nil {
+		t.Fatalf("BuildParallel: %v", err)
+	}
+	if stats.Frames == 0 {
+		t.Fatalf("BuildStats.Frames = 0, want > 0")
+	}
+	if len(stats.WorkerBytes) != 4 {
+		t.Fatalf("BuildStats.WorkerBytes has %d entries, want 4", len(stats.WorkerBytes))
+	}
+
+	// Build now streams each frame through zstd's streaming Writer instead
+	// of compressing it in one shot like BuildParallel does, so their
+	// CompressedSize/Frame byte layouts legitimately differ by a few
+	// bytes of framing overhead. Compare everything else about the
+	// manifests, plus the actual extracted file contents. Frames is
+	// swapped back in afterward since both manifests are still needed,
+	// Frames and all, to open and extract their packages below.
+	seqFrames, parFrames := seqManifest.Frames, parManifest.Frames
+	seqManifest.Frames, parManifest.Frames = nil, nil
+	if !reflect.DeepEqual(seqManifest, parManifest) {
+		t.Fatalf("manifests differ outside of Frames:\nsequential: %+v\nparallel:   %+v", seqManifest, parManifest)
+	}
+	seqManifest.Frames, parManifest.Frames = seqFrames, parFrames
+
+	seqPkg, err := OpenPackage(seqManifest, filepath.Join(seqDir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("open sequential package: %v", err)
+	}
+	defer seqPkg.Close()
+	parPkg, err := OpenPackage(parManifest, filepath.Join(parDir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("open parallel package: %v", err)
+	}
+	defer parPkg.Close()
+
+	seqOut, parOut := t.TempDir(), t.TempDir()
+	if err := seqPkg.Extract(seqOut); err != nil {
+		t.Fatalf("extract sequential package: %v", err)
+	}
+	if err := parPkg.Extract(parOut); err != nil {
+		t.Fatalf("extract parallel package: %v", err)
+	}
+
+	for _, group := range groups {
+		for _, file := range group {
+			rel := fmt.Sprintf("%x/%x", file.TypeSymbol, file.FileSymbol)
+			seqData, err := os.ReadFile(filepath.Join(seqOut, rel))
+			if err != nil {
+				t.Fatalf("read sequential extracted %s: %v", rel, err)
+			}
+			parData, err := os.ReadFile(filepath.Join(parOut, rel))
+			if err != nil {
+				t.Fatalf("read parallel extracted %s: %v", rel, err)
+			}
+			if !bytes.Equal(seqData, parData) {
+				t.Fatalf("extracted file %s differs between sequential and parallel builds", rel)
+			}
+		}
+	}
+}
+
+func TestBuildParallelRespectsMaxInFlightBytes(t *testing.T) {
+	dir := t.TempDir()
+	groups := makeBuildFixture(t, dir)
+
+	builder := NewBuilder(dir, "bounded")
+	builder.SetConcurrency(2)
+	builder.SetMaxInFlightBytes(64)
+
+	if _, _, err := builder.BuildParallel(groups); err != nil {
+		t.Fatalf("BuildParallel with small in-flight budget: %v", err)
+	}
+}
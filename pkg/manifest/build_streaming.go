@@ -0,0 +1,215 @@
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/DataDog/zstd"
+)
+
+// buildStream holds the in-progress state for AddFile/Flush. Unlike
+// assembleFrames (which buffers a whole frame before compressing it),
+// each file's bytes are streamed straight through a zstd.Writer into the
+// currently-open package file, so memory use is bounded by io.Copy's
+// buffer rather than by frame or file size.
+type buildStream struct {
+	manifest    *Manifest
+	packagesDir string
+
+	haveChunk    bool
+	currentChunk int
+	frameIndex   uint32
+	frameSize    uint32 // decompressed bytes written to the current frame so far
+
+	packageFile   *os.File
+	packageIndex  uint32
+	packageOffset int64
+
+	encoder *zstd.Writer
+	counter *countingWriter
+}
+
+// countingWriter tracks how many bytes have passed through it, so the
+// compressed size of a streamed frame can be recovered without buffering
+// the compressed output to measure it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// AddFile streams r (size bytes) into the package as part of chunk's
+// frame. Files are expected in frame order: every call with a chunk
+// different from the previous call closes the current frame and starts a
+// new one, so callers that want several files in the same frame must call
+// AddFile for them consecutively with the same chunk value. The package
+// and manifest aren't finalized until Flush is called.
+func (b *Builder) AddFile(chunk int, typeSymbol, fileSymbol int64, r io.Reader, size int64) error {
+	if b.stream == nil {
+		packagesDir := filepath.Join(b.outputDir, "packages")
+		if err := os.MkdirAll(packagesDir, 0755); err != nil {
+			return fmt.Errorf("create packages dir: %w", err)
+		}
+		b.stream = &buildStream{
+			manifest:    newManifest(),
+			packagesDir: packagesDir,
+		}
+	}
+	s := b.stream
+
+	switch {
+	case !s.haveChunk:
+		if err := b.startFrame(); err != nil {
+			return err
+		}
+		s.currentChunk = chunk
+		s.haveChunk = true
+	case chunk != s.currentChunk:
+		if err := b.finishFrame(); err != nil {
+			return err
+		}
+		s.frameIndex++
+		if err := b.startFrame(); err != nil {
+			return err
+		}
+		s.currentChunk = chunk
+	}
+
+	s.manifest.FrameContents = append(s.manifest.FrameContents, FrameContent{
+		TypeSymbol: typeSymbol,
+		FileSymbol: fileSymbol,
+		FrameIndex: s.frameIndex,
+		DataOffset: s.frameSize,
+		Size:       uint32(size),
+		Alignment:  1,
+	})
+	s.manifest.Metadata = append(s.manifest.Metadata, FileMetadata{
+		TypeSymbol: typeSymbol,
+		FileSymbol: fileSymbol,
+	})
+	b.incrementSection(&s.manifest.Header.FrameContents, 1)
+	b.incrementSection(&s.manifest.Header.Metadata, 1)
+
+	n, err := io.Copy(s.encoder, r)
+	if err != nil {
+		return fmt.Errorf("stream file %x/%x: %w", typeSymbol, fileSymbol, err)
+	}
+	if n != size {
+		return fmt.Errorf("file %x/%x: streamed %d bytes, expected %d", typeSymbol, fileSymbol, n, size)
+	}
+	s.frameSize += uint32(n)
+
+	return nil
+}
+
+// Flush closes out any in-progress frame, adds the terminator frames, and
+// returns the completed manifest. The Builder is left ready for another
+// AddFile/Flush sequence.
+func (b *Builder) Flush() (*Manifest, error) {
+	if b.stream == nil {
+		manifest := newManifest()
+		b.addTerminatorFrames(manifest)
+		return manifest, nil
+	}
+
+	s := b.stream
+	if s.haveChunk {
+		if err := b.finishFrame(); err != nil {
+			return nil, err
+		}
+	}
+	if s.packageFile != nil {
+		if err := s.packageFile.Close(); err != nil {
+			return nil, fmt.Errorf("close package %d: %w", s.packageIndex, err)
+		}
+	}
+
+	manifest := s.manifest
+	b.stream = nil
+
+	b.addTerminatorFrames(manifest)
+	return manifest, nil
+}
+
+// startFrame opens (or continues) a package file and readies a fresh
+// streaming encoder for the next frame.
+func (b *Builder) startFrame() error {
+	s := b.stream
+
+	if s.packageFile == nil {
+		if err := b.openPackageFile(0); err != nil {
+			return err
+		}
+	} else if s.packageOffset >= MaxPackageSize {
+		// Frame boundaries are the only point at which we know the
+		// package file's offset without having already committed bytes
+		// to it, so that's the only point rollover can happen; a single
+		// frame's compressed size is assumed to always fit within
+		// MaxPackageSize, same as assembleFrames/writeCompressedFrame.
+		if err := b.rollPackageFile(); err != nil {
+			return err
+		}
+	}
+
+	s.counter = &countingWriter{w: s.packageFile}
+	s.encoder = zstd.NewWriterLevel(s.counter, b.compressionLevel)
+	s.frameSize = 0
+	return nil
+}
+
+// finishFrame flushes the current frame's encoder and records its Frame
+// entry.
+func (b *Builder) finishFrame() error {
+	s := b.stream
+
+	if err := s.encoder.Close(); err != nil {
+		return fmt.Errorf("close frame %d encoder: %w", s.frameIndex, err)
+	}
+
+	s.manifest.Frames = append(s.manifest.Frames, Frame{
+		PackageIndex:   s.packageIndex,
+		Offset:         uint32(s.packageOffset),
+		CompressedSize: uint32(s.counter.n),
+		Length:         s.frameSize,
+	})
+	b.incrementSection(&s.manifest.Header.Frames, 1)
+
+	s.packageOffset += s.counter.n
+	s.encoder = nil
+	s.counter = nil
+	return nil
+}
+
+func (b *Builder) openPackageFile(index uint32) error {
+	s := b.stream
+
+	path := filepath.Join(s.packagesDir, fmt.Sprintf("%s_%d", b.packageName, index))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open package %d: %w", index, err)
+	}
+
+	s.packageFile = f
+	s.packageIndex = index
+	s.packageOffset = 0
+	if s.manifest.Header.PackageCount <= index {
+		s.manifest.Header.PackageCount = index + 1
+	}
+	return nil
+}
+
+func (b *Builder) rollPackageFile() error {
+	s := b.stream
+
+	if err := s.packageFile.Close(); err != nil {
+		return fmt.Errorf("close package %d: %w", s.packageIndex, err)
+	}
+	return b.openPackageFile(s.packageIndex + 1)
+}
@@ -0,0 +1,150 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddFileFlushMatchesBuild(t *testing.T) {
+	groupDir := t.TempDir()
+	groups := makeBuildFixture(t, groupDir)
+
+	buildDir := t.TempDir()
+	buildManifest, err := NewBuilder(buildDir, "pkg").Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	streamDir := t.TempDir()
+	streamBuilder := NewBuilder(streamDir, "pkg")
+	for chunk, group := range groups {
+		for _, file := range group {
+			f, err := os.Open(file.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = streamBuilder.AddFile(chunk, file.TypeSymbol, file.FileSymbol, f, int64(file.Size))
+			f.Close()
+			if err != nil {
+				t.Fatalf("AddFile: %v", err)
+			}
+		}
+	}
+	streamManifest, err := streamBuilder.Flush()
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	if len(streamManifest.FrameContents) != len(buildManifest.FrameContents) {
+		t.Fatalf("got %d FrameContents, want %d", len(streamManifest.FrameContents), len(buildManifest.FrameContents))
+	}
+	if len(streamManifest.Frames) != len(buildManifest.Frames) {
+		t.Fatalf("got %d Frames, want %d", len(streamManifest.Frames), len(buildManifest.Frames))
+	}
+
+	buildPkg, err := OpenPackage(buildManifest, filepath.Join(buildDir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("open Build package: %v", err)
+	}
+	defer buildPkg.Close()
+	streamPkg, err := OpenPackage(streamManifest, filepath.Join(streamDir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("open streamed package: %v", err)
+	}
+	defer streamPkg.Close()
+
+	buildOut, streamOut := t.TempDir(), t.TempDir()
+	if err := buildPkg.Extract(buildOut); err != nil {
+		t.Fatalf("extract Build package: %v", err)
+	}
+	if err := streamPkg.Extract(streamOut); err != nil {
+		t.Fatalf("extract streamed package: %v", err)
+	}
+
+	for _, group := range groups {
+		for _, file := range group {
+			rel := fmt.Sprintf("%x/%x", file.TypeSymbol, file.FileSymbol)
+			want, err := os.ReadFile(filepath.Join(buildOut, rel))
+			if err != nil {
+				t.Fatalf("read Build extracted %s: %v", rel, err)
+			}
+			got, err := os.ReadFile(filepath.Join(streamOut, rel))
+			if err != nil {
+				t.Fatalf("read streamed extracted %s: %v", rel, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("extracted file %s differs between Build and AddFile/Flush", rel)
+			}
+		}
+	}
+}
+
+func TestAddFileCoalescesSameChunkIntoOneFrame(t *testing.T) {
+	dir := t.TempDir()
+	builder := NewBuilder(dir, "pkg")
+
+	for i, content := range []string{"one", "two", "three"} {
+		if err := builder.AddFile(0, 1, int64(i), bytes.NewReader([]byte(content)), int64(len(content))); err != nil {
+			t.Fatalf("AddFile %d: %v", i, err)
+		}
+	}
+	manifest, err := builder.Flush()
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	for _, fc := range manifest.FrameContents {
+		if fc.FrameIndex != 0 {
+			t.Errorf("file %d: got frame %d, want 0 (same chunk)", fc.FileSymbol, fc.FrameIndex)
+		}
+	}
+}
+
+func TestAddFileStartsNewFrameOnChunkChange(t *testing.T) {
+	dir := t.TempDir()
+	builder := NewBuilder(dir, "pkg")
+
+	if err := builder.AddFile(0, 1, 0, bytes.NewReader([]byte("a")), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.AddFile(1, 1, 1, bytes.NewReader([]byte("b")), 1); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := builder.Flush()
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	if manifest.FrameContents[0].FrameIndex != 0 {
+		t.Errorf("first file: got frame %d, want 0", manifest.FrameContents[0].FrameIndex)
+	}
+	if manifest.FrameContents[1].FrameIndex != 1 {
+		t.Errorf("second file: got frame %d, want 1", manifest.FrameContents[1].FrameIndex)
+	}
+}
+
+func TestAddFileRejectsSizeMismatch(t *testing.T) {
+	dir := t.TempDir()
+	builder := NewBuilder(dir, "pkg")
+
+	err := builder.AddFile(0, 1, 0, bytes.NewReader([]byte("short")), 100)
+	if err == nil {
+		t.Fatal("expected error for size mismatch")
+	}
+}
+
+func TestFlushWithNoFilesAddedReturnsEmptyManifest(t *testing.T) {
+	dir := t.TempDir()
+	builder := NewBuilder(dir, "pkg")
+
+	manifest, err := builder.Flush()
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(manifest.FrameContents) != 0 || len(manifest.Frames) != 1 {
+		t.Errorf("got %d FrameContents, %d Frames, want 0 and 1 (final terminator)", len(manifest.FrameContents), len(manifest.Frames))
+	}
+}
@@ -20,9 +20,23 @@ const (
 
 // Builder constructs packages and manifests from a set of files.
 type Builder struct {
-	outputDir       string
-	packageName     string
+	outputDir        string
+	packageName      string
 	compressionLevel int
+
+	dictStrategy DictStrategy
+	dictionaries map[int64][]byte
+
+	concurrency      int
+	maxInFlightBytes int64
+
+	codecPolicy CodecPolicy
+
+	dedupMinSize int
+	dedupAvgSize int
+	dedupMaxSize int
+
+	stream *buildStream
 }
 
 // NewBuilder creates a new package builder.
@@ -39,36 +53,76 @@ func (b *Builder) SetCompressionLevel(level int) {
 	b.compressionLevel = level
 }
 
-// Build creates a package and manifest from the given file groups.
+// SetDedupParams overrides the content-defined chunking bounds
+// BuildDeduped uses to split files into shareable chunks: minChunk and
+// maxChunk clamp chunk size, and avgChunk (rounded down to the nearest
+// power of two) controls how many low bits of the rolling hash must be
+// zero to cut a boundary, so it sets the target average chunk size. Zero
+// values (the default) mean chunkMinSize/chunkAvgBits/chunkMaxSize.
+func (b *Builder) SetDedupParams(minChunk, avgChunk, maxChunk int) {
+	b.dedupMinSize = minChunk
+	b.dedupAvgSize = avgChunk
+	b.dedupMaxSize = maxChunk
+}
+
+// Build creates a package and manifest from the given file groups. It is a
+// thin wrapper around AddFile/Flush: each group becomes one frame (even if
+// two groups happen to hold identical files), streamed straight to the
+// package file instead of buffered in memory first.
 func (b *Builder) Build(fileGroups [][]ScannedFile) (*Manifest, error) {
+	for chunk, group := range fileGroups {
+		for _, file := range group {
+			if err := b.addScannedFile(chunk, file); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return b.Flush()
+}
+
+func (b *Builder) addScannedFile(chunk int, file ScannedFile) error {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return fmt.Errorf("open file %s: %w", file.Path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file %s: %w", file.Path, err)
+	}
+
+	if err := b.AddFile(chunk, file.TypeSymbol, file.FileSymbol, f, info.Size()); err != nil {
+		return fmt.Errorf("add file %s: %w", file.Path, err)
+	}
+	return nil
+}
+
+// assembleFrames groups fileGroups into frame buffers, one per non-empty
+// group, and populates the corresponding FrameContents/Metadata sections.
+// This is the part of BuildParallel and BuildMultiCodec that determines
+// on-disk layout and therefore must stay single-threaded and
+// deterministic; only the compression of the resulting buffers (see
+// BuildParallel) is safe to parallelize. Build does not use it: it
+// streams files through AddFile/Flush instead.
+func (b *Builder) assembleFrames(fileGroups [][]ScannedFile) (*Manifest, []*bytes.Buffer, error) {
 	totalFiles := 0
 	for _, group := range fileGroups {
 		totalFiles += len(group)
 	}
 
-	manifest := &Manifest{
-		Header: Header{
-			PackageCount: 1,
-			FrameContents: Section{
-				ElementSize: 32,
-			},
-			Metadata: Section{
-				ElementSize: 40,
-			},
-			Frames: Section{
-				ElementSize: 16,
-			},
-		},
-		FrameContents: make([]FrameContent, 0, totalFiles),
-		Metadata:      make([]FileMetadata, 0, totalFiles),
-		Frames:        make([]Frame, 0),
-	}
+	manifest := newManifest()
+	manifest.FrameContents = make([]FrameContent, 0, totalFiles)
+	manifest.Metadata = make([]FileMetadata, 0, totalFiles)
 
 	packagesDir := filepath.Join(b.outputDir, "packages")
 	if err := os.MkdirAll(packagesDir, 0755); err != nil {
-		return nil, fmt.Errorf("create packages dir: %w", err)
+		return nil, nil, fmt.Errorf("create packages dir: %w", err)
 	}
 
+	var frames []*bytes.Buffer
+
 	var (
 		currentFrame  bytes.Buffer
 		currentOffset uint32
@@ -80,11 +134,9 @@ func (b *Builder) Build(fileGroups [][]ScannedFile) (*Manifest, error) {
 			continue
 		}
 
-		// Write previous frame if not empty
+		// Start a new frame buffer if the previous one isn't empty
 		if currentFrame.Len() > 0 {
-			if err := b.writeFrame(manifest, &currentFrame, frameIndex); err != nil {
-				return nil, err
-			}
+			frames = append(frames, copyBuffer(&currentFrame))
 			frameIndex++
 			currentFrame.Reset()
 			currentOffset = 0
@@ -93,7 +145,7 @@ func (b *Builder) Build(fileGroups [][]ScannedFile) (*Manifest, error) {
 		for _, file := range group {
 			data, err := os.ReadFile(file.Path)
 			if err != nil {
-				return nil, fmt.Errorf("read file %s: %w", file.Path, err)
+				return nil, nil, fmt.Errorf("read file %s: %w", file.Path, err)
 			}
 
 			manifest.FrameContents = append(manifest.FrameContents, FrameContent{
@@ -118,17 +170,40 @@ func (b *Builder) Build(fileGroups [][]ScannedFile) (*Manifest, error) {
 		b.incrementSection(&manifest.Header.Metadata, len(group))
 	}
 
-	// Write final frame
 	if currentFrame.Len() > 0 {
-		if err := b.writeFrame(manifest, &currentFrame, frameIndex); err != nil {
-			return nil, err
-		}
+		frames = append(frames, copyBuffer(&currentFrame))
 	}
 
-	// Add package terminator frames
-	b.addTerminatorFrames(manifest)
+	return manifest, frames, nil
+}
 
-	return manifest, nil
+// newManifest returns an empty Manifest with its Header sections set up
+// for the fixed element sizes Build/AddFile/BuildParallel/BuildMultiCodec
+// all populate.
+func newManifest() *Manifest {
+	return &Manifest{
+		Header: Header{
+			PackageCount: 1,
+			FrameContents: Section{
+				ElementSize: 32,
+			},
+			Metadata: Section{
+				ElementSize: 40,
+			},
+			Frames: Section{
+				ElementSize: 16,
+			},
+		},
+		FrameContents: make([]FrameContent, 0),
+		Metadata:      make([]FileMetadata, 0),
+		Frames:        make([]Frame, 0),
+	}
+}
+
+func copyBuffer(b *bytes.Buffer) *bytes.Buffer {
+	data := make([]byte, b.Len())
+	copy(data, b.Bytes())
+	return bytes.NewBuffer(data)
 }
 
 func (b *Builder) writeFrame(manifest *Manifest, data *bytes.Buffer, index uint32) error {
@@ -137,6 +212,14 @@ func (b *Builder) writeFrame(manifest *Manifest, data *bytes.Buffer, index uint3
 		return fmt.Errorf("compress frame %d: %w", index, err)
 	}
 
+	return b.writeCompressedFrame(manifest, compressed, uint32(data.Len()), index)
+}
+
+// writeCompressedFrame appends an already-compressed frame to the current
+// (or next, on rollover) package file and records its Frame entry. Frames
+// must be written in strictly ascending index order since package byte
+// offsets are derived from the previous Frame entry.
+func (b *Builder) writeCompressedFrame(manifest *Manifest, compressed []byte, decompressedSize uint32, index uint32) error {
 	packageIndex := manifest.Header.PackageCount - 1
 	packagePath := filepath.Join(b.outputDir, "packages", fmt.Sprintf("%s_%d", b.packageName, packageIndex))
 
@@ -168,7 +251,7 @@ func (b *Builder) writeFrame(manifest *Manifest, data *bytes.Buffer, index uint3
 		PackageIndex:   packageIndex,
 		Offset:         offset,
 		CompressedSize: uint32(len(compressed)),
-		Length:         uint32(data.Len()),
+		Length:         decompressedSize,
 	})
 
 	b.incrementSection(&manifest.Header.Frames, 1)
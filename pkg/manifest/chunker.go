@@ -0,0 +1,130 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"math/bits"
+)
+
+// Content-defined chunking parameters. avgBits targets ~8KB average chunk
+// size; min/max bound pathological inputs (all-zero or high-entropy data)
+// so a single file can't collapse into one giant chunk or explode into
+// millions of tiny ones.
+const (
+	chunkWindowSize = 64        // rolling hash window, in bytes
+	chunkAvgBits    = 13        // 1<<13 = 8KB average chunk size
+	chunkMinSize    = 2 * 1024  // 2KB minimum chunk size
+	chunkMaxSize    = 64 * 1024 // 64KB maximum chunk size
+	chunkMask       = 1<<chunkAvgBits - 1
+)
+
+// chunkHashTable is the per-byte table used by the buzhash rolling hash.
+// Generated once from a fixed seed so chunk boundaries are reproducible
+// across runs and machines.
+var chunkHashTable = buildChunkHashTable()
+
+func buildChunkHashTable() [256]uint32 {
+	var table [256]uint32
+	// Simple deterministic PRNG (splitmix64) so we don't depend on math/rand
+	// seeding behavior across Go versions.
+	state := uint64(0x9e3779b97f4a7c15)
+	next := func() uint64 {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		return z ^ (z >> 31)
+	}
+	for i := range table {
+		table[i] = uint32(next())
+	}
+	return table
+}
+
+func rotl32(v uint32, bits uint) uint32 {
+	return v<<bits | v>>(32-bits)
+}
+
+// ChunkHash identifies a unique chunk by its truncated content hash.
+type ChunkHash [16]byte
+
+// hashChunk computes the dedup key for a chunk: SHA-256 truncated to 128 bits.
+func hashChunk(data []byte) ChunkHash {
+	sum := sha256.Sum256(data)
+	var h ChunkHash
+	copy(h[:], sum[:16])
+	return h
+}
+
+// chunkParams bounds where splitChunks is allowed to cut chunk
+// boundaries. The zero value is not valid; use defaultChunkParams or
+// Builder.chunkParams to build one.
+type chunkParams struct {
+	min, max int
+	mask     uint32
+}
+
+// defaultChunkParams returns the package-level chunkMinSize/chunkAvgBits/
+// chunkMaxSize bounds, used by BuildDeduped when SetDedupParams hasn't
+// been called.
+func defaultChunkParams() chunkParams {
+	return chunkParams{min: chunkMinSize, max: chunkMaxSize, mask: chunkMask}
+}
+
+// chunkParams resolves b's SetDedupParams overrides (if any) against the
+// package defaults.
+func (b *Builder) chunkParams() chunkParams {
+	p := defaultChunkParams()
+	if b.dedupMinSize > 0 {
+		p.min = b.dedupMinSize
+	}
+	if b.dedupMaxSize > 0 {
+		p.max = b.dedupMaxSize
+	}
+	if b.dedupAvgSize > 1 {
+		avgBits := bits.Len(uint(b.dedupAvgSize)) - 1
+		p.mask = 1<<uint(avgBits) - 1
+	}
+	return p
+}
+
+// splitChunks splits data into content-defined chunks using a buzhash
+// rolling hash over a sliding window of chunkWindowSize bytes, cutting a
+// boundary whenever the hash's low bits (per params.mask) are all zero,
+// subject to params.min/params.max bounds. Returns the chunk byte ranges.
+func splitChunks(data []byte, params chunkParams) [][]byte {
+	if len(data) <= params.min {
+		if len(data) == 0 {
+			return nil
+		}
+		return [][]byte{data}
+	}
+
+	var chunks [][]byte
+	start := 0
+	var h uint32
+
+	for i := 0; i < len(data); i++ {
+		in := data[i]
+		h = rotl32(h, 1) ^ chunkHashTable[in]
+		if i-start >= chunkWindowSize {
+			out := data[i-chunkWindowSize]
+			h ^= rotl32(chunkHashTable[out], chunkWindowSize%32)
+		}
+
+		size := i - start + 1
+		if size < params.min {
+			continue
+		}
+		if size >= params.max || (h&params.mask) == 0 {
+			chunks = append(chunks, data[start:i+1])
+			start = i + 1
+			h = 0
+		}
+	}
+
+	if start < len(data) {
+		chunks = append(chunks, data[start:])
+	}
+
+	return chunks
+}
@@ -0,0 +1,201 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/DataDog/zstd"
+	"github.com/andybalholm/brotli"
+	"github.com/pierrec/lz4/v4"
+)
+
+// CodecTag identifies which codec compressed a frame. It is persisted in
+// a CodecIndex sidecar (see WriteFile/ReadCodecIndex) rather than in the
+// fixed-layout Frame struct, so manifests built without multi-codec
+// support stay byte-identical to the original format; legacy readers
+// that don't know about CodecIndex simply treat every frame as zstd, as
+// they always have.
+type CodecTag uint8
+
+const (
+	CodecZstd     CodecTag = iota // github.com/DataDog/zstd
+	CodecBrotli                   // github.com/andybalholm/brotli
+	CodecStored                   // uncompressed passthrough
+	CodecLZ4                      // github.com/pierrec/lz4
+	CodecZstdDict                 // zstd seeded with a per-TypeSymbol DictTable entry
+)
+
+// Codec compresses and decompresses frame payloads.
+type Codec interface {
+	Tag() CodecTag
+	Compress(data []byte, level int) ([]byte, error)
+	Decompress(dst, src []byte) ([]byte, error)
+}
+
+type zstdCodec struct{}
+
+func (zstdCodec) Tag() CodecTag { return CodecZstd }
+func (zstdCodec) Compress(data []byte, level int) ([]byte, error) {
+	return zstd.CompressLevel(nil, data, level)
+}
+func (zstdCodec) Decompress(dst, src []byte) ([]byte, error) {
+	return zstd.Decompress(dst, src)
+}
+
+type brotliCodec struct{}
+
+func (brotliCodec) Tag() CodecTag { return CodecBrotli }
+func (brotliCodec) Compress(data []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	w := brotli.NewWriterLevel(&buf, brotliLevel(level))
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("brotli write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("brotli close: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+func (brotliCodec) Decompress(dst, src []byte) ([]byte, error) {
+	r := brotli.NewReader(bytes.NewReader(src))
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("brotli read: %w", err)
+	}
+	return append(dst[:0], data...), nil
+}
+
+// brotliLevel maps a zstd-style level (negative..22) onto brotli's 0-11
+// quality range so callers can use one SetCompressionLevel knob.
+func brotliLevel(zstdLevel int) int {
+	switch {
+	case zstdLevel <= 1:
+		return 5
+	case zstdLevel >= 19:
+		return 11
+	default:
+		return 9
+	}
+}
+
+type storedCodec struct{}
+
+func (storedCodec) Tag() CodecTag { return CodecStored }
+func (storedCodec) Compress(data []byte, _ int) ([]byte, error) {
+	return append([]byte(nil), data...), nil
+}
+func (storedCodec) Decompress(dst, src []byte) ([]byte, error) {
+	return append(dst[:0], src...), nil
+}
+
+type lz4CodecImpl struct{}
+
+func (lz4CodecImpl) Tag() CodecTag { return CodecLZ4 }
+func (lz4CodecImpl) Compress(data []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	w := lz4.NewWriter(&buf)
+	if err := w.Apply(lz4.CompressionLevelOption(lz4Level(level))); err != nil {
+		return nil, fmt.Errorf("configure lz4 writer: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("lz4 write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("lz4 close: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+func (lz4CodecImpl) Decompress(dst, src []byte) ([]byte, error) {
+	r := lz4.NewReader(bytes.NewReader(src))
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("lz4 read: %w", err)
+	}
+	return append(dst[:0], data...), nil
+}
+
+// lz4Level maps a zstd-style level (negative..22) onto lz4's fixed set of
+// compression levels, the same way brotliLevel does for brotli; it
+// mirrors archive.lz4Level, which serves the same purpose for the
+// archive package's own lz4 codec.
+func lz4Level(zstdLevel int) lz4.CompressionLevel {
+	switch {
+	case zstdLevel <= 1:
+		return lz4.Fast
+	case zstdLevel >= 19:
+		return lz4.Level9
+	default:
+		return lz4.Level5
+	}
+}
+
+// registeredCodecs holds every Codec available to CodecFor, keyed by
+// Tag(). It mirrors archive.registeredCodecs: a small built-in set that
+// RegisterCodec can extend, rather than a closed switch, so a caller with
+// its own Codec implementation can make it a WithCodecs candidate without
+// forking this package. CodecZstdDict is deliberately absent - it needs a
+// per-frame dictionary the Codec interface has no room for, so it's only
+// ever produced via compressFrameBestOf/DictTable, never via CodecFor.
+var registeredCodecs = map[CodecTag]Codec{
+	CodecZstd:   zstdCodec{},
+	CodecBrotli: brotliCodec{},
+	CodecStored: storedCodec{},
+	CodecLZ4:    lz4CodecImpl{},
+}
+
+// RegisterCodec makes a custom Codec available to CodecFor (and therefore
+// to WithCodecs and DecompressFrame) under its own Tag(). Callers adding a
+// custom codec should pick a CodecTag value above CodecZstdDict so it
+// never collides with a codec this package adds later.
+func RegisterCodec(codec Codec) {
+	registeredCodecs[codec.Tag()] = codec
+}
+
+// CodecFor returns the Codec implementation for tag.
+func CodecFor(tag CodecTag) (Codec, error) {
+	codec, ok := registeredCodecs[tag]
+	if !ok {
+		return nil, fmt.Errorf("unknown codec tag %d", tag)
+	}
+	return codec, nil
+}
+
+// defaultCodec is the codec Repack/QuickRepack use for a modified frame
+// when the caller passes neither WithCodecs nor WithDictionaries,
+// preserving the always-zstd behavior every version before WithCodecs
+// existed.
+var defaultCodec = CodecZstd
+
+// SetDefaultCodec changes the package-wide default codec Repack and
+// QuickRepack fall back to when WithCodecs isn't passed. It's a process-
+// wide setting, the Repack equivalent of DefaultCodecPolicy for
+// BuildMultiCodec, meant to be set once (e.g. from main) rather than
+// toggled mid-repack.
+func SetDefaultCodec(tag CodecTag) {
+	defaultCodec = tag
+}
+
+// CodecPolicy picks a codec for a group of files about to be written as a
+// single frame.
+type CodecPolicy func(group []ScannedFile) Codec
+
+// DefaultCodecPolicy heuristically picks zstd for most content. It is a
+// reasonable default until a caller supplies something smarter via
+// Builder.SetCodecPolicy; actual per-group compressibility isn't known
+// until after compression, which BuildMultiCodec accounts for separately
+// via its incompressible-data fallback to CodecStored.
+func DefaultCodecPolicy(group []ScannedFile) Codec {
+	return zstdCodec{}
+}
+
+// SetCodecPolicy overrides how BuildMultiCodec picks a codec per frame.
+func (b *Builder) SetCodecPolicy(policy CodecPolicy) {
+	b.codecPolicy = policy
+}
+
+// storedRatioThreshold is how close to 1.0 (no savings) a frame's
+// compressed/uncompressed ratio must be before BuildMultiCodec stores it
+// uncompressed instead, to skip paying decompression cost for data that
+// didn't shrink.
+const storedRatioThreshold = 0.95
@@ -0,0 +1,105 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// CodecIndex records which Codec compressed each frame in a package
+// built with BuildMultiCodec. Like ChunkIndex, it is stored as a sidecar
+// file ("<manifest>.codecs") next to the manifest instead of inside the
+// fixed-layout manifest binary.
+type CodecIndex struct {
+	// Codecs[i] is the CodecTag used for Manifest.Frames[i]. A frame index
+	// with no entry (or a CodecIndex that wasn't loaded at all) defaults
+	// to CodecZstd, matching every manifest built before this existed.
+	Codecs []CodecTag
+}
+
+const codecIndexMagic = "EVRCODC1"
+
+// WriteFile serializes the codec index to path, creating its directory
+// if needed.
+func (ci *CodecIndex) WriteFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create codec index dir: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(codecIndexMagic)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(ci.Codecs)))
+	for _, c := range ci.Codecs {
+		buf.WriteByte(byte(c))
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// codecIndexPath is where WithCodecs persists a package's CodecIndex,
+// matching the "manifests/<pkg>.chunks" / "manifests/<pkg>.dicts" layout
+// ChunkStore and DictTable already use.
+func codecIndexPath(dir, packageName string) string {
+	return filepath.Join(dir, "manifests", packageName+".codecs")
+}
+
+// LoadCodecIndex reads a codec index previously written by WriteFile. A
+// missing file is not an error: it returns an empty index, since the
+// first WithCodecs-enabled run of a package has nothing to load yet -
+// matching LoadChunkStore/LoadDictTable.
+func LoadCodecIndex(path string) (*CodecIndex, error) {
+	ci, err := ReadCodecIndex(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &CodecIndex{}, nil
+	}
+	return ci, err
+}
+
+// recordFrame stores the codec tag used for frameIndex, padding Codecs
+// with CodecZstd (the zero value, and CodecForFrame's own default) for
+// any frame indices skipped so far.
+func (ci *CodecIndex) recordFrame(frameIndex uint32, tag CodecTag) {
+	for uint32(len(ci.Codecs)) <= frameIndex {
+		ci.Codecs = append(ci.Codecs, CodecZstd)
+	}
+	ci.Codecs[frameIndex] = tag
+}
+
+// ReadCodecIndex loads a codec index previously written by WriteFile.
+func ReadCodecIndex(path string) (*CodecIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read codec index: %w", err)
+	}
+	if len(data) < len(codecIndexMagic) || string(data[:len(codecIndexMagic)]) != codecIndexMagic {
+		return nil, fmt.Errorf("invalid codec index magic")
+	}
+	r := bytes.NewReader(data[len(codecIndexMagic):])
+
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("read codec count: %w", err)
+	}
+
+	ci := &CodecIndex{Codecs: make([]CodecTag, count)}
+	for i := range ci.Codecs {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("read codec tag %d: %w", i, err)
+		}
+		ci.Codecs[i] = CodecTag(b)
+	}
+	return ci, nil
+}
+
+// CodecForFrame returns the codec tag for frameIndex, defaulting to
+// CodecZstd if the index doesn't cover it (including a nil *CodecIndex).
+func (ci *CodecIndex) CodecForFrame(frameIndex uint32) CodecTag {
+	if ci == nil || int(frameIndex) >= len(ci.Codecs) {
+		return CodecZstd
+	}
+	return ci.Codecs[frameIndex]
+}
@@ -0,0 +1,213 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog "), 200)
+
+	for _, tag := range []CodecTag{CodecZstd, CodecBrotli, CodecStored, CodecLZ4} {
+		codec, err := CodecFor(tag)
+		if err != nil {
+			t.Fatalf("CodecFor(%d): %v", tag, err)
+		}
+
+		compressed, err := codec.Compress(data, DefaultCompressionLevel)
+		if err != nil {
+			t.Fatalf("tag %d: Compress: %v", tag, err)
+		}
+
+		decompressed, err := codec.Decompress(nil, compressed)
+		if err != nil {
+			t.Fatalf("tag %d: Decompress: %v", tag, err)
+		}
+
+		if !bytes.Equal(decompressed, data) {
+			t.Fatalf("tag %d: round trip mismatch", tag)
+		}
+	}
+}
+
+func TestCodecForUnknownTag(t *testing.T) {
+	if _, err := CodecFor(CodecTag(99)); err == nil {
+		t.Fatal("expected error for unknown codec tag")
+	}
+}
+
+// TestCodecForZstdDictIsUnregistered documents that CodecZstdDict is never
+// resolvable via CodecFor: it needs a per-frame dictionary the Codec
+// interface has no room for, so it only ever comes from
+// compressFrameBestOf/DictTable.
+func TestCodecForZstdDictIsUnregistered(t *testing.T) {
+	if _, err := CodecFor(CodecZstdDict); err == nil {
+		t.Fatal("expected CodecZstdDict to be unregistered in CodecFor")
+	}
+}
+
+func TestRegisterCodecAddsCustomTag(t *testing.T) {
+	const customTag CodecTag = 200
+	RegisterCodec(storedCodecAs{tag: customTag})
+	defer delete(registeredCodecs, customTag)
+
+	codec, err := CodecFor(customTag)
+	if err != nil {
+		t.Fatalf("CodecFor(customTag): %v", err)
+	}
+	if codec.Tag() != customTag {
+		t.Errorf("got tag %d, want %d", codec.Tag(), customTag)
+	}
+}
+
+// storedCodecAs lets TestRegisterCodecAddsCustomTag stand in a Codec under
+// an arbitrary tag without needing a real third-party compressor.
+type storedCodecAs struct {
+	tag CodecTag
+}
+
+func (s storedCodecAs) Tag() CodecTag { return s.tag }
+func (storedCodecAs) Compress(data []byte, _ int) ([]byte, error) { return data, nil }
+func (storedCodecAs) Decompress(dst, src []byte) ([]byte, error) {
+	return append(dst[:0], src...), nil
+}
+
+func TestSetDefaultCodecChangesRepackDefault(t *testing.T) {
+	t.Cleanup(func() { SetDefaultCodec(CodecZstd) })
+
+	SetDefaultCodec(CodecBrotli)
+	data := bytes.Repeat([]byte("hello default codec "), 100)
+	encoded, tag, dictID, err := compressModifiedFrame(&repackConfig{}, data, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("compressModifiedFrame: %v", err)
+	}
+	if tag != CodecBrotli {
+		t.Errorf("got codec %d, want CodecBrotli", tag)
+	}
+	if dictID != 0 {
+		t.Errorf("got dictID %d, want 0", dictID)
+	}
+
+	codec, err := CodecFor(CodecBrotli)
+	if err != nil {
+		t.Fatalf("CodecFor: %v", err)
+	}
+	decoded, err := codec.Decompress(nil, encoded)
+	if err != nil {
+		t.Fatalf("Decompress: %v", err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Fatal("round trip mismatch after SetDefaultCodec")
+	}
+}
+
+func TestCodecIndexWriteReadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pkg.codecs")
+
+	ci := &CodecIndex{Codecs: []CodecTag{CodecZstd, CodecBrotli, CodecStored, CodecZstd}}
+	if err := ci.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	loaded, err := ReadCodecIndex(path)
+	if err != nil {
+		t.Fatalf("ReadCodecIndex: %v", err)
+	}
+
+	if len(loaded.Codecs) != len(ci.Codecs) {
+		t.Fatalf("got %d codecs, want %d", len(loaded.Codecs), len(ci.Codecs))
+	}
+	for i, tag := range ci.Codecs {
+		if loaded.Codecs[i] != tag {
+			t.Errorf("frame %d: got codec %d, want %d", i, loaded.Codecs[i], tag)
+		}
+	}
+}
+
+func TestCodecForFrameDefaultsToZstd(t *testing.T) {
+	var nilIndex *CodecIndex
+	if tag := nilIndex.CodecForFrame(0); tag != CodecZstd {
+		t.Errorf("nil index: got %d, want CodecZstd", tag)
+	}
+
+	ci := &CodecIndex{Codecs: []CodecTag{CodecBrotli}}
+	if tag := ci.CodecForFrame(5); tag != CodecZstd {
+		t.Errorf("out-of-range frame: got %d, want CodecZstd", tag)
+	}
+	if tag := ci.CodecForFrame(0); tag != CodecBrotli {
+		t.Errorf("frame 0: got %d, want CodecBrotli", tag)
+	}
+}
+
+func TestBuildMultiCodecUsesPolicyAndStores(t *testing.T) {
+	dir := t.TempDir()
+
+	var incompressible []byte
+	for seed := 0; len(incompressible) < 4096; seed++ {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("incompressible-seed-%d", seed)))
+		incompressible = append(incompressible, sum[:]...)
+	}
+	incompressible = incompressible[:4096]
+
+	var groups [][]ScannedFile
+	for g, content := range [][]byte{
+		bytes.Repeat([]byte("compressible payload "), 500),
+		incompressible,
+	} {
+		path := filepath.Join(dir, "files", fmt.Sprintf("f%d", g))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		groups = append(groups, []ScannedFile{{
+			TypeSymbol: int64(g + 1),
+			FileSymbol: int64(g),
+			Path:       path,
+			Size:       uint32(len(content)),
+		}})
+	}
+
+	builder := NewBuilder(dir, "pkg")
+	builder.SetCodecPolicy(func(group []ScannedFile) Codec {
+		return brotliCodec{}
+	})
+
+	manifest, codecIndex, err := builder.BuildMultiCodec(groups)
+	if err != nil {
+		t.Fatalf("BuildMultiCodec: %v", err)
+	}
+
+	if len(codecIndex.Codecs) != 2 {
+		t.Fatalf("got %d codec entries, want 2", len(codecIndex.Codecs))
+	}
+	if codecIndex.Codecs[0] != CodecBrotli {
+		t.Errorf("frame 0: got codec %d, want CodecBrotli", codecIndex.Codecs[0])
+	}
+	if codecIndex.Codecs[1] != CodecStored {
+		t.Errorf("frame 1 (incompressible): got codec %d, want CodecStored", codecIndex.Codecs[1])
+	}
+
+	packagePath := filepath.Join(dir, "packages", "pkg_0")
+	packageData, err := os.ReadFile(packagePath)
+	if err != nil {
+		t.Fatalf("read package: %v", err)
+	}
+
+	for i, frame := range manifest.Frames[:2] {
+		compressed := packageData[frame.Offset : frame.Offset+frame.CompressedSize]
+		got, err := DecompressFrame(codecIndex, uint32(i), compressed, frame.Length)
+		if err != nil {
+			t.Fatalf("frame %d: DecompressFrame: %v", i, err)
+		}
+		if uint32(len(got)) != frame.Length {
+			t.Errorf("frame %d: got %d bytes, want %d", i, len(got), frame.Length)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// ChunkRef locates a single content-defined chunk within a built package:
+// the frame it lives in, its byte offset inside the decompressed frame,
+// and its length. This mirrors FrameContent's (FrameIndex, DataOffset,
+// Size) tuple, but at chunk granularity instead of whole-file granularity.
+type ChunkRef struct {
+	FrameIndex uint32
+	DataOffset uint32
+	Size       uint32
+}
+
+// FileChunks describes how to reassemble one file from an ordered list of
+// chunks, each of which may be shared with other files.
+type FileChunks struct {
+	TypeSymbol int64
+	FileSymbol int64
+	Chunks     []uint32 // indices into ChunkIndex.Chunks, in assembly order
+}
+
+// ChunkIndex is the sidecar dedup table produced by Builder.BuildDeduped.
+// It is stored alongside the manifest (same directory, "<name>.chunks")
+// rather than inside the fixed-layout manifest binary, so manifests built
+// without dedup remain byte-identical to the original format.
+type ChunkIndex struct {
+	Chunks []ChunkRef
+	Files  []FileChunks
+}
+
+const chunkIndexMagic = "EVRCDC1\x00"
+
+// WriteFile serializes the chunk index to path.
+func (ci *ChunkIndex) WriteFile(path string) error {
+	var buf bytes.Buffer
+	buf.WriteString(chunkIndexMagic)
+
+	binary.Write(&buf, binary.LittleEndian, uint32(len(ci.Chunks)))
+	for _, c := range ci.Chunks {
+		binary.Write(&buf, binary.LittleEndian, c.FrameIndex)
+		binary.Write(&buf, binary.LittleEndian, c.DataOffset)
+		binary.Write(&buf, binary.LittleEndian, c.Size)
+	}
+
+	binary.Write(&buf, binary.LittleEndian, uint32(len(ci.Files)))
+	for _, f := range ci.Files {
+		binary.Write(&buf, binary.LittleEndian, f.TypeSymbol)
+		binary.Write(&buf, binary.LittleEndian, f.FileSymbol)
+		binary.Write(&buf, binary.LittleEndian, uint32(len(f.Chunks)))
+		for _, idx := range f.Chunks {
+			binary.Write(&buf, binary.LittleEndian, idx)
+		}
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// ReadChunkIndex loads a chunk index previously written by WriteFile.
+func ReadChunkIndex(path string) (*ChunkIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read chunk index: %w", err)
+	}
+
+	if len(data) < len(chunkIndexMagic) || string(data[:len(chunkIndexMagic)]) != chunkIndexMagic {
+		return nil, fmt.Errorf("invalid chunk index magic")
+	}
+	r := bytes.NewReader(data[len(chunkIndexMagic):])
+
+	ci := &ChunkIndex{}
+
+	var chunkCount uint32
+	if err := binary.Read(r, binary.LittleEndian, &chunkCount); err != nil {
+		return nil, fmt.Errorf("read chunk count: %w", err)
+	}
+	ci.Chunks = make([]ChunkRef, chunkCount)
+	for i := range ci.Chunks {
+		binary.Read(r, binary.LittleEndian, &ci.Chunks[i].FrameIndex)
+		binary.Read(r, binary.LittleEndian, &ci.Chunks[i].DataOffset)
+		binary.Read(r, binary.LittleEndian, &ci.Chunks[i].Size)
+	}
+
+	var fileCount uint32
+	if err := binary.Read(r, binary.LittleEndian, &fileCount); err != nil {
+		return nil, fmt.Errorf("read file count: %w", err)
+	}
+	ci.Files = make([]FileChunks, fileCount)
+	for i := range ci.Files {
+		binary.Read(r, binary.LittleEndian, &ci.Files[i].TypeSymbol)
+		binary.Read(r, binary.LittleEndian, &ci.Files[i].FileSymbol)
+		var n uint32
+		binary.Read(r, binary.LittleEndian, &n)
+		ci.Files[i].Chunks = make([]uint32, n)
+		for j := range ci.Files[i].Chunks {
+			binary.Read(r, binary.LittleEndian, &ci.Files[i].Chunks[j])
+		}
+	}
+
+	return ci, nil
+}
+
+// BuildDeduped builds a package like Build, but splits each file's data
+// into content-defined chunks (see splitChunks) and stores each unique
+// chunk only once. Files that share near-duplicate content - common for
+// tint tables and small per-variant JSON blobs - end up referencing the
+// same underlying bytes instead of each carrying their own copy.
+//
+// The returned Manifest's FrameContents section describes only the unique
+// chunks actually written (so existing tooling can still list what's in a
+// package), each under the symbols of the file that first wrote it, while
+// Metadata holds one entry per original file; the returned ChunkIndex
+// describes how to
+// reassemble each original file from those chunks and must be persisted
+// alongside the manifest (see ChunkIndex.WriteFile) for extraction.
+func (b *Builder) BuildDeduped(fileGroups [][]ScannedFile) (*Manifest, *ChunkIndex, error) {
+	manifest := &Manifest{
+		Header: Header{
+			PackageCount:  1,
+			FrameContents: Section{ElementSize: FrameContentSize},
+			Metadata:      Section{ElementSize: FileMetadataSize},
+			Frames:        Section{ElementSize: FrameSize},
+		},
+	}
+
+	index := &ChunkIndex{}
+	seen := make(map[ChunkHash]uint32)
+
+	packagesDir := b.outputDir + "/packages"
+	if err := os.MkdirAll(packagesDir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("create packages dir: %w", err)
+	}
+
+	var (
+		currentFrame  bytes.Buffer
+		currentOffset uint32
+		frameIndex    uint32
+	)
+
+	flushFrame := func() error {
+		if currentFrame.Len() == 0 {
+			return nil
+		}
+		if err := b.writeFrame(manifest, &currentFrame, frameIndex); err != nil {
+			return err
+		}
+		frameIndex++
+		currentFrame.Reset()
+		currentOffset = 0
+		return nil
+	}
+
+	params := b.chunkParams()
+
+	for _, group := range fileGroups {
+		if len(group) == 0 {
+			continue
+		}
+
+		if err := flushFrame(); err != nil {
+			return nil, nil, err
+		}
+
+		for _, file := range group {
+			data, err := os.ReadFile(file.Path)
+			if err != nil {
+				return nil, nil, fmt.Errorf("read file %s: %w", file.Path, err)
+			}
+
+			fc := FileChunks{TypeSymbol: file.TypeSymbol, FileSymbol: file.FileSymbol}
+
+			for _, chunk := range splitChunks(data, params) {
+				h := hashChunk(chunk)
+				if idx, ok := seen[h]; ok {
+					fc.Chunks = append(fc.Chunks, idx)
+					continue
+				}
+
+				idx := uint32(len(index.Chunks))
+				index.Chunks = append(index.Chunks, ChunkRef{
+					FrameIndex: frameIndex,
+					DataOffset: currentOffset,
+					Size:       uint32(len(chunk)),
+				})
+				seen[h] = idx
+				fc.Chunks = append(fc.Chunks, idx)
+
+				currentFrame.Write(chunk)
+				currentOffset += uint32(len(chunk))
+
+				manifest.FrameContents = append(manifest.FrameContents, FrameContent{
+					TypeSymbol: file.TypeSymbol,
+					FileSymbol: file.FileSymbol,
+					FrameIndex: frameIndex,
+					DataOffset: index.Chunks[idx].DataOffset,
+					Size:       index.Chunks[idx].Size,
+					Alignment:  1,
+				})
+				b.incrementSection(&manifest.Header.FrameContents, 1)
+			}
+
+			index.Files = append(index.Files, fc)
+
+			manifest.Metadata = append(manifest.Metadata, FileMetadata{
+				TypeSymbol: file.TypeSymbol,
+				FileSymbol: file.FileSymbol,
+			})
+			b.incrementSection(&manifest.Header.Metadata, 1)
+		}
+	}
+
+	if err := flushFrame(); err != nil {
+		return nil, nil, err
+	}
+
+	b.addTerminatorFrames(manifest)
+
+	return manifest, index, nil
+}
+
+// ReassembleFile reconstructs a deduped file's original bytes by
+// concatenating its chunks, fetched via get(frameIndex, offset, size).
+func (fc *FileChunks) ReassembleFile(index *ChunkIndex, get func(frameIndex, offset, size uint32) ([]byte, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, chunkIdx := range fc.Chunks {
+		if int(chunkIdx) >= len(index.Chunks) {
+			return nil, fmt.Errorf("chunk index %d out of range", chunkIdx)
+		}
+		ref := index.Chunks[chunkIdx]
+		data, err := get(ref.FrameIndex, ref.DataOffset, ref.Size)
+		if err != nil {
+			return nil, fmt.Errorf("fetch chunk %d: %w", chunkIdx, err)
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,213 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitChunksDeterministic(t *testing.T) {
+	data := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog "), 500)
+
+	a := splitChunks(data, defaultChunkParams())
+	b := splitChunks(data, defaultChunkParams())
+
+	if len(a) != len(b) {
+		t.Fatalf("non-deterministic chunk count: %d vs %d", len(a), len(b))
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			t.Fatalf("chunk %d differs between runs", i)
+		}
+	}
+
+	var reassembled bytes.Buffer
+	for _, c := range a {
+		reassembled.Write(c)
+	}
+	if !bytes.Equal(reassembled.Bytes(), data) {
+		t.Fatal("chunks do not reassemble to original data")
+	}
+
+	for _, c := range a {
+		if len(c) > chunkMaxSize {
+			t.Errorf("chunk exceeds chunkMaxSize: %d", len(c))
+		}
+	}
+}
+
+func TestSplitChunksSmallInput(t *testing.T) {
+	data := []byte("short")
+	chunks := splitChunks(data, defaultChunkParams())
+	if len(chunks) != 1 || !bytes.Equal(chunks[0], data) {
+		t.Fatalf("expected single chunk for short input, got %v", chunks)
+	}
+
+	if chunks := splitChunks(nil, defaultChunkParams()); chunks != nil {
+		t.Fatalf("expected nil chunks for empty input, got %v", chunks)
+	}
+}
+
+func TestBuildDedupedSharesDuplicateChunks(t *testing.T) {
+	dir := t.TempDir()
+
+	shared := bytes.Repeat([]byte("duplicate-tint-payload-"), 200)
+	fileA := filepath.Join(dir, "a.bin")
+	fileB := filepath.Join(dir, "b.bin")
+	if err := os.WriteFile(fileA, shared, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fileB, shared, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	builder := NewBuilder(dir, "dedup_test")
+	groups := [][]ScannedFile{{
+		{TypeSymbol: 1, FileSymbol: 1, Path: fileA, Size: uint32(len(shared))},
+		{TypeSymbol: 1, FileSymbol: 2, Path: fileB, Size: uint32(len(shared))},
+	}}
+
+	m, index, err := builder.BuildDeduped(groups)
+	if err != nil {
+		t.Fatalf("BuildDeduped: %v", err)
+	}
+
+	if len(index.Files) != 2 {
+		t.Fatalf("expected 2 file assemblies, got %d", len(index.Files))
+	}
+	if len(index.Files[0].Chunks) != len(index.Files[1].Chunks) {
+		t.Fatalf("identical files produced different chunk counts")
+	}
+	for i, idx := range index.Files[0].Chunks {
+		if idx != index.Files[1].Chunks[i] {
+			t.Fatalf("identical files should reference the same chunk indices")
+		}
+	}
+
+	if m.FileCount() != len(index.Chunks) {
+		t.Fatalf("manifest should only record unique chunks once: FileCount=%d uniqueChunks=%d",
+			m.FileCount(), len(index.Chunks))
+	}
+}
+
+func TestSetDedupParamsChangesChunkBoundaries(t *testing.T) {
+	data := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog "), 500)
+
+	dir := t.TempDir()
+	builder := NewBuilder(dir, "dedup_params")
+	builder.SetDedupParams(64, 256, 1024)
+
+	small := splitChunks(data, builder.chunkParams())
+	large := splitChunks(data, defaultChunkParams())
+
+	if len(small) <= len(large) {
+		t.Fatalf("expected a smaller average chunk size to produce more chunks: got %d small vs %d default", len(small), len(large))
+	}
+	for _, c := range small {
+		if len(c) > 1024 {
+			t.Errorf("chunk exceeds configured max of 1024: %d", len(c))
+		}
+	}
+}
+
+func TestChunkIndexWriteReadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "package.chunks")
+
+	original := &ChunkIndex{
+		Chunks: []ChunkRef{
+			{FrameIndex: 0, DataOffset: 0, Size: 100},
+			{FrameIndex: 0, DataOffset: 100, Size: 50},
+		},
+		Files: []FileChunks{
+			{TypeSymbol: 1, FileSymbol: 2, Chunks: []uint32{0, 1}},
+			{TypeSymbol: 1, FileSymbol: 3, Chunks: []uint32{0}},
+		},
+	}
+
+	if err := original.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	loaded, err := ReadChunkIndex(path)
+	if err != nil {
+		t.Fatalf("ReadChunkIndex: %v", err)
+	}
+
+	if len(loaded.Chunks) != len(original.Chunks) || len(loaded.Files) != len(original.Files) {
+		t.Fatalf("round trip mismatch: %+v vs %+v", loaded, original)
+	}
+	for i := range original.Chunks {
+		if loaded.Chunks[i] != original.Chunks[i] {
+			t.Errorf("chunk %d mismatch: got %+v, want %+v", i, loaded.Chunks[i], original.Chunks[i])
+		}
+	}
+}
+
+// dedupBenchFixture simulates a small texture set where each "texture"
+// shares a common base payload with a few unique bytes appended, the kind
+// of near-duplicate content BuildDeduped is meant to collapse.
+func dedupBenchFixture(b *testing.B, dir string) [][]ScannedFile {
+	b.Helper()
+
+	base := bytes.Repeat([]byte("EVRTEX-base-mip-payload-"), 2000) // ~48KB shared body
+	var group []ScannedFile
+	for i := 0; i < 40; i++ {
+		content := append(append([]byte{}, base...), []byte(fmt.Sprintf("-variant-%d", i))...)
+		path := filepath.Join(dir, fmt.Sprintf("tex_%d.bin", i))
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			b.Fatal(err)
+		}
+		group = append(group, ScannedFile{
+			TypeSymbol: 1,
+			FileSymbol: int64(i),
+			Path:       path,
+			Size:       uint32(len(content)),
+		})
+	}
+	return [][]ScannedFile{group}
+}
+
+// BenchmarkBuildDedupedVsBuild compares total on-disk package size between
+// Build (each file stored whole) and BuildDeduped (shared chunks stored
+// once) for a texture set made mostly of near-duplicate content.
+func BenchmarkBuildDedupedVsBuild(b *testing.B) {
+	b.Run("Build", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			dir := b.TempDir()
+			groups := dedupBenchFixture(b, dir)
+			if _, err := NewBuilder(dir, "pkg").Build(groups); err != nil {
+				b.Fatalf("Build: %v", err)
+			}
+			b.ReportMetric(float64(packageSetSize(b, dir, "pkg")), "bytes/op")
+		}
+	})
+
+	b.Run("BuildDeduped", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			dir := b.TempDir()
+			groups := dedupBenchFixture(b, dir)
+			if _, _, err := NewBuilder(dir, "pkg").BuildDeduped(groups); err != nil {
+				b.Fatalf("BuildDeduped: %v", err)
+			}
+			b.ReportMetric(float64(packageSetSize(b, dir, "pkg")), "bytes/op")
+		}
+	})
+}
+
+// packageSetSize sums the size of every "<name>_N" package file Builder
+// wrote under dir/packages.
+func packageSetSize(b *testing.B, dir, name string) int64 {
+	b.Helper()
+	var total int64
+	for i := 0; ; i++ {
+		info, err := os.Stat(filepath.Join(dir, "packages", fmt.Sprintf("%s_%d", name, i)))
+		if err != nil {
+			break
+		}
+		total += info.Size()
+	}
+	return total
+}
@@ -0,0 +1,190 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/DataDog/zstd"
+)
+
+// DictStrategy controls how Builder.TrainDictionaries groups samples into
+// dictionaries.
+type DictStrategy int
+
+const (
+	// DictStrategyNone disables dictionary compression.
+	DictStrategyNone DictStrategy = iota
+	// DictStrategyPerType trains one dictionary per TypeSymbol.
+	DictStrategyPerType
+	// DictStrategyShared trains a single dictionary shared by all types.
+	DictStrategyShared
+)
+
+// sharedDictType is the pseudo TypeSymbol key used for the single
+// dictionary produced under DictStrategyShared.
+const sharedDictType = 0
+
+// DictRef points at the package frame holding a trained dictionary for a
+// given TypeSymbol (or sharedDictType under DictStrategyShared).
+type DictRef struct {
+	TypeSymbol int64
+	FrameIndex uint32
+}
+
+// SetDictionaryStrategy selects how TrainDictionaries groups samples.
+func (b *Builder) SetDictionaryStrategy(s DictStrategy) {
+	b.dictStrategy = s
+}
+
+// TrainDictionaries builds compression dictionaries from representative
+// samples, grouped according to the builder's DictStrategy (set via
+// SetDictionaryStrategy; defaults to DictStrategyPerType). Small, similar
+// assets - tint entries, per-variant JSON blobs - compress poorly alone at
+// BestSpeed because there isn't enough data for zstd to find repeated
+// patterns; a shared dictionary seeds the compressor with those patterns
+// up front.
+//
+// The vendored zstd binding does not expose ZDICT_trainFromBuffer, so
+// dictionaries are built with a simpler frequency-based sampler: samples
+// are split into content-defined chunks (see splitChunks) and the most
+// common chunks, by how many distinct samples they appear in, are
+// concatenated up to dictSize bytes. This does not replace the proper
+// COVER/FastCover algorithm but captures the common case of many
+// near-duplicate small files.
+func (b *Builder) TrainDictionaries(sampleFileGroups [][]ScannedFile, dictSize int) (map[int64][]byte, error) {
+	strategy := b.dictStrategy
+	if strategy == DictStrategyNone {
+		strategy = DictStrategyPerType
+	}
+
+	samplesByType := make(map[int64][][]byte)
+	for _, group := range sampleFileGroups {
+		for _, file := range group {
+			data, err := os.ReadFile(file.Path)
+			if err != nil {
+				return nil, fmt.Errorf("read sample %s: %w", file.Path, err)
+			}
+
+			key := file.TypeSymbol
+			if strategy == DictStrategyShared {
+				key = sharedDictType
+			}
+			samplesByType[key] = append(samplesByType[key], data)
+		}
+	}
+
+	dicts := make(map[int64][]byte, len(samplesByType))
+	for typeSymbol, samples := range samplesByType {
+		dict := trainDictionary(samples, dictSize)
+		if len(dict) == 0 {
+			continue
+		}
+		dicts[typeSymbol] = dict
+	}
+
+	if b.dictionaries == nil {
+		b.dictionaries = make(map[int64][]byte)
+	}
+	for k, v := range dicts {
+		b.dictionaries[k] = v
+	}
+
+	return dicts, nil
+}
+
+// trainDictionary picks the chunks that recur across the most samples and
+// concatenates them (most common first, so truncation to dictSize keeps
+// the highest-value content) to form a dictionary of at most dictSize
+// bytes.
+func trainDictionary(samples [][]byte, dictSize int) []byte {
+	type chunkStat struct {
+		hash  ChunkHash
+		data  []byte
+		count int
+	}
+
+	stats := make(map[ChunkHash]*chunkStat)
+	for _, sample := range samples {
+		seenInSample := make(map[ChunkHash]bool)
+		for _, chunk := range splitChunks(sample, defaultChunkParams()) {
+			h := hashChunk(chunk)
+			if seenInSample[h] {
+				continue
+			}
+			seenInSample[h] = true
+
+			if s, ok := stats[h]; ok {
+				s.count++
+			} else {
+				cp := make([]byte, len(chunk))
+				copy(cp, chunk)
+				stats[h] = &chunkStat{hash: h, data: cp, count: 1}
+			}
+		}
+	}
+
+	ordered := make([]*chunkStat, 0, len(stats))
+	for _, s := range stats {
+		if s.count > 1 {
+			ordered = append(ordered, s)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].count != ordered[j].count {
+			return ordered[i].count > ordered[j].count
+		}
+		return bytes.Compare(ordered[i].data, ordered[j].data) < 0
+	})
+
+	var dict bytes.Buffer
+	for _, s := range ordered {
+		if dict.Len()+len(s.data) > dictSize {
+			if room := dictSize - dict.Len(); room > 0 {
+				dict.Write(s.data[:room])
+			}
+			break
+		}
+		dict.Write(s.data)
+	}
+
+	return dict.Bytes()
+}
+
+// dictionaryFor returns the trained dictionary applicable to typeSymbol,
+// honoring DictStrategyShared.
+func (b *Builder) dictionaryFor(typeSymbol int64) []byte {
+	if len(b.dictionaries) == 0 {
+		return nil
+	}
+	if b.dictStrategy == DictStrategyShared {
+		return b.dictionaries[sharedDictType]
+	}
+	return b.dictionaries[typeSymbol]
+}
+
+// compressWithDict compresses data using dict as a zstd dictionary, or
+// falls back to plain compression when dict is empty.
+func compressWithDict(data, dict []byte, level int) ([]byte, error) {
+	if len(dict) == 0 {
+		return zstd.CompressLevel(nil, data, level)
+	}
+	proc, err := zstd.NewBulkProcessor(dict, level)
+	if err != nil {
+		return nil, fmt.Errorf("create dictionary compressor: %w", err)
+	}
+	return proc.Compress(nil, data)
+}
+
+// decompressWithDict is the inverse of compressWithDict.
+func decompressWithDict(dst, data, dict []byte) ([]byte, error) {
+	if len(dict) == 0 {
+		return zstd.Decompress(dst, data)
+	}
+	proc, err := zstd.NewBulkProcessor(dict, zstd.DefaultCompression)
+	if err != nil {
+		return nil, fmt.Errorf("create dictionary decompressor: %w", err)
+	}
+	return proc.Decompress(dst, data)
+}
@@ -0,0 +1,87 @@
+package manifest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrainDictionaryFindsRecurringChunks(t *testing.T) {
+	// Long enough that the content-defined chunker is guaranteed to cut at
+	// least one boundary inside the shared prefix, well before the
+	// per-sample suffix, regardless of where the rolling hash happens to
+	// land on any given run.
+	common := bytes.Repeat([]byte("rwd_tint_common_prefix_"), 5000)
+
+	samples := [][]byte{
+		append(bytes.Clone(common), []byte("-variant-a")...),
+		append(bytes.Clone(common), []byte("-variant-b")...),
+		append(bytes.Clone(common), []byte("-variant-c")...),
+	}
+
+	dict := trainDictionary(samples, 4096)
+	if len(dict) == 0 {
+		t.Fatal("expected non-empty dictionary for samples with shared content")
+	}
+	if !bytes.Contains(dict, []byte("rwd_tint_common_prefix_")) {
+		t.Errorf("expected dictionary to capture the recurring prefix")
+	}
+}
+
+func TestTrainDictionaryRespectsSizeLimit(t *testing.T) {
+	common := bytes.Repeat([]byte("x"), 10000)
+	samples := [][]byte{bytes.Clone(common), bytes.Clone(common)}
+
+	dict := trainDictionary(samples, 256)
+	if len(dict) > 256 {
+		t.Fatalf("dictionary exceeds dictSize: got %d, want <= 256", len(dict))
+	}
+}
+
+func TestBuilderTrainDictionariesPerType(t *testing.T) {
+	dir := t.TempDir()
+	common := bytes.Repeat([]byte("shared-tint-payload-"), 5000)
+
+	pathA := filepath.Join(dir, "a.bin")
+	pathB := filepath.Join(dir, "b.bin")
+	os.WriteFile(pathA, append(bytes.Clone(common), []byte("-a")...), 0644)
+	os.WriteFile(pathB, append(bytes.Clone(common), []byte("-b")...), 0644)
+
+	builder := NewBuilder(dir, "dict_test")
+	groups := [][]ScannedFile{{
+		{TypeSymbol: 7, FileSymbol: 1, Path: pathA},
+		{TypeSymbol: 7, FileSymbol: 2, Path: pathB},
+	}}
+
+	dicts, err := builder.TrainDictionaries(groups, 4096)
+	if err != nil {
+		t.Fatalf("TrainDictionaries: %v", err)
+	}
+
+	if _, ok := dicts[7]; !ok {
+		t.Fatalf("expected a dictionary for TypeSymbol 7, got keys %v", dicts)
+	}
+	if builder.dictionaryFor(7) == nil {
+		t.Errorf("expected builder.dictionaryFor(7) to return the trained dictionary")
+	}
+}
+
+func TestCompressWithDictRoundTrip(t *testing.T) {
+	dict := bytes.Repeat([]byte("dictionary-seed-content-"), 20)
+	data := append(bytes.Clone(dict), []byte("-payload-specific-bytes")...)
+
+	compressed, err := compressWithDict(data, dict, 3)
+	if err != nil {
+		t.Fatalf("compressWithDict: %v", err)
+	}
+
+	decompressed, err := decompressWithDict(nil, compressed, dict)
+	if err != nil {
+		t.Fatalf("decompressWithDict: %v", err)
+	}
+
+	if !bytes.Equal(decompressed, data) {
+		t.Fatalf("round trip mismatch")
+	}
+}
@@ -0,0 +1,238 @@
+package manifest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/zstd"
+)
+
+// ExtractFormat selects the archive container ExtractTo streams files
+// into.
+type ExtractFormat int
+
+const (
+	// ExtractFormatTar writes a tar stream via archive/tar.
+	ExtractFormatTar ExtractFormat = iota
+	// ExtractFormatZip writes a zip stream via archive/zip.
+	ExtractFormatZip
+)
+
+// zipFrameMetadataExtraID is the zip local/central-directory extra field
+// ID ExtractTo uses to carry frame/file metadata, since zip has no
+// PAX-record equivalent. It has no meaning outside this package; readers
+// that don't recognize it will simply ignore it per the zip spec.
+const zipFrameMetadataExtraID = 0x4556 // "EV"
+
+// WithParallelism sets how many goroutines Extract and ExtractTo use to
+// decompress frames concurrently. The zero value (the default) means
+// runtime.GOMAXPROCS(0).
+func WithParallelism(n int) ExtractOption {
+	return func(c *extractConfig) { c.parallelism = n }
+}
+
+// decompressedFrame is one frame's decoded bytes, tagged with its index
+// so ExtractTo's writer goroutine can restore frame order regardless of
+// which worker finished first.
+type decompressedFrame struct {
+	index uint32
+	data  []byte
+	err   error
+}
+
+// archiveEntryWriter abstracts over tar.Writer/zip.Writer so ExtractTo
+// can share one decompress-and-reorder pipeline for both formats.
+type archiveEntryWriter interface {
+	writeEntry(name string, fc FrameContent, data []byte) error
+	Close() error
+}
+
+// ExtractTo streams every file in the package into w as a single tar or
+// zip archive instead of writing individual files under a directory like
+// Extract does, which avoids the filesystem overhead of thousands of tiny
+// files (particularly slow on Windows). Entry paths follow the same
+// WithPreserveGroups layout as Extract. Frames are decompressed
+// concurrently across WithParallelism workers, but always written to the
+// archive in ascending frame order, so the output is byte-for-byte
+// deterministic no matter how many workers ran.
+func (p *Package) ExtractTo(w io.Writer, format ExtractFormat, opts ...ExtractOption) error {
+	cfg := &extractConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	concurrency := cfg.parallelism
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	var archiveWriter archiveEntryWriter
+	switch format {
+	case ExtractFormatTar:
+		archiveWriter = &tarEntryWriter{tw: tar.NewWriter(w)}
+	case ExtractFormatZip:
+		archiveWriter = &zipEntryWriter{zw: zip.NewWriter(w)}
+	default:
+		return fmt.Errorf("unknown extract format %d", format)
+	}
+
+	contentsByFrame := make(map[uint32][]FrameContent, len(p.manifest.FrameContents))
+	for _, fc := range p.manifest.FrameContents {
+		contentsByFrame[fc.FrameIndex] = append(contentsByFrame[fc.FrameIndex], fc)
+	}
+
+	var validFrames []uint32
+	for idx, frame := range p.manifest.Frames {
+		if frame.Length == 0 || frame.CompressedSize == 0 {
+			continue
+		}
+		validFrames = append(validFrames, uint32(idx))
+	}
+
+	jobs := make(chan uint32)
+	results := make(chan decompressedFrame, concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := zstd.NewCtx()
+			for idx := range jobs {
+				frame := p.manifest.Frames[idx]
+				compressed, err := p.readFrameCompressed(frame)
+				if err != nil {
+					results <- decompressedFrame{index: idx, err: err}
+					continue
+				}
+				decompressed, err := ctx.Decompress(make([]byte, frame.Length), compressed)
+				if err != nil {
+					results <- decompressedFrame{index: idx, err: fmt.Errorf("decompress frame %d: %w", idx, err)}
+					continue
+				}
+				results <- decompressedFrame{index: idx, data: decompressed}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, idx := range validFrames {
+			jobs <- idx
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[uint32]decompressedFrame, concurrency)
+	nextPos := 0
+	var firstErr error
+
+	for result := range results {
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			continue
+		}
+		pending[result.index] = result
+
+		for nextPos < len(validFrames) {
+			idx := validFrames[nextPos]
+			res, ok := pending[idx]
+			if !ok {
+				break
+			}
+			delete(pending, idx)
+			nextPos++
+
+			if firstErr != nil {
+				continue
+			}
+			for _, fc := range contentsByFrame[idx] {
+				dirParts, fileName := frameContentPathParts(fc, cfg.preserveGroups)
+				name := strings.Join(append(dirParts, fileName), "/")
+				data := res.data[fc.DataOffset : fc.DataOffset+fc.Size]
+				if err := archiveWriter.writeEntry(name, fc, data); err != nil {
+					firstErr = fmt.Errorf("write entry %s: %w", name, err)
+					break
+				}
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if nextPos != len(validFrames) {
+		return fmt.Errorf("wrote %d of %d frames", nextPos, len(validFrames))
+	}
+
+	return archiveWriter.Close()
+}
+
+// tarEntryWriter writes package files as tar entries, with per-file frame
+// index/FileSymbol/TypeSymbol carried as PAX records so downstream tools
+// can reconstruct manifest relationships without reparsing the manifest.
+type tarEntryWriter struct {
+	tw *tar.Writer
+}
+
+func (w *tarEntryWriter) writeEntry(name string, fc FrameContent, data []byte) error {
+	hdr := &tar.Header{
+		Name: name,
+		Mode: 0644,
+		Size: int64(len(data)),
+		PAXRecords: map[string]string{
+			"EVR.frame_index": strconv.FormatUint(uint64(fc.FrameIndex), 10),
+			"EVR.file_symbol": strconv.FormatInt(fc.FileSymbol, 10),
+			"EVR.type_symbol": strconv.FormatInt(fc.TypeSymbol, 10),
+		},
+	}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+func (w *tarEntryWriter) Close() error { return w.tw.Close() }
+
+// zipEntryWriter writes package files as zip entries, with per-file frame
+// index/FileSymbol/TypeSymbol packed into a private extra field (see
+// zipFrameMetadataExtraID) since zip has no PAX-record equivalent.
+type zipEntryWriter struct {
+	zw *zip.Writer
+}
+
+func (w *zipEntryWriter) writeEntry(name string, fc FrameContent, data []byte) error {
+	const extraDataSize = 4 + 8 + 8 // frame index + file symbol + type symbol
+	extra := make([]byte, 4+extraDataSize)
+	binary.LittleEndian.PutUint16(extra[0:2], zipFrameMetadataExtraID)
+	binary.LittleEndian.PutUint16(extra[2:4], extraDataSize)
+	binary.LittleEndian.PutUint32(extra[4:8], fc.FrameIndex)
+	binary.LittleEndian.PutUint64(extra[8:16], uint64(fc.FileSymbol))
+	binary.LittleEndian.PutUint64(extra[16:24], uint64(fc.TypeSymbol))
+
+	hdr := &zip.FileHeader{
+		Name:   name,
+		Method: zip.Deflate,
+		Extra:  extra,
+	}
+	fw, err := w.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+func (w *zipEntryWriter) Close() error { return w.zw.Close() }
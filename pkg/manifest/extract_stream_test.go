@@ -0,0 +1,205 @@
+package manifest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractToTarMatchesExtract(t *testing.T) {
+	dir := t.TempDir()
+	groups := makeBuildFixture(t, dir)
+
+	manifest, err := NewBuilder(dir, "pkg").Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	pkg, err := OpenPackage(manifest, filepath.Join(dir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("OpenPackage: %v", err)
+	}
+	defer pkg.Close()
+
+	outDir := t.TempDir()
+	if err := pkg.Extract(outDir); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pkg.ExtractTo(&buf, ExtractFormatTar, WithParallelism(3)); err != nil {
+		t.Fatalf("ExtractTo: %v", err)
+	}
+
+	tr := tar.NewReader(&buf)
+	count := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar.Next: %v", err)
+		}
+		count++
+
+		got, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("read tar entry %s: %v", hdr.Name, err)
+		}
+		want, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(hdr.Name)))
+		if err != nil {
+			t.Fatalf("read extracted %s: %v", hdr.Name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("tar entry %s differs from Extract output", hdr.Name)
+		}
+
+		if hdr.PAXRecords["EVR.type_symbol"] == "" {
+			t.Errorf("entry %s missing EVR.type_symbol PAX record", hdr.Name)
+		}
+	}
+
+	wantCount := 0
+	for _, group := range groups {
+		wantCount += len(group)
+	}
+	if count != wantCount {
+		t.Fatalf("got %d tar entries, want %d", count, wantCount)
+	}
+}
+
+func TestExtractToZipMatchesExtract(t *testing.T) {
+	dir := t.TempDir()
+	groups := makeBuildFixture(t, dir)
+
+	manifest, err := NewBuilder(dir, "pkg").Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	pkg, err := OpenPackage(manifest, filepath.Join(dir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("OpenPackage: %v", err)
+	}
+	defer pkg.Close()
+
+	outDir := t.TempDir()
+	if err := pkg.Extract(outDir, WithPreserveGroups(true)); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pkg.ExtractTo(&buf, ExtractFormatZip, WithPreserveGroups(true)); err != nil {
+		t.Fatalf("ExtractTo: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open zip entry %s: %v", f.Name, err)
+		}
+		got, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read zip entry %s: %v", f.Name, err)
+		}
+		want, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(f.Name)))
+		if err != nil {
+			t.Fatalf("read extracted %s: %v", f.Name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("zip entry %s differs from Extract output", f.Name)
+		}
+	}
+
+	wantCount := 0
+	for _, group := range groups {
+		wantCount += len(group)
+	}
+	if len(zr.File) != wantCount {
+		t.Fatalf("got %d zip entries, want %d", len(zr.File), wantCount)
+	}
+}
+
+func TestExtractToIsOrderedRegardlessOfParallelism(t *testing.T) {
+	dir := t.TempDir()
+	groups := makeBuildFixture(t, dir)
+
+	manifest, err := NewBuilder(dir, "pkg").Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	pkg, err := OpenPackage(manifest, filepath.Join(dir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("OpenPackage: %v", err)
+	}
+	defer pkg.Close()
+
+	var serial, parallel bytes.Buffer
+	if err := pkg.ExtractTo(&serial, ExtractFormatTar, WithParallelism(1)); err != nil {
+		t.Fatalf("ExtractTo serial: %v", err)
+	}
+	if err := pkg.ExtractTo(&parallel, ExtractFormatTar, WithParallelism(8)); err != nil {
+		t.Fatalf("ExtractTo parallel: %v", err)
+	}
+
+	if !bytes.Equal(serial.Bytes(), parallel.Bytes()) {
+		t.Fatal("ExtractTo output differs between parallelism=1 and parallelism=8")
+	}
+}
+
+func TestExtractMatchesAcrossParallelism(t *testing.T) {
+	dir := t.TempDir()
+	groups := makeBuildFixture(t, dir)
+
+	manifest, err := NewBuilder(dir, "pkg").Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	pkg, err := OpenPackage(manifest, filepath.Join(dir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("OpenPackage: %v", err)
+	}
+	defer pkg.Close()
+
+	serialOut, parallelOut := t.TempDir(), t.TempDir()
+	if err := pkg.Extract(serialOut, WithParallelism(1)); err != nil {
+		t.Fatalf("Extract serial: %v", err)
+	}
+	if err := pkg.Extract(parallelOut, WithParallelism(8)); err != nil {
+		t.Fatalf("Extract parallel: %v", err)
+	}
+
+	for _, group := range groups {
+		for _, file := range group {
+			rel := filepath.Join(fmt.Sprintf("%x", file.TypeSymbol), fmt.Sprintf("%x", file.FileSymbol))
+			want, err := os.ReadFile(file.Path)
+			if err != nil {
+				t.Fatalf("read source %s: %v", file.Path, err)
+			}
+			serialData, err := os.ReadFile(filepath.Join(serialOut, rel))
+			if err != nil {
+				t.Fatalf("read serial extracted %s: %v", rel, err)
+			}
+			parallelData, err := os.ReadFile(filepath.Join(parallelOut, rel))
+			if err != nil {
+				t.Fatalf("read parallel extracted %s: %v", rel, err)
+			}
+			if !bytes.Equal(serialData, parallelData) {
+				t.Fatalf("extracted file %s differs between parallelism=1 and parallelism=8", rel)
+			}
+			if !bytes.Equal(serialData, want) {
+				t.Fatalf("extracted file %s does not match source content", rel)
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package manifest
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultServerCacheSize is the decompressed-frame cache size Server uses
+// when SetCacheSize hasn't been called.
+const DefaultServerCacheSize = 64 * 1024 * 1024
+
+type frameCacheKey struct {
+	packageIndex uint32
+	frameIndex   uint32
+}
+
+type frameCacheEntry struct {
+	key  frameCacheKey
+	data []byte
+}
+
+// frameCache is a byte-bounded LRU cache of decompressed frames. It exists
+// so Server doesn't repay zstd decompression cost for every file served
+// out of a frame that's already been read once.
+type frameCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	curBytes int
+	order    *list.List
+	items    map[frameCacheKey]*list.Element
+}
+
+func newFrameCache(maxBytes int) *frameCache {
+	if maxBytes <= 0 {
+		maxBytes = DefaultServerCacheSize
+	}
+	return &frameCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[frameCacheKey]*list.Element),
+	}
+}
+
+func (c *frameCache) get(key frameCacheKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*frameCacheEntry).data, true
+}
+
+func (c *frameCache) put(key frameCacheKey, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.curBytes -= len(el.Value.(*frameCacheEntry).data)
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+
+	if len(data) > c.maxBytes {
+		return
+	}
+
+	el := c.order.PushFront(&frameCacheEntry{key: key, data: data})
+	c.items[key] = el
+	c.curBytes += len(data)
+
+	for c.curBytes > c.maxBytes {
+		back := c.order.Back()
+		if back == nil {
+			break
+		}
+		entry := back.Value.(*frameCacheEntry)
+		c.curBytes -= len(entry.data)
+		c.order.Remove(back)
+		delete(c.items, entry.key)
+	}
+}
@@ -0,0 +1,255 @@
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/DataDog/zstd"
+)
+
+// FS returns an fs.FS view of p using the same hex/decimal path layout
+// Extract writes to disk: "{typeSymbol}/{fileSymbol}" by default, or
+// "{frameIndex}/{typeSymbol}/{fileSymbol}" when WithPreserveGroups(true)
+// is passed. The returned value also implements fs.ReadDirFS and
+// fs.StatFS, so it works with fs.WalkDir, http.FileServerFS, io/fs.Sub,
+// and similar stdlib consumers without extracting to disk first. File
+// contents are decoded from their backing zstd frame lazily, on Open, and
+// cached so repeat opens of files sharing a frame don't repay
+// decompression cost.
+func (p *Package) FS(opts ...ExtractOption) fs.FS {
+	cfg := &extractConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	pfs := &packageFS{
+		pkg:   p,
+		files: make(map[string]FrameContent, len(p.manifest.FrameContents)),
+		dirs:  make(map[string]map[string]bool),
+		cache: newFrameCache(DefaultServerCacheSize),
+	}
+	pfs.dirs["."] = make(map[string]bool) // root always exists, even for an empty package
+	for _, fc := range p.manifest.FrameContents {
+		dirParts, fileName := frameContentPathParts(fc, cfg.preserveGroups)
+		name := path.Join(append(append([]string{}, dirParts...), fileName)...)
+		pfs.files[name] = fc
+		pfs.addDirEntries(name)
+	}
+	return pfs
+}
+
+// packageFS is the fs.FS implementation behind Package.FS.
+type packageFS struct {
+	pkg   *Package
+	files map[string]FrameContent    // full slash path -> backing content
+	dirs  map[string]map[string]bool // dir path (or "." for root) -> immediate child base names
+	cache *frameCache
+}
+
+// addDirEntries registers every ancestor directory of name, so ReadDir
+// and Stat work for intermediate path components even though the FS only
+// ever stores leaf FrameContents.
+func (pfs *packageFS) addDirEntries(name string) {
+	dir := path.Dir(name)
+	base := path.Base(name)
+	for {
+		if pfs.dirs[dir] == nil {
+			pfs.dirs[dir] = make(map[string]bool)
+		}
+		pfs.dirs[dir][base] = true
+		if dir == "." {
+			break
+		}
+		base = path.Base(dir)
+		dir = path.Dir(dir)
+	}
+}
+
+// Open implements fs.FS.
+func (pfs *packageFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if fc, ok := pfs.files[name]; ok {
+		return pfs.openFile(name, fc)
+	}
+	if _, ok := pfs.dirs[name]; ok {
+		return pfs.openDir(name)
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (pfs *packageFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	children, ok := pfs.dirs[name]
+	if !ok {
+		if _, ok := pfs.files[name]; ok {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("not a directory")}
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for base := range children {
+		entries = append(entries, pfs.dirEntry(path.Join(name, base), base))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Stat implements fs.StatFS.
+func (pfs *packageFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if fc, ok := pfs.files[name]; ok {
+		return pfs.fileInfo(path.Base(name), fc), nil
+	}
+	if _, ok := pfs.dirs[name]; ok {
+		return dirInfo{name: path.Base(name)}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (pfs *packageFS) dirEntry(fullName, base string) fs.DirEntry {
+	if fc, ok := pfs.files[fullName]; ok {
+		return fs.FileInfoToDirEntry(pfs.fileInfo(base, fc))
+	}
+	return fs.FileInfoToDirEntry(dirInfo{name: base})
+}
+
+func (pfs *packageFS) fileInfo(name string, fc FrameContent) fs.FileInfo {
+	var modTime time.Time
+	if int(fc.FrameIndex) < len(pfs.pkg.manifest.Frames) {
+		modTime, _ = pfs.pkg.frameModTime(pfs.pkg.manifest.Frames[fc.FrameIndex])
+	}
+	return fileInfo{name: name, size: int64(fc.Size), modTime: modTime}
+}
+
+func (pfs *packageFS) openDir(name string) (fs.File, error) {
+	entries, err := pfs.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return &packageDirFile{info: dirInfo{name: path.Base(name)}, entries: entries}, nil
+}
+
+func (pfs *packageFS) openFile(name string, fc FrameContent) (fs.File, error) {
+	if int(fc.FrameIndex) >= len(pfs.pkg.manifest.Frames) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("frame index %d out of range", fc.FrameIndex)}
+	}
+	frame := pfs.pkg.manifest.Frames[fc.FrameIndex]
+
+	key := frameCacheKey{packageIndex: frame.PackageIndex, frameIndex: fc.FrameIndex}
+	decompressed, ok := pfs.cache.get(key)
+	if !ok {
+		compressed, err := pfs.pkg.readFrameCompressed(frame)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		decompressed, err = zstd.Decompress(make([]byte, 0, frame.Length), compressed)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("decompress frame: %w", err)}
+		}
+		pfs.cache.put(key, decompressed)
+	}
+
+	if int64(fc.DataOffset)+int64(fc.Size) > int64(len(decompressed)) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("file extends past decompressed frame")}
+	}
+
+	return &packageOpenFile{
+		info: pfs.fileInfo(path.Base(name), fc),
+		data: decompressed[fc.DataOffset : fc.DataOffset+fc.Size],
+	}, nil
+}
+
+// fileInfo implements fs.FileInfo for a file backed by a FrameContent.
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) Mode() fs.FileMode  { return 0444 }
+func (i fileInfo) ModTime() time.Time { return i.modTime }
+func (i fileInfo) IsDir() bool        { return false }
+func (i fileInfo) Sys() any           { return nil }
+
+// dirInfo implements fs.FileInfo for a synthetic directory node (package
+// content has no on-disk directories of its own; these exist only to give
+// Stat/ReadDir something to describe).
+type dirInfo struct {
+	name string
+}
+
+func (i dirInfo) Name() string       { return i.name }
+func (i dirInfo) Size() int64        { return 0 }
+func (i dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (i dirInfo) ModTime() time.Time { return time.Time{} }
+func (i dirInfo) IsDir() bool        { return true }
+func (i dirInfo) Sys() any           { return nil }
+
+// packageOpenFile is the fs.File returned for a leaf entry: a read-only
+// view over already-decompressed bytes.
+type packageOpenFile struct {
+	info fs.FileInfo
+	data []byte
+	pos  int
+}
+
+func (f *packageOpenFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *packageOpenFile) Read(p []byte) (int, error) {
+	if f.pos >= len(f.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[f.pos:])
+	f.pos += n
+	return n, nil
+}
+
+func (f *packageOpenFile) Close() error { return nil }
+
+// packageDirFile is the fs.ReadDirFile returned for a directory node.
+type packageDirFile struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	pos     int
+}
+
+func (f *packageDirFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *packageDirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: f.info.Name(), Err: fmt.Errorf("is a directory")}
+}
+
+func (f *packageDirFile) Close() error { return nil }
+
+func (f *packageDirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		remaining := f.entries[f.pos:]
+		f.pos = len(f.entries)
+		return remaining, nil
+	}
+	if f.pos >= len(f.entries) {
+		return nil, io.EOF
+	}
+	end := f.pos + n
+	if end > len(f.entries) {
+		end = len(f.entries)
+	}
+	out := f.entries[f.pos:end]
+	f.pos = end
+	return out, nil
+}
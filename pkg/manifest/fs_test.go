@@ -0,0 +1,150 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func makeFSFixture(t *testing.T) *Package {
+	t.Helper()
+
+	dir := t.TempDir()
+	groups := makeBuildFixture(t, dir)
+
+	manifest, err := NewBuilder(dir, "pkg").Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	pkg, err := OpenPackage(manifest, filepath.Join(dir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("OpenPackage: %v", err)
+	}
+	t.Cleanup(func() { pkg.Close() })
+
+	return pkg
+}
+
+func TestFSOpenMatchesExtract(t *testing.T) {
+	pkg := makeFSFixture(t)
+	pkgFS := pkg.FS()
+
+	outDir := t.TempDir()
+	if err := pkg.Extract(outDir); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	for _, fc := range pkg.manifest.FrameContents {
+		name := fmt.Sprintf("%x/%x", fc.TypeSymbol, fc.FileSymbol)
+
+		want, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("read extracted %s: %v", name, err)
+		}
+		got, err := fs.ReadFile(pkgFS, name)
+		if err != nil {
+			t.Fatalf("fs.ReadFile(%s): %v", name, err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("FS content for %s differs from Extract output", name)
+		}
+	}
+}
+
+func TestFSWithPreserveGroupsMatchesExtractLayout(t *testing.T) {
+	pkg := makeFSFixture(t)
+	pkgFS := pkg.FS(WithPreserveGroups(true))
+
+	outDir := t.TempDir()
+	if err := pkg.Extract(outDir, WithPreserveGroups(true)); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	for _, fc := range pkg.manifest.FrameContents {
+		name := fmt.Sprintf("%d/%x/%x", fc.FrameIndex, fc.TypeSymbol, fc.FileSymbol)
+
+		want, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("read extracted %s: %v", name, err)
+		}
+		got, err := fs.ReadFile(pkgFS, name)
+		if err != nil {
+			t.Fatalf("fs.ReadFile(%s): %v", name, err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("FS content for %s differs from Extract output", name)
+		}
+	}
+}
+
+func TestFSWalkDirVisitsEveryFile(t *testing.T) {
+	pkg := makeFSFixture(t)
+	pkgFS := pkg.FS()
+
+	var files []string
+	if err := fs.WalkDir(pkgFS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("WalkDir: %v", err)
+	}
+
+	sort.Strings(files)
+	if len(files) != len(pkg.manifest.FrameContents) {
+		t.Fatalf("walked %d files, want %d", len(files), len(pkg.manifest.FrameContents))
+	}
+}
+
+func TestFSStatAndReadDir(t *testing.T) {
+	pkg := makeFSFixture(t)
+	pkgFS := pkg.FS()
+
+	fc := pkg.manifest.FrameContents[0]
+	typeDir := fmt.Sprintf("%x", fc.TypeSymbol)
+	name := fmt.Sprintf("%s/%x", typeDir, fc.FileSymbol)
+
+	info, err := fs.Stat(pkgFS, name)
+	if err != nil {
+		t.Fatalf("Stat(%s): %v", name, err)
+	}
+	if info.IsDir() {
+		t.Errorf("Stat(%s): got IsDir() = true, want false", name)
+	}
+	if uint32(info.Size()) != fc.Size {
+		t.Errorf("Stat(%s): got size %d, want %d", name, info.Size(), fc.Size)
+	}
+
+	dirInfo, err := fs.Stat(pkgFS, typeDir)
+	if err != nil {
+		t.Fatalf("Stat(%s): %v", typeDir, err)
+	}
+	if !dirInfo.IsDir() {
+		t.Errorf("Stat(%s): got IsDir() = false, want true", typeDir)
+	}
+
+	entries, err := fs.ReadDir(pkgFS, typeDir)
+	if err != nil {
+		t.Fatalf("ReadDir(%s): %v", typeDir, err)
+	}
+	if len(entries) == 0 {
+		t.Errorf("ReadDir(%s): got no entries", typeDir)
+	}
+}
+
+func TestFSOpenMissingReturnsNotExist(t *testing.T) {
+	pkg := makeFSFixture(t)
+	pkgFS := pkg.FS()
+
+	if _, err := pkgFS.Open("ff/ff"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v, want fs.ErrNotExist", err)
+	}
+}
@@ -114,7 +114,10 @@ func (m *Manifest) UnmarshalBinary(data []byte) error {
 	offset += SectionSize
 
 	// Decode FrameContents
-	count := int(m.Header.FrameContents.ElementCount)
+	count, err := sectionCount("FrameContents", m.Header.FrameContents.ElementCount, FrameContentSize, offset, len(data))
+	if err != nil {
+		return err
+	}
 	m.FrameContents = make([]FrameContent, count)
 	for i := 0; i < count; i++ {
 		m.FrameContents[i].TypeSymbol = int64(binary.LittleEndian.Uint64(data[offset:]))
@@ -127,7 +130,10 @@ func (m *Manifest) UnmarshalBinary(data []byte) error {
 	}
 
 	// Decode Metadata
-	count = int(m.Header.Metadata.ElementCount)
+	count, err = sectionCount("Metadata", m.Header.Metadata.ElementCount, FileMetadataSize, offset, len(data))
+	if err != nil {
+		return err
+	}
 	m.Metadata = make([]FileMetadata, count)
 	for i := 0; i < count; i++ {
 		m.Metadata[i].TypeSymbol = int64(binary.LittleEndian.Uint64(data[offset:]))
@@ -139,7 +145,10 @@ func (m *Manifest) UnmarshalBinary(data []byte) error {
 	}
 
 	// Decode Frames
-	count = int(m.Header.Frames.ElementCount)
+	count, err = sectionCount("Frames", m.Header.Frames.ElementCount, FrameSize, offset, len(data))
+	if err != nil {
+		return err
+	}
 	m.Frames = make([]Frame, count)
 	for i := 0; i < count; i++ {
 		m.Frames[i].PackageIndex = binary.LittleEndian.Uint32(data[offset:])
@@ -152,6 +161,28 @@ func (m *Manifest) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// sectionCount validates a section's declared element count against the
+// bytes actually available in data at offset before UnmarshalBinary
+// indexes into it, so a truncated or count-inflated manifest returns an
+// error instead of panicking on an out-of-bounds slice access.
+func sectionCount(name string, elementCount uint64, elementSize, offset, dataLen int) (int, error) {
+	if offset > dataLen {
+		return 0, fmt.Errorf("%s: offset %d past end of data (%d bytes)", name, offset, dataLen)
+	}
+	// elementCount can't exceed the number of bytes remaining, since every
+	// element is at least 1 byte; this also guarantees the int(elementCount)
+	// conversion below can't overflow or go negative.
+	if elementCount > uint64(dataLen-offset) {
+		return 0, fmt.Errorf("%s: element count %d exceeds remaining data (%d bytes)", name, elementCount, dataLen-offset)
+	}
+	count := int(elementCount)
+	need := int64(count) * int64(elementSize)
+	if need > int64(dataLen-offset) {
+		return 0, fmt.Errorf("%s: truncated data: need %d bytes at offset %d, have %d", name, need, offset, dataLen-offset)
+	}
+	return count, nil
+}
+
 func decodeSection(s *Section, data []byte) {
 	s.Length = binary.LittleEndian.Uint64(data[0:])
 	s.Unk1 = binary.LittleEndian.Uint64(data[8:])
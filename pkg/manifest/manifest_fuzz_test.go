@@ -0,0 +1,64 @@
+package manifest
+
+import "testing"
+
+// FuzzManifestUnmarshalBinary feeds arbitrary byte streams through
+// UnmarshalBinary, seeded with real manifest byte layouts. Before
+// sectionCount was added, a truncated or count-inflated section would
+// index past len(data) and panic; this asserts that never happens again.
+func FuzzManifestUnmarshalBinary(f *testing.F) {
+	valid := &Manifest{
+		Header: Header{
+			PackageCount: 2,
+			FrameContents: Section{
+				Length:       64,
+				ElementSize:  32,
+				Count:        2,
+				ElementCount: 2,
+			},
+			Metadata: Section{
+				Length:       80,
+				ElementSize:  40,
+				Count:        2,
+				ElementCount: 2,
+			},
+			Frames: Section{
+				Length:       32,
+				ElementSize:  16,
+				Count:        2,
+				ElementCount: 2,
+			},
+		},
+		FrameContents: []FrameContent{
+			{TypeSymbol: 100, FileSymbol: 200, FrameIndex: 0, DataOffset: 0, Size: 1024, Alignment: 1},
+			{TypeSymbol: 101, FileSymbol: 201, FrameIndex: 1, DataOffset: 0, Size: 2048, Alignment: 1},
+		},
+		Metadata: []FileMetadata{
+			{TypeSymbol: 100, FileSymbol: 200},
+			{TypeSymbol: 101, FileSymbol: 201},
+		},
+		Frames: []Frame{
+			{PackageIndex: 0, Offset: 0, CompressedSize: 512, Length: 1024},
+			{PackageIndex: 0, Offset: 512, CompressedSize: 1024, Length: 2048},
+		},
+	}
+	data, err := valid.MarshalBinary()
+	if err != nil {
+		f.Fatalf("seed marshal: %v", err)
+	}
+	f.Add(data)
+	f.Add(data[:HeaderSize])
+	f.Add(data[:len(data)-1])
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("UnmarshalBinary panicked on %q: %v", data, r)
+			}
+		}()
+
+		m := &Manifest{}
+		_ = m.UnmarshalBinary(data)
+	})
+}
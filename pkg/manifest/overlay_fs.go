@@ -0,0 +1,182 @@
+package manifest
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OverlayFS layers copy-on-write staging over a Package's read-only FS
+// (see Package.FS): writes accumulate in memory, keyed by the same
+// "{typeSymbol}/{fileSymbol}" paths Package.FS exposes, and only reach
+// the package's manifest and package files once Commit feeds them
+// through QuickRepack. This lets tooling and tests read and edit a
+// package through the standard io/fs interfaces - fs.WalkDir,
+// fs.ReadFile, io.Copy, and so on - without the current pattern of
+// exporting the whole package to disk first.
+//
+// Like QuickRepack itself, OverlayFS can only overwrite the content of a
+// TypeSymbol/FileSymbol pair that already exists in the package; it has
+// no way to introduce a brand new one.
+type OverlayFS struct {
+	pkg         *Package
+	dataDir     string
+	packageName string
+	base        fs.FS
+
+	mu     sync.Mutex
+	staged map[string][]byte
+}
+
+// NewOverlayFS wraps pkg, which must have been opened with OpenPackage
+// against dataDir/packageName, with a copy-on-write staging layer. pkg
+// must stay open for as long as the OverlayFS is read from.
+func NewOverlayFS(pkg *Package, dataDir, packageName string) *OverlayFS {
+	return &OverlayFS{
+		pkg:         pkg,
+		dataDir:     dataDir,
+		packageName: packageName,
+		base:        pkg.FS(),
+		staged:      make(map[string][]byte),
+	}
+}
+
+// Open implements fs.FS, preferring a staged write over the package's
+// committed content.
+func (o *OverlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	o.mu.Lock()
+	data, staged := o.staged[name]
+	o.mu.Unlock()
+	if !staged {
+		return o.base.Open(name)
+	}
+
+	return &packageOpenFile{
+		info: fileInfo{name: path.Base(name), size: int64(len(data)), modTime: time.Now()},
+		data: data,
+	}, nil
+}
+
+// ReadDir implements fs.ReadDirFS by delegating to the underlying
+// Package.FS listing; OverlayFS never adds a name ReadDir wouldn't
+// already report, since it can only overwrite existing entries.
+func (o *OverlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(o.base, name)
+}
+
+// Stat implements fs.StatFS, reporting a staged write's in-memory size
+// in place of the committed one.
+func (o *OverlayFS) Stat(name string) (fs.FileInfo, error) {
+	o.mu.Lock()
+	data, staged := o.staged[name]
+	o.mu.Unlock()
+	if !staged {
+		return fs.Stat(o.base, name)
+	}
+	if _, err := fs.Stat(o.base, name); err != nil {
+		return nil, err
+	}
+	return fileInfo{name: path.Base(name), size: int64(len(data)), modTime: time.Now()}, nil
+}
+
+// WriteFile stages data as the new content for name, which must already
+// exist in the underlying package. The write is only visible through
+// Open/Stat/ReadFile until Commit persists it; Commit is what actually
+// touches the package on disk.
+func (o *OverlayFS) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	if _, err := fs.Stat(o.base, name); err != nil {
+		return fmt.Errorf("stage %s: %w", name, err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.staged[name] = append([]byte(nil), data...)
+	return nil
+}
+
+// Pending reports how many staged writes Commit would persist.
+func (o *OverlayFS) Pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.staged)
+}
+
+// Commit writes every staged change through QuickRepack and clears the
+// staging area. QuickRepack rewrites the manifest and package files in
+// place, so pkg (and any OverlayFS built from it) is stale afterward -
+// the caller must reopen the package, the same as every other
+// QuickRepack caller does, to read what was just committed.
+func (o *OverlayFS) Commit(opts ...RepackOption) error {
+	o.mu.Lock()
+	staged := o.staged
+	o.staged = make(map[string][]byte)
+	o.mu.Unlock()
+
+	if len(staged) == 0 {
+		return nil
+	}
+
+	stageDir, err := os.MkdirTemp("", "overlayfs-commit-*")
+	if err != nil {
+		return fmt.Errorf("create staging dir: %w", err)
+	}
+	defer os.RemoveAll(stageDir)
+
+	group := make([]ScannedFile, 0, len(staged))
+	for name, data := range staged {
+		typeSymbol, fileSymbol, err := parseOverlayPath(name)
+		if err != nil {
+			return err
+		}
+
+		stagePath := filepath.Join(stageDir, fmt.Sprintf("%d_%d", typeSymbol, fileSymbol))
+		if err := os.WriteFile(stagePath, data, 0644); err != nil {
+			return fmt.Errorf("stage %s: %w", name, err)
+		}
+
+		group = append(group, ScannedFile{
+			TypeSymbol: typeSymbol,
+			FileSymbol: fileSymbol,
+			Path:       stagePath,
+			Size:       uint32(len(data)),
+		})
+	}
+
+	_, err = (&Repacker{}).QuickRepack(o.pkg.Manifest(), [][]ScannedFile{group}, o.dataDir, o.packageName, opts...)
+	return err
+}
+
+// parseOverlayPath recovers the TypeSymbol/FileSymbol pair Package.FS
+// encoded as name ("{typeSymbol}/{fileSymbol}", both hex, see
+// frameContentPathParts), so Commit can hand QuickRepack a ScannedFile
+// describing it.
+func parseOverlayPath(name string) (typeSymbol, fileSymbol int64, err error) {
+	dir, file := path.Split(name)
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "" || file == "" || strings.Contains(dir, "/") {
+		return 0, 0, fmt.Errorf("path %q is not a type/file entry", name)
+	}
+
+	typeSymbol, err = strconv.ParseInt(dir, 16, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("path %q: parse type symbol: %w", name, err)
+	}
+	fileSymbol, err = strconv.ParseInt(file, 16, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("path %q: parse file symbol: %w", name, err)
+	}
+	return typeSymbol, fileSymbol, nil
+}
@@ -0,0 +1,145 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+func makeOverlayFixture(t *testing.T) (dataDir string, pkg *Package) {
+	t.Helper()
+
+	dataDir = t.TempDir()
+	groups := makeBuildFixture(t, dataDir)
+
+	manifest, err := NewBuilder(dataDir, "pkg").Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	pkg, err = OpenPackage(manifest, filepath.Join(dataDir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("OpenPackage: %v", err)
+	}
+	t.Cleanup(func() { pkg.Close() })
+
+	return dataDir, pkg
+}
+
+func TestOverlayFSOpenPrefersStagedOverCommitted(t *testing.T) {
+	dataDir, pkg := makeOverlayFixture(t)
+	overlay := NewOverlayFS(pkg, dataDir, "pkg")
+
+	const name = "1/1"
+	original, err := fs.ReadFile(overlay, name)
+	if err != nil {
+		t.Fatalf("ReadFile before staging: %v", err)
+	}
+
+	staged := append(append([]byte(nil), original...), "-staged"...)
+	if err := overlay.WriteFile(name, staged); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	got, err := fs.ReadFile(overlay, name)
+	if err != nil {
+		t.Fatalf("ReadFile after staging: %v", err)
+	}
+	if !bytes.Equal(got, staged) {
+		t.Fatalf("got %q, want staged content %q", got, staged)
+	}
+
+	info, err := fs.Stat(overlay, name)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size() != int64(len(staged)) {
+		t.Errorf("got size %d, want %d", info.Size(), len(staged))
+	}
+
+	if overlay.Pending() != 1 {
+		t.Errorf("got %d pending writes, want 1", overlay.Pending())
+	}
+}
+
+func TestOverlayFSWriteFileRejectsUnknownPath(t *testing.T) {
+	dataDir, pkg := makeOverlayFixture(t)
+	overlay := NewOverlayFS(pkg, dataDir, "pkg")
+
+	if err := overlay.WriteFile("ff/ff", []byte("data")); err == nil {
+		t.Fatal("expected an error staging a TypeSymbol/FileSymbol pair absent from the package")
+	}
+}
+
+func TestOverlayFSCommitPersistsViaQuickRepack(t *testing.T) {
+	dataDir, pkg := makeOverlayFixture(t)
+	overlay := NewOverlayFS(pkg, dataDir, "pkg")
+
+	const name = "1/1"
+	newContent := bytes.Repeat([]byte("overlay-committed-content "), 20)
+	if err := overlay.WriteFile(name, newContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := overlay.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if overlay.Pending() != 0 {
+		t.Errorf("got %d pending writes after Commit, want 0", overlay.Pending())
+	}
+
+	pkg.Close()
+
+	manifestPath := filepath.Join(dataDir, "manifests", "pkg")
+	committed, err := ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("read committed manifest: %v", err)
+	}
+	committedPkg, err := OpenPackage(committed, filepath.Join(dataDir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("open committed package: %v", err)
+	}
+	defer committedPkg.Close()
+
+	got, err := fs.ReadFile(committedPkg.FS(), name)
+	if err != nil {
+		t.Fatalf("ReadFile after commit: %v", err)
+	}
+	if !bytes.Equal(got, newContent) {
+		t.Fatalf("got %q, want %q", got, newContent)
+	}
+}
+
+func TestOverlayFSCommitWithNoStagedWritesIsNoOp(t *testing.T) {
+	dataDir, pkg := makeOverlayFixture(t)
+	overlay := NewOverlayFS(pkg, dataDir, "pkg")
+
+	if err := overlay.Commit(); err != nil {
+		t.Fatalf("Commit with nothing staged: %v", err)
+	}
+}
+
+func TestParseOverlayPathRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		typeSymbol, fileSymbol int64
+	}{
+		{1, 0}, {4, 399}, {0xabc, 0xdef},
+	} {
+		name := fmt.Sprintf("%x/%x", tc.typeSymbol, tc.fileSymbol)
+		gotType, gotFile, err := parseOverlayPath(name)
+		if err != nil {
+			t.Fatalf("parseOverlayPath(%q): %v", name, err)
+		}
+		if gotType != tc.typeSymbol || gotFile != tc.fileSymbol {
+			t.Errorf("parseOverlayPath(%q) = (%d, %d), want (%d, %d)", name, gotType, gotFile, tc.typeSymbol, tc.fileSymbol)
+		}
+	}
+
+	if _, _, err := parseOverlayPath("not-a-hex-pair"); err == nil {
+		t.Fatal("expected error for a path with no type/file separator")
+	}
+	if _, _, err := parseOverlayPath("too/many/parts"); err == nil {
+		t.Fatal("expected error for a path with more than one directory component")
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
 
 	"github.com/DataDog/zstd"
 )
@@ -20,6 +23,7 @@ type packageFile interface {
 	io.ReaderAt
 	io.Seeker
 	io.Closer
+	Stat() (os.FileInfo, error)
 }
 
 // OpenPackage opens a multi-part package from the given base path.
@@ -65,81 +69,149 @@ func (p *Package) Manifest() *Manifest {
 	return p.manifest
 }
 
-// Extract extracts all files from the package to the output directory.
-func (p *Package) Extract(outputDir string, opts ...ExtractOption) error {
-	cfg := &extractConfig{}
-	for _, opt := range opts {
-		opt(cfg)
+// readFrameCompressed reads frame's raw compressed bytes via ReadAt, so it
+// is safe to call concurrently across goroutines sharing the same
+// Package, as Extract and ExtractTo's worker pools both do.
+func (p *Package) readFrameCompressed(frame Frame) ([]byte, error) {
+	if int(frame.PackageIndex) >= len(p.files) {
+		return nil, fmt.Errorf("package index %d out of range", frame.PackageIndex)
+	}
+	if frame.CompressedSize == 0 {
+		return nil, fmt.Errorf("frame has no data")
 	}
 
-	ctx := zstd.NewCtx()
-	compressed := make([]byte, 32*1024*1024)
-	decompressed := make([]byte, 32*1024*1024)
-	filesWritten := 0
-
-	for frameIdx, frame := range p.manifest.Frames {
-		if frame.Length == 0 || frame.CompressedSize == 0 {
-			continue
-		}
-
-		// Ensure buffers are large enough
-		if int(frame.CompressedSize) > len(compressed) {
-			compressed = make([]byte, frame.CompressedSize)
-		}
-		if int(frame.Length) > len(decompressed) {
-			decompressed = make([]byte, frame.Length)
-		}
+	buf := make([]byte, frame.CompressedSize)
+	if _, err := p.files[frame.PackageIndex].ReadAt(buf, int64(frame.Offset)); err != nil {
+		return nil, fmt.Errorf("read frame: %w", err)
+	}
+	return buf, nil
+}
 
-		// Read compressed data
-		file := p.files[frame.PackageIndex]
-		if _, err := file.Seek(int64(frame.Offset), io.SeekStart); err != nil {
-			return fmt.Errorf("seek frame %d: %w", frameIdx, err)
-		}
+// ReadContent decompresses fc's backing frame and returns just fc's
+// slice of it, the same per-entry decode FS and QuickRepack's identical-
+// file check need.
+func (p *Package) ReadContent(fc *FrameContent) ([]byte, error) {
+	if int(fc.FrameIndex) >= len(p.manifest.Frames) {
+		return nil, fmt.Errorf("frame index %d out of range", fc.FrameIndex)
+	}
+	frame := p.manifest.Frames[fc.FrameIndex]
 
-		if _, err := io.ReadFull(file, compressed[:frame.CompressedSize]); err != nil {
-			return fmt.Errorf("read frame %d: %w", frameIdx, err)
-		}
+	compressed, err := p.readFrameCompressed(frame)
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := zstd.Decompress(make([]byte, 0, frame.Length), compressed)
+	if err != nil {
+		return nil, fmt.Errorf("decompress frame %d: %w", fc.FrameIndex, err)
+	}
 
-		// Decompress
-		if _, err := ctx.Decompress(decompressed[:frame.Length], compressed[:frame.CompressedSize]); err != nil {
-			return fmt.Errorf("decompress frame %d: %w", frameIdx, err)
-		}
+	if int64(fc.DataOffset)+int64(fc.Size) > int64(len(decompressed)) {
+		return nil, fmt.Errorf("content extends past decompressed frame")
+	}
+	return decompressed[fc.DataOffset : fc.DataOffset+fc.Size], nil
+}
 
-		// Extract files from this frame
-		for _, fc := range p.manifest.FrameContents {
-			if fc.FrameIndex != uint32(frameIdx) {
-				continue
-			}
+// frameModTime returns the modification time of the package file backing
+// frame. The manifest format has no per-file timestamps, so this is the
+// closest available signal for an HTTP Last-Modified header.
+func (p *Package) frameModTime(frame Frame) (time.Time, bool) {
+	if int(frame.PackageIndex) >= len(p.files) {
+		return time.Time{}, false
+	}
+	info, err := p.files[frame.PackageIndex].Stat()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
 
-			fileName := fmt.Sprintf("%x", fc.FileSymbol)
-			fileType := fmt.Sprintf("%x", fc.TypeSymbol)
+// Extract extracts all files from the package to the output directory.
+// Unlike ExtractTo, file writes don't need to be reordered (each file is
+// its own independent write, not a position in a single output stream),
+// so frames are decompressed and written entirely within WithParallelism
+// worker goroutines with no reorder stage.
+func (p *Package) Extract(outputDir string, opts ...ExtractOption) error {
+	cfg := &extractConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	concurrency := cfg.parallelism
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
 
-			var basePath string
-			if cfg.preserveGroups {
-				basePath = filepath.Join(outputDir, fmt.Sprintf("%d", fc.FrameIndex), fileType)
-			} else {
-				basePath = filepath.Join(outputDir, fileType)
-			}
+	contentsByFrame := make(map[uint32][]FrameContent, len(p.manifest.FrameContents))
+	for _, fc := range p.manifest.FrameContents {
+		contentsByFrame[fc.FrameIndex] = append(contentsByFrame[fc.FrameIndex], fc)
+	}
 
-			if err := os.MkdirAll(basePath, 0755); err != nil {
-				return fmt.Errorf("create dir %s: %w", basePath, err)
-			}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var firstErr error
+	var errMu sync.Mutex
+	setErr := func(err error) {
+		errMu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		errMu.Unlock()
+	}
 
-			filePath := filepath.Join(basePath, fileName)
-			if err := os.WriteFile(filePath, decompressed[fc.DataOffset:fc.DataOffset+fc.Size], 0644); err != nil {
-				return fmt.Errorf("write file %s: %w", filePath, err)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := zstd.NewCtx()
+			for frameIdx := range jobs {
+				frame := p.manifest.Frames[frameIdx]
+
+				compressed, err := p.readFrameCompressed(frame)
+				if err != nil {
+					setErr(fmt.Errorf("read frame %d: %w", frameIdx, err))
+					continue
+				}
+				decompressed, err := ctx.Decompress(make([]byte, frame.Length), compressed)
+				if err != nil {
+					setErr(fmt.Errorf("decompress frame %d: %w", frameIdx, err))
+					continue
+				}
+
+				for _, fc := range contentsByFrame[uint32(frameIdx)] {
+					dirParts, fileName := frameContentPathParts(fc, cfg.preserveGroups)
+					basePath := filepath.Join(append([]string{outputDir}, dirParts...)...)
+
+					if err := os.MkdirAll(basePath, 0755); err != nil {
+						setErr(fmt.Errorf("create dir %s: %w", basePath, err))
+						break
+					}
+
+					filePath := filepath.Join(basePath, fileName)
+					data := decompressed[fc.DataOffset : fc.DataOffset+fc.Size]
+					if err := os.WriteFile(filePath, data, 0644); err != nil {
+						setErr(fmt.Errorf("write file %s: %w", filePath, err))
+						break
+					}
+				}
 			}
+		}()
+	}
 
-			filesWritten++
+	for frameIdx, frame := range p.manifest.Frames {
+		if frame.Length == 0 || frame.CompressedSize == 0 {
+			continue
 		}
+		jobs <- frameIdx
 	}
+	close(jobs)
+	wg.Wait()
 
-	return nil
+	return firstErr
 }
 
 // extractConfig holds extraction options.
 type extractConfig struct {
 	preserveGroups bool
+	parallelism    int
 }
 
 // ExtractOption configures extraction behavior.
@@ -151,3 +223,16 @@ func WithPreserveGroups(preserve bool) ExtractOption {
 		c.preserveGroups = preserve
 	}
 }
+
+// frameContentPathParts returns the directory components and file name
+// fc is written under, shared by Extract's on-disk layout and ExtractTo's
+// archive entry names so WithPreserveGroups means the same thing for
+// both.
+func frameContentPathParts(fc FrameContent, preserveGroups bool) (dirParts []string, fileName string) {
+	fileName = fmt.Sprintf("%x", fc.FileSymbol)
+	fileType := fmt.Sprintf("%x", fc.TypeSymbol)
+	if preserveGroups {
+		return []string{fmt.Sprintf("%d", fc.FrameIndex), fileType}, fileName
+	}
+	return []string{fileType}, fileName
+}
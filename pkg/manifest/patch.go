@@ -0,0 +1,569 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/DataDog/zstd"
+)
+
+// PatchOp identifies how a PatchEntry's TypeSymbol/FileSymbol pair
+// changed between the old and new package DiffPackages compared.
+type PatchOp uint8
+
+const (
+	PatchOpCarry   PatchOp = iota // unchanged - ApplyPatch reads it straight from the base package
+	PatchOpAdd                    // new pair, absent from the base package
+	PatchOpReplace                // changed too much for a delta to be worth shipping - full new content
+	PatchOpDelta                  // changed - Data is a chunk-delta against the base content
+	PatchOpRemove                 // present in the base package, absent from the new one
+)
+
+// PatchEntry describes one TypeSymbol/FileSymbol pair's fate.
+type PatchEntry struct {
+	TypeSymbol int64
+	FileSymbol int64
+	Op         PatchOp
+
+	// NewFrameIndex is which frame this pair belongs to in the new
+	// package (unused for PatchOpRemove). ApplyPatch groups entries by
+	// NewFrameIndex when rebuilding, so the patched package keeps the
+	// frame grouping the new package had.
+	NewFrameIndex uint32
+
+	// NewSize is the pair's decompressed content size in the new package
+	// (unused for PatchOpRemove).
+	NewSize uint32
+
+	// Data is Op-specific and zstd-compressed: empty for PatchOpCarry/
+	// PatchOpRemove, the full content for PatchOpAdd/PatchOpReplace, or
+	// an encoded deltaInstruction stream (see encodeDelta) for
+	// PatchOpDelta.
+	Data []byte
+}
+
+// Patch is the in-memory form of a patch file written by DiffPackages
+// and consumed by ApplyPatch.
+type Patch struct {
+	PackageName string
+
+	// NewPackageCount records the new manifest's Header.PackageCount.
+	// ApplyPatch never splices this into anything - it rebuilds package
+	// files from scratch via Builder - it's carried only so a reader of
+	// the patch file can see a package rotation happened without
+	// decoding every entry.
+	NewPackageCount uint32
+
+	Entries []PatchEntry
+}
+
+const patchMagic = "EVRPTCH1"
+
+// WriteFile serializes the patch to path, creating its directory if
+// needed.
+func (p *Patch) WriteFile(path string) error {
+	var buf bytes.Buffer
+	buf.WriteString(patchMagic)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(p.PackageName)))
+	buf.WriteString(p.PackageName)
+	binary.Write(&buf, binary.LittleEndian, p.NewPackageCount)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(p.Entries)))
+	for _, e := range p.Entries {
+		binary.Write(&buf, binary.LittleEndian, e.TypeSymbol)
+		binary.Write(&buf, binary.LittleEndian, e.FileSymbol)
+		buf.WriteByte(byte(e.Op))
+		binary.Write(&buf, binary.LittleEndian, e.NewFrameIndex)
+		binary.Write(&buf, binary.LittleEndian, e.NewSize)
+		binary.Write(&buf, binary.LittleEndian, uint32(len(e.Data)))
+		buf.Write(e.Data)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create patch dir: %w", err)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// ReadPatch reads a patch previously written by Patch.WriteFile.
+func ReadPatch(path string) (*Patch, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read patch: %w", err)
+	}
+	if len(raw) < len(patchMagic) || string(raw[:len(patchMagic)]) != patchMagic {
+		return nil, fmt.Errorf("invalid patch magic")
+	}
+	r := bytes.NewReader(raw[len(patchMagic):])
+
+	var nameLen uint32
+	if err := binary.Read(r, binary.LittleEndian, &nameLen); err != nil {
+		return nil, fmt.Errorf("read package name length: %w", err)
+	}
+	nameBuf := make([]byte, nameLen)
+	if _, err := io.ReadFull(r, nameBuf); err != nil {
+		return nil, fmt.Errorf("read package name: %w", err)
+	}
+
+	patch := &Patch{PackageName: string(nameBuf)}
+	if err := binary.Read(r, binary.LittleEndian, &patch.NewPackageCount); err != nil {
+		return nil, fmt.Errorf("read package count: %w", err)
+	}
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("read entry count: %w", err)
+	}
+
+	patch.Entries = make([]PatchEntry, count)
+	for i := range patch.Entries {
+		e := &patch.Entries[i]
+		if err := binary.Read(r, binary.LittleEndian, &e.TypeSymbol); err != nil {
+			return nil, fmt.Errorf("read entry %d type symbol: %w", i, err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &e.FileSymbol); err != nil {
+			return nil, fmt.Errorf("read entry %d file symbol: %w", i, err)
+		}
+		opByte, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("read entry %d op: %w", i, err)
+		}
+		e.Op = PatchOp(opByte)
+		if err := binary.Read(r, binary.LittleEndian, &e.NewFrameIndex); err != nil {
+			return nil, fmt.Errorf("read entry %d frame index: %w", i, err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &e.NewSize); err != nil {
+			return nil, fmt.Errorf("read entry %d new size: %w", i, err)
+		}
+		var dataLen uint32
+		if err := binary.Read(r, binary.LittleEndian, &dataLen); err != nil {
+			return nil, fmt.Errorf("read entry %d data length: %w", i, err)
+		}
+		e.Data = make([]byte, dataLen)
+		if _, err := io.ReadFull(r, e.Data); err != nil {
+			return nil, fmt.Errorf("read entry %d data: %w", i, err)
+		}
+	}
+
+	return patch, nil
+}
+
+// fcKey identifies a FrameContent by its TypeSymbol/FileSymbol pair,
+// same as the [128]byte lookup keys repack.go builds, but sized for a
+// plain map key here since patch.go doesn't need repack.go's binary
+// encoding.
+type fcKey struct {
+	typeSymbol int64
+	fileSymbol int64
+}
+
+// DiffPackages compares packageName between oldDir and newDir and writes
+// a patch describing the difference to outPatch: for a pair whose
+// content changed, a content-defined-chunk delta against the old bytes
+// (see diffContent), or a full replacement if the delta doesn't end up
+// smaller; for a pair only in the new package, the new content in full;
+// for a pair only in the old package, a removal marker; for a pair whose
+// content didn't change at all, nothing but the marker itself - ApplyPatch
+// reads those straight from oldDir.
+//
+// This is meant for shipping package updates: a patch touching a
+// handful of files out of a large package stays small, instead of
+// re-sending every package file after each Repack/QuickRepack.
+func DiffPackages(oldDir, newDir, packageName, outPatch string) error {
+	oldManifest, err := ReadFile(filepath.Join(oldDir, "manifests", packageName))
+	if err != nil {
+		return fmt.Errorf("read old manifest: %w", err)
+	}
+	newManifest, err := ReadFile(filepath.Join(newDir, "manifests", packageName))
+	if err != nil {
+		return fmt.Errorf("read new manifest: %w", err)
+	}
+
+	oldPkg, err := OpenPackage(oldManifest, filepath.Join(oldDir, "packages", packageName))
+	if err != nil {
+		return fmt.Errorf("open old package: %w", err)
+	}
+	defer oldPkg.Close()
+	newPkg, err := OpenPackage(newManifest, filepath.Join(newDir, "packages", packageName))
+	if err != nil {
+		return fmt.Errorf("open new package: %w", err)
+	}
+	defer newPkg.Close()
+
+	oldByKey := make(map[fcKey]FrameContent, len(oldManifest.FrameContents))
+	for _, fc := range oldManifest.FrameContents {
+		oldByKey[fcKey{fc.TypeSymbol, fc.FileSymbol}] = fc
+	}
+	newByKey := make(map[fcKey]bool, len(newManifest.FrameContents))
+
+	params := defaultChunkParams()
+	patch := &Patch{PackageName: packageName, NewPackageCount: newManifest.Header.PackageCount}
+
+	for _, newFC := range newManifest.FrameContents {
+		key := fcKey{newFC.TypeSymbol, newFC.FileSymbol}
+		newByKey[key] = true
+
+		entry := PatchEntry{
+			TypeSymbol:    newFC.TypeSymbol,
+			FileSymbol:    newFC.FileSymbol,
+			NewFrameIndex: newFC.FrameIndex,
+			NewSize:       newFC.Size,
+		}
+
+		oldFC, existed := oldByKey[key]
+		if !existed {
+			newData, err := newPkg.ReadContent(&newFC)
+			if err != nil {
+				return fmt.Errorf("read new content for %d/%d: %w", newFC.TypeSymbol, newFC.FileSymbol, err)
+			}
+			entry.Op = PatchOpAdd
+			if entry.Data, err = compressPatchData(newData); err != nil {
+				return fmt.Errorf("compress content for %d/%d: %w", newFC.TypeSymbol, newFC.FileSymbol, err)
+			}
+			patch.Entries = append(patch.Entries, entry)
+			continue
+		}
+
+		oldData, err := oldPkg.ReadContent(&oldFC)
+		if err != nil {
+			return fmt.Errorf("read old content for %d/%d: %w", newFC.TypeSymbol, newFC.FileSymbol, err)
+		}
+		newData, err := newPkg.ReadContent(&newFC)
+		if err != nil {
+			return fmt.Errorf("read new content for %d/%d: %w", newFC.TypeSymbol, newFC.FileSymbol, err)
+		}
+
+		if bytes.Equal(oldData, newData) {
+			entry.Op = PatchOpCarry
+			patch.Entries = append(patch.Entries, entry)
+			continue
+		}
+
+		deltaData, err := compressPatchData(encodeDelta(diffContent(oldData, newData, params)))
+		if err != nil {
+			return fmt.Errorf("compress delta for %d/%d: %w", newFC.TypeSymbol, newFC.FileSymbol, err)
+		}
+		fullData, err := compressPatchData(newData)
+		if err != nil {
+			return fmt.Errorf("compress content for %d/%d: %w", newFC.TypeSymbol, newFC.FileSymbol, err)
+		}
+
+		if len(deltaData) < len(fullData) {
+			entry.Op = PatchOpDelta
+			entry.Data = deltaData
+		} else {
+			entry.Op = PatchOpReplace
+			entry.Data = fullData
+		}
+		patch.Entries = append(patch.Entries, entry)
+	}
+
+	for key, oldFC := range oldByKey {
+		if newByKey[key] {
+			continue
+		}
+		patch.Entries = append(patch.Entries, PatchEntry{TypeSymbol: oldFC.TypeSymbol, FileSymbol: oldFC.FileSymbol, Op: PatchOpRemove})
+	}
+
+	sort.Slice(patch.Entries, func(i, j int) bool {
+		a, b := patch.Entries[i], patch.Entries[j]
+		if a.TypeSymbol != b.TypeSymbol {
+			return a.TypeSymbol < b.TypeSymbol
+		}
+		return a.FileSymbol < b.FileSymbol
+	})
+
+	return patch.WriteFile(outPatch)
+}
+
+// ApplyPatch reconstructs the package DiffPackages compared into outDir,
+// starting from the untouched copy in baseDir (patch.PackageName
+// locates both the base and output package/manifest) and the changes
+// recorded at patchPath. The rebuilt package is written fresh via
+// Builder, so
+// outDir ends up content-equivalent to the new package DiffPackages
+// compared against, not a byte-identical copy of it.
+func ApplyPatch(baseDir, patchPath, outDir string) error {
+	patch, err := ReadPatch(patchPath)
+	if err != nil {
+		return fmt.Errorf("read patch: %w", err)
+	}
+
+	baseManifest, err := ReadFile(filepath.Join(baseDir, "manifests", patch.PackageName))
+	if err != nil {
+		return fmt.Errorf("read base manifest: %w", err)
+	}
+	basePkg, err := OpenPackage(baseManifest, filepath.Join(baseDir, "packages", patch.PackageName))
+	if err != nil {
+		return fmt.Errorf("open base package: %w", err)
+	}
+	defer basePkg.Close()
+
+	baseByKey := make(map[fcKey]FrameContent, len(baseManifest.FrameContents))
+	for _, fc := range baseManifest.FrameContents {
+		baseByKey[fcKey{fc.TypeSymbol, fc.FileSymbol}] = fc
+	}
+
+	stageDir, err := os.MkdirTemp("", "applypatch-*")
+	if err != nil {
+		return fmt.Errorf("create staging dir: %w", err)
+	}
+	defer os.RemoveAll(stageDir)
+
+	type stagedFile struct {
+		frameIndex uint32
+		file       ScannedFile
+	}
+	var staged []stagedFile
+	var maxFrameIndex uint32
+
+	for i, entry := range patch.Entries {
+		if entry.Op == PatchOpRemove {
+			continue
+		}
+
+		data, err := resolvePatchEntry(entry, i, basePkg, baseByKey)
+		if err != nil {
+			return err
+		}
+
+		stagePath := filepath.Join(stageDir, fmt.Sprintf("%d_%d", entry.TypeSymbol, entry.FileSymbol))
+		if err := os.WriteFile(stagePath, data, 0644); err != nil {
+			return fmt.Errorf("patch entry %d: stage content: %w", i, err)
+		}
+
+		if entry.NewFrameIndex > maxFrameIndex {
+			maxFrameIndex = entry.NewFrameIndex
+		}
+		staged = append(staged, stagedFile{
+			frameIndex: entry.NewFrameIndex,
+			file: ScannedFile{
+				TypeSymbol: entry.TypeSymbol,
+				FileSymbol: entry.FileSymbol,
+				Path:       stagePath,
+				Size:       uint32(len(data)),
+			},
+		})
+	}
+
+	fileGroups := make([][]ScannedFile, maxFrameIndex+1)
+	for _, sf := range staged {
+		fileGroups[sf.frameIndex] = append(fileGroups[sf.frameIndex], sf.file)
+	}
+
+	newManifest, err := NewBuilder(outDir, patch.PackageName).Build(fileGroups)
+	if err != nil {
+		return fmt.Errorf("build patched package: %w", err)
+	}
+
+	manifestPath := filepath.Join(outDir, "manifests", patch.PackageName)
+	if err := os.MkdirAll(filepath.Dir(manifestPath), 0755); err != nil {
+		return fmt.Errorf("create manifest dir: %w", err)
+	}
+	return WriteFile(manifestPath, newManifest)
+}
+
+// resolvePatchEntry decodes entry's full content, reading from basePkg
+// (via baseByKey) for the ops that need base content - PatchOpCarry
+// outright, PatchOpDelta as the delta's starting point.
+func resolvePatchEntry(entry PatchEntry, index int, basePkg *Package, baseByKey map[fcKey]FrameContent) ([]byte, error) {
+	switch entry.Op {
+	case PatchOpCarry:
+		baseFC, ok := baseByKey[fcKey{entry.TypeSymbol, entry.FileSymbol}]
+		if !ok {
+			return nil, fmt.Errorf("patch entry %d: carried file %d/%d missing from base package", index, entry.TypeSymbol, entry.FileSymbol)
+		}
+		data, err := basePkg.ReadContent(&baseFC)
+		if err != nil {
+			return nil, fmt.Errorf("patch entry %d: read base content: %w", index, err)
+		}
+		return data, nil
+
+	case PatchOpAdd, PatchOpReplace:
+		data, err := decompressPatchData(entry.Data, entry.NewSize)
+		if err != nil {
+			return nil, fmt.Errorf("patch entry %d: decompress content: %w", index, err)
+		}
+		return data, nil
+
+	case PatchOpDelta:
+		baseFC, ok := baseByKey[fcKey{entry.TypeSymbol, entry.FileSymbol}]
+		if !ok {
+			return nil, fmt.Errorf("patch entry %d: delta base %d/%d missing from base package", index, entry.TypeSymbol, entry.FileSymbol)
+		}
+		baseData, err := basePkg.ReadContent(&baseFC)
+		if err != nil {
+			return nil, fmt.Errorf("patch entry %d: read base content: %w", index, err)
+		}
+		instrData, err := decompressPatchData(entry.Data, 0)
+		if err != nil {
+			return nil, fmt.Errorf("patch entry %d: decompress delta: %w", index, err)
+		}
+		instrs, err := decodeDelta(instrData)
+		if err != nil {
+			return nil, fmt.Errorf("patch entry %d: decode delta: %w", index, err)
+		}
+		data, err := applyDelta(baseData, instrs)
+		if err != nil {
+			return nil, fmt.Errorf("patch entry %d: apply delta: %w", index, err)
+		}
+		return data, nil
+
+	default:
+		return nil, fmt.Errorf("patch entry %d: unknown op %d", index, entry.Op)
+	}
+}
+
+func compressPatchData(data []byte) ([]byte, error) {
+	return zstd.CompressLevel(nil, data, DefaultCompressionLevel)
+}
+
+func decompressPatchData(data []byte, sizeHint uint32) ([]byte, error) {
+	return zstd.Decompress(make([]byte, 0, sizeHint), data)
+}
+
+// deltaOp identifies one instruction in a PatchOpDelta instruction
+// stream (see encodeDelta/applyDelta).
+type deltaOp uint8
+
+const (
+	deltaCopy   deltaOp = iota // copy offset:offset+length from the base content
+	deltaInsert                // append literal bytes not found anywhere in the base content
+)
+
+// deltaInstruction is one step of reconstructing new content from base
+// content plus literal bytes.
+type deltaInstruction struct {
+	op      deltaOp
+	offset  uint32 // deltaCopy
+	length  uint32 // deltaCopy
+	literal []byte // deltaInsert
+}
+
+// diffContent builds a content-defined-chunk delta of newData against
+// oldData: oldData is split into chunks (see splitChunks) and indexed by
+// chunk hash, then newData's own chunks are matched against that index -
+// a match becomes a deltaCopy instruction pointing at the matching span
+// in oldData, a run of unmatched chunks is coalesced into one
+// deltaInsert literal. Unlike a fixed-offset diff, a chunk that merely
+// shifted position (an insertion or deletion earlier in the file) still
+// matches by content, so a single small edit to a large frame produces
+// a delta proportional to the edit, not to the file size.
+func diffContent(oldData, newData []byte, params chunkParams) []deltaInstruction {
+	type span struct{ offset, length uint32 }
+	oldIndex := make(map[ChunkHash]span)
+	var offset uint32
+	for _, c := range splitChunks(oldData, params) {
+		h := hashChunk(c)
+		if _, exists := oldIndex[h]; !exists {
+			oldIndex[h] = span{offset, uint32(len(c))}
+		}
+		offset += uint32(len(c))
+	}
+
+	var instrs []deltaInstruction
+	var pendingLiteral []byte
+	flushLiteral := func() {
+		if len(pendingLiteral) > 0 {
+			instrs = append(instrs, deltaInstruction{op: deltaInsert, literal: pendingLiteral})
+			pendingLiteral = nil
+		}
+	}
+
+	for _, c := range splitChunks(newData, params) {
+		h := hashChunk(c)
+		if sp, ok := oldIndex[h]; ok {
+			flushLiteral()
+			instrs = append(instrs, deltaInstruction{op: deltaCopy, offset: sp.offset, length: sp.length})
+		} else {
+			pendingLiteral = append(pendingLiteral, c...)
+		}
+	}
+	flushLiteral()
+
+	return instrs
+}
+
+// applyDelta reconstructs new content from base content plus instrs, the
+// inverse of diffContent.
+func applyDelta(baseData []byte, instrs []deltaInstruction) ([]byte, error) {
+	var out bytes.Buffer
+	for i, ins := range instrs {
+		switch ins.op {
+		case deltaCopy:
+			if int64(ins.offset)+int64(ins.length) > int64(len(baseData)) {
+				return nil, fmt.Errorf("delta instruction %d: copy range extends past base content", i)
+			}
+			out.Write(baseData[ins.offset : ins.offset+ins.length])
+		case deltaInsert:
+			out.Write(ins.literal)
+		default:
+			return nil, fmt.Errorf("delta instruction %d: unknown op %d", i, ins.op)
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// encodeDelta serializes instrs into the byte stream a PatchOpDelta
+// entry's Data carries (zstd-compressed by the caller).
+func encodeDelta(instrs []deltaInstruction) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(len(instrs)))
+	for _, ins := range instrs {
+		buf.WriteByte(byte(ins.op))
+		switch ins.op {
+		case deltaCopy:
+			binary.Write(&buf, binary.LittleEndian, ins.offset)
+			binary.Write(&buf, binary.LittleEndian, ins.length)
+		case deltaInsert:
+			binary.Write(&buf, binary.LittleEndian, uint32(len(ins.literal)))
+			buf.Write(ins.literal)
+		}
+	}
+	return buf.Bytes()
+}
+
+// decodeDelta parses the instruction stream encodeDelta produced.
+func decodeDelta(data []byte) ([]deltaInstruction, error) {
+	r := bytes.NewReader(data)
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("read instruction count: %w", err)
+	}
+
+	instrs := make([]deltaInstruction, count)
+	for i := range instrs {
+		opByte, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("read op %d: %w", i, err)
+		}
+		instrs[i].op = deltaOp(opByte)
+
+		switch instrs[i].op {
+		case deltaCopy:
+			if err := binary.Read(r, binary.LittleEndian, &instrs[i].offset); err != nil {
+				return nil, fmt.Errorf("read copy offset %d: %w", i, err)
+			}
+			if err := binary.Read(r, binary.LittleEndian, &instrs[i].length); err != nil {
+				return nil, fmt.Errorf("read copy length %d: %w", i, err)
+			}
+		case deltaInsert:
+			var n uint32
+			if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+				return nil, fmt.Errorf("read insert length %d: %w", i, err)
+			}
+			literal := make([]byte, n)
+			if _, err := io.ReadFull(r, literal); err != nil {
+				return nil, fmt.Errorf("read insert literal %d: %w", i, err)
+			}
+			instrs[i].literal = literal
+		default:
+			return nil, fmt.Errorf("unknown delta op %d", instrs[i].op)
+		}
+	}
+
+	return instrs, nil
+}
@@ -0,0 +1,247 @@
+package manifest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildPatchFixturePackage builds a package in a fresh temp dir from groups and
+// returns its directory, ready for DiffPackages/ApplyPatch to read via
+// the standard manifests/packages layout.
+func buildPatchFixturePackage(t *testing.T, groups [][]ScannedFile) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	m, err := NewBuilder(dir, "pkg").Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	manifestDir := filepath.Join(dir, "manifests")
+	if err := os.MkdirAll(manifestDir, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := WriteFile(filepath.Join(manifestDir, "pkg"), m); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return dir
+}
+
+func TestDiffAndApplyPatchReproducesChangedFile(t *testing.T) {
+	oldGroups := makeBuildFixture(t, t.TempDir())
+	oldDir := buildPatchFixturePackage(t, oldGroups)
+
+	newGroups := make([][]ScannedFile, len(oldGroups))
+	for i, group := range oldGroups {
+		newGroups[i] = append([]ScannedFile(nil), group...)
+	}
+	editedPath := filepath.Join(t.TempDir(), "edited")
+	editedContent := bytes.Repeat([]byte("payload-"), 100)
+	editedContent = append(editedContent, "-with-an-edit-near-the-end"...)
+	if err := writeTestFile(t, editedPath, editedContent); err != nil {
+		t.Fatalf("write edited fixture: %v", err)
+	}
+	newGroups[0] = append([]ScannedFile(nil), newGroups[0]...)
+	newGroups[0][0] = ScannedFile{
+		TypeSymbol: newGroups[0][0].TypeSymbol,
+		FileSymbol: newGroups[0][0].FileSymbol,
+		Path:       editedPath,
+		Size:       uint32(len(editedContent)),
+	}
+	newDir := buildPatchFixturePackage(t, newGroups)
+
+	patchPath := filepath.Join(t.TempDir(), "pkg.patch")
+	if err := DiffPackages(oldDir, newDir, "pkg", patchPath); err != nil {
+		t.Fatalf("DiffPackages: %v", err)
+	}
+
+	outDir := t.TempDir()
+	if err := ApplyPatch(oldDir, patchPath, outDir); err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+
+	assertPackagesMatch(t, newDir, outDir, "pkg")
+}
+
+func TestDiffAndApplyPatchCarriesUnchangedFiles(t *testing.T) {
+	groups := makeBuildFixture(t, t.TempDir())
+	oldDir := buildPatchFixturePackage(t, groups)
+	newDir := buildPatchFixturePackage(t, groups)
+
+	patchPath := filepath.Join(t.TempDir(), "pkg.patch")
+	if err := DiffPackages(oldDir, newDir, "pkg", patchPath); err != nil {
+		t.Fatalf("DiffPackages: %v", err)
+	}
+
+	patch, err := ReadPatch(patchPath)
+	if err != nil {
+		t.Fatalf("ReadPatch: %v", err)
+	}
+	for _, e := range patch.Entries {
+		if e.Op != PatchOpCarry {
+			t.Fatalf("got op %d for %d/%d, want PatchOpCarry for an unchanged package", e.Op, e.TypeSymbol, e.FileSymbol)
+		}
+		if len(e.Data) != 0 {
+			t.Errorf("carried entry %d/%d has %d bytes of data, want 0", e.TypeSymbol, e.FileSymbol, len(e.Data))
+		}
+	}
+
+	outDir := t.TempDir()
+	if err := ApplyPatch(oldDir, patchPath, outDir); err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+	assertPackagesMatch(t, newDir, outDir, "pkg")
+}
+
+func TestDiffAndApplyPatchHandlesAddedAndRemovedFiles(t *testing.T) {
+	oldGroups := makeBuildFixture(t, t.TempDir())
+	oldDir := buildPatchFixturePackage(t, oldGroups)
+
+	newGroups := make([][]ScannedFile, len(oldGroups))
+	for i, group := range oldGroups {
+		newGroups[i] = append([]ScannedFile(nil), group...)
+	}
+	// Drop the last group's first file and add a brand new TypeSymbol.
+	last := len(newGroups) - 1
+	newGroups[last] = newGroups[last][1:]
+
+	addedPath := filepath.Join(t.TempDir(), "added")
+	addedContent := bytes.Repeat([]byte("new-file-content-"), 50)
+	if err := writeTestFile(t, addedPath, addedContent); err != nil {
+		t.Fatalf("write added fixture: %v", err)
+	}
+	newGroups = append(newGroups, []ScannedFile{{
+		TypeSymbol: 999,
+		FileSymbol: 1,
+		Path:       addedPath,
+		Size:       uint32(len(addedContent)),
+	}})
+	newDir := buildPatchFixturePackage(t, newGroups)
+
+	patchPath := filepath.Join(t.TempDir(), "pkg.patch")
+	if err := DiffPackages(oldDir, newDir, "pkg", patchPath); err != nil {
+		t.Fatalf("DiffPackages: %v", err)
+	}
+
+	patch, err := ReadPatch(patchPath)
+	if err != nil {
+		t.Fatalf("ReadPatch: %v", err)
+	}
+	var sawAdd, sawRemove bool
+	for _, e := range patch.Entries {
+		if e.Op == PatchOpAdd && e.TypeSymbol == 999 {
+			sawAdd = true
+		}
+		if e.Op == PatchOpRemove {
+			sawRemove = true
+		}
+	}
+	if !sawAdd {
+		t.Error("expected a PatchOpAdd entry for the new TypeSymbol")
+	}
+	if !sawRemove {
+		t.Error("expected a PatchOpRemove entry for the dropped file")
+	}
+
+	outDir := t.TempDir()
+	if err := ApplyPatch(oldDir, patchPath, outDir); err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+	assertPackagesMatch(t, newDir, outDir, "pkg")
+}
+
+func TestDiffContentAndApplyDeltaRoundTrip(t *testing.T) {
+	params := defaultChunkParams()
+	oldData := bytes.Repeat([]byte("abcdefgh"), 2000)
+	newData := append(append([]byte{}, oldData[:4000]...), append([]byte("INSERTED-BYTES-THAT-DONT-MATCH-ANY-OLD-CHUNK"), oldData[4000:]...)...)
+
+	instrs := diffContent(oldData, newData, params)
+	got, err := applyDelta(oldData, instrs)
+	if err != nil {
+		t.Fatalf("applyDelta: %v", err)
+	}
+	if !bytes.Equal(got, newData) {
+		t.Fatal("applyDelta(oldData, diffContent(oldData, newData)) != newData")
+	}
+
+	encoded := encodeDelta(instrs)
+	decoded, err := decodeDelta(encoded)
+	if err != nil {
+		t.Fatalf("decodeDelta: %v", err)
+	}
+	got2, err := applyDelta(oldData, decoded)
+	if err != nil {
+		t.Fatalf("applyDelta after encode/decode: %v", err)
+	}
+	if !bytes.Equal(got2, newData) {
+		t.Fatal("round trip through encodeDelta/decodeDelta changed the result")
+	}
+}
+
+func TestApplyDeltaRejectsOutOfRangeCopy(t *testing.T) {
+	_, err := applyDelta([]byte("short"), []deltaInstruction{{op: deltaCopy, offset: 0, length: 100}})
+	if err == nil {
+		t.Fatal("expected an error for a copy instruction past the end of the base content")
+	}
+}
+
+func writeTestFile(t *testing.T, path string, data []byte) error {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func assertPackagesMatch(t *testing.T, wantDir, gotDir, packageName string) {
+	t.Helper()
+
+	wantManifest, err := ReadFile(filepath.Join(wantDir, "manifests", packageName))
+	if err != nil {
+		t.Fatalf("read want manifest: %v", err)
+	}
+	wantPkg, err := OpenPackage(wantManifest, filepath.Join(wantDir, "packages", packageName))
+	if err != nil {
+		t.Fatalf("open want package: %v", err)
+	}
+	defer wantPkg.Close()
+
+	gotManifest, err := ReadFile(filepath.Join(gotDir, "manifests", packageName))
+	if err != nil {
+		t.Fatalf("read got manifest: %v", err)
+	}
+	gotPkg, err := OpenPackage(gotManifest, filepath.Join(gotDir, "packages", packageName))
+	if err != nil {
+		t.Fatalf("open got package: %v", err)
+	}
+	defer gotPkg.Close()
+
+	if len(wantManifest.FrameContents) != len(gotManifest.FrameContents) {
+		t.Fatalf("got %d FrameContents, want %d", len(gotManifest.FrameContents), len(wantManifest.FrameContents))
+	}
+
+	gotByKey := make(map[fcKey]FrameContent, len(gotManifest.FrameContents))
+	for _, fc := range gotManifest.FrameContents {
+		gotByKey[fcKey{fc.TypeSymbol, fc.FileSymbol}] = fc
+	}
+
+	for _, wantFC := range wantManifest.FrameContents {
+		gotFC, ok := gotByKey[fcKey{wantFC.TypeSymbol, wantFC.FileSymbol}]
+		if !ok {
+			t.Fatalf("missing %d/%d in reconstructed package", wantFC.TypeSymbol, wantFC.FileSymbol)
+		}
+
+		wantData, err := wantPkg.ReadContent(&wantFC)
+		if err != nil {
+			t.Fatalf("read want content for %d/%d: %v", wantFC.TypeSymbol, wantFC.FileSymbol, err)
+		}
+		gotData, err := gotPkg.ReadContent(&gotFC)
+		if err != nil {
+			t.Fatalf("read got content for %d/%d: %v", wantFC.TypeSymbol, wantFC.FileSymbol, err)
+		}
+		if !bytes.Equal(wantData, gotData) {
+			t.Fatalf("content mismatch for %d/%d", wantFC.TypeSymbol, wantFC.FileSymbol)
+		}
+	}
+}
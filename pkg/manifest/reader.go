@@ -0,0 +1,194 @@
+package manifest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/DataDog/zstd"
+	"github.com/goopsie/evrFileTools/pkg/archive"
+)
+
+// Reader streams a manifest's FrameContents one entry at a time instead
+// of decoding the full FrameContents/Metadata/Frames arrays UnmarshalBinary
+// does, so tools that only need to list or filter files (like the
+// -export textures CLI path) don't pay for a Metadata allocation they
+// never look at when a package holds hundreds of thousands of entries.
+//
+// FrameContent.FrameIndex refers into the Frames section, which is
+// physically stored after FrameContents and Metadata in the manifest's
+// binary layout, so NewReader makes a first pass over src to read past
+// FrameContents/Metadata (without decoding FrameContents) and decode the
+// much smaller Frames section, then seeks back to stream FrameContents
+// from the start for Next(). This is why NewReader takes an
+// io.ReadSeeker rather than a plain io.Reader.
+type Reader struct {
+	src       io.ReadSeeker
+	ar        *archive.Reader
+	header    Header
+	frames    []Frame
+	remaining int
+	cur       FrameContent
+}
+
+// NewReader parses src's header and Frames section and returns a Reader
+// positioned to stream FrameContents via Next().
+func NewReader(src io.ReadSeeker) (*Reader, error) {
+	header, frames, err := scanFrames(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek to start: %w", err)
+	}
+	ar, err := archive.NewReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("open manifest archive: %w", err)
+	}
+	var hdrBuf [HeaderSize]byte
+	if _, err := io.ReadFull(ar, hdrBuf[:]); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	return &Reader{
+		src:       src,
+		ar:        ar,
+		header:    header,
+		frames:    frames,
+		remaining: int(header.FrameContents.ElementCount),
+	}, nil
+}
+
+// scanFrames makes the lightweight first pass described on Reader: it
+// decodes the header, skips over FrameContents and Metadata without
+// allocating per-entry structs, and decodes Frames.
+func scanFrames(src io.ReadSeeker) (Header, []Frame, error) {
+	ar, err := archive.NewReader(src)
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("open manifest archive: %w", err)
+	}
+	defer ar.Close()
+
+	var hdrBuf [HeaderSize]byte
+	if _, err := io.ReadFull(ar, hdrBuf[:]); err != nil {
+		return Header{}, nil, fmt.Errorf("read header: %w", err)
+	}
+	header := decodeHeader(hdrBuf[:])
+
+	skip := int64(header.FrameContents.ElementCount)*FrameContentSize + int64(header.Metadata.ElementCount)*FileMetadataSize
+	if _, err := io.CopyN(io.Discard, ar, skip); err != nil {
+		return Header{}, nil, fmt.Errorf("skip FrameContents/Metadata: %w", err)
+	}
+
+	count := int(header.Frames.ElementCount)
+	frames := make([]Frame, count)
+	var frameBuf [FrameSize]byte
+	for i := 0; i < count; i++ {
+		if _, err := io.ReadFull(ar, frameBuf[:]); err != nil {
+			return Header{}, nil, fmt.Errorf("read frame %d: %w", i, err)
+		}
+		frames[i] = decodeFrameRow(frameBuf[:])
+	}
+
+	return header, frames, nil
+}
+
+func decodeHeader(data []byte) Header {
+	var h Header
+	offset := 0
+	h.PackageCount = binary.LittleEndian.Uint32(data[offset:])
+	offset += 4
+	h.Unk1 = binary.LittleEndian.Uint32(data[offset:])
+	offset += 4
+	h.Unk2 = binary.LittleEndian.Uint64(data[offset:])
+	offset += 8
+
+	decodeSection(&h.FrameContents, data[offset:])
+	offset += SectionSize + 16
+	decodeSection(&h.Metadata, data[offset:])
+	offset += SectionSize + 16
+	decodeSection(&h.Frames, data[offset:])
+
+	return h
+}
+
+func decodeFrameRow(data []byte) Frame {
+	return Frame{
+		PackageIndex:   binary.LittleEndian.Uint32(data[0:]),
+		Offset:         binary.LittleEndian.Uint32(data[4:]),
+		CompressedSize: binary.LittleEndian.Uint32(data[8:]),
+		Length:         binary.LittleEndian.Uint32(data[12:]),
+	}
+}
+
+// Header returns the manifest header read by NewReader.
+func (r *Reader) Header() *Header {
+	return &r.header
+}
+
+// Frames returns the manifest's Frame table, already available once
+// NewReader returns since the first pass has to read past it.
+func (r *Reader) Frames() []Frame {
+	return r.frames
+}
+
+// Next decodes and returns the next FrameContent entry, or io.EOF once
+// every entry has been returned. The returned pointer is only valid
+// until the next call to Next or OpenFrame.
+func (r *Reader) Next() (*FrameContent, error) {
+	if r.remaining <= 0 {
+		return nil, io.EOF
+	}
+
+	var buf [FrameContentSize]byte
+	if _, err := io.ReadFull(r.ar, buf[:]); err != nil {
+		return nil, fmt.Errorf("read frame content: %w", err)
+	}
+	r.cur = FrameContent{
+		TypeSymbol: int64(binary.LittleEndian.Uint64(buf[0:])),
+		FileSymbol: int64(binary.LittleEndian.Uint64(buf[8:])),
+		FrameIndex: binary.LittleEndian.Uint32(buf[16:]),
+		DataOffset: binary.LittleEndian.Uint32(buf[20:]),
+		Size:       binary.LittleEndian.Uint32(buf[24:]),
+		Alignment:  binary.LittleEndian.Uint32(buf[28:]),
+	}
+	r.remaining--
+
+	return &r.cur, nil
+}
+
+// OpenFrame returns a streaming decompressor over the most recent entry
+// returned by Next(), reading that entry's compressed frame bytes from
+// pkg via ReadAt. Unlike Package.readFrameCompressed followed by a full
+// zstd.Decompress, the returned reader holds only the zstd decoder's
+// window, not the whole decompressed frame, at the cost of decompressing
+// shared frames once per entry rather than once per frame - a fine
+// tradeoff for a lister that usually only opens the few entries that
+// matched its filter. Callers must Close the returned reader.
+func (r *Reader) OpenFrame(pkg io.ReaderAt) (io.ReadCloser, error) {
+	if r.cur.FrameIndex >= uint32(len(r.frames)) {
+		return nil, fmt.Errorf("frame index %d out of range", r.cur.FrameIndex)
+	}
+	frame := r.frames[r.cur.FrameIndex]
+	if frame.CompressedSize == 0 {
+		return nil, fmt.Errorf("frame has no data")
+	}
+
+	section := io.NewSectionReader(pkg, int64(frame.Offset), int64(frame.CompressedSize))
+	zr := zstd.NewReader(section)
+	if _, err := io.CopyN(io.Discard, zr, int64(r.cur.DataOffset)); err != nil {
+		zr.Close()
+		return nil, fmt.Errorf("seek to entry offset: %w", err)
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(zr, int64(r.cur.Size)), zr}, nil
+}
+
+// Close releases resources held by the underlying archive reader.
+func (r *Reader) Close() error {
+	return r.ar.Close()
+}
@@ -0,0 +1,145 @@
+package manifest
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReaderMatchesUnmarshalBinary(t *testing.T) {
+	dataDir := t.TempDir()
+	groups := makeBuildFixture(t, dataDir)
+
+	m, err := NewBuilder(dataDir, "pkg").Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	manifestPath := filepath.Join(dataDir, "pkg.manifest")
+	if err := WriteFile(manifestPath, m); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	f, err := os.Open(manifestPath)
+	if err != nil {
+		t.Fatalf("open manifest: %v", err)
+	}
+	defer f.Close()
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer r.Close()
+
+	if len(r.Frames()) != len(m.Frames) {
+		t.Fatalf("Frames() len = %d, want %d", len(r.Frames()), len(m.Frames))
+	}
+	for i, frame := range r.Frames() {
+		if frame != m.Frames[i] {
+			t.Fatalf("frame %d = %+v, want %+v", i, frame, m.Frames[i])
+		}
+	}
+
+	var got []FrameContent
+	for {
+		fc, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		got = append(got, *fc)
+	}
+
+	if len(got) != len(m.FrameContents) {
+		t.Fatalf("streamed %d FrameContents, want %d", len(got), len(m.FrameContents))
+	}
+	for i, fc := range got {
+		if fc != m.FrameContents[i] {
+			t.Fatalf("FrameContent %d = %+v, want %+v", i, fc, m.FrameContents[i])
+		}
+	}
+
+	if _, err := r.Next(); err != io.EOF {
+		t.Fatalf("Next past the end: got %v, want io.EOF", err)
+	}
+}
+
+func TestReaderOpenFrameMatchesPackageExtract(t *testing.T) {
+	dataDir := t.TempDir()
+	groups := makeBuildFixture(t, dataDir)
+
+	m, err := NewBuilder(dataDir, "pkg").Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	manifestPath := filepath.Join(dataDir, "pkg.manifest")
+	if err := WriteFile(manifestPath, m); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	pkg, err := OpenPackage(m, filepath.Join(dataDir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("OpenPackage: %v", err)
+	}
+	defer pkg.Close()
+
+	outDir := t.TempDir()
+	if err := pkg.Extract(outDir); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	f, err := os.Open(manifestPath)
+	if err != nil {
+		t.Fatalf("open manifest: %v", err)
+	}
+	defer f.Close()
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer r.Close()
+
+	packageFile, err := os.Open(filepath.Join(dataDir, "packages", "pkg_0"))
+	if err != nil {
+		t.Fatalf("open package file: %v", err)
+	}
+	defer packageFile.Close()
+
+	for {
+		fc, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+
+		frameReader, err := r.OpenFrame(packageFile)
+		if err != nil {
+			t.Fatalf("OpenFrame: %v", err)
+		}
+		streamed, err := io.ReadAll(frameReader)
+		frameReader.Close()
+		if err != nil {
+			t.Fatalf("read streamed frame content: %v", err)
+		}
+
+		dirParts, fileName := frameContentPathParts(*fc, false)
+		wantPath := filepath.Join(append([]string{outDir}, append(dirParts, fileName)...)...)
+		want, err := os.ReadFile(wantPath)
+		if err != nil {
+			t.Fatalf("read extracted file %s: %v", wantPath, err)
+		}
+
+		if !bytes.Equal(streamed, want) {
+			t.Fatalf("streamed content for %+v = %q, want %q", *fc, streamed, want)
+		}
+	}
+}
@@ -31,6 +31,47 @@ type frameResult struct {
 	shouldSkip       bool
 	rawReadBuf       []byte
 	decompBuf        []byte
+
+	// dedupLocations holds, when WithDedup is set, the resolved
+	// (FrameIndex, DataOffset, Size) for each file in this frame's
+	// fcWrapper list (keyed the same way as modifiedFilesLookupTable),
+	// decided by the producer goroutine so the consumer doesn't have to
+	// re-run (and risk disagreeing with) the dedup lookup.
+	dedupLocations map[[128]byte]ChunkLocation
+
+	// dedupSaved is, when WithDedup is set, how many decompressed bytes
+	// this frame avoided writing by pointing at an existing chunk instead
+	// - the producer goroutine's contribution to RepackResult.DedupSavedBytes.
+	dedupSaved int64
+
+	// dictID is, when WithDictionaries is set, the DictTable id the
+	// producer goroutine compressed this frame with (0 for none), so the
+	// consumer can record it against the frame's final index.
+	dictID uint32
+
+	// codecTag is the CodecTag compressModifiedFrame chose for this frame
+	// (CodecZstd, the zero value, for every frame written before WithCodecs
+	// existed), so the consumer can feed WithCodecs' CodecIndex/RepackStats.
+	codecTag CodecTag
+}
+
+// releaseBuffers returns every pooled buffer res holds back to its pool,
+// so an aborted or errored frame doesn't leak them - the same cleanup
+// the success path already does for each pool.
+func (res frameResult) releaseBuffers() {
+	if res.rawReadBuf != nil {
+		readPool.Put(res.rawReadBuf)
+	}
+	if res.decompBuf != nil {
+		decompPool.Put(res.decompBuf)
+	}
+	if res.isModified {
+		if res.data != nil {
+			compPool.Put(res.data)
+		}
+	} else if res.data != nil {
+		readPool.Put(res.data)
+	}
 }
 
 type fcWrapper struct {
@@ -148,8 +189,91 @@ func incrementSection(s *Section, count int) {
 	s.Length += s.ElementSize * uint64(count)
 }
 
-func Repack(manifest *Manifest, fileMap [][]ScannedFile, outputDir, packageName, dataDir string) error {
-	fmt.Println("Mapping modified files...")
+// packageFileSizes stats every packages/<packageName>_N file under dir for
+// N in [0, count) and returns their sizes, for RepackResult.PackageSizes.
+func packageFileSizes(dir, packageName string, count uint32) map[uint32]int64 {
+	sizes := make(map[uint32]int64, count)
+	for i := uint32(0); i < count; i++ {
+		path := fmt.Sprintf("%s/packages/%s_%d", dir, packageName, i)
+		if stat, err := os.Stat(path); err == nil {
+			sizes[i] = stat.Size()
+		}
+	}
+	return sizes
+}
+
+// repackConfig holds Repack options.
+type repackConfig struct {
+	fastCopy    bool
+	dedup       bool
+	dict        bool
+	codecs      []CodecTag
+	codecBudget int
+	stats       *RepackStats
+}
+
+// RepackOption configures Repack behavior.
+type RepackOption func(*repackConfig)
+
+// WithFastCopy controls whether Repack copies the raw compressed bytes of
+// frames untouched by fileMap directly from the source package into the
+// output (the default, enable=true) instead of decompressing and
+// recompressing them like every modified frame. Pass false to force every
+// frame through decompress/recompress, e.g. to re-encode an entire
+// package at a new compression level.
+func WithFastCopy(enable bool) RepackOption {
+	return func(c *repackConfig) { c.fastCopy = enable }
+}
+
+// WithDedup enables content-defined-chunk dedup (see fingerprintFile and
+// ChunkStore) for every modified frame: a file whose fingerprint matches
+// one already written - earlier in this same repack, or in a previous
+// run via the persisted "manifests/<pkg>.chunks" sidecar - is pointed at
+// that existing frame span instead of having its bytes written again.
+// This targets the common case of a modded asset being byte-identical
+// to the original or to an unmodified sibling.
+func WithDedup() RepackOption {
+	return func(c *repackConfig) { c.dedup = true }
+}
+
+// WithDictionaries enables trained per-TypeSymbol zstd dictionary
+// compression (see DictTable and trainDictionariesFromSource) for every
+// modified frame: a preparation pass samples existing content from the
+// source package grouped by TypeSymbol, trains one dictionary per type,
+// and the frame writer picks the dictionary matching whichever TypeSymbol
+// makes up most of that frame. This targets small, similar per-type
+// assets (tint variants, per-entity JSON blobs) that compress poorly on
+// their own at BestSpeed but share enough structure for a dictionary to
+// seed the compressor with.
+func WithDictionaries() RepackOption {
+	return func(c *repackConfig) { c.dict = true }
+}
+
+// Repack rewrites every package file from scratch into outputDir,
+// applying fileMap's replacements on top of the source package at
+// dataDir. It honors r.Context: once that context is done, in-flight
+// frame goroutines abort at their next checkpoint and Repack returns the
+// context's error after returning every pooled buffer it was holding.
+// r.Progress (if set) is called as each frame finishes, and r.Logger (if
+// set) receives the same progress messages Repack used to print to
+// stdout.
+func (r *Repacker) Repack(manifest *Manifest, fileMap [][]ScannedFile, outputDir, packageName, dataDir string, opts ...RepackOption) (*RepackResult, error) {
+	ctx := r.ctx()
+	cfg := &repackConfig{fastCopy: true}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	// A full Repack lays out every frame from scratch, so a dedup store
+	// from a previous run can't be trusted - start fresh and write a new
+	// sidecar once the final frame layout is known.
+	var dedupStore *ChunkStore
+	dedupParams := defaultChunkParams()
+	if cfg.dedup {
+		dedupStore = NewChunkStore()
+	}
+
+	r.logf("Mapping modified files...")
 
 	totalFiles := 0
 	for _, chunk := range fileMap {
@@ -179,7 +303,7 @@ func Repack(manifest *Manifest, fileMap [][]ScannedFile, outputDir, packageName,
 			}
 		}
 	}
-	fmt.Printf("Mapped %d files to modify.\n", len(modifiedFilesLookupTable))
+	r.logf("Mapped %d files to modify.", len(modifiedFilesLookupTable))
 
 	contentsByFrame := make(map[uint32][]fcWrapper)
 	for k, v := range manifest.FrameContents {
@@ -201,12 +325,34 @@ func Repack(manifest *Manifest, fileMap [][]ScannedFile, outputDir, packageName,
 		pFilePath := fmt.Sprintf("%s/packages/%s_%d", dataDir, packageName, i)
 		f, err := os.Open(pFilePath)
 		if err != nil {
-			return fmt.Errorf("failed to open package %s: %v", pFilePath, err)
+			return nil, fmt.Errorf("failed to open package %s: %v", pFilePath, err)
 		}
 		packages[uint32(i)] = f
 		defer f.Close()
 	}
 
+	var dictTable *DictTable
+	var dictProcs *dictProcessorCache
+	if cfg.dict {
+		r.logf("Training per-type dictionaries...")
+		dictTable = trainDictionariesFromSource(manifest, func(frame Frame) ([]byte, error) {
+			buf := make([]byte, frame.CompressedSize)
+			if _, err := packages[frame.PackageIndex].ReadAt(buf, int64(frame.Offset)); err != nil {
+				return nil, err
+			}
+			return buf, nil
+		})
+		dictProcs = newDictProcessorCache()
+	}
+
+	// A full Repack lays out every frame from scratch, same reasoning as
+	// dedupStore above - a CodecIndex from a previous run can't describe
+	// this run's frame layout, so WithCodecs always starts a fresh one.
+	var codecIndex *CodecIndex
+	if len(cfg.codecs) > 0 {
+		codecIndex = &CodecIndex{}
+	}
+
 	totalFrames := int(manifest.Header.Frames.ElementCount)
 	lookaheadSize := runtime.NumCPU() * 16
 	futureResults := make(chan chan frameResult, lookaheadSize)
@@ -216,12 +362,26 @@ func Repack(manifest *Manifest, fileMap [][]ScannedFile, outputDir, packageName,
 	go func() {
 		defer close(futureResults)
 		for i := 0; i < totalFrames; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
 			resultChan := make(chan frameResult, 1)
 			futureResults <- resultChan
 
 			go func(idx int, ch chan frameResult) {
+				select {
+				case <-ctx.Done():
+					ch <- frameResult{index: idx, err: ctx.Err()}
+					return
+				default:
+				}
+
 				v := manifest.Frames[idx]
-				isMod := modifiedFrames[uint32(idx)]
+				sourcePath := fmt.Sprintf("%s/packages/%s_%d", dataDir, packageName, v.PackageIndex)
+				isMod := modifiedFrames[uint32(idx)] || !cfg.fastCopy
 				res := frameResult{index: idx, isModified: isMod, decompressedSize: v.Length}
 
 				rawReadBuf := readPool.Get().([]byte)
@@ -240,14 +400,18 @@ func Repack(manifest *Manifest, fileMap [][]ScannedFile, outputDir, packageName,
 							ch <- res
 							return
 						}
-						res.err = err
+						res.err = fmt.Errorf("frame %d (%s): %w", idx, sourcePath, err)
 						ch <- res
 						return
 					}
 				}
 
 				if !isMod {
+					// rawReadBuf's ownership moves to res.data; releaseBuffers
+					// must not also see it as res.rawReadBuf or it would be
+					// returned to readPool twice.
 					res.data = rawReadBuf
+					res.rawReadBuf = nil
 					ch <- res
 					return
 				}
@@ -255,7 +419,7 @@ func Repack(manifest *Manifest, fileMap [][]ScannedFile, outputDir, packageName,
 				decompBuf := decompPool.Get().([]byte)
 				decompBytes, err := zstd.Decompress(decompBuf[:0], rawReadBuf)
 				if err != nil {
-					res.err = err
+					res.err = fmt.Errorf("frame %d (%s): %w", idx, sourcePath, err)
 					ch <- res
 					return
 				}
@@ -274,34 +438,60 @@ func Repack(manifest *Manifest, fileMap [][]ScannedFile, outputDir, packageName,
 					return sorted[a].fc.DataOffset < sorted[b].fc.DataOffset
 				})
 
+				var dedupOffset uint32
+				if cfg.dedup {
+					res.dedupLocations = make(map[[128]byte]ChunkLocation, len(sorted))
+				}
+
 				for j := 0; j < len(sorted); j++ {
 					buf := [128]byte{}
 					binary.LittleEndian.PutUint64(buf[0:64], uint64(sorted[j].fc.TypeSymbol))
 					binary.LittleEndian.PutUint64(buf[64:128], uint64(sorted[j].fc.FileSymbol))
 
+					var data []byte
+					var filePath string
 					if modFile, exists := modifiedFilesLookupTable[buf]; exists && modFile.FileSymbol != 0 {
+						filePath = modFile.Path
 						modData, err := os.ReadFile(modFile.Path)
 						if err != nil {
-							res.err = err
+							res.err = fmt.Errorf("frame %d (%s): %w", idx, filePath, err)
 							ch <- res
 							return
 						}
-						constructionBuf.Write(modData)
+						data = modData
 					} else {
 						start := sorted[j].fc.DataOffset
 						end := start + sorted[j].fc.Size
-						constructionBuf.Write(decompBytes[start:end])
+						data = decompBytes[start:end]
 					}
+
+					if !cfg.dedup {
+						constructionBuf.Write(data)
+						continue
+					}
+
+					hash := fingerprintFile(data, dedupParams)
+					if loc, ok := dedupStore.lookup(hash); ok {
+						res.dedupLocations[buf] = loc
+						res.dedupSaved += int64(len(data))
+						continue
+					}
+
+					own := ChunkLocation{FrameIndex: uint32(idx), DataOffset: dedupOffset, Size: uint32(len(data))}
+					res.dedupLocations[buf] = dedupStore.registerIfAbsent(hash, own)
+					constructionBuf.Write(data)
+					dedupOffset += uint32(len(data))
 				}
 
-				compBuf := compPool.Get().([]byte)
-				encodedData, err := zstd.CompressLevel(compBuf[:0], constructionBuf.Bytes(), zstd.BestSpeed)
-				if err != nil {
-					res.err = fmt.Errorf("compress frame: %w", err)
+				encodedData, codecTag, dictID, cerr := compressModifiedFrame(cfg, constructionBuf.Bytes(), dictTable, dictProcs, sorted)
+				if cerr != nil {
+					res.err = fmt.Errorf("frame %d (%s): %w", idx, sourcePath, cerr)
 					ch <- res
 					return
 				}
 				res.data = encodedData
+				res.codecTag = codecTag
+				res.dictID = dictID
 				res.decompressedSize = uint32(constructionBuf.Len())
 
 				ch <- res
@@ -309,23 +499,21 @@ func Repack(manifest *Manifest, fileMap [][]ScannedFile, outputDir, packageName,
 		}
 	}()
 
-	fmt.Println("Starting repack...")
+	r.logf("Starting repack...")
+	result := &RepackResult{}
 	for resultCh := range futureResults {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		res := <-resultCh
 		if res.err != nil {
-			return res.err
+			res.releaseBuffers()
+			return nil, res.err
 		}
 
 		if res.shouldSkip {
-			if res.rawReadBuf != nil {
-				readPool.Put(res.rawReadBuf)
-			}
-			if res.decompBuf != nil {
-				decompPool.Put(res.decompBuf)
-			}
-			if res.isModified && res.data != nil {
-				compPool.Put(res.data)
-			}
+			res.releaseBuffers()
 			continue
 		}
 
@@ -344,6 +532,19 @@ func Repack(manifest *Manifest, fileMap [][]ScannedFile, outputDir, packageName,
 				binary.LittleEndian.PutUint64(buf[0:64], uint64(sorted[j].fc.TypeSymbol))
 				binary.LittleEndian.PutUint64(buf[64:128], uint64(sorted[j].fc.FileSymbol))
 
+				if cfg.dedup {
+					loc := res.dedupLocations[buf]
+					newManifest.FrameContents[sorted[j].index] = FrameContent{
+						TypeSymbol: sorted[j].fc.TypeSymbol,
+						FileSymbol: sorted[j].fc.FileSymbol,
+						FrameIndex: loc.FrameIndex,
+						DataOffset: loc.DataOffset,
+						Size:       loc.Size,
+						Alignment:  sorted[j].fc.Alignment,
+					}
+					continue
+				}
+
 				size := sorted[j].fc.Size
 				if modFile, exists := modifiedFilesLookupTable[buf]; exists && modFile.FileSymbol != 0 {
 					size = modFile.Size
@@ -361,25 +562,28 @@ func Repack(manifest *Manifest, fileMap [][]ScannedFile, outputDir, packageName,
 			}
 		}
 
-		if err := writer.write(&newManifest, res.data, res.decompressedSize); err != nil {
-			return err
+		if cfg.dict {
+			dictTable.recordFrame(uint32(len(newManifest.Frames)), res.dictID)
+		}
+		if codecIndex != nil {
+			codecIndex.recordFrame(uint32(len(newManifest.Frames)), res.codecTag)
 		}
-
 		if res.isModified {
-			if res.rawReadBuf != nil {
-				readPool.Put(res.rawReadBuf)
-			}
-			if res.decompBuf != nil {
-				decompPool.Put(res.decompBuf)
-			}
-			if res.data != nil {
-				compPool.Put(res.data)
-			}
-		} else {
-			if res.data != nil {
-				readPool.Put(res.data)
-			}
+			cfg.stats.record(res.codecTag, int(res.decompressedSize), len(res.data))
 		}
+
+		if err := writer.write(&newManifest, res.data, res.decompressedSize); err != nil {
+			res.releaseBuffers()
+			return nil, err
+		}
+
+		result.FramesProcessed++
+		result.BytesIn += int64(res.decompressedSize)
+		result.BytesOut += int64(len(res.data))
+		result.DedupSavedBytes += res.dedupSaved
+		r.report(result.FramesProcessed, totalFrames, "repack", result.BytesIn, result.BytesOut)
+
+		res.releaseBuffers()
 	}
 
 	writer.close()
@@ -414,15 +618,63 @@ func Repack(manifest *Manifest, fileMap [][]ScannedFile, outputDir, packageName,
 
 	manifestDir := filepath.Join(outputDir, "manifests")
 	if err := os.MkdirAll(manifestDir, 0755); err != nil {
-		return fmt.Errorf("create manifest dir: %w", err)
+		return nil, fmt.Errorf("create manifest dir: %w", err)
+	}
+
+	if cfg.dedup {
+		dedupStore.resolvePackageIndexes(newManifest.Frames)
+		if err := dedupStore.Save(dedupSidecarPath(outputDir, packageName)); err != nil {
+			return nil, fmt.Errorf("save chunk store: %w", err)
+		}
 	}
 
-	return WriteFile(filepath.Join(manifestDir, packageName), &newManifest)
+	if cfg.dict {
+		if err := dictTable.Save(dictTablePath(outputDir, packageName)); err != nil {
+			return nil, fmt.Errorf("save dict table: %w", err)
+		}
+	}
+
+	if codecIndex != nil {
+		if err := codecIndex.WriteFile(codecIndexPath(outputDir, packageName)); err != nil {
+			return nil, fmt.Errorf("save codec index: %w", err)
+		}
+	}
+
+	result.PackageSizes = packageFileSizes(outputDir, packageName, newManifest.Header.PackageCount)
+
+	if err := WriteFile(filepath.Join(manifestDir, packageName), &newManifest); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 // QuickRepack modifies the existing package files in-place by appending new frames
 // and updating the manifest. This avoids rewriting the entire package set.
-func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageName string) error {
+//
+// Unlike Repack, WithDedup here loads the ChunkStore sidecar left by a
+// previous dedup-enabled run (if any) instead of starting empty: since
+// QuickRepack only ever appends to package files, a frame location
+// recorded in an earlier run is still exactly where it left it.
+//
+// QuickRepack honors r.Context, r.Progress, and r.Logger the same way
+// Repack does - see Repack's doc comment.
+func (r *Repacker) QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageName string, opts ...RepackOption) (*RepackResult, error) {
+	ctx := r.ctx()
+	cfg := &repackConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var dedupStore *ChunkStore
+	dedupParams := defaultChunkParams()
+	if cfg.dedup {
+		var err error
+		dedupStore, err = LoadChunkStore(dedupSidecarPath(dataDir, packageName))
+		if err != nil {
+			return nil, fmt.Errorf("load chunk store: %w", err)
+		}
+	}
+
 	manifestPath := filepath.Join(dataDir, "manifests", packageName)
 	originalManifestPath := manifestPath + ".bak"
 
@@ -436,15 +688,15 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 
 	if _, err := os.Stat(originalManifestPath); err == nil {
 		// Backup exists, load it as the source of truth
-		fmt.Println("Loading original manifest from backup...")
+		r.logf("Loading original manifest from backup...")
 		origM, err := ReadFile(originalManifestPath)
 		if err != nil {
-			return fmt.Errorf("failed to read backup manifest: %w", err)
+			return nil, fmt.Errorf("failed to read backup manifest: %w", err)
 		}
 		*manifest = *origM
 	} else {
 		// No backup, create one from current (assumed original)
-		fmt.Println("Creating backup of original manifest...")
+		r.logf("Creating backup of original manifest...")
 		input, err := os.ReadFile(manifestPath)
 		if err == nil {
 			os.WriteFile(originalManifestPath, input, 0644)
@@ -457,11 +709,39 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 	pkgPath := filepath.Join(dataDir, "packages", packageName)
 	srcPkg, err := OpenPackage(manifest, pkgPath)
 	if err != nil {
-		return fmt.Errorf("failed to open source package: %w", err)
+		return nil, fmt.Errorf("failed to open source package: %w", err)
 	}
 	defer srcPkg.Close()
 
-	fmt.Println("Starting Quick Swap (In-Place Modification)...")
+	var dictTable *DictTable
+	var dictProcs *dictProcessorCache
+	if cfg.dict {
+		var err error
+		dictTable, err = LoadDictTable(dictTablePath(dataDir, packageName))
+		if err != nil {
+			return nil, fmt.Errorf("load dict table: %w", err)
+		}
+		r.logf("Training per-type dictionaries...")
+		trained := trainDictionariesFromSource(manifest, srcPkg.readFrameCompressed)
+		for i, ts := range trained.TypeSymbols {
+			dictTable.addIfAbsent(ts, trained.Dicts[i])
+		}
+		dictProcs = newDictProcessorCache()
+	}
+
+	// Unlike Repack, QuickRepack only ever appends new frames, so a
+	// CodecIndex from a previous run still describes every existing
+	// frame correctly - load it (if any) and extend it, matching
+	// ChunkStore/DictTable's own load-then-extend behavior here.
+	var codecIndex *CodecIndex
+	if len(cfg.codecs) > 0 {
+		codecIndex, err = LoadCodecIndex(codecIndexPath(dataDir, packageName))
+		if err != nil {
+			return nil, fmt.Errorf("load codec index: %w", err)
+		}
+	}
+
+	r.logf("Starting Quick Swap (In-Place Modification)...")
 
 	totalFiles := 0
 	for _, chunk := range fileMap {
@@ -490,7 +770,7 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 		}
 	}
 
-	fmt.Println("Checking for identical files...")
+	r.logf("Checking for identical files...")
 	type checkItem struct {
 		key [128]byte
 		fc  FrameContent
@@ -514,7 +794,7 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 	for _, item := range checks {
 		newData, err := os.ReadFile(item.mod.Path)
 		if err != nil {
-			return fmt.Errorf("read input %s: %w", item.mod.Path, err)
+			return nil, fmt.Errorf("read input %s: %w", item.mod.Path, err)
 		}
 
 		if uint32(len(newData)) == item.fc.Size {
@@ -527,12 +807,12 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 	}
 
 	if skippedCount > 0 {
-		fmt.Printf("Skipped %d identical files.\n", skippedCount)
+		r.logf("Skipped %d identical files.", skippedCount)
 	}
 
 	if len(modifiedFilesLookupTable) == 0 {
-		fmt.Println("No files changed. Nothing to repack.")
-		return nil
+		r.logf("No files changed. Nothing to repack.")
+		return &RepackResult{PackageSizes: packageFileSizes(dataDir, packageName, manifest.Header.PackageCount)}, nil
 	}
 
 	affectedFrames := make(map[uint32]bool)
@@ -541,7 +821,7 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 			affectedFrames[fc.FrameIndex] = true
 		}
 	}
-	fmt.Printf("Mapped %d files to modify across %d frames.\n", len(modifiedFilesLookupTable), len(affectedFrames))
+	r.logf("Mapped %d files to modify across %d frames.", len(modifiedFilesLookupTable), len(affectedFrames))
 
 	contentsByFrame := make(map[uint32][]fcWrapper)
 	for k, v := range manifest.FrameContents {
@@ -569,17 +849,40 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 	}
 	sort.Ints(framesToProcess)
 
+	// New frames are appended in framesToProcess order, so each original
+	// frame's eventual index is known up front - the dedup-enabled
+	// producer goroutines below need this to register a ChunkLocation
+	// without waiting for the sequential writer to catch up.
+	newFrameIndexByOrigIdx := make(map[int]uint32, len(framesToProcess))
+	for i, idx := range framesToProcess {
+		newFrameIndexByOrigIdx[idx] = uint32(len(manifest.Frames) + i)
+	}
+
 	lookaheadSize := runtime.NumCPU() * 4
 	futureResults := make(chan chan frameResult, lookaheadSize)
 
 	go func() {
 		defer close(futureResults)
 		for _, idx := range framesToProcess {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
 			resultChan := make(chan frameResult, 1)
 			futureResults <- resultChan
 
 			go func(idx int, ch chan frameResult) {
+				select {
+				case <-ctx.Done():
+					ch <- frameResult{index: idx, err: ctx.Err()}
+					return
+				default:
+				}
+
 				v := manifest.Frames[idx]
+				sourcePath := fmt.Sprintf("%s/packages/%s_%d", dataDir, packageName, v.PackageIndex)
 				res := frameResult{index: idx, isModified: true, decompressedSize: v.Length}
 
 				rawReadBuf := readPool.Get().([]byte)
@@ -591,7 +894,7 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 				res.rawReadBuf = rawReadBuf
 
 				if int(v.PackageIndex) >= len(srcPkg.files) {
-					res.err = fmt.Errorf("invalid package index %d", v.PackageIndex)
+					res.err = fmt.Errorf("frame %d (%s): invalid package index %d", idx, sourcePath, v.PackageIndex)
 					ch <- res
 					return
 				}
@@ -599,7 +902,7 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 
 				if v.CompressedSize > 0 {
 					if _, err := activeFile.ReadAt(rawReadBuf, int64(v.Offset)); err != nil {
-						res.err = err
+						res.err = fmt.Errorf("frame %d (%s): %w", idx, sourcePath, err)
 						ch <- res
 						return
 					}
@@ -608,7 +911,7 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 				decompBuf := decompPool.Get().([]byte)
 				decompBytes, err := zstd.Decompress(decompBuf[:0], rawReadBuf)
 				if err != nil {
-					res.err = err
+					res.err = fmt.Errorf("frame %d (%s): %w", idx, sourcePath, err)
 					ch <- res
 					return
 				}
@@ -627,34 +930,63 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 					return sorted[a].fc.DataOffset < sorted[b].fc.DataOffset
 				})
 
+				var dedupOffset uint32
+				if cfg.dedup {
+					res.dedupLocations = make(map[[128]byte]ChunkLocation, len(sorted))
+				}
+
 				for j := 0; j < len(sorted); j++ {
 					buf := [128]byte{}
 					binary.LittleEndian.PutUint64(buf[0:64], uint64(sorted[j].fc.TypeSymbol))
 					binary.LittleEndian.PutUint64(buf[64:128], uint64(sorted[j].fc.FileSymbol))
 
+					var data []byte
 					if modFile, exists := modifiedFilesLookupTable[buf]; exists && modFile.FileSymbol != 0 {
 						modData, err := os.ReadFile(modFile.Path)
 						if err != nil {
-							res.err = err
+							res.err = fmt.Errorf("frame %d (%s): %w", idx, modFile.Path, err)
 							ch <- res
 							return
 						}
-						constructionBuf.Write(modData)
+						data = modData
 					} else {
 						start := sorted[j].fc.DataOffset
 						end := start + sorted[j].fc.Size
 						if end > uint32(len(decompBytes)) {
-							res.err = fmt.Errorf("frame content out of bounds")
+							res.err = fmt.Errorf("frame %d (%s): frame content out of bounds", idx, sourcePath)
 							ch <- res
 							return
 						}
-						constructionBuf.Write(decompBytes[start:end])
+						data = decompBytes[start:end]
+					}
+
+					if !cfg.dedup {
+						constructionBuf.Write(data)
+						continue
+					}
+
+					hash := fingerprintFile(data, dedupParams)
+					if loc, ok := dedupStore.lookup(hash); ok {
+						res.dedupLocations[buf] = loc
+						res.dedupSaved += int64(len(data))
+						continue
 					}
+
+					own := ChunkLocation{FrameIndex: newFrameIndexByOrigIdx[idx], DataOffset: dedupOffset, Size: uint32(len(data))}
+					res.dedupLocations[buf] = dedupStore.registerIfAbsent(hash, own)
+					constructionBuf.Write(data)
+					dedupOffset += uint32(len(data))
 				}
 
-				compBuf := compPool.Get().([]byte)
-				encodedData, _ := zstd.CompressLevel(compBuf[:0], constructionBuf.Bytes(), zstd.BestSpeed)
+				encodedData, codecTag, dictID, cerr := compressModifiedFrame(cfg, constructionBuf.Bytes(), dictTable, dictProcs, sorted)
+				if cerr != nil {
+					res.err = fmt.Errorf("frame %d (%s): %w", idx, sourcePath, cerr)
+					ch <- res
+					return
+				}
 				res.data = encodedData
+				res.codecTag = codecTag
+				res.dictID = dictID
 				res.decompressedSize = uint32(constructionBuf.Len())
 
 				ch <- res
@@ -662,11 +994,17 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 		}
 	}()
 
-	fmt.Println("Writing modified frames...")
+	r.logf("Writing modified frames...")
+	result := &RepackResult{}
 	for resultCh := range futureResults {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		res := <-resultCh
 		if res.err != nil {
-			return res.err
+			res.releaseBuffers()
+			return nil, res.err
 		}
 
 		newFrameIndex := len(manifest.Frames)
@@ -685,6 +1023,19 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 			binary.LittleEndian.PutUint64(buf[0:64], uint64(sorted[j].fc.TypeSymbol))
 			binary.LittleEndian.PutUint64(buf[64:128], uint64(sorted[j].fc.FileSymbol))
 
+			if cfg.dedup {
+				loc := res.dedupLocations[buf]
+				manifest.FrameContents[sorted[j].index] = FrameContent{
+					TypeSymbol: sorted[j].fc.TypeSymbol,
+					FileSymbol: sorted[j].fc.FileSymbol,
+					FrameIndex: loc.FrameIndex,
+					DataOffset: loc.DataOffset,
+					Size:       loc.Size,
+					Alignment:  sorted[j].fc.Alignment,
+				}
+				continue
+			}
+
 			size := sorted[j].fc.Size
 			if modFile, exists := modifiedFilesLookupTable[buf]; exists && modFile.FileSymbol != 0 {
 				size = modFile.Size
@@ -701,23 +1052,58 @@ func QuickRepack(manifest *Manifest, fileMap [][]ScannedFile, dataDir, packageNa
 			currentOffset += size
 		}
 
+		if cfg.dict {
+			dictTable.recordFrame(uint32(newFrameIndex), res.dictID)
+		}
+		if codecIndex != nil {
+			codecIndex.recordFrame(uint32(newFrameIndex), res.codecTag)
+		}
+		cfg.stats.record(res.codecTag, int(res.decompressedSize), len(res.data))
+
 		if err := writer.write(manifest, res.data, res.decompressedSize); err != nil {
-			return err
+			res.releaseBuffers()
+			return nil, err
 		}
 
-		if res.rawReadBuf != nil {
-			readPool.Put(res.rawReadBuf)
+		result.FramesProcessed++
+		result.BytesIn += int64(res.decompressedSize)
+		result.BytesOut += int64(len(res.data))
+		result.DedupSavedBytes += res.dedupSaved
+		r.report(result.FramesProcessed, len(framesToProcess), "repack", result.BytesIn, result.BytesOut)
+
+		res.releaseBuffers()
+	}
+
+	writer.close()
+
+	if cfg.dedup {
+		dedupStore.resolvePackageIndexes(manifest.Frames)
+		if err := dedupStore.Save(dedupSidecarPath(dataDir, packageName)); err != nil {
+			return nil, fmt.Errorf("save chunk store: %w", err)
 		}
-		if res.decompBuf != nil {
-			decompPool.Put(res.decompBuf)
+	}
+
+	if cfg.dict {
+		if err := dictTable.Save(dictTablePath(dataDir, packageName)); err != nil {
+			return nil, fmt.Errorf("save dict table: %w", err)
 		}
-		if res.data != nil {
-			compPool.Put(res.data)
+	}
+
+	if codecIndex != nil {
+		if err := codecIndex.WriteFile(codecIndexPath(dataDir, packageName)); err != nil {
+			return nil, fmt.Errorf("save codec index: %w", err)
 		}
 	}
 
-	writer.close()
+	result.PackageSizes = packageFileSizes(dataDir, packageName, manifest.Header.PackageCount)
+
+	if err := os.MkdirAll(filepath.Dir(manifestPath), 0755); err != nil {
+		return nil, fmt.Errorf("create manifest dir: %w", err)
+	}
 
-	fmt.Printf("Updating manifest: %s\n", manifestPath)
-	return WriteFile(manifestPath, manifest)
+	r.logf("Updating manifest: %s", manifestPath)
+	if err := WriteFile(manifestPath, manifest); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
@@ -0,0 +1,202 @@
+package manifest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/DataDog/zstd"
+)
+
+// CodecStats accumulates bytes-in (decompressed)/bytes-out (compressed
+// written)/frame-count counters for one codec tag across a Repack or
+// QuickRepack run.
+type CodecStats struct {
+	Frames   int
+	BytesIn  int64
+	BytesOut int64
+}
+
+// RepackStats reports per-codec compression counters gathered by a
+// Repack/QuickRepack run configured with WithStats. Only frames actually
+// recompressed this run are counted - frames WithFastCopy passed through
+// untouched never went through compressModifiedFrame, so they contribute
+// nothing here regardless of what codec built them originally.
+type RepackStats struct {
+	mu     sync.Mutex
+	Codecs map[CodecTag]*CodecStats
+}
+
+// NewRepackStats returns an empty RepackStats ready to pass to WithStats.
+func NewRepackStats() *RepackStats {
+	return &RepackStats{Codecs: make(map[CodecTag]*CodecStats)}
+}
+
+// record adds one frame's counters under tag, creating the entry if this
+// is the first frame seen for that codec. A nil *RepackStats (the
+// default, when WithStats isn't passed) is a no-op.
+func (s *RepackStats) record(tag CodecTag, bytesIn, bytesOut int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cs, ok := s.Codecs[tag]
+	if !ok {
+		cs = &CodecStats{}
+		s.Codecs[tag] = cs
+	}
+	cs.Frames++
+	cs.BytesIn += int64(bytesIn)
+	cs.BytesOut += int64(bytesOut)
+}
+
+// WithCodecs enables multi-codec best-of compression for every modified
+// frame: instead of always compressing with defaultCodec (or, with
+// WithDictionaries also set, the dictionary-or-plain-zstd choice that
+// option makes on its own), Repack/QuickRepack compress the frame with
+// every tag in codecs - up to WithCodecBudget of them concurrently - and
+// keep whichever result is smallest. Pass CodecZstdDict alongside
+// WithDictionaries to let trained dictionaries compete with the plain
+// codecs; a frame whose dominant TypeSymbol has no trained dictionary
+// simply drops that one candidate rather than failing the frame.
+func WithCodecs(codecs ...CodecTag) RepackOption {
+	return func(c *repackConfig) { c.codecs = codecs }
+}
+
+// WithCodecBudget caps how many codecs WithCodecs compresses a frame with
+// concurrently. The default (0) tries every enabled codec for a frame at
+// once; a smaller budget trades wall-clock time for peak CPU/memory use
+// on machines repacking with a large codec set.
+func WithCodecBudget(n int) RepackOption {
+	return func(c *repackConfig) { c.codecBudget = n }
+}
+
+// WithStats collects per-codec bytes-in/bytes-out/frame counters into
+// dst as Repack/QuickRepack writes frames. dst is only safe to read once
+// the call returns.
+func WithStats(dst *RepackStats) RepackOption {
+	return func(c *repackConfig) { c.stats = dst }
+}
+
+// compressModifiedFrame compresses a freshly reconstructed frame's bytes
+// for Repack/QuickRepack, returning the bytes to write and which codec
+// (plus, for CodecZstdDict, which trained dictionary id) produced them.
+//
+// With neither WithCodecs nor WithDictionaries set, this keeps the
+// pre-multi-codec behavior - defaultCodec at zstd.BestSpeed - byte for
+// byte, pooling its output buffer exactly like the original inline code
+// did. WithDictionaries alone keeps its own dictionary-or-plain-zstd
+// choice. WithCodecs replaces both with compressFrameBestOf's comparison
+// across the requested codec set.
+func compressModifiedFrame(cfg *repackConfig, data []byte, dictTable *DictTable, dictProcs *dictProcessorCache, sorted []fcWrapper) ([]byte, CodecTag, uint32, error) {
+	if len(cfg.codecs) > 0 {
+		return compressFrameBestOf(data, cfg.codecs, cfg.codecBudget, zstd.BestSpeed, dictTable, dictProcs, dominantTypeSymbol(sorted))
+	}
+
+	if cfg.dict {
+		dictID := dictTable.dictIDFor(dominantTypeSymbol(sorted))
+		proc, err := dictProcs.get(dictID, dictTable.dictFor(dictID), zstd.BestSpeed)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if proc != nil {
+			encoded, err := proc.Compress(nil, data)
+			return encoded, CodecZstdDict, dictID, err
+		}
+		compBuf := compPool.Get().([]byte)
+		encoded, err := zstd.CompressLevel(compBuf[:0], data, zstd.BestSpeed)
+		return encoded, CodecZstd, 0, err
+	}
+
+	if defaultCodec == CodecZstd {
+		compBuf := compPool.Get().([]byte)
+		encoded, err := zstd.CompressLevel(compBuf[:0], data, zstd.BestSpeed)
+		return encoded, CodecZstd, 0, err
+	}
+
+	codec, err := CodecFor(defaultCodec)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	encoded, err := codec.Compress(data, zstd.BestSpeed)
+	return encoded, defaultCodec, 0, err
+}
+
+// codecAttempt is one candidate result from compressFrameBestOf.
+type codecAttempt struct {
+	tag    CodecTag
+	dictID uint32
+	data   []byte
+	err    error
+}
+
+// compressFrameBestOf compresses data with every tag in codecs - at most
+// budget of them running concurrently (0 meaning len(codecs), i.e. no
+// cap) - and returns whichever succeeded with the smallest output.
+//
+// CodecZstdDict is handled outside the generic Codec interface: it asks
+// dictTable for the dictionary trained for dominantType and is skipped as
+// a candidate (not a hard error, unless every candidate fails) if
+// dictTable is nil or has nothing trained for that type, since Codec's
+// Compress signature has no way to thread a per-frame dictionary through.
+func compressFrameBestOf(data []byte, codecs []CodecTag, budget, level int, dictTable *DictTable, dictProcs *dictProcessorCache, dominantType int64) ([]byte, CodecTag, uint32, error) {
+	if budget <= 0 || budget > len(codecs) {
+		budget = len(codecs)
+	}
+
+	results := make([]codecAttempt, len(codecs))
+	sem := make(chan struct{}, budget)
+	var wg sync.WaitGroup
+	for i, tag := range codecs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, tag CodecTag) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = compressOneCandidate(tag, data, level, dictTable, dictProcs, dominantType)
+		}(i, tag)
+	}
+	wg.Wait()
+
+	var best codecAttempt
+	have := false
+	for _, r := range results {
+		if r.err != nil {
+			continue
+		}
+		if !have || len(r.data) < len(best.data) {
+			best, have = r, true
+		}
+	}
+	if !have {
+		return nil, 0, 0, fmt.Errorf("no codec in %v produced a usable result", codecs)
+	}
+	return best.data, best.tag, best.dictID, nil
+}
+
+// compressOneCandidate runs a single WithCodecs candidate for
+// compressFrameBestOf.
+func compressOneCandidate(tag CodecTag, data []byte, level int, dictTable *DictTable, dictProcs *dictProcessorCache, dominantType int64) codecAttempt {
+	if tag == CodecZstdDict {
+		if dictTable == nil {
+			return codecAttempt{tag: tag, err: fmt.Errorf("zstd+dict: WithDictionaries not enabled")}
+		}
+		dictID := dictTable.dictIDFor(dominantType)
+		proc, err := dictProcs.get(dictID, dictTable.dictFor(dictID), level)
+		if err != nil {
+			return codecAttempt{tag: tag, err: err}
+		}
+		if proc == nil {
+			return codecAttempt{tag: tag, err: fmt.Errorf("zstd+dict: no trained dictionary for type %d", dominantType)}
+		}
+		out, err := proc.Compress(nil, data)
+		return codecAttempt{tag: tag, dictID: dictID, data: out, err: err}
+	}
+
+	codec, err := CodecFor(tag)
+	if err != nil {
+		return codecAttempt{tag: tag, err: err}
+	}
+	out, err := codec.Compress(data, level)
+	return codecAttempt{tag: tag, data: out, err: err}
+}
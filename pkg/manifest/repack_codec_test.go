@@ -0,0 +1,146 @@
+package manifest
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestCodecIndexRecordFramePadsWithZstd(t *testing.T) {
+	ci := &CodecIndex{}
+	ci.recordFrame(2, CodecBrotli)
+
+	if len(ci.Codecs) != 3 {
+		t.Fatalf("got %d entries, want 3", len(ci.Codecs))
+	}
+	for i, tag := range ci.Codecs[:2] {
+		if tag != CodecZstd {
+			t.Errorf("padded frame %d: got %d, want CodecZstd", i, tag)
+		}
+	}
+	if ci.Codecs[2] != CodecBrotli {
+		t.Errorf("frame 2: got %d, want CodecBrotli", ci.Codecs[2])
+	}
+}
+
+func TestLoadCodecIndexMissingFileReturnsEmpty(t *testing.T) {
+	dir := t.TempDir()
+	ci, err := LoadCodecIndex(codecIndexPath(dir, "pkg"))
+	if err != nil {
+		t.Fatalf("LoadCodecIndex: %v", err)
+	}
+	if len(ci.Codecs) != 0 {
+		t.Fatalf("got %d codecs, want 0", len(ci.Codecs))
+	}
+}
+
+func TestLoadCodecIndexLoadsExisting(t *testing.T) {
+	dir := t.TempDir()
+	path := codecIndexPath(dir, "pkg")
+
+	written := &CodecIndex{Codecs: []CodecTag{CodecLZ4, CodecStored}}
+	if err := written.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	loaded, err := LoadCodecIndex(path)
+	if err != nil {
+		t.Fatalf("LoadCodecIndex: %v", err)
+	}
+	if len(loaded.Codecs) != 2 || loaded.Codecs[0] != CodecLZ4 || loaded.Codecs[1] != CodecStored {
+		t.Fatalf("got %v, want [%d %d]", loaded.Codecs, CodecLZ4, CodecStored)
+	}
+}
+
+func TestCompressFrameBestOfPicksSmallest(t *testing.T) {
+	data := bytes.Repeat([]byte("best-of candidate payload "), 400)
+
+	encoded, tag, dictID, err := compressFrameBestOf(data, []CodecTag{CodecStored, CodecZstd, CodecBrotli}, 0, DefaultCompressionLevel, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("compressFrameBestOf: %v", err)
+	}
+	if tag == CodecStored {
+		t.Fatalf("picked CodecStored over a compressing codec for repetitive input")
+	}
+	if dictID != 0 {
+		t.Errorf("got dictID %d, want 0 for a non-dict codec", dictID)
+	}
+
+	codec, err := CodecFor(tag)
+	if err != nil {
+		t.Fatalf("CodecFor(%d): %v", tag, err)
+	}
+	decoded, err := codec.Decompress(nil, encoded)
+	if err != nil {
+		t.Fatalf("Decompress: %v", err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Fatal("round trip mismatch")
+	}
+}
+
+// TestCompressFrameBestOfSkipsMissingDict checks that a CodecZstdDict
+// candidate with no trained dictionary (dictTable nil here) is dropped
+// rather than failing the whole comparison, as long as another codec in
+// the set still succeeds.
+func TestCompressFrameBestOfSkipsMissingDict(t *testing.T) {
+	data := bytes.Repeat([]byte("payload without a trained dictionary "), 50)
+
+	_, tag, _, err := compressFrameBestOf(data, []CodecTag{CodecZstdDict, CodecZstd}, 0, DefaultCompressionLevel, nil, nil, 42)
+	if err != nil {
+		t.Fatalf("compressFrameBestOf: %v", err)
+	}
+	if tag != CodecZstd {
+		t.Errorf("got codec %d, want CodecZstd", tag)
+	}
+}
+
+func TestCompressFrameBestOfAllCandidatesFail(t *testing.T) {
+	_, _, _, err := compressFrameBestOf([]byte("x"), []CodecTag{CodecZstdDict}, 0, DefaultCompressionLevel, nil, nil, 0)
+	if err == nil {
+		t.Fatal("expected error when every candidate fails")
+	}
+}
+
+func TestRepackStatsRecordAccumulatesPerCodec(t *testing.T) {
+	stats := NewRepackStats()
+	stats.record(CodecZstd, 100, 40)
+	stats.record(CodecZstd, 50, 20)
+	stats.record(CodecBrotli, 200, 70)
+
+	zstdStats := stats.Codecs[CodecZstd]
+	if zstdStats == nil || zstdStats.Frames != 2 || zstdStats.BytesIn != 150 || zstdStats.BytesOut != 60 {
+		t.Fatalf("got %+v, want {Frames:2 BytesIn:150 BytesOut:60}", zstdStats)
+	}
+
+	brotliStats := stats.Codecs[CodecBrotli]
+	if brotliStats == nil || brotliStats.Frames != 1 || brotliStats.BytesIn != 200 || brotliStats.BytesOut != 70 {
+		t.Fatalf("got %+v, want {Frames:1 BytesIn:200 BytesOut:70}", brotliStats)
+	}
+}
+
+func TestRepackStatsRecordOnNilIsNoOp(t *testing.T) {
+	var stats *RepackStats
+	stats.record(CodecZstd, 1, 1) // must not panic
+}
+
+func TestCodecIndexPathMatchesConvention(t *testing.T) {
+	got := codecIndexPath("/data", "pkg")
+	want := filepath.Join("/data", "manifests", "pkg.codecs")
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestWithCodecsAndWithCodecBudgetConfigureRepack(t *testing.T) {
+	cfg := &repackConfig{}
+	WithCodecs(CodecZstd, CodecBrotli, CodecLZ4)(cfg)
+	WithCodecBudget(2)(cfg)
+
+	if len(cfg.codecs) != 3 {
+		t.Fatalf("got %d codecs, want 3", len(cfg.codecs))
+	}
+	if cfg.codecBudget != 2 {
+		t.Fatalf("got budget %d, want 2", cfg.codecBudget)
+	}
+}
@@ -0,0 +1,61 @@
+package manifest
+
+import "context"
+
+// Logger is satisfied by *log.Logger and lets Repacker report progress
+// messages to a caller-chosen destination instead of stdout.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// Progress reports Repack/QuickRepack progress as frames complete: done
+// and total count frames in the current phase, phase names the stage
+// (Repack and QuickRepack both report a single "repack" phase), and
+// bytesIn/bytesOut are the cumulative
+// decompressed/compressed bytes processed so far this phase.
+type Progress func(done, total int, phase string, bytesIn, bytesOut int64)
+
+// RepackResult summarizes a completed Repack or QuickRepack run.
+type RepackResult struct {
+	FramesProcessed int
+	BytesIn         int64
+	BytesOut        int64
+
+	// DedupSavedBytes is how many decompressed bytes WithDedup pointed at
+	// an already-written chunk instead of writing again; zero whenever
+	// WithDedup wasn't passed.
+	DedupSavedBytes int64
+
+	// PackageSizes is each output package file's final size in bytes,
+	// keyed by package index.
+	PackageSizes map[uint32]int64
+}
+
+// Repacker runs Repack/QuickRepack with an optional cancellation
+// context, progress callback, and logger. The zero value runs
+// uncancellably and prints nothing: Context defaults to
+// context.Background(), and a nil Progress/Logger is simply never called.
+type Repacker struct {
+	Context  context.Context
+	Progress Progress
+	Logger   Logger
+}
+
+func (r *Repacker) ctx() context.Context {
+	if r.Context != nil {
+		return r.Context
+	}
+	return context.Background()
+}
+
+func (r *Repacker) logf(format string, args ...interface{}) {
+	if r.Logger != nil {
+		r.Logger.Printf(format, args...)
+	}
+}
+
+func (r *Repacker) report(done, total int, phase string, bytesIn, bytesOut int64) {
+	if r.Progress != nil {
+		r.Progress(done, total, phase, bytesIn, bytesOut)
+	}
+}
@@ -0,0 +1,179 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ChunkLocation is where a previously-stored file's bytes already live:
+// which frame holds them, the frame-relative offset and length, and
+// (filled in once the manifest's final layout is known, see
+// ChunkStore.resolvePackageIndexes) the package file that frame belongs
+// to. It mirrors ChunkRef, but is addressed by content fingerprint rather
+// than by position in a single build's chunk list, and is meant to
+// survive across separate Repack/QuickRepack runs instead of just one.
+type ChunkLocation struct {
+	PackageIndex uint32
+	FrameIndex   uint32
+	DataOffset   uint32
+	Size         uint32
+}
+
+// ChunkStore is the persistent dedup sidecar WithDedup consults: a
+// file's content-defined-chunk fingerprint (see fingerprintFile) maps to
+// the frame that already holds its bytes, so Repack/QuickRepack can
+// point a new or modified file straight at existing storage instead of
+// writing another copy. It is stored alongside the manifest as
+// "<name>.chunks" (see dedupSidecarPath), same convention as ChunkIndex.
+//
+// A full Repack rewrites every package file from scratch, so a sidecar
+// from a previous run can't be trusted to still describe valid frame
+// locations - Repack always starts from an empty ChunkStore and writes a
+// fresh one. QuickRepack only ever appends frames to existing packages,
+// so bytes it recorded in one run are still exactly where it left them;
+// QuickRepack with WithDedup loads the existing sidecar (if any) and
+// extends it.
+type ChunkStore struct {
+	mu      sync.Mutex
+	entries map[ChunkHash]ChunkLocation
+}
+
+// NewChunkStore returns an empty ChunkStore.
+func NewChunkStore() *ChunkStore {
+	return &ChunkStore{entries: make(map[ChunkHash]ChunkLocation)}
+}
+
+const chunkStoreMagic = "EVRCST1\x00"
+
+// dedupSidecarPath is where WithDedup persists a package's ChunkStore,
+// matching the "manifests/<pkg>.chunks" layout ChunkIndex already uses.
+func dedupSidecarPath(dir, packageName string) string {
+	return filepath.Join(dir, "manifests", packageName+".chunks")
+}
+
+// LoadChunkStore reads a ChunkStore previously written by Save. A
+// missing file is not an error: it returns an empty store, since the
+// first dedup-enabled run of a package has nothing to load yet.
+func LoadChunkStore(path string) (*ChunkStore, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewChunkStore(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read chunk store: %w", err)
+	}
+
+	if len(data) < len(chunkStoreMagic) || string(data[:len(chunkStoreMagic)]) != chunkStoreMagic {
+		return nil, fmt.Errorf("invalid chunk store magic")
+	}
+	r := bytes.NewReader(data[len(chunkStoreMagic):])
+
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("read chunk store count: %w", err)
+	}
+
+	store := &ChunkStore{entries: make(map[ChunkHash]ChunkLocation, count)}
+	for i := uint32(0); i < count; i++ {
+		var hash ChunkHash
+		var loc ChunkLocation
+		if _, err := r.Read(hash[:]); err != nil {
+			return nil, fmt.Errorf("read chunk store hash: %w", err)
+		}
+		binary.Read(r, binary.LittleEndian, &loc.PackageIndex)
+		binary.Read(r, binary.LittleEndian, &loc.FrameIndex)
+		binary.Read(r, binary.LittleEndian, &loc.DataOffset)
+		binary.Read(r, binary.LittleEndian, &loc.Size)
+		store.entries[hash] = loc
+	}
+
+	return store, nil
+}
+
+// Save serializes the chunk store to path, creating its directory if
+// needed.
+func (s *ChunkStore) Save(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create chunk store dir: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(chunkStoreMagic)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(s.entries)))
+	for hash, loc := range s.entries {
+		buf.Write(hash[:])
+		binary.Write(&buf, binary.LittleEndian, loc.PackageIndex)
+		binary.Write(&buf, binary.LittleEndian, loc.FrameIndex)
+		binary.Write(&buf, binary.LittleEndian, loc.DataOffset)
+		binary.Write(&buf, binary.LittleEndian, loc.Size)
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// lookup returns the known location of hash, if any.
+func (s *ChunkStore) lookup(hash ChunkHash) (ChunkLocation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	loc, ok := s.entries[hash]
+	return loc, ok
+}
+
+// registerIfAbsent records loc under hash unless an entry already exists
+// (the first writer of a given fingerprint wins; later frames just
+// reference it), returning the entry that ends up stored either way.
+func (s *ChunkStore) registerIfAbsent(hash ChunkHash, loc ChunkLocation) ChunkLocation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.entries[hash]; ok {
+		return existing
+	}
+	s.entries[hash] = loc
+	return loc
+}
+
+// resolvePackageIndexes backfills the PackageIndex of every entry whose
+// frame was written during this run, now that frames carries the final
+// layout. It's a no-op for entries that were already valid (loaded from
+// a prior run, or untouched this run).
+func (s *ChunkStore) resolvePackageIndexes(frames []Frame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, loc := range s.entries {
+		if int(loc.FrameIndex) < len(frames) {
+			loc.PackageIndex = frames[loc.FrameIndex].PackageIndex
+			s.entries[hash] = loc
+		}
+	}
+}
+
+// fingerprintFile computes a content-defined-chunk fingerprint for data:
+// it splits data into chunks with splitChunks, hashes each chunk (see
+// hashChunk), then hashes the concatenation of those chunk hashes into a
+// single digest.
+//
+// A manifest can only give one (FrameIndex, DataOffset, Size) span per
+// file, so WithDedup can't splice a file back together from chunks
+// scattered across old and new storage the way BuildDeduped's
+// ChunkIndex/FileChunks can - it can only recognize a file as a whole
+// exact repeat of something already stored and point at that existing
+// span. fingerprintFile still goes through the same rolling-hash
+// chunker and per-chunk strong hash the dedup story is built on, rather
+// than a plain whole-file hash, so chunk-level reuse stays the natural
+// next step if FrameContent ever grows multi-segment support.
+func fingerprintFile(data []byte, params chunkParams) ChunkHash {
+	chunks := splitChunks(data, params)
+	var combined bytes.Buffer
+	for _, c := range chunks {
+		h := hashChunk(c)
+		combined.Write(h[:])
+	}
+	return hashChunk(combined.Bytes())
+}
@@ -0,0 +1,321 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/DataDog/zstd"
+)
+
+// repackDictSize is the per-TypeSymbol dictionary size WithDictionaries
+// trains to, the same order of magnitude dictionary_test.go exercises and
+// small enough that loading a dictionary per frame stays cheap.
+const repackDictSize = 16 * 1024
+
+// repackDictSampleCap is how much decompressed content WithDictionaries
+// samples per TypeSymbol before it stops reading further source frames
+// for that type - training on every byte of a large package isn't worth
+// the extra decompression work for a sampler that's already frequency-
+// based rather than a true COVER/FastCover trainer (see trainDictionary).
+const repackDictSampleCap = 100 * 1024 * 1024
+
+// DictTable is the trained-dictionary sidecar WithDictionaries persists
+// alongside a repacked manifest ("manifests/<pkg>.dicts"), mirroring
+// ChunkStore and CodecIndex: a small out-of-band table next to the
+// manifest rather than a change to the fixed-layout manifest binary, so a
+// manifest built without WithDictionaries stays byte-identical to the
+// original format and legacy readers need not know it exists.
+type DictTable struct {
+	// TypeSymbols[id-1] is the TypeSymbol Dicts[id-1] was trained for. id
+	// 0 is reserved to mean "no dictionary", so FrameDictID can default to
+	// zero for every frame written before WithDictionaries existed.
+	TypeSymbols []int64
+	Dicts       [][]byte
+
+	// FrameDictID[i] is the 1-based id into TypeSymbols/Dicts used to
+	// compress Manifest.Frames[i], or 0 if that frame carries no
+	// dictionary.
+	FrameDictID []uint32
+}
+
+const dictTableMagic = "EVRDICT1"
+
+// dictTablePath is where WithDictionaries persists a package's DictTable,
+// matching the "manifests/<pkg>.chunks" / "manifests/<pkg>.codecs" layout
+// ChunkStore and CodecIndex already use.
+func dictTablePath(dir, packageName string) string {
+	return filepath.Join(dir, "manifests", packageName+".dicts")
+}
+
+// LoadDictTable reads a DictTable previously written by Save. A missing
+// file is not an error: it returns an empty table, since the first
+// dictionary-enabled run of a package has nothing to load yet.
+func LoadDictTable(path string) (*DictTable, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &DictTable{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read dict table: %w", err)
+	}
+
+	if len(data) < len(dictTableMagic) || string(data[:len(dictTableMagic)]) != dictTableMagic {
+		return nil, fmt.Errorf("invalid dict table magic")
+	}
+	r := bytes.NewReader(data[len(dictTableMagic):])
+
+	var dictCount uint32
+	if err := binary.Read(r, binary.LittleEndian, &dictCount); err != nil {
+		return nil, fmt.Errorf("read dict count: %w", err)
+	}
+
+	table := &DictTable{
+		TypeSymbols: make([]int64, dictCount),
+		Dicts:       make([][]byte, dictCount),
+	}
+	for i := uint32(0); i < dictCount; i++ {
+		if err := binary.Read(r, binary.LittleEndian, &table.TypeSymbols[i]); err != nil {
+			return nil, fmt.Errorf("read dict %d type symbol: %w", i, err)
+		}
+		var size uint32
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return nil, fmt.Errorf("read dict %d size: %w", i, err)
+		}
+		dict := make([]byte, size)
+		if _, err := r.Read(dict); err != nil {
+			return nil, fmt.Errorf("read dict %d bytes: %w", i, err)
+		}
+		table.Dicts[i] = dict
+	}
+
+	var frameCount uint32
+	if err := binary.Read(r, binary.LittleEndian, &frameCount); err != nil {
+		return nil, fmt.Errorf("read frame count: %w", err)
+	}
+	table.FrameDictID = make([]uint32, frameCount)
+	for i := range table.FrameDictID {
+		if err := binary.Read(r, binary.LittleEndian, &table.FrameDictID[i]); err != nil {
+			return nil, fmt.Errorf("read frame dict id %d: %w", i, err)
+		}
+	}
+
+	return table, nil
+}
+
+// Save serializes the dict table to path, creating its directory if
+// needed.
+func (t *DictTable) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create dict table dir: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(dictTableMagic)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(t.Dicts)))
+	for i, dict := range t.Dicts {
+		binary.Write(&buf, binary.LittleEndian, t.TypeSymbols[i])
+		binary.Write(&buf, binary.LittleEndian, uint32(len(dict)))
+		buf.Write(dict)
+	}
+	binary.Write(&buf, binary.LittleEndian, uint32(len(t.FrameDictID)))
+	for _, id := range t.FrameDictID {
+		binary.Write(&buf, binary.LittleEndian, id)
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// dictIDFor returns the 1-based dictionary id trained for typeSymbol, or 0
+// if none was.
+func (t *DictTable) dictIDFor(typeSymbol int64) uint32 {
+	for i, ts := range t.TypeSymbols {
+		if ts == typeSymbol {
+			return uint32(i + 1)
+		}
+	}
+	return 0
+}
+
+// dictFor returns the raw trained dictionary bytes for id (as returned by
+// dictIDFor/DictIDForFrame), or nil for id 0 or an id this table doesn't
+// know about.
+func (t *DictTable) dictFor(id uint32) []byte {
+	if id == 0 || int(id) > len(t.Dicts) {
+		return nil
+	}
+	return t.Dicts[id-1]
+}
+
+// addIfAbsent adds dict as typeSymbol's trained dictionary unless one is
+// already recorded, returning the id either way. QuickRepack relies on
+// this to fold freshly retrained dictionaries into a table loaded from a
+// previous run without disturbing the ids earlier FrameDictID entries
+// already point at.
+func (t *DictTable) addIfAbsent(typeSymbol int64, dict []byte) uint32 {
+	if id := t.dictIDFor(typeSymbol); id != 0 {
+		return id
+	}
+	t.TypeSymbols = append(t.TypeSymbols, typeSymbol)
+	t.Dicts = append(t.Dicts, dict)
+	return uint32(len(t.Dicts))
+}
+
+// DictIDForFrame returns the dictionary id recorded for frameIndex, or 0
+// if the table doesn't cover it (including a nil *DictTable).
+func (t *DictTable) DictIDForFrame(frameIndex uint32) uint32 {
+	if t == nil || int(frameIndex) >= len(t.FrameDictID) {
+		return 0
+	}
+	return t.FrameDictID[frameIndex]
+}
+
+// recordFrame stores the dictionary id used for frameIndex, padding
+// FrameDictID with zeros (meaning "no dictionary") for any frame indices
+// skipped so far.
+func (t *DictTable) recordFrame(frameIndex uint32, dictID uint32) {
+	for uint32(len(t.FrameDictID)) <= frameIndex {
+		t.FrameDictID = append(t.FrameDictID, 0)
+	}
+	t.FrameDictID[frameIndex] = dictID
+}
+
+// Decompress decompresses a frame written by WithDictionaries, given the
+// dictID DictIDForFrame recorded for it (0 meaning it was written without
+// a dictionary). Package.Extract does not yet consult a DictTable on its
+// own - same as CodecIndex, this is a sidecar a caller loads and applies
+// explicitly until extraction grows pluggable per-frame dictionary/codec
+// support.
+func (t *DictTable) Decompress(dictID uint32, compressed []byte, decompressedSize uint32) ([]byte, error) {
+	return decompressWithDict(make([]byte, 0, decompressedSize), compressed, t.dictFor(dictID))
+}
+
+// dominantTypeSymbol returns the TypeSymbol appearing in the most entries
+// of sorted (ties broken toward the lowest value, for determinism), the
+// same "what is this frame mostly made of" question CodecPolicy answers
+// per frame in BuildMultiCodec.
+func dominantTypeSymbol(sorted []fcWrapper) int64 {
+	counts := make(map[int64]int, len(sorted))
+	for _, w := range sorted {
+		counts[w.fc.TypeSymbol]++
+	}
+
+	best := int64(0)
+	bestCount := -1
+	for ts, c := range counts {
+		if c > bestCount || (c == bestCount && ts < best) {
+			best, bestCount = ts, c
+		}
+	}
+	return best
+}
+
+// trainDictionariesFromSource runs WithDictionaries' preparation pass: it
+// groups manifest's FrameContents by TypeSymbol, reads back (via
+// readFrame) and decompresses whichever frames hold them until it has
+// sampled up to repackDictSampleCap bytes per type, and trains one
+// dictionary per type from those samples via trainDictionary. A source
+// frame that fails to read or decompress is simply skipped for sampling
+// purposes - it still goes through the normal repack path later, just
+// without contributing training data.
+func trainDictionariesFromSource(manifest *Manifest, readFrame func(Frame) ([]byte, error)) *DictTable {
+	byFrame := make(map[uint32][]FrameContent)
+	for _, fc := range manifest.FrameContents {
+		byFrame[fc.FrameIndex] = append(byFrame[fc.FrameIndex], fc)
+	}
+
+	samplesByType := make(map[int64][][]byte)
+	sampledBytes := make(map[int64]int)
+
+	for frameIdx, contents := range byFrame {
+		needsSampling := false
+		for _, fc := range contents {
+			if sampledBytes[fc.TypeSymbol] < repackDictSampleCap {
+				needsSampling = true
+				break
+			}
+		}
+		if !needsSampling {
+			continue
+		}
+
+		if int(frameIdx) >= len(manifest.Frames) {
+			continue
+		}
+		frame := manifest.Frames[frameIdx]
+		if frame.CompressedSize == 0 {
+			continue
+		}
+
+		compressed, err := readFrame(frame)
+		if err != nil {
+			continue
+		}
+		decompressed, err := zstd.Decompress(make([]byte, 0, frame.Length), compressed)
+		if err != nil {
+			continue
+		}
+
+		for _, fc := range contents {
+			if sampledBytes[fc.TypeSymbol] >= repackDictSampleCap {
+				continue
+			}
+			if int(fc.DataOffset+fc.Size) > len(decompressed) {
+				continue
+			}
+			data := decompressed[fc.DataOffset : fc.DataOffset+fc.Size]
+			samplesByType[fc.TypeSymbol] = append(samplesByType[fc.TypeSymbol], data)
+			sampledBytes[fc.TypeSymbol] += len(data)
+		}
+	}
+
+	table := &DictTable{}
+	for typeSymbol, samples := range samplesByType {
+		dict := trainDictionary(samples, repackDictSize)
+		if len(dict) == 0 {
+			continue
+		}
+		table.TypeSymbols = append(table.TypeSymbols, typeSymbol)
+		table.Dicts = append(table.Dicts, dict)
+	}
+
+	return table
+}
+
+// dictProcessorCache caches a zstd.BulkProcessor per dictionary id so the
+// repack pipeline doesn't pay dictionary-loading cost on every frame that
+// shares one - the same reason readPool/decompPool/compPool exist instead
+// of allocating fresh buffers per frame, just for processor objects
+// instead of []byte.
+type dictProcessorCache struct {
+	mu    sync.Mutex
+	procs map[uint32]*zstd.BulkProcessor
+}
+
+func newDictProcessorCache() *dictProcessorCache {
+	return &dictProcessorCache{procs: make(map[uint32]*zstd.BulkProcessor)}
+}
+
+// get returns the cached compressor for dictID (creating and caching one
+// from dict on first use), or nil if dictID is 0.
+func (c *dictProcessorCache) get(dictID uint32, dict []byte, level int) (*zstd.BulkProcessor, error) {
+	if dictID == 0 {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if proc, ok := c.procs[dictID]; ok {
+		return proc, nil
+	}
+
+	proc, err := zstd.NewBulkProcessor(dict, level)
+	if err != nil {
+		return nil, fmt.Errorf("create dictionary processor %d: %w", dictID, err)
+	}
+	c.procs[dictID] = proc
+	return proc, nil
+}
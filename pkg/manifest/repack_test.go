@@ -0,0 +1,126 @@
+package manifest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/zstd"
+)
+
+// readFrameContent decompresses fc's backing frame in pkg and slices out
+// just fc's bytes, without depending on Package.Extract's on-disk layout.
+func readFrameContent(t *testing.T, pkg *Package, fc FrameContent) []byte {
+	t.Helper()
+	frame := pkg.manifest.Frames[fc.FrameIndex]
+	compressed, err := pkg.readFrameCompressed(frame)
+	if err != nil {
+		t.Fatalf("read frame %d: %v", fc.FrameIndex, err)
+	}
+	decompressed, err := zstd.Decompress(nil, compressed)
+	if err != nil {
+		t.Fatalf("decompress frame %d: %v", fc.FrameIndex, err)
+	}
+	return decompressed[fc.DataOffset : fc.DataOffset+fc.Size]
+}
+
+func TestRepackFastCopyPassesThroughUnmodifiedFrames(t *testing.T) {
+	dataDir := t.TempDir()
+	groups := makeBuildFixture(t, dataDir)
+
+	origManifest, err := NewBuilder(dataDir, "pkg").Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	outDir := t.TempDir()
+	if _, err := (&Repacker{}).Repack(origManifest, nil, outDir, "pkg", dataDir); err != nil {
+		t.Fatalf("Repack: %v", err)
+	}
+
+	srcPkg, err := OpenPackage(origManifest, filepath.Join(dataDir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("open source package: %v", err)
+	}
+	defer srcPkg.Close()
+
+	repackedManifest, err := ReadFile(filepath.Join(outDir, "manifests", "pkg"))
+	if err != nil {
+		t.Fatalf("read repacked manifest: %v", err)
+	}
+	dstPkg, err := OpenPackage(repackedManifest, filepath.Join(outDir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("open repacked package: %v", err)
+	}
+	defer dstPkg.Close()
+
+	// No fileMap entries means every frame is untouched, so WithFastCopy's
+	// default true should have copied each frame's compressed bytes
+	// directly rather than decompressing and recompressing them.
+	for i := 0; i < len(origManifest.Frames)-1; i++ {
+		frame := origManifest.Frames[i]
+		if frame.Length == 0 || frame.CompressedSize == 0 {
+			continue
+		}
+		srcBytes, err := srcPkg.readFrameCompressed(frame)
+		if err != nil {
+			t.Fatalf("read source frame %d: %v", i, err)
+		}
+		dstBytes, err := dstPkg.readFrameCompressed(repackedManifest.Frames[i])
+		if err != nil {
+			t.Fatalf("read repacked frame %d: %v", i, err)
+		}
+		if !bytes.Equal(srcBytes, dstBytes) {
+			t.Fatalf("frame %d compressed bytes differ after fast-copy repack", i)
+		}
+	}
+}
+
+func TestRepackRecompressesModifiedFrames(t *testing.T) {
+	dataDir := t.TempDir()
+	groups := makeBuildFixture(t, dataDir)
+
+	origManifest, err := NewBuilder(dataDir, "pkg").Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	// Replace the content of one file with new bytes of the same size, so
+	// only its frame should be decompressed/recompressed. groups[0][0] has
+	// FileSymbol 0, which Repack treats as "no replacement", so use
+	// groups[0][1] instead.
+	target := groups[0][1]
+	newContent := bytes.Repeat([]byte("updated!"), int(target.Size)/8+1)[:target.Size]
+	if err := os.WriteFile(target.Path, newContent, 0644); err != nil {
+		t.Fatalf("rewrite fixture file: %v", err)
+	}
+
+	outDir := t.TempDir()
+	fileMap := [][]ScannedFile{{target}}
+	if _, err := (&Repacker{}).Repack(origManifest, fileMap, outDir, "pkg", dataDir); err != nil {
+		t.Fatalf("Repack: %v", err)
+	}
+
+	repackedManifest, err := ReadFile(filepath.Join(outDir, "manifests", "pkg"))
+	if err != nil {
+		t.Fatalf("read repacked manifest: %v", err)
+	}
+	dstPkg, err := OpenPackage(repackedManifest, filepath.Join(outDir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("open repacked package: %v", err)
+	}
+	defer dstPkg.Close()
+
+	for _, fc := range repackedManifest.FrameContents {
+		if fc.TypeSymbol != target.TypeSymbol || fc.FileSymbol != target.FileSymbol {
+			continue
+		}
+		data := readFrameContent(t, dstPkg, fc)
+		if !bytes.Equal(data, newContent) {
+			t.Fatalf("repacked content = %q, want %q", data, newContent)
+		}
+		return
+	}
+	t.Fatal("modified file not found in repacked manifest")
+}
@@ -0,0 +1,247 @@
+package manifest
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/zstd"
+)
+
+// assetKey identifies a single file within a Package's manifest.
+type assetKey struct {
+	typeSymbol int64
+	fileSymbol int64
+}
+
+// Server serves the individual files inside an opened Package over HTTP,
+// at paths of the form /{typeSymbol}/{fileSymbol} (hex-encoded, matching
+// the naming Package.Extract writes to disk). It decompresses frames on
+// demand and caches the result so repeated requests into the same frame
+// don't repay decompression cost.
+type Server struct {
+	pkg   *Package
+	index map[assetKey]FrameContent
+
+	mimeTypes map[int64]string
+	cache     *frameCache
+}
+
+// defaultMIMETypes maps known TypeSymbol values to MIME types. The game's
+// type symbols aren't a documented enum, so this starts empty; callers
+// that know their asset catalog's scheme should register it via
+// SetMIMEType. Anything with no match falls back to
+// application/octet-stream.
+var defaultMIMETypes = map[int64]string{}
+
+// NewServer wraps pkg for HTTP serving. SetCacheSize and SetMIMEType
+// should be called, if at all, before the Server starts receiving
+// requests.
+func NewServer(pkg *Package) *Server {
+	index := make(map[assetKey]FrameContent, len(pkg.manifest.FrameContents))
+	for _, fc := range pkg.manifest.FrameContents {
+		index[assetKey{fc.TypeSymbol, fc.FileSymbol}] = fc
+	}
+
+	return &Server{
+		pkg:       pkg,
+		index:     index,
+		mimeTypes: make(map[int64]string),
+		cache:     newFrameCache(DefaultServerCacheSize),
+	}
+}
+
+// SetMIMEType registers the Content-Type served for files with the given
+// TypeSymbol, overriding defaultMIMETypes.
+func (s *Server) SetMIMEType(typeSymbol int64, mimeType string) {
+	s.mimeTypes[typeSymbol] = mimeType
+}
+
+// SetCacheSize bounds the total decompressed bytes held in the server's
+// frame cache.
+func (s *Server) SetCacheSize(bytes int) {
+	s.cache = newFrameCache(bytes)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	typeSymbol, fileSymbol, ok := parseAssetPath(r.URL.Path)
+	if !ok {
+		http.Error(w, "expected path /{typeSymbol}/{fileSymbol}", http.StatusBadRequest)
+		return
+	}
+
+	fc, ok := s.index[assetKey{typeSymbol, fileSymbol}]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if int(fc.FrameIndex) >= len(s.pkg.manifest.Frames) {
+		http.Error(w, "frame index out of range", http.StatusInternalServerError)
+		return
+	}
+	frame := s.pkg.manifest.Frames[fc.FrameIndex]
+
+	compressed, err := s.pkg.readFrameCompressed(frame)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", frameETag(compressed))
+	if modTime, ok := s.pkg.frameModTime(frame); ok {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	contentType := s.mimeTypeFor(fc.TypeSymbol)
+
+	if acceptsZstd(r.Header.Get("Accept-Encoding")) {
+		// The client asked to avoid paying decompression cost on the
+		// server too: ship the whole compressed frame as-is and use
+		// Content-Range purely to tell the client where this file's
+		// bytes land once it decompresses the frame itself. This isn't
+		// a byte-range of the response body (which is the full frame),
+		// so the status stays 200 rather than 206.
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Encoding", "zstd")
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", fc.DataOffset, fc.DataOffset+fc.Size-1, frame.Length))
+		w.Header().Set("Content-Length", strconv.Itoa(len(compressed)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(compressed)
+		return
+	}
+
+	decompressed, err := s.decompressFrame(frameCacheKey{packageIndex: frame.PackageIndex, frameIndex: fc.FrameIndex}, frame, compressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if int64(fc.DataOffset)+int64(fc.Size) > int64(len(decompressed)) {
+		http.Error(w, "file extends past decompressed frame", http.StatusInternalServerError)
+		return
+	}
+	data := decompressed[fc.DataOffset : fc.DataOffset+fc.Size]
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	if start, end, ok := parseRange(r.Header.Get("Range"), int64(len(data))); ok {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start : end+1])
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (s *Server) decompressFrame(key frameCacheKey, frame Frame, compressed []byte) ([]byte, error) {
+	if data, ok := s.cache.get(key); ok {
+		return data, nil
+	}
+
+	decompressed, err := zstd.Decompress(make([]byte, 0, frame.Length), compressed)
+	if err != nil {
+		return nil, fmt.Errorf("decompress frame: %w", err)
+	}
+
+	s.cache.put(key, decompressed)
+	return decompressed, nil
+}
+
+func (s *Server) mimeTypeFor(typeSymbol int64) string {
+	if mt, ok := s.mimeTypes[typeSymbol]; ok {
+		return mt
+	}
+	if mt, ok := defaultMIMETypes[typeSymbol]; ok {
+		return mt
+	}
+	return "application/octet-stream"
+}
+
+func frameETag(compressed []byte) string {
+	hash := hashChunk(compressed)
+	return fmt.Sprintf("%q", hex.EncodeToString(hash[:]))
+}
+
+func parseAssetPath(path string) (typeSymbol, fileSymbol int64, ok bool) {
+	path = strings.Trim(path, "/")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	typeSymbol, err := strconv.ParseInt(parts[0], 16, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	fileSymbol, err = strconv.ParseInt(parts[1], 16, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return typeSymbol, fileSymbol, true
+}
+
+func acceptsZstd(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if name == "zstd" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange parses a single-range "bytes=start-end" Range header against
+// a resource of the given size. Multi-range requests aren't supported and
+// fall back to serving the full body, same as a missing or malformed
+// header.
+func parseRange(header string, size int64) (start, end int64, ok bool) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return 0, 0, false
+	}
+	spec := strings.TrimPrefix(header, prefix)
+	if strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	if parts[0] == "" {
+		suffixLen, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || suffixLen <= 0 {
+			return 0, 0, false
+		}
+		if suffixLen > size {
+			suffixLen = size
+		}
+		return size - suffixLen, size - 1, size > 0
+	}
+
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+
+	if parts[1] == "" {
+		return start, size - 1, true
+	}
+
+	end, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || end < start {
+		return 0, 0, false
+	}
+	if end >= size {
+		end = size - 1
+	}
+	return start, end, true
+}
@@ -0,0 +1,166 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func makeServerFixture(t *testing.T) *Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	filesDir := filepath.Join(dir, "files")
+	if err := os.MkdirAll(filesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	groups := [][]ScannedFile{{}}
+	contents := []string{"hello, world", "goodbye, world"}
+	for i, content := range contents {
+		path := filepath.Join(filesDir, fmt.Sprintf("f%d", i))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		groups[0] = append(groups[0], ScannedFile{
+			TypeSymbol: 1,
+			FileSymbol: int64(i),
+			Path:       path,
+			Size:       uint32(len(content)),
+		})
+	}
+
+	builder := NewBuilder(dir, "pkg")
+	manifest, err := builder.Build(groups)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	pkg, err := OpenPackage(manifest, filepath.Join(dir, "packages", "pkg"))
+	if err != nil {
+		t.Fatalf("OpenPackage: %v", err)
+	}
+	t.Cleanup(func() { pkg.Close() })
+
+	return NewServer(pkg)
+}
+
+func TestServerServesFileByTypeAndFileSymbol(t *testing.T) {
+	server := makeServerFixture(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/1/0", nil)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", rec.Code)
+	}
+	if got := rec.Body.String(); got != "hello, world" {
+		t.Errorf("got body %q, want %q", got, "hello, world")
+	}
+	if rec.Header().Get("ETag") == "" {
+		t.Error("expected ETag header to be set")
+	}
+}
+
+func TestServerServesSecondFileInSameFrame(t *testing.T) {
+	server := makeServerFixture(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/1/1", nil)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", rec.Code)
+	}
+	if got := rec.Body.String(); got != "goodbye, world" {
+		t.Errorf("got body %q, want %q", got, "goodbye, world")
+	}
+}
+
+func TestServerReturnsNotFoundForUnknownAsset(t *testing.T) {
+	server := makeServerFixture(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/1/99", nil)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, want 404", rec.Code)
+	}
+}
+
+func TestServerRejectsMalformedPath(t *testing.T) {
+	server := makeServerFixture(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/not-hex/0", nil)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d, want 400", rec.Code)
+	}
+}
+
+func TestServerHonorsRangeHeader(t *testing.T) {
+	server := makeServerFixture(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/1/0", nil)
+	req.Header.Set("Range", "bytes=0-4")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("got status %d, want 206", rec.Code)
+	}
+	if got := rec.Body.String(); got != "hello" {
+		t.Errorf("got body %q, want %q", got, "hello")
+	}
+	if cr := rec.Header().Get("Content-Range"); cr != "bytes 0-4/12" {
+		t.Errorf("got Content-Range %q, want %q", cr, "bytes 0-4/12")
+	}
+}
+
+func TestServerZstdPassthroughAvoidsDecompression(t *testing.T) {
+	server := makeServerFixture(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/1/0", nil)
+	req.Header.Set("Accept-Encoding", "zstd")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", rec.Code)
+	}
+	if enc := rec.Header().Get("Content-Encoding"); enc != "zstd" {
+		t.Errorf("got Content-Encoding %q, want zstd", enc)
+	}
+	if bytes.Equal(rec.Body.Bytes(), []byte("hello, world")) {
+		t.Error("expected compressed body, got raw decompressed bytes")
+	}
+	if cr := rec.Header().Get("Content-Range"); cr == "" {
+		t.Error("expected Content-Range header describing the sub-range")
+	}
+}
+
+func TestServerCachesDecompressedFrame(t *testing.T) {
+	server := makeServerFixture(t)
+	server.SetCacheSize(1024)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/1/0", nil)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: got status %d, want 200", i, rec.Code)
+		}
+	}
+
+	if len(server.cache.items) != 1 {
+		t.Errorf("got %d cached frames, want 1", len(server.cache.items))
+	}
+}
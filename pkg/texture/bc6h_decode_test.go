@@ -0,0 +1,78 @@
+package texture
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHalf16ToFloat32(t *testing.T) {
+	cases := []struct {
+		name string
+		h    uint16
+		want float32
+	}{
+		{"positive zero", 0x0000, 0},
+		{"negative zero", 0x8000, float32(math.Copysign(0, -1))},
+		{"one", 0x3C00, 1},
+		{"negative one", 0xBC00, -1},
+		{"two", 0x4000, 2},
+		{"smallest normal", 0x0400, 6.103515625e-05},
+		{"largest subnormal", 0x03FF, 6.097555160522461e-05},
+		{"infinity", 0x7C00, float32(math.Inf(1))},
+		{"negative infinity", 0xFC00, float32(math.Inf(-1))},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := half16ToFloat32(c.h)
+			if got != c.want {
+				t.Errorf("half16ToFloat32(%#04x) = %v, want %v", c.h, got, c.want)
+			}
+		})
+	}
+
+	if got := half16ToFloat32(0x7E00); !math.IsNaN(float64(got)) {
+		t.Errorf("half16ToFloat32(NaN) = %v, want NaN", got)
+	}
+}
+
+func TestDecompressBC6HTwoRegionModeNotImplemented(t *testing.T) {
+	data := make([]byte, 16) // first 2 bits zero: BC6H mode 1, two-region
+
+	_, err := decompressBC6H(data, 4, 4, false)
+	if err == nil {
+		t.Fatal("expected decompressBC6H to report the two-region block as not implemented")
+	}
+}
+
+func TestDecompressBC6HSingleRegionModeDecodes(t *testing.T) {
+	block := make([]byte, 16)
+	bw := &bc7BitWriterForTest{data: block}
+	bw.writeBits(3, 2) // first2 = 3: single-region mode family
+	bw.writeBits(0, 3) // next3 = 0: mode 11, direct, 10-bit endpoints
+	// Both endpoints left zero (60 bits for 6 components), all 16
+	// indices left zero: every texel should decode to RGB (0,0,0).
+
+	img, err := decompressBC6H(block, 4, 4, false)
+	if err != nil {
+		t.Fatalf("decompressBC6H: %v", err)
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if got := img.At4f(x, y); got != ([4]float32{0, 0, 0, 1}) {
+				t.Errorf("At4f(%d,%d) = %v, want {0 0 0 1}", x, y, got)
+			}
+		}
+	}
+}
+
+func TestDecompressBC6HInvalidDimensions(t *testing.T) {
+	if _, err := decompressBC6H(make([]byte, 16), 0, 4, false); err == nil {
+		t.Error("expected error for zero width")
+	}
+}
+
+func TestDecompressBC6HShortData(t *testing.T) {
+	if _, err := decompressBC6H(make([]byte, 8), 4, 4, false); err == nil {
+		t.Error("expected error for truncated block data")
+	}
+}
@@ -0,0 +1,281 @@
+package texture
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// quantizeBC6HComponent finds the bits-wide value (unsigned, or
+// two's-complement signed if signed is true) whose decoded value - via
+// unquantizeBC6H, then finishUnquantizeBC6H, then half16ToFloat32 - is
+// closest to target. unquantizeBC6H and finishUnquantizeBC6H are both
+// non-decreasing in their input, so the decoded float is non-decreasing in
+// v over the code's whole range, which makes a binary search exact rather
+// than relying on an inverted formula that would need its own separate
+// verification.
+func quantizeBC6HComponent(target float32, bits int, signed bool) int32 {
+	decode := func(v int32) float32 {
+		return half16ToFloat32(finishUnquantizeBC6H(unquantizeBC6H(v, bits, signed), signed))
+	}
+
+	var lo, hi int32
+	if signed {
+		lo, hi = -(1 << uint(bits-1)), (1<<uint(bits-1))-1
+	} else {
+		lo, hi = 0, (1<<uint(bits))-1
+	}
+	loBound := lo
+
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if decode(mid) < target {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	best := lo
+	if lo > loBound && math.Abs(float64(decode(lo-1)-target)) < math.Abs(float64(decode(lo)-target)) {
+		best = lo - 1
+	}
+	return best
+}
+
+// writeBC6HValue writes v's low bits bits, two's-complement-wrapped the
+// same way readBC6HSigned reads them back.
+func writeBC6HValue(bw *bc7BitWriter, v int32, bits int) {
+	mask := uint32(1<<uint(bits)) - 1
+	bw.writeBits(uint32(v)&mask, bits)
+}
+
+// pcaEndpointsHDR derives a 4x4 HDR tile's two RGB endpoints the same way
+// pcaEndpoints4 does for BC7's RGBA tiles: project every sample onto the
+// dominant eigenvector of the tile's covariance matrix and take the
+// extremes of that projection.
+func pcaEndpointsHDR(samples [16][3]float64) (min, max [3]float64) {
+	var mean [3]float64
+	for _, s := range samples {
+		for c := 0; c < 3; c++ {
+			mean[c] += s[c]
+		}
+	}
+	for c := range mean {
+		mean[c] /= float64(len(samples))
+	}
+
+	var cov [3][3]float64
+	for _, s := range samples {
+		var d [3]float64
+		for c := 0; c < 3; c++ {
+			d[c] = s[c] - mean[c]
+		}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				cov[i][j] += d[i] * d[j]
+			}
+		}
+	}
+
+	axis := [3]float64{1, 1, 1}
+	for iter := 0; iter < 8; iter++ {
+		var next [3]float64
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				next[i] += cov[i][j] * axis[j]
+			}
+		}
+		var norm float64
+		for _, v := range next {
+			norm += v * v
+		}
+		norm = math.Sqrt(norm)
+		if norm < 1e-9 {
+			break
+		}
+		for i := range axis {
+			axis[i] = next[i] / norm
+		}
+	}
+
+	minProj, maxProj := math.Inf(1), math.Inf(-1)
+	for _, s := range samples {
+		var d [3]float64
+		var proj float64
+		for c := 0; c < 3; c++ {
+			d[c] = s[c] - mean[c]
+			proj += d[c] * axis[c]
+		}
+		if proj < minProj {
+			minProj, min = proj, s
+		}
+		if proj > maxProj {
+			maxProj, max = proj, s
+		}
+	}
+	return min, max
+}
+
+// encodeBC6HSingleRegionBlock encodes a 4x4 HDR tile as BC6H's "mode 14"
+// single-region block (first2=3, next3=3 in decompressBC6H's scheme, i.e.
+// bc6hSingleRegionModes[3]): 16-bit direct endpoints with a narrow 4-bit
+// signed delta between them, 16 per-texel indices. Mode 14 is picked over
+// the narrower-endpoint single-region modes (11 through 13) because at
+// those widths unquantizeBC6H's formula only spans a small fraction of the
+// half-float range before hitting its max-code saturation special case (see
+// quantizeBC6HComponent), so anything but very small HDR values round-trips
+// badly; mode 14's 16-bit endpoints are unquantized by unquantizeBC6H as an
+// identity (bits >= 15/16), giving a linear mapping across the whole
+// representable range with no such dead zone. The tradeoff is the opposite
+// one: the two endpoints must be within the 4-bit delta's ±8 of each other
+// in the 16-bit domain, which just means CompressBCHDR spends its bits on
+// per-channel magnitude rather than per-tile contrast - the same kind of
+// single-fixed-mode compromise encodeBC7Mode6/5 make by never searching
+// BC7's other modes.
+//
+// Endpoints come from PCA over the tile's linear RGB cloud
+// (pcaEndpointsHDR), quantized with quantizeBC6HComponent; indices are
+// chosen by least squared error against what decodeBC6HSingleRegionBlock
+// would reconstruct, the same way encodeBC7Mode6 scores its index choice
+// against this package's own BC7 decoder.
+func encodeBC6HSingleRegionBlock(pixels [16][3]float32, signed bool) [16]byte {
+	mode := bc6hSingleRegionModes[3] // mode 14
+	bits := mode.endpointBits
+
+	var pts [16][3]float64
+	for i, p := range pixels {
+		pts[i] = [3]float64{float64(p[0]), float64(p[1]), float64(p[2])}
+	}
+	lo, hi := pcaEndpointsHDR(pts)
+
+	maxDelta := int32(1<<uint(mode.deltaBits-1)) - 1
+	minDelta := -(int32(1) << uint(mode.deltaBits-1))
+	endpointMask := int32(1<<uint(bits)) - 1
+
+	var base, second, delta [3]int32
+	for c := 0; c < 3; c++ {
+		base[c] = quantizeBC6HComponent(float32(hi[c]), bits, signed)
+		ideal := quantizeBC6HComponent(float32(lo[c]), bits, signed)
+
+		d := ideal - base[c]
+		if d > maxDelta {
+			d = maxDelta
+		} else if d < minDelta {
+			d = minDelta
+		}
+		delta[c] = d
+
+		wrapped := (base[c] + d) & endpointMask
+		if signed && wrapped&(1<<uint(bits-1)) != 0 {
+			wrapped -= 1 << uint(bits)
+		}
+		second[c] = wrapped
+	}
+
+	var unq0, unq1 [3]int32
+	for c := 0; c < 3; c++ {
+		unq0[c] = unquantizeBC6H(base[c], bits, signed)
+		unq1[c] = unquantizeBC6H(second[c], bits, signed)
+	}
+
+	indices := make([]int, 16)
+	for i, p := range pixels {
+		maxIdx := 15
+		if i == 0 {
+			maxIdx = 7 // anchor texel: only 3 index bits are written
+		}
+		best, bestErr := 0, math.Inf(1)
+		for idx := 0; idx <= maxIdx; idx++ {
+			w := int32(bc7Weights4[idx])
+			var e float64
+			for c := 0; c < 3; c++ {
+				interp := (unq0[c]*(64-w) + unq1[c]*w + 32) >> 6
+				decoded := half16ToFloat32(finishUnquantizeBC6H(interp, signed))
+				d := float64(p[c]) - float64(decoded)
+				e += d * d
+			}
+			if e < bestErr {
+				bestErr, best = e, idx
+			}
+		}
+		indices[i] = best
+	}
+
+	bw := newBC7BitWriter(16)
+	bw.writeBits(3, 2) // first2 = 3: single-region mode family
+	bw.writeBits(3, 3) // next3 = 3: mode 14, 16-bit endpoints + 4-bit delta
+	for c := 0; c < 3; c++ {
+		writeBC6HValue(bw, base[c], bits)
+	}
+	for c := 0; c < 3; c++ {
+		writeBC6HValue(bw, delta[c], mode.deltaBits)
+	}
+	for i, idx := range indices {
+		n := 4
+		if i == 0 {
+			n = 3
+		}
+		bw.writeBits(uint32(idx), n)
+	}
+
+	var out [16]byte
+	copy(out[:], bw.data)
+	return out
+}
+
+// CompressBCHDR compresses img (linear, unclamped HDR RGB) into raw BC6H
+// block data in the same row-major 4x4-tile layout DecodeBCHDR reads back.
+// signed selects BC6H's signed (SF16) vs unsigned (UF16) variant. Only the
+// single-region "mode 14" layout is emitted (see
+// encodeBC6HSingleRegionBlock for why that mode specifically) - this
+// package has the same gap encoding the two-region modes that
+// decompressBC6H has decoding them: no validated partition/fixup table to
+// check a transcription against - so every tile is encoded as if it were a
+// single region, which costs quality on tiles with a sharp two-region split
+// but always round-trips through DecodeBCHDR.
+func CompressBCHDR(img HDRImage, signed bool) ([]byte, error) {
+	if img == nil {
+		return nil, fmt.Errorf("image is required")
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid dimensions %dx%d", width, height)
+	}
+
+	blocksWide := (width + 3) / 4
+	blocksHigh := (height + 3) / 4
+	out := make([]byte, blocksWide*blocksHigh*16)
+
+	for by := 0; by < blocksHigh; by++ {
+		for bx := 0; bx < blocksWide; bx++ {
+			block := gatherBlockHDR(img, bounds, bx, by)
+			offset := (by*blocksWide + bx) * 16
+			encoded := encodeBC6HSingleRegionBlock(block, signed)
+			copy(out[offset:offset+16], encoded[:])
+		}
+	}
+	return out, nil
+}
+
+// gatherBlockHDR reads a 4x4 HDR tile at (bx, by), replicating edge pixels
+// the same way gatherBlock does for LDR tiles whose bounds aren't a
+// multiple of 4.
+func gatherBlockHDR(img HDRImage, bounds image.Rectangle, bx, by int) [16][3]float32 {
+	var block [16][3]float32
+	for row := 0; row < 4; row++ {
+		y := bounds.Min.Y + by*4 + row
+		if y > bounds.Max.Y-1 {
+			y = bounds.Max.Y - 1
+		}
+		for col := 0; col < 4; col++ {
+			x := bounds.Min.X + bx*4 + col
+			if x > bounds.Max.X-1 {
+				x = bounds.Max.X - 1
+			}
+			p := img.At4f(x, y)
+			block[row*4+col] = [3]float32{p[0], p[1], p[2]}
+		}
+	}
+	return block
+}
@@ -0,0 +1,82 @@
+package texture
+
+import (
+	"image"
+	"testing"
+)
+
+// fillSolidHDR returns a 4x4 NRGBA128F filled with a single RGB color, so a
+// round trip through CompressBCHDR/decompressBC6H should come back close to
+// exact.
+func fillSolidHDR(r, g, b float32) *NRGBA128F {
+	img := NewNRGBA128F(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetAt4f(x, y, [4]float32{r, g, b, 1})
+		}
+	}
+	return img
+}
+
+func approxEqualF32(a, b, tolerance float32) bool {
+	d := a - b
+	if d < 0 {
+		d = -d
+	}
+	return d <= tolerance
+}
+
+func TestCompressBCHDRRoundTripSolidColorUnsigned(t *testing.T) {
+	want := [3]float32{0.5, 1.25, 3.0}
+	raw, err := CompressBCHDR(fillSolidHDR(want[0], want[1], want[2]), false)
+	if err != nil {
+		t.Fatalf("CompressBCHDR: %v", err)
+	}
+	if len(raw) != 16 {
+		t.Fatalf("BC6H block size = %d, want 16", len(raw))
+	}
+
+	img, err := decompressBC6H(raw, 4, 4, false)
+	if err != nil {
+		t.Fatalf("decompressBC6H: %v", err)
+	}
+	got := img.At4f(0, 0)
+	for c := 0; c < 3; c++ {
+		if !approxEqualF32(got[c], want[c], want[c]*0.05+0.01) {
+			t.Errorf("round-tripped channel %d = %v, want close to %v", c, got[c], want[c])
+		}
+	}
+}
+
+func TestCompressBCHDRRoundTripSolidColorSigned(t *testing.T) {
+	want := [3]float32{-2.0, 0.75, -0.125}
+	raw, err := CompressBCHDR(fillSolidHDR(want[0], want[1], want[2]), true)
+	if err != nil {
+		t.Fatalf("CompressBCHDR: %v", err)
+	}
+
+	img, err := decompressBC6H(raw, 4, 4, true)
+	if err != nil {
+		t.Fatalf("decompressBC6H: %v", err)
+	}
+	got := img.At4f(0, 0)
+	for c := 0; c < 3; c++ {
+		if !approxEqualF32(got[c], want[c], float32(abs32(want[c]))*0.05+0.01) {
+			t.Errorf("round-tripped channel %d = %v, want close to %v", c, got[c], want[c])
+		}
+	}
+}
+
+func abs32(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestCompressBCHDRInvalidDimensions(t *testing.T) {
+	empty := NewNRGBA128F(image.Rect(0, 0, 0, 0))
+	if _, err := CompressBCHDR(empty, false); err == nil {
+		t.Error("expected error for zero-sized image")
+	}
+}
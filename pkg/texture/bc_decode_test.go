@@ -0,0 +1,344 @@
+package texture
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// rgb565 packs 8-bit components down to a BC1 RGB565 endpoint, rounding
+// the same way unpack565 expands them back out so round-trip tests land
+// on exact values.
+func rgb565(r, g, b uint8) uint16 {
+	return uint16(r>>3)<<11 | uint16(g>>2)<<5 | uint16(b>>3)
+}
+
+func TestDecodeBC1FourColor(t *testing.T) {
+	block := make([]byte, 8)
+	c0 := rgb565(255, 0, 0)
+	c1 := rgb565(0, 0, 255)
+	binary := func(v uint16) []byte { return []byte{byte(v), byte(v >> 8)} }
+	copy(block[0:2], binary(c0))
+	copy(block[2:4], binary(c1))
+	// indices are 2 bits each, LSB-first per pixel: pixel0 -> 0 (c0), pixel1 -> 1 (c1)
+	block[4] = 0x04
+
+	pixels, err := decodeBC1Block(block)
+	if err != nil {
+		t.Fatalf("decodeBC1Block: %v", err)
+	}
+	if pixels[0].A != 255 || pixels[0].R != 255 {
+		t.Errorf("pixel0 = %+v, want opaque red", pixels[0])
+	}
+	if pixels[1].B != 255 || pixels[1].A != 255 {
+		t.Errorf("pixel1 = %+v, want opaque blue", pixels[1])
+	}
+}
+
+func TestDecodeBC1PunchThroughAlpha(t *testing.T) {
+	block := make([]byte, 8)
+	c0 := rgb565(0, 255, 0)
+	c1 := rgb565(255, 255, 255)
+	// c0 <= c1 numerically selects the punch-through variant.
+	block[0], block[1] = byte(c0), byte(c0>>8)
+	block[2], block[3] = byte(c1), byte(c1>>8)
+	// pixel0 -> index 3 (transparent), pixel1 -> index 0 (opaque color0)
+	block[4] = 0x03
+
+	pixels, err := decodeBC1Block(block)
+	if err != nil {
+		t.Fatalf("decodeBC1Block: %v", err)
+	}
+	if pixels[0].A != 0 {
+		t.Errorf("pixel0 alpha = %d, want 0 (punch-through)", pixels[0].A)
+	}
+	if pixels[1].A != 255 || pixels[1].G != 255 {
+		t.Errorf("pixel1 = %+v, want opaque green", pixels[1])
+	}
+}
+
+func TestDecodeBC4Block(t *testing.T) {
+	block := make([]byte, 8)
+	block[0], block[1] = 0, 255 // a0=0, a1=255 -> 8-value interpolation
+	// all indices 0 -> endpoint a0 (0)
+	pixels, err := decodeBC4Block(block, false)
+	if err != nil {
+		t.Fatalf("decodeBC4Block: %v", err)
+	}
+	if pixels[0].R != 0 || pixels[0].A != 255 {
+		t.Errorf("pixel0 = %+v, want R=0", pixels[0])
+	}
+
+	// set every index to 1 (endpoint a1, 255) via the 48-bit index table
+	for i := 2; i < 8; i++ {
+		block[i] = 0x49 // 0b01001001: three 3-bit "1" indices per byte
+	}
+	pixels, err = decodeBC4Block(block, false)
+	if err != nil {
+		t.Fatalf("decodeBC4Block: %v", err)
+	}
+	if pixels[0].R != 255 {
+		t.Errorf("pixel0.R = %d, want 255", pixels[0].R)
+	}
+}
+
+func TestDecodeBC5BlockChannels(t *testing.T) {
+	block := make([]byte, 16)
+	block[0], block[1] = 0, 255 // red endpoints
+	block[8], block[9] = 255, 0 // green endpoints (reversed)
+
+	pixels, err := decodeBC5Block(block, false)
+	if err != nil {
+		t.Fatalf("decodeBC5Block: %v", err)
+	}
+	if pixels[0].R != 0 {
+		t.Errorf("pixel0.R = %d, want 0", pixels[0].R)
+	}
+	if pixels[0].G != 255 {
+		t.Errorf("pixel0.G = %d, want 255", pixels[0].G)
+	}
+	if pixels[0].B != 0 || pixels[0].A != 255 {
+		t.Errorf("pixel0 = %+v, want B=0, A=255", pixels[0])
+	}
+}
+
+func TestDecodeBC7Mode6(t *testing.T) {
+	// Mode bit: bit6 set (7 header bits "0000001" LSB-first means bits
+	// 0-5 are 0 and bit6 is 1).
+	block := make([]byte, 16)
+	br := &bc7BitWriterForTest{data: block}
+	br.writeBit(0) // mode bits 0-5 zero
+	for i := 1; i < 6; i++ {
+		br.writeBit(0)
+	}
+	br.writeBit(1) // mode = 6
+
+	// endpoint0: opaque red (R=254>>1=127 stored as 7 bits, P-bit fills LSB)
+	br.writeBits(127, 7) // r0
+	br.writeBits(0, 7)   // r1
+	br.writeBits(0, 7)   // g0
+	br.writeBits(0, 7)   // g1
+	br.writeBits(0, 7)   // b0
+	br.writeBits(0, 7)   // b1
+	br.writeBits(0, 7)   // a0
+	br.writeBits(0, 7)   // a1
+	br.writeBit(1)       // p0 -> endpoint0 R = 127<<1|1 = 255
+	br.writeBit(0)       // p1 -> endpoint1 = 0
+
+	// all 16 indices -> 0 (endpoint0), anchor uses 3 bits, rest use 4 bits
+	br.writeBits(0, 3)
+	for i := 1; i < 16; i++ {
+		br.writeBits(0, 4)
+	}
+
+	pixels, err := decodeBC7Block(block)
+	if err != nil {
+		t.Fatalf("decodeBC7Block: %v", err)
+	}
+	// endpoint0's shared P-bit (1) ORs into the LSB of every component,
+	// so G/B/A come out as 1 rather than 0 even though their raw 7-bit
+	// fields are 0.
+	if pixels[0].R != 255 || pixels[0].G != 1 || pixels[0].B != 1 || pixels[0].A != 1 {
+		t.Errorf("pixel0 = %+v, want opaque-endpoint0 red", pixels[0])
+	}
+}
+
+func TestDecodeBC7Mode4(t *testing.T) {
+	// Mode bit: bit4 set (unary "00001").
+	block := make([]byte, 16)
+	br := &bc7BitWriterForTest{data: block}
+	for i := 0; i < 4; i++ {
+		br.writeBit(0)
+	}
+	br.writeBit(1) // mode = 4
+
+	br.writeBits(0, 2) // rotation: none
+	br.writeBits(0, 1) // idxMode: 0 -> color gets 2-bit indices, alpha gets 3-bit
+
+	// endpoint0: opaque red, endpoint1: black. All indices stay zero
+	// (left unwritten, so they default to endpoint0), so every decoded
+	// pixel should land on endpoint0 unchanged.
+	br.writeBits(31, 5) // r0 (max 5-bit value -> 255 after bit replication)
+	br.writeBits(0, 5)  // r1
+	br.writeBits(0, 5)  // g0
+	br.writeBits(0, 5)  // g1
+	br.writeBits(0, 5)  // b0
+	br.writeBits(0, 5)  // b1
+	br.writeBits(63, 6) // a0 (max 6-bit value -> 255 after bit replication)
+	br.writeBits(0, 6)  // a1
+
+	pixels, err := decodeBC7Block(block)
+	if err != nil {
+		t.Fatalf("decodeBC7Block: %v", err)
+	}
+	if pixels[0] != (color.NRGBA{255, 0, 0, 255}) {
+		t.Errorf("pixel0 = %+v, want opaque endpoint0 red", pixels[0])
+	}
+}
+
+func TestDecodeBC7UnsupportedMode(t *testing.T) {
+	block := make([]byte, 16)
+	block[0] = 0x01 // mode 0
+	if _, err := decodeBC7Block(block); err == nil {
+		t.Fatal("expected error for unimplemented BC7 mode 0")
+	}
+}
+
+func TestDecodeBCBC7Format(t *testing.T) {
+	block := make([]byte, 16)
+	br := &bc7BitWriterForTest{data: block}
+	br.writeBit(0) // mode bits 0-5 zero
+	for i := 1; i < 6; i++ {
+		br.writeBit(0)
+	}
+	br.writeBit(1) // mode = 6
+
+	br.writeBits(127, 7) // r0
+	br.writeBits(0, 7)   // r1
+	br.writeBits(0, 7)   // g0
+	br.writeBits(0, 7)   // g1
+	br.writeBits(0, 7)   // b0
+	br.writeBits(0, 7)   // b1
+	br.writeBits(0, 7)   // a0
+	br.writeBits(0, 7)   // a1
+	br.writeBit(1)       // p0
+	br.writeBit(0)       // p1
+	br.writeBits(0, 3)
+	for i := 1; i < 16; i++ {
+		br.writeBits(0, 4)
+	}
+
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC7_UNORM}
+	img, err := DecodeBC(block, meta)
+	if err != nil {
+		t.Fatalf("DecodeBC: %v", err)
+	}
+	if got := img.NRGBAAt(0, 0); got.R != 255 {
+		t.Errorf("pixel(0,0) = %+v, want R=255", got)
+	}
+}
+
+func TestDecompressBC7UnsupportedModePropagates(t *testing.T) {
+	raw := make([]byte, 16)
+	raw[0] = 0x01 // mode 0, unimplemented
+	if _, err := decompressBC7(raw, 4, 4, false); err == nil {
+		t.Fatal("expected decompressBC7 to propagate the block's mode-0 error")
+	}
+}
+
+func TestDecompressBC7InvalidDimensions(t *testing.T) {
+	if _, err := decompressBC7(make([]byte, 16), 0, 4, false); err == nil {
+		t.Error("expected error for zero width")
+	}
+}
+
+func TestDecompressBC7ShortData(t *testing.T) {
+	if _, err := decompressBC7(make([]byte, 8), 4, 4, false); err == nil {
+		t.Error("expected error for truncated block data")
+	}
+}
+
+func TestDecodeBCDispatchAndClipping(t *testing.T) {
+	meta := &TextureMetadata{Width: 5, Height: 5, DXGIFormat: DXGI_FORMAT_BC1_UNORM}
+	// 2x2 blocks of 8 bytes each to cover a 5x5 (non-multiple-of-4) image.
+	raw := make([]byte, 4*8)
+	c0 := rgb565(10, 20, 30)
+	for b := 0; b < 4; b++ {
+		raw[b*8], raw[b*8+1] = byte(c0), byte(c0>>8)
+		raw[b*8+2], raw[b*8+3] = byte(c0), byte(c0>>8)
+	}
+
+	img, err := DecodeBC(raw, meta)
+	if err != nil {
+		t.Fatalf("DecodeBC: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 5, 5) {
+		t.Errorf("bounds = %v, want 5x5", img.Bounds())
+	}
+	if got := img.NRGBAAt(4, 4); got.A != 255 {
+		t.Errorf("corner pixel = %+v, want decoded (not left blank)", got)
+	}
+}
+
+func TestDecodeBCTooShort(t *testing.T) {
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC1_UNORM}
+	if _, err := DecodeBC([]byte{1, 2, 3}, meta); err == nil {
+		t.Fatal("expected error for truncated raw data")
+	}
+}
+
+func TestEncodePNGRoundTrip(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+
+	var buf bytes.Buffer
+	if err := EncodePNG(&buf, img); err != nil {
+		t.Fatalf("EncodePNG: %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Fatal("EncodePNG wrote no data")
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("\x89PNG\r\n\x1a\n")) {
+		t.Error("output missing PNG signature")
+	}
+}
+
+// bc7BitWriterForTest is the LSB-first inverse of bc7BitReader, used only
+// to build exact block fixtures for the BC7 decoder tests above.
+type bc7BitWriterForTest struct {
+	data []byte
+	pos  int
+}
+
+func (bw *bc7BitWriterForTest) writeBit(bit uint32) {
+	if bit != 0 {
+		byteIdx := bw.pos / 8
+		bitIdx := uint(bw.pos % 8)
+		bw.data[byteIdx] |= 1 << bitIdx
+	}
+	bw.pos++
+}
+
+func (bw *bc7BitWriterForTest) writeBits(v uint32, n int) {
+	for i := 0; i < n; i++ {
+		bw.writeBit((v >> uint(i)) & 1)
+	}
+}
+
+func TestNRGBA128FSetGet(t *testing.T) {
+	img := NewNRGBA128F(image.Rect(0, 0, 2, 2))
+	img.SetAt4f(1, 1, [4]float32{1.5, -2.25, 100, 1})
+
+	if got := img.At4f(1, 1); got != [4]float32{1.5, -2.25, 100, 1} {
+		t.Errorf("At4f(1,1) = %v, want {1.5 -2.25 100 1}", got)
+	}
+	if got := img.At4f(0, 0); got != ([4]float32{}) {
+		t.Errorf("At4f(0,0) = %v, want zero value", got)
+	}
+	if img.Bounds() != image.Rect(0, 0, 2, 2) {
+		t.Errorf("Bounds() = %v, want 0,0,2,2", img.Bounds())
+	}
+}
+
+func TestDecodeBCHDRRejectsNonHDRFormat(t *testing.T) {
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC1_UNORM}
+	if _, err := DecodeBCHDR(make([]byte, 8), meta); err == nil {
+		t.Error("expected error decoding a non-HDR format as HDR")
+	}
+}
+
+func TestDecodeBCHDRNotYetImplemented(t *testing.T) {
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC6H_UF16}
+	raw := make([]byte, 16) // one BC6H block
+	if _, err := DecodeBCHDR(raw, meta); err == nil {
+		t.Error("expected DecodeBCHDR to report BC6H decode isn't implemented yet")
+	}
+}
+
+func TestDecodeBCHDRShortData(t *testing.T) {
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC6H_SF16}
+	if _, err := DecodeBCHDR(make([]byte, 4), meta); err == nil {
+		t.Error("expected error decoding truncated BC6H data")
+	}
+}
@@ -0,0 +1,497 @@
+package texture
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"io"
+	"math"
+	"sort"
+
+	"golang.org/x/image/tiff"
+)
+
+// convertConfig holds ConvertToPNG/ConvertToTIFF's options.
+type convertConfig struct {
+	mipLevel    int
+	orientation int
+}
+
+// ConvertOption configures ConvertToPNG and ConvertToTIFF.
+type ConvertOption func(*convertConfig)
+
+// WithMipLevel selects which mip level to export. Mips are assumed to be
+// packed contiguously in raw, largest first, as in a DDS body. It
+// defaults to 0, the full-resolution image.
+func WithMipLevel(level int) ConvertOption {
+	return func(c *convertConfig) { c.mipLevel = level }
+}
+
+// WithOrientation applies an EXIF Orientation tag value (1-8) to the
+// decoded image before encoding, so callers extracting embedded
+// JPEG/PNG-sourced textures from evr archives get correctly-oriented
+// pixels without a second pass through an image library. It defaults to
+// 1 (identity).
+func WithOrientation(orientation int) ConvertOption {
+	return func(c *convertConfig) { c.orientation = orientation }
+}
+
+// ConvertToPNG decodes raw's BC-compressed mip level (see DecodeBC) and
+// encodes it as a PNG, so extracted textures can be used without a DDS
+// toolchain. *_SRGB DXGI formats get an sRGB chunk so PNG viewers and
+// downstream tooling don't reinterpret the gamma.
+func ConvertToPNG(raw []byte, meta *TextureMetadata, opts ...ConvertOption) ([]byte, error) {
+	if meta != nil && IsHDRFormat(meta.DXGIFormat) {
+		return nil, fmt.Errorf("%s is an HDR format: PNG can't represent it without tone-mapping, use ConvertToTIFF instead", FormatName(meta.DXGIFormat))
+	}
+
+	img, mipMeta, err := decodeMip(raw, meta, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := EncodePNG(&buf, img); err != nil {
+		return nil, err
+	}
+	out := buf.Bytes()
+
+	if isSRGBFormat(mipMeta.DXGIFormat) {
+		out, err = injectPNGsRGBChunk(out)
+		if err != nil {
+			return nil, fmt.Errorf("inject sRGB chunk: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// ConvertToTIFF decodes raw's BC-compressed mip level and encodes it as a
+// TIFF. HDR formats (BC6H) are decoded via DecodeBCHDR and written as a
+// Deflate-compressed float32 TIFF (SampleFormat=IEEEFP) that preserves
+// their linear dynamic range instead of clamping to 8 bits; everything
+// else goes through DecodeBC into an uncompressed 8-bit TIFF. *_SRGB
+// DXGI formats get the WhitePoint/PrimaryChromaticities colorimetry tags
+// (TIFF 6.0 section 22) set to the sRGB standard's values, since
+// x/image/tiff's encoder has no option for writing them itself.
+func ConvertToTIFF(raw []byte, meta *TextureMetadata, opts ...ConvertOption) ([]byte, error) {
+	if meta != nil && IsHDRFormat(meta.DXGIFormat) {
+		return convertHDRToTIFF(raw, meta, opts)
+	}
+
+	img, mipMeta, err := decodeMip(raw, meta, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tiff.Encode(&buf, img, nil); err != nil {
+		return nil, fmt.Errorf("encode TIFF: %w", err)
+	}
+	out := buf.Bytes()
+
+	if isSRGBFormat(mipMeta.DXGIFormat) {
+		out, err = injectTIFFColorimetryTags(out)
+		if err != nil {
+			return nil, fmt.Errorf("inject TIFF colorimetry tags: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// convertHDRToTIFF is ConvertToTIFF's path for HDR formats: it decodes
+// through DecodeBCHDR instead of DecodeBC, then hands the float image
+// straight to encodeHDRTIFF without tone-mapping.
+func convertHDRToTIFF(raw []byte, meta *TextureMetadata, opts []ConvertOption) ([]byte, error) {
+	mipMeta, mipRaw, err := resolveMip(raw, meta, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := DecodeBCHDR(mipRaw, mipMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := encodeHDRTIFF(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeMip resolves opts' mip level against raw/meta, decodes it, and
+// applies opts' orientation correction.
+func decodeMip(raw []byte, meta *TextureMetadata, opts []ConvertOption) (image.Image, *TextureMetadata, error) {
+	cfg := parseConvertConfig(opts)
+	mipMeta, mipRaw, err := resolveMipWithConfig(raw, meta, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	img, err := DecodeBC(mipRaw, mipMeta)
+	if err != nil {
+		return nil, nil, err
+	}
+	return applyOrientation(img, cfg.orientation), mipMeta, nil
+}
+
+// parseConvertConfig applies opts against a zero-valued convertConfig.
+func parseConvertConfig(opts []ConvertOption) *convertConfig {
+	cfg := &convertConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// resolveMip applies opts' mip level selection against raw/meta, ahead of
+// either DecodeBC or DecodeBCHDR.
+func resolveMip(raw []byte, meta *TextureMetadata, opts []ConvertOption) (*TextureMetadata, []byte, error) {
+	return resolveMipWithConfig(raw, meta, parseConvertConfig(opts))
+}
+
+// resolveMipWithConfig is resolveMip's implementation, taking an
+// already-parsed config so decodeMip doesn't parse opts twice.
+func resolveMipWithConfig(raw []byte, meta *TextureMetadata, cfg *convertConfig) (*TextureMetadata, []byte, error) {
+	if meta == nil {
+		return nil, nil, fmt.Errorf("metadata is required")
+	}
+	if cfg.mipLevel == 0 {
+		return meta, raw, nil
+	}
+	return mipData(meta, raw, cfg.mipLevel)
+}
+
+// mipData returns the metadata and raw byte slice for a single mip level,
+// assuming mips are packed contiguously largest-first as in a DDS body.
+func mipData(meta *TextureMetadata, raw []byte, level int) (*TextureMetadata, []byte, error) {
+	if level < 0 || (meta.MipLevels > 0 && uint32(level) >= meta.MipLevels) {
+		return nil, nil, fmt.Errorf("mip level %d out of range (have %d)", level, meta.MipLevels)
+	}
+
+	width, height := meta.Width, meta.Height
+	offset := uint32(0)
+	for l := 0; l < level; l++ {
+		offset += calculateLinearSize(width, height, meta.DXGIFormat)
+		if width > 1 {
+			width /= 2
+		}
+		if height > 1 {
+			height /= 2
+		}
+	}
+	size := calculateLinearSize(width, height, meta.DXGIFormat)
+	if offset+size > uint32(len(raw)) {
+		return nil, nil, fmt.Errorf("mip %d: raw data too short for %dx%d", level, width, height)
+	}
+
+	mipMeta := *meta
+	mipMeta.Width, mipMeta.Height = width, height
+	return &mipMeta, raw[offset : offset+size], nil
+}
+
+// isSRGBFormat reports whether format is one of the *_SRGB DXGI variants.
+func isSRGBFormat(format uint32) bool {
+	switch format {
+	case DXGI_FORMAT_BC1_UNORM_SRGB, DXGI_FORMAT_BC2_UNORM_SRGB, DXGI_FORMAT_BC3_UNORM_SRGB, DXGI_FORMAT_BC7_UNORM_SRGB:
+		return true
+	default:
+		return false
+	}
+}
+
+// pngSRGBPerceptual is the PNG sRGB chunk's "rendering intent" byte; 0
+// (perceptual) matches what most DCC tools stamp on sRGB exports.
+const pngSRGBPerceptual = 0
+
+// injectPNGsRGBChunk inserts an sRGB chunk right after png's IHDR, which
+// is where the PNG spec requires it to appear (before PLTE/IDAT).
+// image/png's encoder has no option for writing ancillary chunks, so this
+// patches the chunk in after the fact.
+func injectPNGsRGBChunk(png []byte) ([]byte, error) {
+	const sigLen = 8
+	if len(png) < sigLen+8 {
+		return nil, fmt.Errorf("not a valid PNG: too short")
+	}
+	ihdrDataLen := binary.BigEndian.Uint32(png[sigLen:])
+	insertAt := sigLen + 8 + int(ihdrDataLen) + 4 // length+type+data+crc
+	if insertAt > len(png) {
+		return nil, fmt.Errorf("not a valid PNG: IHDR chunk overruns file")
+	}
+
+	chunk := encodePNGChunk("sRGB", []byte{pngSRGBPerceptual})
+
+	out := make([]byte, 0, len(png)+len(chunk))
+	out = append(out, png[:insertAt]...)
+	out = append(out, chunk...)
+	out = append(out, png[insertAt:]...)
+	return out, nil
+}
+
+// encodePNGChunk builds one length-prefixed, CRC-suffixed PNG chunk.
+func encodePNGChunk(typ string, data []byte) []byte {
+	chunk := make([]byte, 4+4+len(data)+4)
+	binary.BigEndian.PutUint32(chunk[0:4], uint32(len(data)))
+	copy(chunk[4:8], typ)
+	copy(chunk[8:8+len(data)], data)
+	binary.BigEndian.PutUint32(chunk[8+len(data):], crc32.ChecksumIEEE(chunk[4:8+len(data)]))
+	return chunk
+}
+
+// tiffEntry is one 12-byte TIFF IFD directory entry.
+type tiffEntry struct {
+	tag, typ uint16
+	count    uint32
+	valOrOff uint32
+}
+
+// tiffTypeSize returns the byte size of one value of a TIFF field type.
+func tiffTypeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	default:
+		return 0
+	}
+}
+
+// sRGBWhitePoint and sRGBPrimaryChromaticities are the CIE xy values the
+// sRGB standard defines for its D65 white point and its red/green/blue
+// primaries, each packed as TIFF RATIONALs (numerator, denominator pairs).
+var (
+	sRGBWhitePoint            = []uint32{312700, 1000000, 329000, 1000000}
+	sRGBPrimaryChromaticities = []uint32{
+		640000, 1000000, 330000, 1000000, // red
+		300000, 1000000, 600000, 1000000, // green
+		150000, 1000000, 60000, 1000000, // blue
+	}
+)
+
+// injectTIFFColorimetryTags adds the TIFF 6.0 WhitePoint (318) and
+// PrimaryChromaticities (319) tags, set to the sRGB standard's values, to
+// a single-IFD TIFF produced by x/image/tiff. Its encoder has no option
+// for writing them, so this patches the directory in after the fact: it
+// grows the IFD by two entries, shifts every existing out-of-line value
+// that lands after the old directory by the resulting offset, and appends
+// the new tags' RATIONAL data at the end of the file.
+func injectTIFFColorimetryTags(tiffData []byte) ([]byte, error) {
+	if len(tiffData) < 8 {
+		return nil, fmt.Errorf("not a valid TIFF: too short")
+	}
+
+	var bo binary.ByteOrder
+	switch {
+	case bytes.Equal(tiffData[0:2], []byte("II")):
+		bo = binary.LittleEndian
+	case bytes.Equal(tiffData[0:2], []byte("MM")):
+		bo = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("not a valid TIFF: bad byte order marker")
+	}
+
+	ifdOffset := bo.Uint32(tiffData[4:8])
+	if int(ifdOffset)+2 > len(tiffData) {
+		return nil, fmt.Errorf("not a valid TIFF: IFD offset out of range")
+	}
+	count := int(bo.Uint16(tiffData[ifdOffset:]))
+	entriesStart := int(ifdOffset) + 2
+	entriesEnd := entriesStart + count*12
+	if entriesEnd+4 > len(tiffData) {
+		return nil, fmt.Errorf("not a valid TIFF: IFD overruns file")
+	}
+
+	entries := make([]tiffEntry, count)
+	for i := 0; i < count; i++ {
+		e := tiffData[entriesStart+i*12:]
+		entries[i] = tiffEntry{
+			tag:      bo.Uint16(e[0:2]),
+			typ:      bo.Uint16(e[2:4]),
+			count:    bo.Uint32(e[4:8]),
+			valOrOff: bo.Uint32(e[8:12]),
+		}
+	}
+	nextIFD := bo.Uint32(tiffData[entriesEnd:])
+
+	const (
+		tagWhitePoint            = 318
+		tagPrimaryChromaticities = 319
+		typeRational             = 5
+		growth                   = 2 * 12 // two new 12-byte directory entries
+	)
+
+	oldDataStart := entriesEnd + 4
+	shiftIfNeeded := func(off uint32) uint32 {
+		if int(off) >= oldDataStart {
+			return off + growth
+		}
+		return off
+	}
+	for i := range entries {
+		if tiffTypeSize(entries[i].typ)*int(entries[i].count) > 4 {
+			entries[i].valOrOff = shiftIfNeeded(entries[i].valOrOff)
+		}
+	}
+	if nextIFD != 0 {
+		nextIFD = shiftIfNeeded(nextIFD)
+	}
+
+	newDataBase := uint32(len(tiffData)) + growth
+	whitePointOff := newDataBase
+	primariesOff := whitePointOff + uint32(len(sRGBWhitePoint))*4
+
+	entries = append(entries,
+		tiffEntry{tag: tagWhitePoint, typ: typeRational, count: 2, valOrOff: whitePointOff},
+		tiffEntry{tag: tagPrimaryChromaticities, typ: typeRational, count: 6, valOrOff: primariesOff},
+	)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	var out bytes.Buffer
+	out.Write(tiffData[:ifdOffset])
+	binary.Write(&out, bo, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&out, bo, e.tag)
+		binary.Write(&out, bo, e.typ)
+		binary.Write(&out, bo, e.count)
+		binary.Write(&out, bo, e.valOrOff)
+	}
+	binary.Write(&out, bo, nextIFD)
+	out.Write(tiffData[oldDataStart:])
+	for _, v := range sRGBWhitePoint {
+		binary.Write(&out, bo, v)
+	}
+	for _, v := range sRGBPrimaryChromaticities {
+		binary.Write(&out, bo, v)
+	}
+	return out.Bytes(), nil
+}
+
+// TIFF tags and field types used by encodeHDRTIFF that aren't already
+// named by tiffTypeSize's callers.
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagPhotometric     = 262
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagSampleFormat    = 339
+
+	typeShort = 3
+	typeLong  = 4
+
+	compressionDeflate            = 8
+	photometricRGB                = 2
+	sampleFormatIEEEFloatingPoint = 3
+	samplesPerPixelRGBA           = 4
+	bitsPerSampleHDR              = 32
+)
+
+// encodeHDRTIFF writes img as a single-strip, Deflate-compressed TIFF
+// with BitsPerSample=32 and SampleFormat=IEEEFP (339=3), so it carries
+// img's float32 samples without quantizing them. x/image/tiff's encoder
+// only ever writes 8/16-bit integer samples, so this builds the IFD by
+// hand the same way injectTIFFColorimetryTags patches one in.
+func encodeHDRTIFF(w io.Writer, img HDRImage) error {
+	bo := binary.LittleEndian
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid image bounds %v", bounds)
+	}
+
+	strip := make([]byte, 0, width*height*samplesPerPixelRGBA*4)
+	var pixel [4]byte
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			for _, v := range img.At4f(x, y) {
+				bo.PutUint32(pixel[:], math.Float32bits(v))
+				strip = append(strip, pixel[:]...)
+			}
+		}
+	}
+
+	var compressed bytes.Buffer
+	zw, err := flate.NewWriter(&compressed, flate.DefaultCompression)
+	if err != nil {
+		return fmt.Errorf("create deflate writer: %w", err)
+	}
+	if _, err := zw.Write(strip); err != nil {
+		return fmt.Errorf("compress strip: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("flush deflate writer: %w", err)
+	}
+
+	const headerSize = 8
+
+	bitsPerSample := []uint16{bitsPerSampleHDR, bitsPerSampleHDR, bitsPerSampleHDR, bitsPerSampleHDR}
+	sampleFormat := []uint16{sampleFormatIEEEFloatingPoint, sampleFormatIEEEFloatingPoint, sampleFormatIEEEFloatingPoint, sampleFormatIEEEFloatingPoint}
+
+	entries := []tiffEntry{
+		{tag: tagImageWidth, typ: typeLong, count: 1, valOrOff: uint32(width)},
+		{tag: tagImageLength, typ: typeLong, count: 1, valOrOff: uint32(height)},
+		{tag: tagBitsPerSample, typ: typeShort, count: uint32(len(bitsPerSample))}, // valOrOff set below
+		{tag: tagCompression, typ: typeShort, count: 1, valOrOff: compressionDeflate},
+		{tag: tagPhotometric, typ: typeShort, count: 1, valOrOff: photometricRGB},
+		{tag: tagStripOffsets, typ: typeLong, count: 1}, // valOrOff set below
+		{tag: tagSamplesPerPixel, typ: typeShort, count: 1, valOrOff: samplesPerPixelRGBA},
+		{tag: tagRowsPerStrip, typ: typeLong, count: 1, valOrOff: uint32(height)},
+		{tag: tagStripByteCounts, typ: typeLong, count: 1, valOrOff: uint32(compressed.Len())},
+		{tag: tagSampleFormat, typ: typeShort, count: uint32(len(sampleFormat))}, // valOrOff set below
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	ifdSize := 2 + len(entries)*12 + 4 // count + entries + next-IFD offset
+	dataBase := uint32(headerSize) + uint32(ifdSize)
+	bitsPerSampleOff := dataBase
+	sampleFormatOff := bitsPerSampleOff + uint32(len(bitsPerSample))*2
+	stripOff := sampleFormatOff + uint32(len(sampleFormat))*2
+
+	for i := range entries {
+		switch entries[i].tag {
+		case tagBitsPerSample:
+			entries[i].valOrOff = bitsPerSampleOff
+		case tagSampleFormat:
+			entries[i].valOrOff = sampleFormatOff
+		case tagStripOffsets:
+			entries[i].valOrOff = stripOff
+		}
+	}
+
+	var out bytes.Buffer
+	out.Write([]byte("II"))
+	binary.Write(&out, bo, uint16(42))
+	binary.Write(&out, bo, uint32(headerSize))
+	binary.Write(&out, bo, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&out, bo, e.tag)
+		binary.Write(&out, bo, e.typ)
+		binary.Write(&out, bo, e.count)
+		binary.Write(&out, bo, e.valOrOff)
+	}
+	binary.Write(&out, bo, uint32(0)) // no next IFD
+	for _, v := range bitsPerSample {
+		binary.Write(&out, bo, v)
+	}
+	for _, v := range sampleFormat {
+		binary.Write(&out, bo, v)
+	}
+	out.Write(compressed.Bytes())
+
+	if _, err := w.Write(out.Bytes()); err != nil {
+		return fmt.Errorf("write TIFF: %w", err)
+	}
+	return nil
+}
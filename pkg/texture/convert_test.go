@@ -0,0 +1,176 @@
+package texture
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+func solidBC1Raw(blocksWide, blocksHigh int, c0 uint16) []byte {
+	raw := make([]byte, blocksWide*blocksHigh*8)
+	for b := 0; b < blocksWide*blocksHigh; b++ {
+		raw[b*8], raw[b*8+1] = byte(c0), byte(c0>>8)
+		raw[b*8+2], raw[b*8+3] = byte(c0), byte(c0>>8)
+	}
+	return raw
+}
+
+func TestConvertToPNG(t *testing.T) {
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC1_UNORM}
+	raw := solidBC1Raw(1, 1, rgb565(255, 0, 0))
+
+	out, err := ConvertToPNG(raw, meta)
+	if err != nil {
+		t.Fatalf("ConvertToPNG: %v", err)
+	}
+	if !bytes.HasPrefix(out, []byte("\x89PNG\r\n\x1a\n")) {
+		t.Error("output missing PNG signature")
+	}
+	if bytes.Contains(out, []byte("sRGB")) {
+		t.Error("non-sRGB format should not get an sRGB chunk")
+	}
+}
+
+func TestConvertToPNGSRGB(t *testing.T) {
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC1_UNORM_SRGB}
+	raw := solidBC1Raw(1, 1, rgb565(255, 0, 0))
+
+	out, err := ConvertToPNG(raw, meta)
+	if err != nil {
+		t.Fatalf("ConvertToPNG: %v", err)
+	}
+	if !bytes.Contains(out, []byte("sRGB")) {
+		t.Error("*_SRGB format should get an sRGB chunk")
+	}
+}
+
+func TestConvertToTIFF(t *testing.T) {
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC1_UNORM}
+	raw := solidBC1Raw(1, 1, rgb565(0, 255, 0))
+
+	out, err := ConvertToTIFF(raw, meta)
+	if err != nil {
+		t.Fatalf("ConvertToTIFF: %v", err)
+	}
+	if !bytes.HasPrefix(out, []byte("II*\x00")) && !bytes.HasPrefix(out, []byte("MM\x00*")) {
+		t.Error("output missing TIFF byte-order marker")
+	}
+}
+
+func TestConvertToTIFFSRGBColorimetryTags(t *testing.T) {
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC1_UNORM_SRGB}
+	raw := solidBC1Raw(1, 1, rgb565(0, 255, 0))
+
+	out, err := ConvertToTIFF(raw, meta)
+	if err != nil {
+		t.Fatalf("ConvertToTIFF: %v", err)
+	}
+
+	img, err := tiff.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("decode patched TIFF: %v", err)
+	}
+	if img.Bounds().Dx() != 4 || img.Bounds().Dy() != 4 {
+		t.Errorf("decoded bounds = %v, want 4x4", img.Bounds())
+	}
+}
+
+func TestConvertToPNGRejectsHDRFormat(t *testing.T) {
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC6H_UF16}
+	if _, err := ConvertToPNG(make([]byte, 16), meta); err == nil {
+		t.Error("expected ConvertToPNG to reject an HDR format")
+	}
+}
+
+func TestEncodeHDRTIFFSampleFormatAndBitDepth(t *testing.T) {
+	img := NewNRGBA128F(image.Rect(0, 0, 2, 2))
+	img.SetAt4f(0, 0, [4]float32{1.5, 2.5, 3.5, 1})
+	img.SetAt4f(1, 1, [4]float32{-0.5, 0, 100000, 1})
+
+	var buf bytes.Buffer
+	if err := encodeHDRTIFF(&buf, img); err != nil {
+		t.Fatalf("encodeHDRTIFF: %v", err)
+	}
+	out := buf.Bytes()
+
+	if !bytes.HasPrefix(out, []byte("II*\x00")) {
+		t.Fatalf("output missing little-endian TIFF byte-order marker: %x", out[:4])
+	}
+
+	bo := binary.LittleEndian
+	ifdOff := bo.Uint32(out[4:8])
+	count := int(bo.Uint16(out[ifdOff:]))
+	entriesStart := int(ifdOff) + 2
+
+	tags := map[uint16]tiffEntry{}
+	for i := 0; i < count; i++ {
+		e := out[entriesStart+i*12:]
+		tags[bo.Uint16(e[0:2])] = tiffEntry{
+			tag:      bo.Uint16(e[0:2]),
+			typ:      bo.Uint16(e[2:4]),
+			count:    bo.Uint32(e[4:8]),
+			valOrOff: bo.Uint32(e[8:12]),
+		}
+	}
+
+	bpsOff := tags[tagBitsPerSample].valOrOff
+	if got := bo.Uint16(out[bpsOff:]); got != bitsPerSampleHDR {
+		t.Errorf("BitsPerSample = %d, want %d", got, bitsPerSampleHDR)
+	}
+	sfOff := tags[tagSampleFormat].valOrOff
+	if got := bo.Uint16(out[sfOff:]); got != sampleFormatIEEEFloatingPoint {
+		t.Errorf("SampleFormat = %d, want %d (IEEEFP)", got, sampleFormatIEEEFloatingPoint)
+	}
+	if got := tags[tagCompression].valOrOff; got != compressionDeflate {
+		t.Errorf("Compression = %d, want %d (Deflate)", got, compressionDeflate)
+	}
+
+	stripOff, stripLen := tags[tagStripOffsets].valOrOff, tags[tagStripByteCounts].valOrOff
+	zr := flate.NewReader(bytes.NewReader(out[stripOff : stripOff+stripLen]))
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("inflate strip: %v", err)
+	}
+	const pixel11Offset = 3 * 4 * 4 // pixel (1,1) is the 4th pixel (row-major), 4 float32 samples each
+	if got := math.Float32frombits(bo.Uint32(decompressed[pixel11Offset:])); got != -0.5 {
+		t.Errorf("pixel (1,1) red sample = %v, want -0.5", got)
+	}
+	if got := math.Float32frombits(bo.Uint32(decompressed[pixel11Offset+8:])); got != 100000 {
+		t.Errorf("pixel (1,1) blue sample = %v, want 100000 (no tone-mapping)", got)
+	}
+}
+
+func TestConvertMipLevelOutOfRange(t *testing.T) {
+	meta := &TextureMetadata{Width: 4, Height: 4, MipLevels: 1, DXGIFormat: DXGI_FORMAT_BC1_UNORM}
+	raw := solidBC1Raw(1, 1, rgb565(255, 0, 0))
+
+	if _, err := ConvertToPNG(raw, meta, WithMipLevel(1)); err == nil {
+		t.Error("expected error selecting a mip level beyond MipLevels")
+	}
+}
+
+func TestConvertMipLevelSelection(t *testing.T) {
+	meta := &TextureMetadata{Width: 8, Height: 8, MipLevels: 2, DXGIFormat: DXGI_FORMAT_BC1_UNORM}
+	mip0 := solidBC1Raw(2, 2, rgb565(255, 0, 0))
+	mip1 := solidBC1Raw(1, 1, rgb565(0, 255, 0))
+	raw := append(append([]byte{}, mip0...), mip1...)
+
+	out, err := ConvertToPNG(raw, meta, WithMipLevel(1))
+	if err != nil {
+		t.Fatalf("ConvertToPNG mip 1: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("decode mip 1 PNG: %v", err)
+	}
+	if img.Bounds().Dx() != 4 || img.Bounds().Dy() != 4 {
+		t.Errorf("mip 1 bounds = %v, want 4x4 (half of 8x8)", img.Bounds())
+	}
+}
@@ -0,0 +1,768 @@
+package texture
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// BCFormat selects which block-compressed format CompressBC encodes a
+// texture into.
+type BCFormat int
+
+const (
+	// BC1 is 4-color RGB, 8 bytes per 4x4 block, no alpha.
+	BC1 BCFormat = iota
+	// BC3 is BC1's color block plus an independent 8-tap interpolated
+	// alpha block, 16 bytes per 4x4 block.
+	BC3
+	// BC5 is two independent 8-tap interpolated channel blocks (red,
+	// green), 16 bytes per 4x4 block - typically used for normal maps.
+	BC5
+	// BC7 is single- or multi-subset RGBA with per-block mode selection,
+	// 16 bytes per 4x4 block. CompressBC only ever emits modes 5 and 6 -
+	// see encodeBC7Block's doc comment for why the rest are out of scope.
+	BC7
+	// BC6H is single-subset HDR RGB stored as signed or unsigned 16-bit
+	// half floats, 16 bytes per 4x4 block. CompressBC doesn't encode it -
+	// see compressBCPureGo's BC6H case.
+	BC6H
+)
+
+// BCQuality controls how hard CompressBC searches for the best BC7 mode per
+// tile; modes 5 and 6 are both always tried regardless, since trying both
+// costs little and CompressBCOptions exists specifically to let a caller ask
+// for more search than that once more modes are implemented. It has no
+// effect on BC1/BC3/BC5/BC6H, which only ever encode one way.
+type BCQuality int
+
+const (
+	// QualityNormal is the default: both implemented BC7 modes are tried
+	// per tile and the lower-error one is kept.
+	QualityNormal BCQuality = iota
+	// QualityFast always uses BC7 mode 6 and skips mode 5's separate
+	// color/alpha index search entirely.
+	QualityFast
+	// QualitySlow is currently identical to QualityNormal - this encoder
+	// only implements modes 5 and 6 (see encodeBC7Block), so there's no
+	// wider search to do yet. It's here so CompressBCOptions keeps working
+	// unchanged once more modes are added.
+	QualitySlow
+)
+
+// bcCompressConfig holds CompressBC's optional settings, built up from
+// CompressBCOption values the way convert.go's ConvertOption does for
+// ConvertToPNG.
+type bcCompressConfig struct {
+	quality BCQuality
+}
+
+// CompressBCOption configures CompressBC/BCEncoder.CompressBC.
+type CompressBCOption func(*bcCompressConfig)
+
+// WithQuality sets how hard CompressBC searches for a BC7 tile's best mode.
+func WithQuality(q BCQuality) CompressBCOption {
+	return func(c *bcCompressConfig) { c.quality = q }
+}
+
+// DetectBCFormat picks a BCFormat for img by inspecting its alpha channel
+// and color complexity: BC1 for opaque images, BC3 for images with alpha
+// that isn't both smoothly varying and complex, and BC7 once both of those
+// are true (BC7's per-tile mode search pays for itself on that content, but
+// isn't worth it on simpler images). img is an *image.NRGBA, which can't
+// hold HDR data in the first place - a caller with an HDRImage already
+// knows it wants BC6H and should call CompressBCHDR directly - so
+// DetectBCFormat never returns BC6H; that's a property of its input type,
+// not a gap to fill in here.
+func DetectBCFormat(img *image.NRGBA) BCFormat {
+	bounds := img.Bounds()
+	opaque := true
+	alphaMin, alphaMax := uint8(255), uint8(0)
+	colors := make(map[color.NRGBA]struct{})
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			p := img.NRGBAAt(x, y)
+			if p.A != 255 {
+				opaque = false
+			}
+			if p.A < alphaMin {
+				alphaMin = p.A
+			}
+			if p.A > alphaMax {
+				alphaMax = p.A
+			}
+			colors[color.NRGBA{R: p.R, G: p.G, B: p.B}] = struct{}{}
+		}
+	}
+
+	if opaque {
+		return BC1
+	}
+
+	alphaRange := int(alphaMax) - int(alphaMin)
+	smoothAlpha := alphaRange > 16 && alphaRange < 240
+	highComplexity := len(colors) > bounds.Dx()*bounds.Dy()/4
+	if smoothAlpha && highComplexity {
+		return BC7
+	}
+	return BC3
+}
+
+// BCEncoder selects how CompressBC encodes a tile: the pure-Go PCA-based
+// encoder below (the zero value, UseCGo: false), or, when UseCGo is true,
+// a libsquish binding. This package has never linked libsquish itself -
+// cmd/texconv's encoder_cgo.go is where that binding lives, gated behind a
+// "cgo" build tag, with this package's CompressBC wired in as
+// encoder_nocgo.go's CGO_ENABLED=0 fallback - so UseCGo exists for API
+// parity with encoders that do offer one, and for now just reports that no
+// CGo path is available through this package; CompressBC always takes the
+// pure-Go path unless and until one is added here too.
+type BCEncoder struct {
+	UseCGo bool
+}
+
+// CompressBC compresses img into raw block-compressed data using a
+// BCEncoder with its zero value, i.e. the pure-Go path. It's a thin
+// wrapper so callers that don't need the UseCGo option don't have to
+// construct a BCEncoder themselves.
+func CompressBC(img *image.NRGBA, format BCFormat, opts ...CompressBCOption) ([]byte, error) {
+	return BCEncoder{}.CompressBC(img, format, opts...)
+}
+
+// CompressBC compresses img (its bounds need not be a multiple of 4; edge
+// pixels are replicated to fill out the last row/column of tiles) into raw
+// BC1, BC3, BC5 or BC7 block data, row-major over 4x4 tiles the same way
+// DecodeBC reads them back. BC6H is accepted by BCFormat for API
+// completeness but always errors - see compressBCPureGo.
+func (e BCEncoder) CompressBC(img *image.NRGBA, format BCFormat, opts ...CompressBCOption) ([]byte, error) {
+	if e.UseCGo {
+		return nil, fmt.Errorf("BCEncoder.UseCGo: no CGo BC encoder is linked into this build")
+	}
+	cfg := &bcCompressConfig{quality: QualityNormal}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return compressBCPureGo(img, format, cfg)
+}
+
+// compressBCPureGo is CompressBC's default path: for each 4x4 tile, derive
+// RGB endpoints via principal component analysis of the tile's pixel
+// cloud, quantize them to RGB565, and pick per-pixel indices under a
+// perceptual (2,4,1)-weighted error; BC3/BC5 additionally run the
+// alpha-block coder (min/max endpoints, 3-bit indices on an 8-tap ramp) on
+// alpha, or red and green independently; BC7 runs encodeBC7Block's mode
+// search instead.
+func compressBCPureGo(img *image.NRGBA, format BCFormat, cfg *bcCompressConfig) ([]byte, error) {
+	if img == nil {
+		return nil, fmt.Errorf("image is required")
+	}
+	if format == BC6H {
+		return nil, fmt.Errorf("BC6H needs HDR input, not an 8-bit image.NRGBA - use CompressBCHDR")
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid dimensions %dx%d", width, height)
+	}
+
+	var blockSize int
+	switch format {
+	case BC1:
+		blockSize = 8
+	case BC3, BC5, BC7:
+		blockSize = 16
+	default:
+		return nil, fmt.Errorf("unsupported BC format for encoding: %v", format)
+	}
+
+	blocksWide := (width + 3) / 4
+	blocksHigh := (height + 3) / 4
+	out := make([]byte, blocksWide*blocksHigh*blockSize)
+
+	for by := 0; by < blocksHigh; by++ {
+		for bx := 0; bx < blocksWide; bx++ {
+			block := gatherBlock(img, bx, by)
+			offset := (by*blocksWide + bx) * blockSize
+
+			switch format {
+			case BC1:
+				colorBlock := encodeBC1ColorBlock(block)
+				copy(out[offset:offset+8], colorBlock[:])
+			case BC3:
+				var alphaValues [16]uint8
+				for i, p := range block {
+					alphaValues[i] = p.A
+				}
+				alphaBlock := encodeAlphaBlockCompress(alphaValues)
+				colorBlock := encodeBC1ColorBlock(block)
+				copy(out[offset:offset+8], alphaBlock[:])
+				copy(out[offset+8:offset+16], colorBlock[:])
+			case BC5:
+				var redValues, greenValues [16]uint8
+				for i, p := range block {
+					redValues[i] = p.R
+					greenValues[i] = p.G
+				}
+				redBlock := encodeAlphaBlockCompress(redValues)
+				greenBlock := encodeAlphaBlockCompress(greenValues)
+				copy(out[offset:offset+8], redBlock[:])
+				copy(out[offset+8:offset+16], greenBlock[:])
+			case BC7:
+				bc7Block := encodeBC7Block(block, cfg.quality)
+				copy(out[offset:offset+16], bc7Block[:])
+			}
+		}
+	}
+	return out, nil
+}
+
+// gatherBlock reads the 4x4 tile at (bx, by) out of img, clamping to the
+// last row/column for images whose dimensions aren't a multiple of 4 -
+// the encode-side counterpart to blitBlock's clipping on decode.
+func gatherBlock(img *image.NRGBA, bx, by int) [16]color.NRGBA {
+	var block [16]color.NRGBA
+	bounds := img.Bounds()
+	for row := 0; row < 4; row++ {
+		y := bounds.Min.Y + by*4 + row
+		if y > bounds.Max.Y-1 {
+			y = bounds.Max.Y - 1
+		}
+		for col := 0; col < 4; col++ {
+			x := bounds.Min.X + bx*4 + col
+			if x > bounds.Max.X-1 {
+				x = bounds.Max.X - 1
+			}
+			block[row*4+col] = img.NRGBAAt(x, y)
+		}
+	}
+	return block
+}
+
+// pcaEndpoints derives a 4x4 tile's two BC1 color endpoints by projecting
+// every pixel onto the dominant eigenvector of the tile's RGB covariance
+// matrix (found via power iteration, a handful of iterations is plenty for
+// a 16-sample 3x3 matrix) and taking the extremes of that projection.
+func pcaEndpoints(block [16]color.NRGBA) (min, max [3]float64) {
+	var pts [16][3]float64
+	var mean [3]float64
+	for i, p := range block {
+		pts[i] = [3]float64{float64(p.R), float64(p.G), float64(p.B)}
+		mean[0] += pts[i][0]
+		mean[1] += pts[i][1]
+		mean[2] += pts[i][2]
+	}
+	for i := range mean {
+		mean[i] /= float64(len(pts))
+	}
+
+	var cov [3][3]float64
+	for _, p := range pts {
+		d := [3]float64{p[0] - mean[0], p[1] - mean[1], p[2] - mean[2]}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				cov[i][j] += d[i] * d[j]
+			}
+		}
+	}
+
+	axis := [3]float64{1, 1, 1}
+	for iter := 0; iter < 8; iter++ {
+		var next [3]float64
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				next[i] += cov[i][j] * axis[j]
+			}
+		}
+		norm := math.Sqrt(next[0]*next[0] + next[1]*next[1] + next[2]*next[2])
+		if norm < 1e-9 {
+			break
+		}
+		axis = [3]float64{next[0] / norm, next[1] / norm, next[2] / norm}
+	}
+
+	minProj, maxProj := math.Inf(1), math.Inf(-1)
+	for _, p := range pts {
+		d := [3]float64{p[0] - mean[0], p[1] - mean[1], p[2] - mean[2]}
+		proj := d[0]*axis[0] + d[1]*axis[1] + d[2]*axis[2]
+		if proj < minProj {
+			minProj, min = proj, p
+		}
+		if proj > maxProj {
+			maxProj, max = proj, p
+		}
+	}
+	return min, max
+}
+
+// quantize565 rounds a float RGB triple to the nearest RGB565 value.
+func quantize565(c [3]float64) uint16 {
+	quant := func(v float64, bits int) uint16 {
+		top := float64(int(1)<<uint(bits) - 1)
+		v = v * top / 255.0
+		if v < 0 {
+			v = 0
+		} else if v > top {
+			v = top
+		}
+		return uint16(math.Round(v))
+	}
+	r, g, b := quant(c[0], 5), quant(c[1], 6), quant(c[2], 5)
+	return r<<11 | g<<5 | b
+}
+
+// encodeBC1ColorBlock encodes a 4x4 tile's RGB into BC1's 8-byte color
+// block, always in 4-color mode (color0 > color1, so decode never takes
+// the punch-through-alpha branch), with per-pixel indices chosen by
+// least (2,4,1)-weighted squared error against the four interpolated
+// palette entries - weighting green heaviest, matching perceived
+// luminance contribution the way most BC1 encoders do.
+func encodeBC1ColorBlock(block [16]color.NRGBA) [8]byte {
+	var out [8]byte
+
+	lo, hi := pcaEndpoints(block)
+	c0, c1 := quantize565(hi), quantize565(lo)
+	if c0 == c1 {
+		if c1 > 0 {
+			c1--
+		} else {
+			c0++
+		}
+	}
+	if c0 < c1 {
+		c0, c1 = c1, c0
+	}
+
+	r0, g0, b0 := unpack565(c0)
+	r1, g1, b1 := unpack565(c1)
+	palette := [4][3]float64{
+		{float64(r0), float64(g0), float64(b0)},
+		{float64(r1), float64(g1), float64(b1)},
+		{(2*float64(r0) + float64(r1)) / 3, (2*float64(g0) + float64(g1)) / 3, (2*float64(b0) + float64(b1)) / 3},
+		{(float64(r0) + 2*float64(r1)) / 3, (float64(g0) + 2*float64(g1)) / 3, (float64(b0) + 2*float64(b1)) / 3},
+	}
+
+	var indexBits uint32
+	for i, p := range block {
+		best, bestErr := 0, math.Inf(1)
+		for k, pal := range palette {
+			dr := float64(p.R) - pal[0]
+			dg := float64(p.G) - pal[1]
+			db := float64(p.B) - pal[2]
+			errv := 2*dr*dr + 4*dg*dg + db*db
+			if errv < bestErr {
+				bestErr, best = errv, k
+			}
+		}
+		indexBits |= uint32(best) << uint(2*i)
+	}
+
+	out[0], out[1] = byte(c0), byte(c0>>8)
+	out[2], out[3] = byte(c1), byte(c1>>8)
+	out[4], out[5] = byte(indexBits), byte(indexBits>>8)
+	out[6], out[7] = byte(indexBits>>16), byte(indexBits>>24)
+	return out
+}
+
+// encodeAlphaBlockCompress encodes 16 single-channel values into the
+// 8-byte interpolated block BC3 (alpha) and BC5 (red/green) both use:
+// min/max endpoints always ordered so decode takes the 8-tap ramp (never
+// the 6-tap-plus-0/255 variant, which only applies when endpoint0 <=
+// endpoint1), and per-pixel indices chosen by nearest ramp value.
+func encodeAlphaBlockCompress(values [16]uint8) [8]byte {
+	var out [8]byte
+
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	a0, a1 := hi, lo
+	if a0 == a1 {
+		if a1 > 0 {
+			a1--
+		} else {
+			a0++
+		}
+	}
+
+	var ramp [8]uint8
+	ramp[0], ramp[1] = a0, a1
+	for i := 1; i <= 6; i++ {
+		ramp[1+i] = uint8((uint16(7-i)*uint16(a0) + uint16(i)*uint16(a1)) / 7)
+	}
+
+	var indexBits uint64
+	for i, v := range values {
+		best, bestErr := 0, 1<<30
+		for k, r := range ramp {
+			d := int(v) - int(r)
+			if d < 0 {
+				d = -d
+			}
+			if d < bestErr {
+				bestErr, best = d, k
+			}
+		}
+		indexBits |= uint64(best) << uint(3*i)
+	}
+
+	out[0], out[1] = a0, a1
+	for i := 0; i < 6; i++ {
+		out[2+i] = byte(indexBits >> uint(8*i))
+	}
+	return out
+}
+
+// bc7BitWriter packs bits LSB-first, the same order bc7BitReader (in
+// bc_decode.go) reads them back in.
+type bc7BitWriter struct {
+	data []byte
+	pos  int
+}
+
+func newBC7BitWriter(size int) *bc7BitWriter {
+	return &bc7BitWriter{data: make([]byte, size)}
+}
+
+func (bw *bc7BitWriter) writeBit(bit uint32) {
+	if bit&1 != 0 {
+		bw.data[bw.pos/8] |= 1 << uint(bw.pos%8)
+	}
+	bw.pos++
+}
+
+func (bw *bc7BitWriter) writeBits(v uint32, n int) {
+	for i := 0; i < n; i++ {
+		bw.writeBit(v >> uint(i))
+	}
+}
+
+// writeBC7ModeHeader writes BC7's unary mode selector: mode zero bits
+// followed by a single one bit, mirroring decodeBC7Block's read loop.
+func writeBC7ModeHeader(bw *bc7BitWriter, mode int) {
+	for i := 0; i < mode; i++ {
+		bw.writeBit(0)
+	}
+	bw.writeBit(1)
+}
+
+// encodeBC7Block picks the lower-error of BC7 modes 6 and 5 for a 4x4 tile.
+// Those two single-subset modes are what decodeBC7Block already implements,
+// so every block this produces round-trips through this package's own
+// decoder, which is used here to score candidates. Mode 1's two-subset
+// partitions stay deliberately out of scope, revisited and unchanged: this
+// package still has no reference corpus to validate a transcription of
+// BC7's 64-entry partition-shape and anchor-index tables against, and a
+// plausible-looking but silently-wrong one is worse than not having it -
+// the same tradeoff decodeBC7Block's doc comment makes on the decode side.
+func encodeBC7Block(block [16]color.NRGBA, quality BCQuality) [16]byte {
+	mode6 := encodeBC7Mode6(block)
+	if quality == QualityFast {
+		return mode6
+	}
+	mode5 := encodeBC7Mode5(block)
+	if bc7BlockError(block, mode5) < bc7BlockError(block, mode6) {
+		return mode5
+	}
+	return mode6
+}
+
+// bc7BlockError decodes encoded with this package's own BC7 decoder and
+// sums its squared RGBA error against block, so mode candidates are scored
+// by the exact bits a real decoder would produce.
+func bc7BlockError(block [16]color.NRGBA, encoded [16]byte) float64 {
+	decoded, err := decodeBC7Block(encoded[:])
+	if err != nil {
+		return math.Inf(1)
+	}
+	var total float64
+	for i, p := range block {
+		dp := decoded[i]
+		dr := float64(p.R) - float64(dp.R)
+		dg := float64(p.G) - float64(dp.G)
+		db := float64(p.B) - float64(dp.B)
+		da := float64(p.A) - float64(dp.A)
+		total += dr*dr + dg*dg + db*db + da*da
+	}
+	return total
+}
+
+// encodeBC7Mode6 encodes a tile as BC7 mode 6: one subset, 7-bit RGBA
+// endpoints with a shared per-endpoint P-bit, 4-bit indices. Endpoints come
+// from PCA over the tile's 4D RGBA cloud; the P-bit is chosen per endpoint
+// to minimize the total quantization error across all four channels, since
+// mode 6 only has one P-bit per endpoint rather than one per channel. If
+// the anchor pixel's index would need its top bit set, the endpoints are
+// swapped and every index mirrored (bc7Weights4 is symmetric around its
+// midpoint, so this reproduces the identical block) since mode 6's index 0
+// is packed in only 3 bits.
+func encodeBC7Mode6(block [16]color.NRGBA) [16]byte {
+	var samples [16][4]float64
+	for i, p := range block {
+		samples[i] = [4]float64{float64(p.R), float64(p.G), float64(p.B), float64(p.A)}
+	}
+	lo, hi := pcaEndpoints4(samples)
+
+	bits0, p0 := quantize7SharedP(hi)
+	bits1, p1 := quantize7SharedP(lo)
+	e0 := [4]uint32{bits0[0]<<1 | p0, bits0[1]<<1 | p0, bits0[2]<<1 | p0, bits0[3]<<1 | p0}
+	e1 := [4]uint32{bits1[0]<<1 | p1, bits1[1]<<1 | p1, bits1[2]<<1 | p1, bits1[3]<<1 | p1}
+
+	indices := make([]int, 16)
+	for i, p := range block {
+		v := [4]float64{float64(p.R), float64(p.G), float64(p.B), float64(p.A)}
+		best, bestErr := 0, math.Inf(1)
+		for k, w := range bc7Weights4 {
+			var e float64
+			for c := 0; c < 4; c++ {
+				d := v[c] - float64(bc7Interpolate(e0[c], e1[c], w))
+				e += d * d
+			}
+			if e < bestErr {
+				bestErr, best = e, k
+			}
+		}
+		indices[i] = best
+	}
+
+	if indices[0] >= 8 {
+		e0, e1 = e1, e0
+		bits0, bits1 = bits1, bits0
+		p0, p1 = p1, p0
+		for i := range indices {
+			indices[i] = 15 - indices[i]
+		}
+	}
+
+	bw := newBC7BitWriter(16)
+	writeBC7ModeHeader(bw, 6)
+	for c := 0; c < 4; c++ {
+		bw.writeBits(bits0[c], 7)
+		bw.writeBits(bits1[c], 7)
+	}
+	bw.writeBits(p0, 1)
+	bw.writeBits(p1, 1)
+	for i, idx := range indices {
+		n := 4
+		if i == 0 {
+			n = 3
+		}
+		bw.writeBits(uint32(idx), n)
+	}
+
+	var out [16]byte
+	copy(out[:], bw.data)
+	return out
+}
+
+// encodeBC7Mode5 encodes a tile as BC7 mode 5: one subset, 7-bit RGB
+// endpoints (bit-replicated to 8 bits) plus an independent 8-bit alpha
+// endpoint pair, each with its own 2-bit index set - useful when color and
+// alpha vary independently in a way a shared index set (mode 6) can't
+// track as well. The channel/alpha rotation mode 5 supports is always left
+// at 0 (no swap); both index sets get the same anchor-bit treatment as
+// mode 6, applied independently since they're separate index sets.
+func encodeBC7Mode5(block [16]color.NRGBA) [16]byte {
+	lo, hi := pcaEndpoints(block)
+
+	quant7 := func(v float64) uint32 {
+		b := math.Round(v * 127.0 / 255.0)
+		if b < 0 {
+			b = 0
+		} else if b > 127 {
+			b = 127
+		}
+		return uint32(b)
+	}
+	expand7 := func(v uint32) uint32 { return v<<1 | v>>6 }
+
+	rBits := [2]uint32{quant7(hi[0]), quant7(lo[0])}
+	gBits := [2]uint32{quant7(hi[1]), quant7(lo[1])}
+	bBits := [2]uint32{quant7(hi[2]), quant7(lo[2])}
+	ce0 := [3]uint32{expand7(rBits[0]), expand7(gBits[0]), expand7(bBits[0])}
+	ce1 := [3]uint32{expand7(rBits[1]), expand7(gBits[1]), expand7(bBits[1])}
+
+	aMin, aMax := block[0].A, block[0].A
+	for _, p := range block {
+		if p.A < aMin {
+			aMin = p.A
+		}
+		if p.A > aMax {
+			aMax = p.A
+		}
+	}
+	a0, a1 := uint32(aMax), uint32(aMin)
+
+	colorIdx := make([]int, 16)
+	alphaIdx := make([]int, 16)
+	for i, p := range block {
+		bestC, bestCErr := 0, math.Inf(1)
+		for k, w := range bc7Weights2 {
+			dr := float64(p.R) - float64(bc7Interpolate(ce0[0], ce1[0], w))
+			dg := float64(p.G) - float64(bc7Interpolate(ce0[1], ce1[1], w))
+			db := float64(p.B) - float64(bc7Interpolate(ce0[2], ce1[2], w))
+			e := 2*dr*dr + 4*dg*dg + db*db
+			if e < bestCErr {
+				bestCErr, bestC = e, k
+			}
+		}
+		colorIdx[i] = bestC
+
+		bestA, bestAErr := 0, math.Inf(1)
+		for k, w := range bc7Weights2 {
+			da := float64(p.A) - float64(bc7Interpolate(a0, a1, w))
+			if e := da * da; e < bestAErr {
+				bestAErr, bestA = e, k
+			}
+		}
+		alphaIdx[i] = bestA
+	}
+
+	if colorIdx[0] >= 2 {
+		ce0, ce1 = ce1, ce0
+		rBits[0], rBits[1] = rBits[1], rBits[0]
+		gBits[0], gBits[1] = gBits[1], gBits[0]
+		bBits[0], bBits[1] = bBits[1], bBits[0]
+		for i := range colorIdx {
+			colorIdx[i] = 3 - colorIdx[i]
+		}
+	}
+	if alphaIdx[0] >= 2 {
+		a0, a1 = a1, a0
+		for i := range alphaIdx {
+			alphaIdx[i] = 3 - alphaIdx[i]
+		}
+	}
+
+	bw := newBC7BitWriter(16)
+	writeBC7ModeHeader(bw, 5)
+	bw.writeBits(0, 2) // rotation: always 0, see doc comment
+	bw.writeBits(rBits[0], 7)
+	bw.writeBits(rBits[1], 7)
+	bw.writeBits(gBits[0], 7)
+	bw.writeBits(gBits[1], 7)
+	bw.writeBits(bBits[0], 7)
+	bw.writeBits(bBits[1], 7)
+	bw.writeBits(a0, 8)
+	bw.writeBits(a1, 8)
+	for i, idx := range colorIdx {
+		n := 2
+		if i == 0 {
+			n = 1
+		}
+		bw.writeBits(uint32(idx), n)
+	}
+	for i, idx := range alphaIdx {
+		n := 2
+		if i == 0 {
+			n = 1
+		}
+		bw.writeBits(uint32(idx), n)
+	}
+
+	var out [16]byte
+	copy(out[:], bw.data)
+	return out
+}
+
+// pcaEndpoints4 is pcaEndpoints extended to BC7 mode 6's 4-channel (RGBA)
+// pixel cloud; see pcaEndpoints for the method.
+func pcaEndpoints4(samples [16][4]float64) (min, max [4]float64) {
+	var mean [4]float64
+	for _, s := range samples {
+		for c := 0; c < 4; c++ {
+			mean[c] += s[c]
+		}
+	}
+	for c := range mean {
+		mean[c] /= float64(len(samples))
+	}
+
+	var cov [4][4]float64
+	for _, s := range samples {
+		var d [4]float64
+		for c := 0; c < 4; c++ {
+			d[c] = s[c] - mean[c]
+		}
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				cov[i][j] += d[i] * d[j]
+			}
+		}
+	}
+
+	axis := [4]float64{1, 1, 1, 1}
+	for iter := 0; iter < 8; iter++ {
+		var next [4]float64
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				next[i] += cov[i][j] * axis[j]
+			}
+		}
+		var norm float64
+		for _, v := range next {
+			norm += v * v
+		}
+		norm = math.Sqrt(norm)
+		if norm < 1e-9 {
+			break
+		}
+		for i := range axis {
+			axis[i] = next[i] / norm
+		}
+	}
+
+	minProj, maxProj := math.Inf(1), math.Inf(-1)
+	for _, s := range samples {
+		var d [4]float64
+		var proj float64
+		for c := 0; c < 4; c++ {
+			d[c] = s[c] - mean[c]
+			proj += d[c] * axis[c]
+		}
+		if proj < minProj {
+			minProj, min = proj, s
+		}
+		if proj > maxProj {
+			maxProj, max = proj, s
+		}
+	}
+	return min, max
+}
+
+// quantize7SharedP quantizes four channel values to mode 6's 7-bit+shared-
+// P-bit encoding: for each candidate P-bit, round every channel to the
+// nearest reconstructible 8-bit value sharing that P-bit, and keep whichever
+// P-bit gives the lower total squared error across all four channels.
+func quantize7SharedP(vals [4]float64) (bits [4]uint32, p uint32) {
+	bestErr := math.Inf(1)
+	var bestBits [4]uint32
+	var bestP uint32
+	for pTry := uint32(0); pTry <= 1; pTry++ {
+		var tryBits [4]uint32
+		var errSum float64
+		for c, v := range vals {
+			b := math.Round((v - float64(pTry)) / 2)
+			if b < 0 {
+				b = 0
+			} else if b > 127 {
+				b = 127
+			}
+			recon := float64(uint32(b))*2 + float64(pTry)
+			d := v - recon
+			errSum += d * d
+			tryBits[c] = uint32(b)
+		}
+		if errSum < bestErr {
+			bestErr, bestBits, bestP = errSum, tryBits, pTry
+		}
+	}
+	return bestBits, bestP
+}
@@ -0,0 +1,180 @@
+package texture
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// fillSolid returns a 4x4 NRGBA filled with a single color, so a round trip
+// through CompressBC/DecodeBC should come back close to exact.
+func fillSolid(c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func approxEqual(a, b uint8, tolerance int) bool {
+	d := int(a) - int(b)
+	if d < 0 {
+		d = -d
+	}
+	return d <= tolerance
+}
+
+func TestCompressBC1RoundTripSolidColor(t *testing.T) {
+	want := color.NRGBA{R: 200, G: 40, B: 90, A: 255}
+	raw, err := CompressBC(fillSolid(want), BC1)
+	if err != nil {
+		t.Fatalf("CompressBC: %v", err)
+	}
+	if len(raw) != 8 {
+		t.Fatalf("BC1 block size = %d, want 8", len(raw))
+	}
+
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC1_UNORM}
+	img, err := DecodeBC(raw, meta)
+	if err != nil {
+		t.Fatalf("DecodeBC: %v", err)
+	}
+	got := img.NRGBAAt(0, 0)
+	if !approxEqual(got.R, want.R, 4) || !approxEqual(got.G, want.G, 2) || !approxEqual(got.B, want.B, 4) {
+		t.Errorf("round-tripped pixel = %+v, want close to %+v", got, want)
+	}
+}
+
+func TestCompressBC3RoundTripAlpha(t *testing.T) {
+	img := fillSolid(color.NRGBA{R: 10, G: 200, B: 10, A: 128})
+	raw, err := CompressBC(img, BC3)
+	if err != nil {
+		t.Fatalf("CompressBC: %v", err)
+	}
+	if len(raw) != 16 {
+		t.Fatalf("BC3 block size = %d, want 16", len(raw))
+	}
+
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC3_UNORM}
+	decoded, err := DecodeBC(raw, meta)
+	if err != nil {
+		t.Fatalf("DecodeBC: %v", err)
+	}
+	if got := decoded.NRGBAAt(0, 0).A; !approxEqual(got, 128, 4) {
+		t.Errorf("round-tripped alpha = %d, want close to 128", got)
+	}
+}
+
+func TestCompressBC5RoundTripChannels(t *testing.T) {
+	img := fillSolid(color.NRGBA{R: 30, G: 220, B: 0, A: 255})
+	raw, err := CompressBC(img, BC5)
+	if err != nil {
+		t.Fatalf("CompressBC: %v", err)
+	}
+
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC5_UNORM}
+	decoded, err := DecodeBC(raw, meta)
+	if err != nil {
+		t.Fatalf("DecodeBC: %v", err)
+	}
+	got := decoded.NRGBAAt(0, 0)
+	if !approxEqual(got.R, 30, 4) || !approxEqual(got.G, 220, 4) {
+		t.Errorf("round-tripped R/G = %d/%d, want close to 30/220", got.R, got.G)
+	}
+}
+
+func TestCompressBC7RoundTripSolidColor(t *testing.T) {
+	want := color.NRGBA{R: 180, G: 90, B: 30, A: 255}
+	raw, err := CompressBC(fillSolid(want), BC7)
+	if err != nil {
+		t.Fatalf("CompressBC: %v", err)
+	}
+	if len(raw) != 16 {
+		t.Fatalf("BC7 block size = %d, want 16", len(raw))
+	}
+
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC7_UNORM}
+	img, err := DecodeBC(raw, meta)
+	if err != nil {
+		t.Fatalf("DecodeBC: %v", err)
+	}
+	got := img.NRGBAAt(0, 0)
+	if !approxEqual(got.R, want.R, 4) || !approxEqual(got.G, want.G, 4) ||
+		!approxEqual(got.B, want.B, 4) || !approxEqual(got.A, want.A, 4) {
+		t.Errorf("round-tripped pixel = %+v, want close to %+v", got, want)
+	}
+}
+
+func TestCompressBC7RoundTripIndependentAlpha(t *testing.T) {
+	// Color varies smoothly across the tile while alpha stays flat, which
+	// plays to mode 5's separate color/alpha index sets.
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 60), G: uint8(y * 60), B: 128, A: 90})
+		}
+	}
+	raw, err := CompressBC(img, BC7)
+	if err != nil {
+		t.Fatalf("CompressBC: %v", err)
+	}
+
+	meta := &TextureMetadata{Width: 4, Height: 4, DXGIFormat: DXGI_FORMAT_BC7_UNORM}
+	decoded, err := DecodeBC(raw, meta)
+	if err != nil {
+		t.Fatalf("DecodeBC: %v", err)
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if got := decoded.NRGBAAt(x, y).A; !approxEqual(got, 90, 4) {
+				t.Errorf("pixel (%d,%d) alpha = %d, want close to 90", x, y, got)
+			}
+		}
+	}
+}
+
+func TestCompressBC7QualityFastUsesModeSix(t *testing.T) {
+	img := fillSolid(color.NRGBA{R: 50, G: 60, B: 70, A: 200})
+	fast, err := CompressBC(img, BC7, WithQuality(QualityFast))
+	if err != nil {
+		t.Fatalf("CompressBC: %v", err)
+	}
+	if got := fast[0] & 0x7f; got != 1<<6 {
+		t.Errorf("mode header bits = %#x, want mode 6's unary terminator at bit 6 (%#x)", got, 1<<6)
+	}
+}
+
+func TestDetectBCFormat(t *testing.T) {
+	if got := DetectBCFormat(fillSolid(color.NRGBA{R: 1, G: 2, B: 3, A: 255})); got != BC1 {
+		t.Errorf("opaque image: DetectBCFormat = %v, want BC1", got)
+	}
+
+	binaryAlpha := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			a := uint8(255)
+			if (x+y)%2 == 0 {
+				a = 0
+			}
+			binaryAlpha.SetNRGBA(x, y, color.NRGBA{R: 10, G: 10, B: 10, A: a})
+		}
+	}
+	if got := DetectBCFormat(binaryAlpha); got != BC3 {
+		t.Errorf("binary-alpha image: DetectBCFormat = %v, want BC3", got)
+	}
+}
+
+func TestCompressBCUnknownFormat(t *testing.T) {
+	if _, err := CompressBC(fillSolid(color.NRGBA{A: 255}), BCFormat(99)); err == nil {
+		t.Fatal("expected an error for an unsupported BCFormat")
+	}
+}
+
+func TestBCEncoderUseCGoUnavailable(t *testing.T) {
+	enc := BCEncoder{UseCGo: true}
+	if _, err := enc.CompressBC(fillSolid(color.NRGBA{A: 255}), BC1); err == nil {
+		t.Fatal("expected an error: no CGo BC encoder is linked into this build")
+	}
+}
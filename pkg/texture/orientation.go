@@ -0,0 +1,95 @@
+package texture
+
+import "image"
+
+// applyOrientation re-lays-out img's pixels according to an EXIF
+// Orientation tag value (1-8), the same 8-way table disintegration/imaging
+// applies when generating oriented thumbnails. Orientation 1 (identity)
+// and any value outside 1-8 return img unchanged.
+func applyOrientation(img *image.NRGBA, orientation int) *image.NRGBA {
+	switch orientation {
+	case 2:
+		return flipH(img)
+	case 3:
+		return rotate180(img)
+	case 4:
+		return flipV(img)
+	case 5:
+		return transpose(img)
+	case 6:
+		return rotate90CW(img)
+	case 7:
+		return transverse(img)
+	case 8:
+		return rotate90CCW(img)
+	default:
+		return img
+	}
+}
+
+// flipH mirrors img left-to-right (EXIF orientation 2).
+func flipH(img *image.NRGBA) *image.NRGBA {
+	b := img.Bounds()
+	out := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.SetNRGBA(b.Max.X-1-(x-b.Min.X), y, img.NRGBAAt(x, y))
+		}
+	}
+	return out
+}
+
+// flipV mirrors img top-to-bottom (EXIF orientation 4).
+func flipV(img *image.NRGBA) *image.NRGBA {
+	b := img.Bounds()
+	out := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.SetNRGBA(x, b.Max.Y-1-(y-b.Min.Y), img.NRGBAAt(x, y))
+		}
+	}
+	return out
+}
+
+// rotate180 rotates img by 180 degrees (EXIF orientation 3).
+func rotate180(img *image.NRGBA) *image.NRGBA {
+	return flipV(flipH(img))
+}
+
+// rotate90CW rotates img 90 degrees clockwise (EXIF orientation 6).
+func rotate90CW(img *image.NRGBA) *image.NRGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	out := image.NewNRGBA(image.Rect(0, 0, h, w))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.SetNRGBA(h-1-(y-b.Min.Y), x-b.Min.X, img.NRGBAAt(x, y))
+		}
+	}
+	return out
+}
+
+// rotate90CCW rotates img 90 degrees counter-clockwise (EXIF orientation 8).
+func rotate90CCW(img *image.NRGBA) *image.NRGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	out := image.NewNRGBA(image.Rect(0, 0, h, w))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.SetNRGBA(y-b.Min.Y, w-1-(x-b.Min.X), img.NRGBAAt(x, y))
+		}
+	}
+	return out
+}
+
+// transpose mirrors img across its top-left/bottom-right diagonal (EXIF
+// orientation 5): rotate90CW followed by flipH.
+func transpose(img *image.NRGBA) *image.NRGBA {
+	return flipH(rotate90CW(img))
+}
+
+// transverse mirrors img across its top-right/bottom-left diagonal (EXIF
+// orientation 7): rotate90CCW followed by flipH.
+func transverse(img *image.NRGBA) *image.NRGBA {
+	return flipH(rotate90CCW(img))
+}
@@ -0,0 +1,104 @@
+package texture
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// newTestImage builds a w×h NRGBA where each pixel's red channel encodes
+// y*w+x, so orientation transforms can be checked by comparing that index
+// at specific (x,y) coordinates.
+func newTestImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(y*w + x), G: 0, B: 0, A: 255})
+		}
+	}
+	return img
+}
+
+func TestApplyOrientationIdentity(t *testing.T) {
+	img := newTestImage(2, 3)
+	out := applyOrientation(img, 1)
+	if out != img {
+		t.Fatal("orientation 1 should return the image unchanged")
+	}
+
+	out = applyOrientation(img, 0)
+	if out != img {
+		t.Fatal("an out-of-range orientation should return the image unchanged")
+	}
+}
+
+func TestApplyOrientationFlipH(t *testing.T) {
+	img := newTestImage(3, 2)
+	out := applyOrientation(img, 2)
+	if out.NRGBAAt(0, 0).R != img.NRGBAAt(2, 0).R {
+		t.Errorf("flip-H: out(0,0)=%d, want img(2,0)=%d", out.NRGBAAt(0, 0).R, img.NRGBAAt(2, 0).R)
+	}
+}
+
+func TestApplyOrientationRotate180(t *testing.T) {
+	img := newTestImage(3, 2)
+	out := applyOrientation(img, 3)
+	if out.NRGBAAt(0, 0).R != img.NRGBAAt(2, 1).R {
+		t.Errorf("rotate-180: out(0,0)=%d, want img(2,1)=%d", out.NRGBAAt(0, 0).R, img.NRGBAAt(2, 1).R)
+	}
+}
+
+func TestApplyOrientationFlipV(t *testing.T) {
+	img := newTestImage(3, 2)
+	out := applyOrientation(img, 4)
+	if out.NRGBAAt(0, 0).R != img.NRGBAAt(0, 1).R {
+		t.Errorf("flip-V: out(0,0)=%d, want img(0,1)=%d", out.NRGBAAt(0, 0).R, img.NRGBAAt(0, 1).R)
+	}
+}
+
+func TestApplyOrientationRotate90CW(t *testing.T) {
+	img := newTestImage(3, 2) // 3 wide, 2 tall
+	out := applyOrientation(img, 6)
+	b := out.Bounds()
+	if b.Dx() != 2 || b.Dy() != 3 {
+		t.Fatalf("rotate-90-CW: got %dx%d, want 2x3", b.Dx(), b.Dy())
+	}
+	if out.NRGBAAt(1, 0).R != img.NRGBAAt(0, 0).R {
+		t.Errorf("rotate-90-CW: out(1,0)=%d, want img(0,0)=%d", out.NRGBAAt(1, 0).R, img.NRGBAAt(0, 0).R)
+	}
+}
+
+func TestApplyOrientationRotate90CCW(t *testing.T) {
+	img := newTestImage(3, 2)
+	out := applyOrientation(img, 8)
+	b := out.Bounds()
+	if b.Dx() != 2 || b.Dy() != 3 {
+		t.Fatalf("rotate-90-CCW: got %dx%d, want 2x3", b.Dx(), b.Dy())
+	}
+	if out.NRGBAAt(0, 2).R != img.NRGBAAt(0, 0).R {
+		t.Errorf("rotate-90-CCW: out(0,2)=%d, want img(0,0)=%d", out.NRGBAAt(0, 2).R, img.NRGBAAt(0, 0).R)
+	}
+}
+
+func TestApplyOrientationTransposeAndTransverse(t *testing.T) {
+	img := newTestImage(3, 2)
+
+	transposed := applyOrientation(img, 5)
+	if transposed.Bounds().Dx() != 2 || transposed.Bounds().Dy() != 3 {
+		t.Fatalf("transpose: got %dx%d, want 2x3", transposed.Bounds().Dx(), transposed.Bounds().Dy())
+	}
+	if transposed.NRGBAAt(0, 0).R != img.NRGBAAt(0, 0).R {
+		t.Errorf("transpose: out(0,0)=%d, want img(0,0)=%d", transposed.NRGBAAt(0, 0).R, img.NRGBAAt(0, 0).R)
+	}
+	if transposed.NRGBAAt(1, 0).R != img.NRGBAAt(0, 1).R {
+		t.Errorf("transpose: out(1,0)=%d, want img(0,1)=%d", transposed.NRGBAAt(1, 0).R, img.NRGBAAt(0, 1).R)
+	}
+
+	transversed := applyOrientation(img, 7)
+	if transversed.Bounds().Dx() != 2 || transversed.Bounds().Dy() != 3 {
+		t.Fatalf("transverse: got %dx%d, want 2x3", transversed.Bounds().Dx(), transversed.Bounds().Dy())
+	}
+	if transversed.NRGBAAt(1, 2).R != img.NRGBAAt(0, 0).R {
+		t.Errorf("transverse: out(1,2)=%d, want img(0,0)=%d", transversed.NRGBAAt(1, 2).R, img.NRGBAAt(0, 0).R)
+	}
+}
@@ -140,6 +140,18 @@ func FormatName(format uint32) string {
 	}
 }
 
+// IsHDRFormat reports whether format stores linear floating-point samples
+// (BC6H) rather than normalized 8-bit channels, so callers know to decode
+// and export it through the HDR/float path instead of the NRGBA one.
+func IsHDRFormat(format uint32) bool {
+	switch format {
+	case DXGI_FORMAT_BC6H_UF16, DXGI_FORMAT_BC6H_SF16:
+		return true
+	default:
+		return false
+	}
+}
+
 // DDS header constants
 const (
 	DDS_MAGIC                    = 0x20534444 // "DDS "
@@ -0,0 +1,334 @@
+// Package bundle implements a small RIFF-style container for sharing a
+// standalone set of tint.TintEntry captures, independent of the
+// manifest/package pair they were extracted from.
+//
+// A bundle file is the 4-byte magic "EVRT", a 4-byte little-endian total
+// size (the number of bytes following the size field), then a sequence of
+// chunks: a 4-byte id, a 4-byte little-endian payload size, the payload,
+// and a single zero pad byte if the payload length is odd. Unrecognized
+// chunk ids can be skipped by their size field alone, so new chunk types
+// stay forward-compatible with older readers.
+package bundle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/EchoTools/evrFileTools/pkg/tint"
+)
+
+// magic identifies a bundle file, written once at offset 0.
+var magic = [4]byte{'E', 'V', 'R', 'T'}
+
+// headerSize is magic plus the total-size field.
+const headerSize = len(magic) + 4
+
+// chunkHeaderSize is a chunk's id and size fields, before its payload.
+const chunkHeaderSize = 4 + 4
+
+// Chunk ids.
+var (
+	// ChunkTint holds one TintEntry, serialized via TintEntry.ToBytes
+	// (96 bytes).
+	ChunkTint = [4]byte{'T', 'I', 'N', 'T'}
+	// ChunkName holds a little-endian uint64 ResourceID followed by its
+	// UTF-8 display name, with no length prefix or terminator (the
+	// chunk's own size field bounds the name).
+	ChunkName = [4]byte{'N', 'A', 'M', 'E'}
+	// ChunkMeta holds JSON-encoded Meta.
+	ChunkMeta = [4]byte{'M', 'E', 'T', 'A'}
+	// ChunkList groups related chunks under a 4-byte list-type tag,
+	// mirroring RIFF's LIST chunk. EncodeTints/DecodeTints use
+	// listTypeTint to group one TintEntry's TINT and optional NAME chunk.
+	ChunkList = [4]byte{'L', 'I', 'S', 'T'}
+)
+
+// listTypeTint is the LIST-chunk type tag EncodeTints/DecodeTints use to
+// group one TintEntry's TINT and optional NAME chunk.
+var listTypeTint = [4]byte{'t', 'i', 'n', 't'}
+
+// Meta is the JSON payload of a ChunkMeta chunk: provenance for the tints
+// in a bundle.
+type Meta struct {
+	SourceFile      string `json:"source_file,omitempty"`
+	ExtractedAt     string `json:"extracted_at,omitempty"`
+	EchoVRBuildHash string `json:"echovr_build_hash,omitempty"`
+}
+
+// MarshalMeta JSON-encodes m for use as a ChunkMeta payload.
+func MarshalMeta(m Meta) ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// UnmarshalMeta decodes a ChunkMeta payload written by MarshalMeta.
+func UnmarshalMeta(data []byte) (Meta, error) {
+	var m Meta
+	err := json.Unmarshal(data, &m)
+	return m, err
+}
+
+// marshalChunk serializes one chunk: id, little-endian size, payload, and
+// an even-alignment pad byte if needed.
+func marshalChunk(id [4]byte, payload []byte) []byte {
+	data := make([]byte, 0, chunkHeaderSize+len(payload)+1)
+	data = append(data, id[:]...)
+	var sizeBuf [4]byte
+	binary.LittleEndian.PutUint32(sizeBuf[:], uint32(len(payload)))
+	data = append(data, sizeBuf[:]...)
+	data = append(data, payload...)
+	if len(payload)%2 != 0 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+// Writer appends chunks to a bundle, patching in the total-size header
+// field once Close is called.
+type Writer struct {
+	dst  io.WriteSeeker
+	size uint32
+}
+
+// NewWriter creates a Writer that writes a bundle to dst, starting with
+// the EVRT magic and a placeholder size patched in by Close.
+func NewWriter(dst io.WriteSeeker) (*Writer, error) {
+	var header [headerSize]byte
+	copy(header[:len(magic)], magic[:])
+	if _, err := dst.Write(header[:]); err != nil {
+		return nil, fmt.Errorf("write bundle header: %w", err)
+	}
+	return &Writer{dst: dst}, nil
+}
+
+// WriteChunk appends one chunk with the given id and payload.
+func (w *Writer) WriteChunk(id [4]byte, payload []byte) error {
+	data := marshalChunk(id, payload)
+	if _, err := w.dst.Write(data); err != nil {
+		return fmt.Errorf("write chunk %q: %w", id, err)
+	}
+	w.size += uint32(len(data))
+	return nil
+}
+
+// Close patches the bundle's total-size field with the number of bytes
+// written after the header.
+func (w *Writer) Close() error {
+	if _, err := w.dst.Seek(int64(len(magic)), io.SeekStart); err != nil {
+		return fmt.Errorf("seek to size field: %w", err)
+	}
+	var sizeBuf [4]byte
+	binary.LittleEndian.PutUint32(sizeBuf[:], w.size)
+	if _, err := w.dst.Write(sizeBuf[:]); err != nil {
+		return fmt.Errorf("write size field: %w", err)
+	}
+	_, err := w.dst.Seek(0, io.SeekEnd)
+	return err
+}
+
+// Reader iterates a bundle's chunks in file order via Next, skipping any
+// it doesn't recognize so new chunk types stay forward-compatible.
+type Reader struct {
+	r      io.ReaderAt
+	size   int64 // total bytes following the header, per the size field
+	offset int64 // read cursor, relative to the start of the chunk sequence
+}
+
+// NewReader opens a bundle for reading, validating its magic against r.
+func NewReader(r io.ReaderAt) (*Reader, error) {
+	var header [headerSize]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return nil, fmt.Errorf("read bundle header: %w", err)
+	}
+	if !bytes.Equal(header[:len(magic)], magic[:]) {
+		return nil, fmt.Errorf("not a bundle: missing EVRT magic")
+	}
+	size := int64(binary.LittleEndian.Uint32(header[len(magic):headerSize]))
+	return &Reader{r: r, size: size}, nil
+}
+
+// Next returns the next chunk's id and a reader over its payload. It
+// returns io.EOF once every chunk has been read.
+func (r *Reader) Next() (chunkID [4]byte, payload io.Reader, err error) {
+	if r.offset >= r.size {
+		return chunkID, nil, io.EOF
+	}
+
+	var hdr [chunkHeaderSize]byte
+	if _, err := r.r.ReadAt(hdr[:], int64(headerSize)+r.offset); err != nil {
+		return chunkID, nil, fmt.Errorf("read chunk header at %d: %w", r.offset, err)
+	}
+	copy(chunkID[:], hdr[:4])
+	payloadSize := int64(binary.LittleEndian.Uint32(hdr[4:chunkHeaderSize]))
+
+	payload = io.NewSectionReader(r.r, int64(headerSize)+r.offset+chunkHeaderSize, payloadSize)
+
+	advance := int64(chunkHeaderSize) + payloadSize
+	if payloadSize%2 != 0 {
+		advance++
+	}
+	r.offset += advance
+
+	return chunkID, payload, nil
+}
+
+// EncodeTints serializes entries as a bundle, one ChunkList per entry
+// grouping its ChunkTint payload with a ChunkName chunk whenever names
+// has a non-empty entry for its ResourceID.
+func EncodeTints(entries []*tint.TintEntry, names map[uint64]string) ([]byte, error) {
+	var dst memBuffer
+	w, err := NewWriter(&dst)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		var list bytes.Buffer
+		list.Write(listTypeTint[:])
+		list.Write(marshalChunk(ChunkTint, entry.ToBytes()))
+		if name := names[entry.ResourceID]; name != "" {
+			var namePayload bytes.Buffer
+			binary.Write(&namePayload, binary.LittleEndian, entry.ResourceID)
+			namePayload.WriteString(name)
+			list.Write(marshalChunk(ChunkName, namePayload.Bytes()))
+		}
+		if err := w.WriteChunk(ChunkList, list.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return dst.Bytes(), nil
+}
+
+// DecodeTints parses a bundle produced by EncodeTints (or any conforming
+// writer) back into TintEntry values, along with any names carried in
+// NAME chunks. Chunks other than a ChunkList with the tint list type,
+// including an unrecognized list type, are skipped.
+func DecodeTints(data []byte) ([]*tint.TintEntry, map[uint64]string, error) {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entries []*tint.TintEntry
+	names := make(map[uint64]string)
+
+	for {
+		id, payload, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if id != ChunkList {
+			continue
+		}
+
+		body, err := io.ReadAll(payload)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read LIST payload: %w", err)
+		}
+		if len(body) < 4 || [4]byte{body[0], body[1], body[2], body[3]} != listTypeTint {
+			continue
+		}
+
+		entry, name, err := decodeTintList(body[4:])
+		if err != nil {
+			return nil, nil, err
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+			if name != "" {
+				names[entry.ResourceID] = name
+			}
+		}
+	}
+
+	return entries, names, nil
+}
+
+// decodeTintList walks the sub-chunks of one tint LIST chunk's body
+// (after its list-type tag), extracting the TINT entry and optional NAME.
+func decodeTintList(body []byte) (*tint.TintEntry, string, error) {
+	var entry *tint.TintEntry
+	var name string
+
+	pos := 0
+	for pos < len(body) {
+		if pos+chunkHeaderSize > len(body) {
+			return nil, "", fmt.Errorf("truncated chunk in LIST")
+		}
+		var id [4]byte
+		copy(id[:], body[pos:pos+4])
+		size := int(binary.LittleEndian.Uint32(body[pos+4 : pos+chunkHeaderSize]))
+		pos += chunkHeaderSize
+		if pos+size > len(body) {
+			return nil, "", fmt.Errorf("truncated %q chunk payload", id)
+		}
+		sub := body[pos : pos+size]
+		pos += size
+		if size%2 != 0 {
+			pos++
+		}
+
+		switch id {
+		case ChunkTint:
+			e := tint.TintEntryFromBytes(sub)
+			if e == nil {
+				return nil, "", fmt.Errorf("malformed TINT chunk")
+			}
+			entry = e
+		case ChunkName:
+			if len(sub) < 8 {
+				return nil, "", fmt.Errorf("malformed NAME chunk")
+			}
+			name = string(sub[8:])
+		}
+	}
+
+	return entry, name, nil
+}
+
+// memBuffer is a minimal in-memory io.WriteSeeker, sufficient for
+// EncodeTints to drive a Writer without requiring a caller-provided
+// seekable destination.
+type memBuffer struct {
+	buf []byte
+	pos int64
+}
+
+func (m *memBuffer) Write(p []byte) (int, error) {
+	end := m.pos + int64(len(p))
+	if end > int64(len(m.buf)) {
+		grown := make([]byte, end)
+		copy(grown, m.buf)
+		m.buf = grown
+	}
+	n := copy(m.buf[m.pos:end], p)
+	m.pos = end
+	return n, nil
+}
+
+func (m *memBuffer) Seek(offset int64, whence int) (int64, error) {
+	var newPos int64
+	switch whence {
+	case io.SeekStart:
+		newPos = offset
+	case io.SeekCurrent:
+		newPos = m.pos + offset
+	case io.SeekEnd:
+		newPos = int64(len(m.buf)) + offset
+	default:
+		return 0, fmt.Errorf("memBuffer: invalid whence %d", whence)
+	}
+	m.pos = newPos
+	return newPos, nil
+}
+
+func (m *memBuffer) Bytes() []byte { return m.buf }
@@ -0,0 +1,229 @@
+package bundle
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/EchoTools/evrFileTools/pkg/tint"
+)
+
+type seekableBuffer struct {
+	*bytes.Buffer
+	pos int64
+}
+
+func (s *seekableBuffer) Seek(offset int64, whence int) (int64, error) {
+	var newPos int64
+	switch whence {
+	case io.SeekStart:
+		newPos = offset
+	case io.SeekCurrent:
+		newPos = s.pos + offset
+	case io.SeekEnd:
+		newPos = int64(s.Buffer.Len()) + offset
+	}
+	s.pos = newPos
+	return newPos, nil
+}
+
+func (s *seekableBuffer) Write(p []byte) (n int, err error) {
+	for int64(s.Buffer.Len()) < s.pos {
+		s.Buffer.WriteByte(0)
+	}
+	if s.pos < int64(s.Buffer.Len()) {
+		data := s.Buffer.Bytes()
+		n = copy(data[s.pos:], p)
+		if n < len(p) {
+			m, err := s.Buffer.Write(p[n:])
+			n += m
+			if err != nil {
+				return n, err
+			}
+		}
+	} else {
+		n, err = s.Buffer.Write(p)
+	}
+	s.pos += int64(n)
+	return n, err
+}
+
+func makeTintFixture() []*tint.TintEntry {
+	return []*tint.TintEntry{
+		{
+			ResourceID: 0x74d228d09dc5dc86,
+			Colors: [5]tint.Color{
+				{R: 1, G: 0, B: 0, A: 1},
+				{R: 0, G: 1, B: 0, A: 1},
+				{R: 0, G: 0, B: 1, A: 1},
+				{R: 1, G: 1, B: 0, A: 1},
+				{R: 0.5, G: 0.25, B: 0.75, A: 1},
+			},
+		},
+		{
+			ResourceID: 0x3e474b60a9416aca,
+			Colors: [5]tint.Color{
+				{R: 0.1, G: 0.2, B: 0.3, A: 1},
+				{R: 0.4, G: 0.5, B: 0.6, A: 1},
+				{R: 0.7, G: 0.8, B: 0.9, A: 1},
+				{R: 1, G: 1, B: 1, A: 1},
+				{R: 0, G: 0, B: 0, A: 1},
+			},
+		},
+	}
+}
+
+func TestWriterReaderRoundTripsArbitraryChunks(t *testing.T) {
+	var buf bytes.Buffer
+	ws := &seekableBuffer{Buffer: &buf}
+
+	w, err := NewWriter(ws)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.WriteChunk(ChunkTint, bytes.Repeat([]byte{0xAB}, tint.TintEntrySize)); err != nil {
+		t.Fatalf("WriteChunk TINT: %v", err)
+	}
+	if err := w.WriteChunk(ChunkMeta, []byte(`{"source_file":"x"}`)); err != nil {
+		t.Fatalf("WriteChunk META: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+
+	var ids [][4]byte
+	for {
+		id, payload, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		ids = append(ids, id)
+		if _, err := io.ReadAll(payload); err != nil {
+			t.Fatalf("read payload %q: %v", id, err)
+		}
+	}
+
+	if len(ids) != 2 || ids[0] != ChunkTint || ids[1] != ChunkMeta {
+		t.Fatalf("got chunk sequence %v, want [TINT META]", ids)
+	}
+}
+
+func TestReaderSkipsUnknownChunks(t *testing.T) {
+	var buf bytes.Buffer
+	ws := &seekableBuffer{Buffer: &buf}
+
+	w, err := NewWriter(ws)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.WriteChunk([4]byte{'X', 'T', 'R', 'A'}, []byte("odd")); err != nil {
+		t.Fatalf("WriteChunk XTRA: %v", err)
+	}
+	if err := w.WriteChunk(ChunkMeta, []byte("{}")); err != nil {
+		t.Fatalf("WriteChunk META: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+
+	id, _, err := r.Next()
+	if err != nil || id != [4]byte{'X', 'T', 'R', 'A'} {
+		t.Fatalf("first chunk = %v, %v, want XTRA", id, err)
+	}
+	id, _, err = r.Next()
+	if err != nil || id != ChunkMeta {
+		t.Fatalf("second chunk = %v, %v, want META (odd-length XTRA payload must still be skippable)", id, err)
+	}
+	if _, _, err := r.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestEncodeDecodeTintsRoundTrip(t *testing.T) {
+	entries := makeTintFixture()
+	names := map[uint64]string{
+		entries[0].ResourceID: "rwd_tint_0000",
+	}
+
+	data, err := EncodeTints(entries, names)
+	if err != nil {
+		t.Fatalf("EncodeTints: %v", err)
+	}
+
+	gotEntries, gotNames, err := DecodeTints(data)
+	if err != nil {
+		t.Fatalf("DecodeTints: %v", err)
+	}
+
+	if len(gotEntries) != len(entries) {
+		t.Fatalf("got %d entries, want %d", len(gotEntries), len(entries))
+	}
+	for i, want := range entries {
+		got := gotEntries[i]
+		if got.ResourceID != want.ResourceID {
+			t.Errorf("entry %d: ResourceID = 0x%016x, want 0x%016x", i, got.ResourceID, want.ResourceID)
+		}
+		if got.Colors != want.Colors {
+			t.Errorf("entry %d: Colors = %v, want %v", i, got.Colors, want.Colors)
+		}
+	}
+
+	if len(gotNames) != 1 || gotNames[entries[0].ResourceID] != "rwd_tint_0000" {
+		t.Errorf("got names %v, want {0x%016x: rwd_tint_0000}", gotNames, entries[0].ResourceID)
+	}
+}
+
+func TestEncodeTintsWithoutNames(t *testing.T) {
+	entries := makeTintFixture()
+
+	data, err := EncodeTints(entries, nil)
+	if err != nil {
+		t.Fatalf("EncodeTints: %v", err)
+	}
+
+	gotEntries, gotNames, err := DecodeTints(data)
+	if err != nil {
+		t.Fatalf("DecodeTints: %v", err)
+	}
+	if len(gotEntries) != len(entries) {
+		t.Fatalf("got %d entries, want %d", len(gotEntries), len(entries))
+	}
+	if len(gotNames) != 0 {
+		t.Errorf("got names %v, want none", gotNames)
+	}
+}
+
+func TestMarshalUnmarshalMeta(t *testing.T) {
+	m := Meta{SourceFile: "echovr.exe", ExtractedAt: "2026-07-28T00:00:00Z", EchoVRBuildHash: "deadbeef"}
+
+	data, err := MarshalMeta(m)
+	if err != nil {
+		t.Fatalf("MarshalMeta: %v", err)
+	}
+	got, err := UnmarshalMeta(data)
+	if err != nil {
+		t.Fatalf("UnmarshalMeta: %v", err)
+	}
+	if got != m {
+		t.Errorf("got %+v, want %+v", got, m)
+	}
+}
+
+func TestNewReaderRejectsBadMagic(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader([]byte("not-a-bundle-at-all"))); err == nil {
+		t.Fatal("expected error for missing EVRT magic")
+	}
+}
@@ -0,0 +1,339 @@
+package tint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// colorRoleNames labels entry.Colors[i] when a tint is exported to a DCC
+// palette format, matching the order ToCSS uses (main-1, accent-1, main-2,
+// accent-2, body).
+var colorRoleNames = [5]string{"main-1", "accent-1", "main-2", "accent-2", "body"}
+
+// ASE block types, per the Adobe Swatch Exchange spec.
+const (
+	aseBlockGroupStart = 0xC001
+	aseBlockColorEntry = 0x0001
+	aseBlockGroupEnd   = 0xC002
+)
+
+// aseColorSpaceRGB is the 4-byte ASE colorspace tag WriteASE/ReadASE use;
+// entries are always written as 3-component float RGB.
+const aseColorSpaceRGB = "RGB "
+
+// aseColorTypeGlobal marks a color entry as a "global" swatch (as opposed
+// to spot or process), which is the common case for a palette export.
+const aseColorTypeGlobal = 0
+
+// WriteASE writes entries as an Adobe Swatch Exchange (.ase) file. Each
+// TintEntry becomes a named group (0xC001/0xC002) containing its 5 colors
+// as RGB color entries (0x0001), labeled by colorRoleNames so a re-import
+// via ReadASE can put them back in the right slot. names resolves a
+// ResourceID to a human-readable group name (e.g. LookupTintName); entries
+// for which it returns "" fall back to the hex ResourceID.
+func WriteASE(w io.Writer, entries []*TintEntry, names func(uint64) string) error {
+	if names == nil {
+		names = func(uint64) string { return "" }
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("ASEF")
+	binary.Write(&buf, binary.BigEndian, uint16(1)) // version major
+	binary.Write(&buf, binary.BigEndian, uint16(0)) // version minor
+
+	blockCount := uint32(len(entries) * (2 + len(colorRoleNames)))
+	binary.Write(&buf, binary.BigEndian, blockCount)
+
+	for _, entry := range entries {
+		groupName := names(entry.ResourceID)
+		if groupName == "" {
+			groupName = fmt.Sprintf("%016x", entry.ResourceID)
+		}
+		writeASEBlock(&buf, aseBlockGroupStart, encodeASEName(groupName))
+		for i, color := range entry.Colors {
+			writeASEColorEntry(&buf, fmt.Sprintf("%s %s", groupName, colorRoleNames[i]), color)
+		}
+		writeASEBlock(&buf, aseBlockGroupEnd, nil)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeASEBlock(buf *bytes.Buffer, blockType uint16, data []byte) {
+	binary.Write(buf, binary.BigEndian, blockType)
+	binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(data)
+}
+
+func writeASEColorEntry(buf *bytes.Buffer, name string, color Color) {
+	var data bytes.Buffer
+	data.Write(encodeASEName(name))
+	data.WriteString(aseColorSpaceRGB)
+	binary.Write(&data, binary.BigEndian, color.R)
+	binary.Write(&data, binary.BigEndian, color.G)
+	binary.Write(&data, binary.BigEndian, color.B)
+	binary.Write(&data, binary.BigEndian, uint16(aseColorTypeGlobal))
+	writeASEBlock(buf, aseBlockColorEntry, data.Bytes())
+}
+
+// encodeASEName encodes name as ASE expects it: a uint16BE code-unit count
+// (including a null terminator) followed by the name as UTF-16BE.
+func encodeASEName(name string) []byte {
+	units := utf16.Encode([]rune(name))
+	units = append(units, 0)
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint16(len(units)))
+	for _, u := range units {
+		binary.Write(&buf, binary.BigEndian, u)
+	}
+	return buf.Bytes()
+}
+
+// decodeASEName decodes a name written by encodeASEName from the front of
+// data, returning the name and the remaining bytes.
+func decodeASEName(data []byte) (name string, rest []byte, err error) {
+	if len(data) < 2 {
+		return "", nil, fmt.Errorf("name length truncated")
+	}
+	units := binary.BigEndian.Uint16(data[0:2])
+	end := 2 + int(units)*2
+	if end > len(data) {
+		return "", nil, fmt.Errorf("name data truncated")
+	}
+	codeUnits := make([]uint16, units)
+	for i := range codeUnits {
+		codeUnits[i] = binary.BigEndian.Uint16(data[2+i*2 : 4+i*2])
+	}
+	return strings.TrimRight(string(utf16.Decode(codeUnits)), "\x00"), data[end:], nil
+}
+
+// ReadASE parses an ASE file produced by WriteASE back into TintEntry
+// values, one per group. resolve maps a group name back to a ResourceID
+// and should usually invert the names function passed to WriteASE (e.g. a
+// reverse lookup over KnownTints); if resolve is nil, group names are
+// parsed as hex ResourceID literals, which is what WriteASE falls back to
+// for unnamed tints.
+func ReadASE(r io.Reader, resolve func(name string) (uint64, bool)) ([]*TintEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[0:4]) != "ASEF" {
+		return nil, fmt.Errorf("tint: not an ASE file")
+	}
+	blockCount := binary.BigEndian.Uint32(data[8:12])
+	pos := 12
+
+	var entries []*TintEntry
+	var current *TintEntry
+	var currentName string
+	colorIdx := 0
+
+	for i := uint32(0); i < blockCount; i++ {
+		if pos+6 > len(data) {
+			return nil, fmt.Errorf("tint: truncated ASE block %d", i)
+		}
+		blockType := binary.BigEndian.Uint16(data[pos : pos+2])
+		blockLen := int(binary.BigEndian.Uint32(data[pos+2 : pos+6]))
+		pos += 6
+		if pos+blockLen > len(data) {
+			return nil, fmt.Errorf("tint: truncated ASE block %d data", i)
+		}
+		block := data[pos : pos+blockLen]
+		pos += blockLen
+
+		switch blockType {
+		case aseBlockGroupStart:
+			name, _, err := decodeASEName(block)
+			if err != nil {
+				return nil, fmt.Errorf("tint: group %d name: %w", i, err)
+			}
+			resourceID, ok := resolveDCCName(name, resolve)
+			if !ok {
+				return nil, fmt.Errorf("tint: unresolvable group name %q", name)
+			}
+			current = &TintEntry{ResourceID: resourceID}
+			currentName = name
+			colorIdx = 0
+		case aseBlockColorEntry:
+			if current == nil {
+				return nil, fmt.Errorf("tint: color entry %d outside any group", i)
+			}
+			_, rest, err := decodeASEName(block)
+			if err != nil {
+				return nil, fmt.Errorf("tint: color %d name: %w", i, err)
+			}
+			if len(rest) < 18 {
+				return nil, fmt.Errorf("tint: color %d data too short", i)
+			}
+			if colorIdx >= len(current.Colors) {
+				return nil, fmt.Errorf("tint: group %q has more than %d colors", currentName, len(current.Colors))
+			}
+			current.Colors[colorIdx] = Color{
+				R: math.Float32frombits(binary.BigEndian.Uint32(rest[4:8])),
+				G: math.Float32frombits(binary.BigEndian.Uint32(rest[8:12])),
+				B: math.Float32frombits(binary.BigEndian.Uint32(rest[12:16])),
+				A: 1,
+			}
+			colorIdx++
+		case aseBlockGroupEnd:
+			if current != nil {
+				entries = append(entries, current)
+				current = nil
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// WriteACO writes entries as an Adobe Color Swatch (.aco) file, version 1.
+// Version 1 has no room for names, so entries are flattened to their 5 raw
+// colors in file order; pair the output with a WriteASE/WriteGPL export
+// (which do carry names) if a designer needs to tell swatches apart.
+func WriteACO(w io.Writer, entries []*TintEntry) error {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint16(1)) // version
+	binary.Write(&buf, binary.BigEndian, uint16(len(entries)*len(colorRoleNames)))
+
+	for _, entry := range entries {
+		for _, color := range entry.Colors {
+			binary.Write(&buf, binary.BigEndian, uint16(0)) // RGB color space
+			binary.Write(&buf, binary.BigEndian, aco16(color.R))
+			binary.Write(&buf, binary.BigEndian, aco16(color.G))
+			binary.Write(&buf, binary.BigEndian, aco16(color.B))
+			binary.Write(&buf, binary.BigEndian, uint16(0)) // unused
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// aco16 scales a 0.0-1.0 component to ACO's 16-bit range.
+func aco16(v float32) uint16 {
+	return uint16(clamp(v, 0, 1) * 65535)
+}
+
+// WriteGPL writes entries as a GIMP palette (.gpl) text file. Colors stay
+// in file order; each swatch is labeled "<name> <role>" (the same
+// colorRoleNames used by WriteASE) so ReadGPL can reconstruct the
+// TintEntry grouping. names resolves a ResourceID to a human-readable
+// name, falling back to the hex ResourceID when it returns "".
+func WriteGPL(w io.Writer, entries []*TintEntry, names func(uint64) string) error {
+	if names == nil {
+		names = func(uint64) string { return "" }
+	}
+
+	var sb strings.Builder
+	sb.WriteString("GIMP Palette\n")
+	sb.WriteString("Name: EchoVR Tints\n")
+	sb.WriteString("Columns: 5\n")
+	sb.WriteString("#\n")
+
+	for _, entry := range entries {
+		name := names(entry.ResourceID)
+		if name == "" {
+			name = fmt.Sprintf("%016x", entry.ResourceID)
+		}
+		for i, color := range entry.Colors {
+			r := uint8(clamp(color.R, 0, 1) * 255)
+			g := uint8(clamp(color.G, 0, 1) * 255)
+			b := uint8(clamp(color.B, 0, 1) * 255)
+			fmt.Fprintf(&sb, "%3d %3d %3d %s %s\n", r, g, b, name, colorRoleNames[i])
+		}
+	}
+
+	_, err := w.Write([]byte(sb.String()))
+	return err
+}
+
+// ReadGPL parses a GPL file produced by WriteGPL back into TintEntry
+// values, resolving each swatch's name the same way ReadASE does.
+func ReadGPL(r io.Reader, resolve func(name string) (uint64, bool)) ([]*TintEntry, error) {
+	scanner := bufio.NewScanner(r)
+
+	var entries []*TintEntry
+	var current *TintEntry
+	var currentName string
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "GIMP Palette") ||
+			strings.HasPrefix(line, "Name:") || strings.HasPrefix(line, "Columns:") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("tint: malformed GPL line %q", line)
+		}
+		red, err1 := strconv.ParseUint(fields[0], 10, 8)
+		green, err2 := strconv.ParseUint(fields[1], 10, 8)
+		blue, err3 := strconv.ParseUint(fields[2], 10, 8)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("tint: malformed GPL color %q", line)
+		}
+
+		role := fields[len(fields)-1]
+		colorIdx, ok := colorRoleIndex(role)
+		if !ok {
+			return nil, fmt.Errorf("tint: unrecognized GPL swatch role %q", role)
+		}
+		name := strings.Join(fields[3:len(fields)-1], " ")
+
+		if current == nil || name != currentName {
+			if current != nil {
+				entries = append(entries, current)
+			}
+			resourceID, ok := resolveDCCName(name, resolve)
+			if !ok {
+				return nil, fmt.Errorf("tint: unresolvable swatch name %q", name)
+			}
+			current = &TintEntry{ResourceID: resourceID}
+			currentName = name
+		}
+		current.Colors[colorIdx] = Color{
+			R: float32(red) / 255,
+			G: float32(green) / 255,
+			B: float32(blue) / 255,
+			A: 1,
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if current != nil {
+		entries = append(entries, current)
+	}
+
+	return entries, nil
+}
+
+func colorRoleIndex(role string) (int, bool) {
+	for i, name := range colorRoleNames {
+		if name == role {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// resolveDCCName maps a palette swatch/group name back to a ResourceID,
+// using resolve if given or parsing name as a hex literal otherwise.
+func resolveDCCName(name string, resolve func(string) (uint64, bool)) (uint64, bool) {
+	if resolve != nil {
+		return resolve(name)
+	}
+	id, err := strconv.ParseUint(name, 16, 64)
+	return id, err == nil
+}
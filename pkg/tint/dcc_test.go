@@ -0,0 +1,163 @@
+package tint
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func makeDCCFixture() []*TintEntry {
+	return []*TintEntry{
+		{
+			ResourceID: 0x74d228d09dc5dc86,
+			Colors: [5]Color{
+				{1.0, 0.0, 0.0, 1.0},
+				{0.0, 1.0, 0.0, 1.0},
+				{0.0, 0.0, 1.0, 1.0},
+				{1.0, 1.0, 0.0, 1.0},
+				{0.5, 0.25, 0.75, 1.0},
+			},
+		},
+		{
+			ResourceID: 0x3e474b60a9416aca,
+			Colors: [5]Color{
+				{0.1, 0.2, 0.3, 1.0},
+				{0.4, 0.5, 0.6, 1.0},
+				{0.7, 0.8, 0.9, 1.0},
+				{1.0, 1.0, 1.0, 1.0},
+				{0.0, 0.0, 0.0, 1.0},
+			},
+		},
+	}
+}
+
+func assertTintEntriesApproxEqual(t *testing.T, got, want []*TintEntry) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ResourceID != want[i].ResourceID {
+			t.Errorf("entry %d: ResourceID = 0x%016x, want 0x%016x", i, got[i].ResourceID, want[i].ResourceID)
+		}
+		for c := range want[i].Colors {
+			wc, gc := want[i].Colors[c], got[i].Colors[c]
+			const eps = 1.0 / 255
+			if abs32(wc.R-gc.R) > eps || abs32(wc.G-gc.G) > eps || abs32(wc.B-gc.B) > eps {
+				t.Errorf("entry %d color %d = %v, want %v", i, c, gc, wc)
+			}
+		}
+	}
+}
+
+func abs32(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestASERoundTripWithKnownNames(t *testing.T) {
+	entries := makeDCCFixture()
+
+	reverse := make(map[string]uint64, len(KnownTints))
+	for id, name := range KnownTints {
+		reverse[name] = id
+	}
+
+	var buf bytes.Buffer
+	if err := WriteASE(&buf, entries, LookupTintName); err != nil {
+		t.Fatalf("WriteASE: %v", err)
+	}
+
+	got, err := ReadASE(&buf, func(name string) (uint64, bool) {
+		id, ok := reverse[name]
+		return id, ok
+	})
+	if err != nil {
+		t.Fatalf("ReadASE: %v", err)
+	}
+	assertTintEntriesApproxEqual(t, got, entries)
+}
+
+func TestASERoundTripWithUnnamedFallback(t *testing.T) {
+	entries := []*TintEntry{
+		{ResourceID: 0xdeadbeefcafef00d, Colors: [5]Color{
+			{1, 0, 0, 1}, {0, 1, 0, 1}, {0, 0, 1, 1}, {1, 1, 1, 1}, {0, 0, 0, 1},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteASE(&buf, entries, LookupTintName); err != nil {
+		t.Fatalf("WriteASE: %v", err)
+	}
+
+	got, err := ReadASE(&buf, nil)
+	if err != nil {
+		t.Fatalf("ReadASE: %v", err)
+	}
+	assertTintEntriesApproxEqual(t, got, entries)
+}
+
+func TestWriteASEHeaderAndGroupName(t *testing.T) {
+	entries := makeDCCFixture()
+
+	var buf bytes.Buffer
+	if err := WriteASE(&buf, entries, LookupTintName); err != nil {
+		t.Fatalf("WriteASE: %v", err)
+	}
+
+	if !bytes.HasPrefix(buf.Bytes(), []byte("ASEF")) {
+		t.Error("ASE output missing ASEF magic")
+	}
+	var nameUTF16BE bytes.Buffer
+	for _, u := range utf16.Encode([]rune("rwd_tint_0000")) {
+		nameUTF16BE.WriteByte(byte(u >> 8))
+		nameUTF16BE.WriteByte(byte(u))
+	}
+	if !bytes.Contains(buf.Bytes(), nameUTF16BE.Bytes()) {
+		t.Error("ASE output missing resolved group name rwd_tint_0000 (UTF-16BE)")
+	}
+}
+
+func TestWriteACOProducesFixedSizeEntries(t *testing.T) {
+	entries := makeDCCFixture()
+
+	var buf bytes.Buffer
+	if err := WriteACO(&buf, entries); err != nil {
+		t.Fatalf("WriteACO: %v", err)
+	}
+
+	wantEntries := len(entries) * 5
+	wantSize := 4 + wantEntries*10 // version+count header, then space+4 uint16 components per entry
+	if buf.Len() != wantSize {
+		t.Errorf("got %d bytes, want %d", buf.Len(), wantSize)
+	}
+}
+
+func TestGPLRoundTripWithKnownNames(t *testing.T) {
+	entries := makeDCCFixture()
+
+	reverse := make(map[string]uint64, len(KnownTints))
+	for id, name := range KnownTints {
+		reverse[name] = id
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGPL(&buf, entries, LookupTintName); err != nil {
+		t.Fatalf("WriteGPL: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "GIMP Palette\n") {
+		t.Error("GPL output missing GIMP Palette header")
+	}
+
+	got, err := ReadGPL(&buf, func(name string) (uint64, bool) {
+		id, ok := reverse[name]
+		return id, ok
+	})
+	if err != nil {
+		t.Fatalf("ReadGPL: %v", err)
+	}
+	assertTintEntriesApproxEqual(t, got, entries)
+}
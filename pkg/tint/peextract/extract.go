@@ -0,0 +1,159 @@
+package peextract
+
+import (
+	"debug/pe"
+	"fmt"
+	"os"
+
+	"github.com/EchoTools/evrFileTools/pkg/tint"
+)
+
+// DefaultItemIDsVA and DefaultSecondaryVA are the runtime addresses of
+// g_TintTable_ItemIDs and g_TintTable_Secondary documented in tint.go,
+// from Ghidra analysis of the echovr.exe build that package was reverse
+// engineered against. A patched or updated binary will usually move
+// them; pass WithPrologueScan to relocate the tables instead of trusting
+// these.
+const (
+	DefaultItemIDsVA   uint64 = 0x1420d3ac0
+	DefaultSecondaryVA uint64 = 0x1420d3ac8
+)
+
+// DefaultMaxEntries bounds how many primary/secondary table entries
+// ExtractTints will walk before giving up, since neither table carries
+// an explicit element count in memory to stop at.
+const DefaultMaxEntries = 4096
+
+// config holds ExtractTints' options.
+type config struct {
+	itemIDsVA    uint64
+	secondaryVA  uint64
+	maxEntries   int
+	scanPrologue bool
+	prologue     []byte
+}
+
+// Option configures ExtractTints.
+type Option func(*config)
+
+// WithItemIDsVA overrides the runtime address of g_TintTable_ItemIDs.
+// It defaults to DefaultItemIDsVA.
+func WithItemIDsVA(va uint64) Option {
+	return func(c *config) { c.itemIDsVA = va }
+}
+
+// WithSecondaryVA overrides the runtime address of g_TintTable_Secondary.
+// It defaults to DefaultSecondaryVA.
+func WithSecondaryVA(va uint64) Option {
+	return func(c *config) { c.secondaryVA = va }
+}
+
+// WithMaxEntries bounds how many table entries ExtractTints walks before
+// giving up. It defaults to DefaultMaxEntries.
+func WithMaxEntries(n int) Option {
+	return func(c *config) { c.maxEntries = n }
+}
+
+// WithPrologueScan tells ExtractTints to locate the tint tables by
+// scanning the binary's code sections for the tint-registration
+// function's prologue bytes instead of trusting
+// ItemIDsVA/SecondaryVA, so extraction keeps working across echovr.exe
+// builds that shift those addresses. A nil prologue falls back to
+// DefaultRegisterTintPrologue.
+func WithPrologueScan(prologue []byte) Option {
+	return func(c *config) {
+		c.scanPrologue = true
+		c.prologue = prologue
+	}
+}
+
+// ExtractTints opens peFile and reads the tint tables directly out of its
+// .data/.rdata sections: it walks g_TintTable_ItemIDs
+// (TintTableEntry_Primary, 0x18 bytes each) and g_TintTable_Secondary
+// (TintTableEntry_Secondary, 0x20 bytes each), joins the two tables by
+// ResourceID, then follows each secondary entry's ItemData pointer to the
+// 0x60-byte TintEntry it references. The returned map gives
+// tint.LookupTintName's result for every extracted entry whose
+// ResourceID matched tint.KnownTints.
+func ExtractTints(peFile string, opts ...Option) ([]*tint.TintEntry, map[uint64]string, error) {
+	cfg := &config{
+		itemIDsVA:   DefaultItemIDsVA,
+		secondaryVA: DefaultSecondaryVA,
+		maxEntries:  DefaultMaxEntries,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	f, err := os.Open(peFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open %s: %w", peFile, err)
+	}
+	defer f.Close()
+
+	pf, err := pe.NewFile(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse PE: %w", err)
+	}
+
+	imageBase, err := imageBaseOf(pf)
+	if err != nil {
+		return nil, nil, err
+	}
+	sm := newSectionMap(pf, imageBase)
+
+	if cfg.scanPrologue {
+		prologue := cfg.prologue
+		if prologue == nil {
+			prologue = DefaultRegisterTintPrologue
+		}
+		itemIDsVA, secondaryVA, err := locateTintTables(pf, sm, prologue)
+		if err != nil {
+			return nil, nil, fmt.Errorf("locate tint tables: %w", err)
+		}
+		cfg.itemIDsVA, cfg.secondaryVA = itemIDsVA, secondaryVA
+	}
+
+	primaries, err := readPrimaryTable(f, sm, cfg.itemIDsVA, cfg.maxEntries)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read primary table: %w", err)
+	}
+	secondaries, err := readSecondaryTable(f, sm, cfg.secondaryVA, cfg.maxEntries)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read secondary table: %w", err)
+	}
+
+	bySymbol := make(map[uint64]tint.TintTableEntry_Secondary, len(secondaries))
+	for _, s := range secondaries {
+		bySymbol[s.ResourceID] = s
+	}
+
+	var entries []*tint.TintEntry
+	names := make(map[uint64]string)
+	for _, p := range primaries {
+		sec, ok := bySymbol[p.ResourceID]
+		if !ok {
+			continue
+		}
+
+		offset, err := sm.fileOffset(sec.ItemData)
+		if err != nil {
+			continue
+		}
+		data := make([]byte, tint.TintEntrySize)
+		if _, err := f.ReadAt(data, int64(offset)); err != nil {
+			continue
+		}
+
+		entry := tint.TintEntryFromBytes(data)
+		if entry == nil {
+			continue
+		}
+		entries = append(entries, entry)
+		if name := tint.LookupTintName(entry.ResourceID); name != "" {
+			names[entry.ResourceID] = name
+		}
+	}
+
+	return entries, names, nil
+}
@@ -0,0 +1,184 @@
+package peextract
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EchoTools/evrFileTools/pkg/tint"
+)
+
+const testImageBase = 0x140000000
+
+// buildTestPE writes a minimal, real PE32+ (x86-64) executable to dir,
+// with a single .rdata section holding rdata, and returns its path. It's
+// assembled directly from the debug/pe structs so the stdlib parser used
+// by ExtractTints reads it exactly like a real echovr.exe.
+func buildTestPE(t *testing.T, rdata []byte) string {
+	t.Helper()
+
+	const sectionFileOffset = 0x200
+	const rdataVA = 0x3000
+
+	var buf bytes.Buffer
+
+	// Lay the PE header out at a fixed, generous offset so we don't have
+	// to hand-compute e_lfanew against variably-sized pieces.
+	const peHeaderOffset = 0x80
+
+	dos := make([]byte, 0x40)
+	dos[0], dos[1] = 'M', 'Z'
+	binary.LittleEndian.PutUint32(dos[0x3c:], peHeaderOffset) // e_lfanew
+	buf.Write(dos)
+	buf.Write(make([]byte, peHeaderOffset-len(dos)))
+
+	buf.WriteString("PE\x00\x00")
+
+	fh := pe.FileHeader{
+		Machine:              pe.IMAGE_FILE_MACHINE_AMD64,
+		NumberOfSections:     1,
+		SizeOfOptionalHeader: uint16(binary.Size(pe.OptionalHeader64{})),
+		Characteristics:      pe.IMAGE_FILE_EXECUTABLE_IMAGE,
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, fh); err != nil {
+		t.Fatalf("write file header: %v", err)
+	}
+
+	oh := pe.OptionalHeader64{
+		Magic:               0x20b,
+		ImageBase:           testImageBase,
+		SectionAlignment:    0x1000,
+		FileAlignment:       0x200,
+		SizeOfImage:         0x10000,
+		SizeOfHeaders:       sectionFileOffset,
+		NumberOfRvaAndSizes: 16,
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, oh); err != nil {
+		t.Fatalf("write optional header: %v", err)
+	}
+
+	var name [8]byte
+	copy(name[:], ".rdata")
+	sh := pe.SectionHeader32{
+		Name:             name,
+		VirtualSize:      uint32(len(rdata)),
+		VirtualAddress:   rdataVA,
+		SizeOfRawData:    uint32(len(rdata)),
+		PointerToRawData: sectionFileOffset,
+		Characteristics:  pe.IMAGE_SCN_CNT_INITIALIZED_DATA | pe.IMAGE_SCN_MEM_READ,
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, sh); err != nil {
+		t.Fatalf("write section header: %v", err)
+	}
+
+	if buf.Len() > sectionFileOffset {
+		t.Fatalf("headers overran reserved space: %d > %d", buf.Len(), sectionFileOffset)
+	}
+	buf.Write(make([]byte, sectionFileOffset-buf.Len()))
+	buf.Write(rdata)
+
+	path := filepath.Join(t.TempDir(), "echovr.exe")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write test PE: %v", err)
+	}
+	return path
+}
+
+func putUint64(b []byte, off int, v uint64) { binary.LittleEndian.PutUint64(b[off:], v) }
+func putUint32(b []byte, off int, v uint32) { binary.LittleEndian.PutUint32(b[off:], v) }
+
+func TestExtractTints(t *testing.T) {
+	const rdataVA = testImageBase + 0x3000
+
+	entry := &tint.TintEntry{
+		ResourceID: 0x74d228d09dc5dc86, // KnownTints: rwd_tint_0000
+		Colors: [5]tint.Color{
+			{R: 1, G: 0, B: 0, A: 1},
+			{R: 0, G: 1, B: 0, A: 1},
+			{R: 0, G: 0, B: 1, A: 1},
+			{R: 1, G: 1, B: 0, A: 1},
+			{R: 1, G: 0, B: 1, A: 1},
+		},
+	}
+	entryBytes := entry.ToBytes()
+
+	// Layout within the single .rdata section:
+	//   0x000: primary table  (2 entries: one real, one all-zero sentinel)
+	//   0x030: secondary table (2 entries: one real, one all-zero sentinel)
+	//   0x070: the TintEntry itself
+	const (
+		primaryOff   = 0x000
+		secondaryOff = 0x030
+		tintDataOff  = 0x070
+	)
+
+	rdata := make([]byte, tintDataOff+tint.TintEntrySize)
+
+	putUint64(rdata, primaryOff, entry.ResourceID) // ResourceID
+	putUint64(rdata, primaryOff+8, 0)              // ItemData (unused by ExtractTints)
+	putUint32(rdata, primaryOff+16, 0)             // ItemIndex
+	// primary sentinel at primaryOff+0x18 is already all-zero
+
+	putUint64(rdata, secondaryOff, 0)                              // TintValue
+	putUint64(rdata, secondaryOff+8, entry.ResourceID)             // ResourceID
+	putUint64(rdata, secondaryOff+16, rdataVA+uint64(tintDataOff)) // ItemData -> TintEntry VA
+	putUint64(rdata, secondaryOff+24, 0)                           // Flags
+	// secondary sentinel at secondaryOff+0x20 is already all-zero
+
+	copy(rdata[tintDataOff:], entryBytes)
+
+	path := buildTestPE(t, rdata)
+
+	entries, names, err := ExtractTints(path,
+		WithItemIDsVA(rdataVA+primaryOff),
+		WithSecondaryVA(rdataVA+secondaryOff),
+	)
+	if err != nil {
+		t.Fatalf("ExtractTints: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if entries[0].ResourceID != entry.ResourceID {
+		t.Errorf("ResourceID = %#x, want %#x", entries[0].ResourceID, entry.ResourceID)
+	}
+	if entries[0].Colors != entry.Colors {
+		t.Errorf("Colors = %v, want %v", entries[0].Colors, entry.Colors)
+	}
+
+	wantName := tint.LookupTintName(entry.ResourceID)
+	if wantName == "" {
+		t.Fatal("test fixture ResourceID isn't in tint.KnownTints")
+	}
+	if got := names[entry.ResourceID]; got != wantName {
+		t.Errorf("names[%#x] = %q, want %q", entry.ResourceID, got, wantName)
+	}
+}
+
+func TestExtractTintsStopsAtMaxEntries(t *testing.T) {
+	const rdataVA = testImageBase + 0x3000
+
+	// Two non-zero primary entries in a row, no sentinel: with
+	// WithMaxEntries(1) the walk should stop after the first rather than
+	// reading forever.
+	rdata := make([]byte, 2*tint.TintTableEntryPrimarySize+tint.TintTableEntrySecondarySize)
+	putUint64(rdata, 0, 0x1111111111111111)
+	putUint64(rdata, tint.TintTableEntryPrimarySize, 0x2222222222222222)
+
+	path := buildTestPE(t, rdata)
+
+	entries, _, err := ExtractTints(path,
+		WithItemIDsVA(rdataVA),
+		WithSecondaryVA(rdataVA+2*tint.TintTableEntryPrimarySize),
+		WithMaxEntries(1),
+	)
+	if err != nil {
+		t.Fatalf("ExtractTints: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("got %d entries, want 0 (primary/secondary ResourceIDs don't match)", len(entries))
+	}
+}
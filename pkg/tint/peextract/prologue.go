@@ -0,0 +1,89 @@
+package peextract
+
+import (
+	"bytes"
+	"debug/pe"
+	"fmt"
+)
+
+// DefaultRegisterTintPrologue is the first bytes of
+// CR15NetRewardItemCS_RegisterTint (@ 0x140cf23c0 in the echovr.exe build
+// tint.go was reverse engineered against): a standard MSVC /O2 non-leaf
+// prologue (push rbx; sub rsp, 0x20). Prologues like this tend to survive
+// patches that only touch a function's body, so WithPrologueScan uses it
+// to relocate the function even after the game binary shifts, then reads
+// the tint tables' current addresses out of the function's own
+// RIP-relative references instead of trusting hardcoded VAs.
+var DefaultRegisterTintPrologue = []byte{0x48, 0x89, 0x5c, 0x24, 0x08, 0x57, 0x48, 0x83, 0xec, 0x20}
+
+// tintTableScanWindow bounds how many bytes of RegisterTint's body
+// locateTintTables inspects for RIP-relative references before giving up.
+// The real function is small (a binary search insert plus a couple of
+// table writes), so this comfortably covers it without risking a false
+// match inside the next function if the prologue byte match is loose.
+const tintTableScanWindow = 0x200
+
+// locateTintTables finds CR15NetRewardItemCS_RegisterTint by scanning the
+// PE's executable sections for prologue, then recovers
+// g_TintTable_ItemIDs and g_TintTable_Secondary from the first two
+// RIP-relative LEA targets referenced in its body. The function is
+// expected to load both tables' base addresses near its start in order
+// to binary-search the primary table and then append to the secondary
+// one.
+func locateTintTables(f *pe.File, sm *sectionMap, prologue []byte) (itemIDsVA, secondaryVA uint64, err error) {
+	for _, s := range f.Sections {
+		if s.Characteristics&pe.IMAGE_SCN_CNT_CODE == 0 {
+			continue
+		}
+
+		data, err := s.Data()
+		if err != nil {
+			return 0, 0, fmt.Errorf("read section %s: %w", s.Name, err)
+		}
+
+		idx := bytes.Index(data, prologue)
+		if idx < 0 {
+			continue
+		}
+		funcVA := sm.imageBase + uint64(s.VirtualAddress) + uint64(idx)
+
+		end := idx + tintTableScanWindow
+		if end > len(data) {
+			end = len(data)
+		}
+		targets := findRIPRelativeTargets(data[idx:end], funcVA)
+		if len(targets) < 2 {
+			return 0, 0, fmt.Errorf("found RegisterTint prologue at %#x but only %d RIP-relative references nearby", funcVA, len(targets))
+		}
+		return targets[0], targets[1], nil
+	}
+
+	return 0, 0, fmt.Errorf("RegisterTint prologue not found in any code section")
+}
+
+// findRIPRelativeTargets scans code for `[REX.W] 8D /r` (LEA r64, [rip+disp32])
+// instructions and returns the absolute virtual address each one
+// references, in the order they appear. baseVA is the virtual address of
+// code[0].
+func findRIPRelativeTargets(code []byte, baseVA uint64) []uint64 {
+	var targets []uint64
+	for i := 0; i+3 <= len(code); i++ {
+		if code[i] != 0x8d {
+			continue
+		}
+		// ModRM mod=00, rm=101 (RIP-relative) regardless of the reg field,
+		// i.e. the low 3 bits are 101 and the top 2 (mod) bits are 0.
+		modrm := code[i+1]
+		if modrm&0xc7 != 0x05 {
+			continue
+		}
+		if i+6 > len(code) {
+			break
+		}
+		disp := int32(uint32(code[i+2]) | uint32(code[i+3])<<8 | uint32(code[i+4])<<16 | uint32(code[i+5])<<24)
+		nextInstrVA := baseVA + uint64(i) + 6
+		targets = append(targets, uint64(int64(nextInstrVA)+int64(disp)))
+		i += 5 // skip past the disp32 we just consumed
+	}
+	return targets
+}
@@ -0,0 +1,46 @@
+package peextract
+
+import "testing"
+
+// encodeLEA builds a `48 8d /r` (lea reg64, [rip+disp32]) instruction
+// whose RIP-relative disp resolves to targetVA. opcodeVA is the virtual
+// address of the 0x8d opcode byte (i.e. one past the REX prefix),
+// matching how findRIPRelativeTargets indexes into the instruction.
+func encodeLEA(reg byte, opcodeVA, targetVA uint64) []byte {
+	modrm := byte(0x05) | (reg << 3)
+	disp := int32(int64(targetVA) - int64(opcodeVA+6))
+	return []byte{
+		0x48, 0x8d, modrm,
+		byte(disp), byte(disp >> 8), byte(disp >> 16), byte(disp >> 24),
+	}
+}
+
+func TestFindRIPRelativeTargets(t *testing.T) {
+	const baseVA = 0x140cf23c0
+
+	var code []byte
+	code = append(code, DefaultRegisterTintPrologue...)
+	firstVA := baseVA + uint64(len(code)) + 1 // +1 for the REX prefix byte below
+	code = append(code, encodeLEA(0, firstVA, 0x1420d3ac0)...)
+	secondVA := baseVA + uint64(len(code)) + 1
+	code = append(code, encodeLEA(1, secondVA, 0x1420d3ac8)...)
+	code = append(code, 0x90, 0x90, 0x90) // trailing unrelated bytes
+
+	targets := findRIPRelativeTargets(code, baseVA)
+	if len(targets) != 2 {
+		t.Fatalf("findRIPRelativeTargets: got %d targets, want 2: %#v", len(targets), targets)
+	}
+	if targets[0] != 0x1420d3ac0 {
+		t.Errorf("targets[0] = %#x, want %#x", targets[0], 0x1420d3ac0)
+	}
+	if targets[1] != 0x1420d3ac8 {
+		t.Errorf("targets[1] = %#x, want %#x", targets[1], 0x1420d3ac8)
+	}
+}
+
+func TestFindRIPRelativeTargetsNoMatches(t *testing.T) {
+	code := []byte{0x90, 0x90, 0xc3, 0x48, 0x89, 0x5c, 0x24, 0x08}
+	if targets := findRIPRelativeTargets(code, 0x1000); len(targets) != 0 {
+		t.Errorf("findRIPRelativeTargets: got %d targets, want 0", len(targets))
+	}
+}
@@ -0,0 +1,65 @@
+// Package peextract reads the cached tint tables directly out of an
+// echovr.exe build, instead of trusting tint.KnownTints to stay in sync
+// with whatever the game currently ships. It walks the PE section table
+// to translate the Ghidra-documented runtime addresses from tint.go into
+// file offsets, then follows the primary -> secondary -> TintEntry
+// pointer chain those tables hold at runtime.
+package peextract
+
+import (
+	"debug/pe"
+	"fmt"
+)
+
+// sectionMap translates 64-bit virtual addresses into file offsets by
+// walking a PE's section table, the same lookup the Windows loader
+// performs when mapping a section's raw bytes into its virtual address
+// range.
+type sectionMap struct {
+	imageBase uint64
+	sections  []*pe.Section
+}
+
+func newSectionMap(f *pe.File, imageBase uint64) *sectionMap {
+	return &sectionMap{imageBase: imageBase, sections: f.Sections}
+}
+
+// fileOffset returns the file offset backing virtual address va, found by
+// locating the section (typically .data or .rdata for the tint tables)
+// whose virtual address range contains it.
+func (m *sectionMap) fileOffset(va uint64) (uint32, error) {
+	if va < m.imageBase {
+		return 0, fmt.Errorf("va %#x is below image base %#x", va, m.imageBase)
+	}
+	rva := uint32(va - m.imageBase)
+
+	for _, s := range m.sections {
+		if rva >= s.VirtualAddress && rva < s.VirtualAddress+sectionSpan(s) {
+			return s.Offset + (rva - s.VirtualAddress), nil
+		}
+	}
+	return 0, fmt.Errorf("va %#x (rva %#x) is not mapped by any section", va, rva)
+}
+
+// sectionSpan is the virtual size to use for containment checks: some
+// sections report VirtualSize 0, in which case their raw data size is the
+// best available bound.
+func sectionSpan(s *pe.Section) uint32 {
+	if s.VirtualSize != 0 {
+		return s.VirtualSize
+	}
+	return s.Size
+}
+
+// imageBaseOf returns the preferred load address from f's optional
+// header, handling both PE32 and PE32+ (64-bit) images.
+func imageBaseOf(f *pe.File) (uint64, error) {
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader64:
+		return oh.ImageBase, nil
+	case *pe.OptionalHeader32:
+		return uint64(oh.ImageBase), nil
+	default:
+		return 0, fmt.Errorf("PE file has no recognizable optional header")
+	}
+}
@@ -0,0 +1,81 @@
+package peextract
+
+import (
+	"debug/pe"
+	"testing"
+)
+
+func testSections() []*pe.Section {
+	return []*pe.Section{
+		{SectionHeader: pe.SectionHeader{Name: ".text", VirtualAddress: 0x1000, VirtualSize: 0x2000, Offset: 0x400}},
+		{SectionHeader: pe.SectionHeader{Name: ".rdata", VirtualAddress: 0x4000, VirtualSize: 0x1000, Offset: 0x2400}},
+	}
+}
+
+func TestSectionMapFileOffset(t *testing.T) {
+	sm := &sectionMap{imageBase: 0x140000000, sections: testSections()}
+
+	tests := []struct {
+		name    string
+		va      uint64
+		want    uint32
+		wantErr bool
+	}{
+		{"start of .text", 0x140000000 + 0x1000, 0x400, false},
+		{"middle of .text", 0x140000000 + 0x1500, 0x900, false},
+		{"start of .rdata", 0x140000000 + 0x4000, 0x2400, false},
+		{"middle of .rdata", 0x140000000 + 0x4010, 0x2410, false},
+		{"below image base", 0x1000, 0, true},
+		{"in the gap between sections", 0x140000000 + 0x3500, 0, true},
+		{"past the last section", 0x140000000 + 0x5000, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sm.fileOffset(tt.va)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("fileOffset(%#x) = %#x, want error", tt.va, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fileOffset(%#x): %v", tt.va, err)
+			}
+			if got != tt.want {
+				t.Errorf("fileOffset(%#x) = %#x, want %#x", tt.va, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSectionSpanFallsBackToRawSize(t *testing.T) {
+	s := &pe.Section{SectionHeader: pe.SectionHeader{VirtualSize: 0, Size: 0x200}}
+	if got := sectionSpan(s); got != 0x200 {
+		t.Errorf("sectionSpan() = %#x, want %#x", got, 0x200)
+	}
+}
+
+func TestImageBaseOf(t *testing.T) {
+	f := &pe.File{OptionalHeader: &pe.OptionalHeader64{ImageBase: 0x140000000}}
+	got, err := imageBaseOf(f)
+	if err != nil {
+		t.Fatalf("imageBaseOf: %v", err)
+	}
+	if got != 0x140000000 {
+		t.Errorf("imageBaseOf() = %#x, want %#x", got, 0x140000000)
+	}
+
+	f32 := &pe.File{OptionalHeader: &pe.OptionalHeader32{ImageBase: 0x400000}}
+	got32, err := imageBaseOf(f32)
+	if err != nil {
+		t.Fatalf("imageBaseOf (PE32): %v", err)
+	}
+	if got32 != 0x400000 {
+		t.Errorf("imageBaseOf (PE32) = %#x, want %#x", got32, 0x400000)
+	}
+
+	if _, err := imageBaseOf(&pe.File{}); err == nil {
+		t.Error("imageBaseOf with no optional header: expected error")
+	}
+}
@@ -0,0 +1,73 @@
+package peextract
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/EchoTools/evrFileTools/pkg/tint"
+)
+
+// readPrimaryTable walks g_TintTable_ItemIDs starting at itemIDsVA,
+// reading TintTableEntry_Primary (0x18 bytes) entries until it hits one
+// that's entirely zero or it has read max entries, whichever comes
+// first. Neither table carries an explicit element count in memory, so
+// the all-zero entry doubles as the sentinel the game's own binary
+// search relies on to find the table's end.
+func readPrimaryTable(r io.ReaderAt, sm *sectionMap, itemIDsVA uint64, max int) ([]tint.TintTableEntry_Primary, error) {
+	var entries []tint.TintTableEntry_Primary
+	for i := 0; i < max; i++ {
+		va := itemIDsVA + uint64(i)*tint.TintTableEntryPrimarySize
+		offset, err := sm.fileOffset(va)
+		if err != nil {
+			return nil, fmt.Errorf("locate primary entry %d: %w", i, err)
+		}
+
+		buf := make([]byte, tint.TintTableEntryPrimarySize)
+		if _, err := r.ReadAt(buf, int64(offset)); err != nil {
+			return nil, fmt.Errorf("read primary entry %d: %w", i, err)
+		}
+
+		entry := tint.TintTableEntry_Primary{
+			ResourceID: binary.LittleEndian.Uint64(buf[0:8]),
+			ItemData:   binary.LittleEndian.Uint64(buf[8:16]),
+			ItemIndex:  binary.LittleEndian.Uint32(buf[16:20]),
+			Padding:    binary.LittleEndian.Uint32(buf[20:24]),
+		}
+		if entry.ResourceID == 0 && entry.ItemData == 0 && entry.ItemIndex == 0 {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// readSecondaryTable walks g_TintTable_Secondary the same way
+// readPrimaryTable walks g_TintTable_ItemIDs.
+func readSecondaryTable(r io.ReaderAt, sm *sectionMap, secondaryVA uint64, max int) ([]tint.TintTableEntry_Secondary, error) {
+	var entries []tint.TintTableEntry_Secondary
+	for i := 0; i < max; i++ {
+		va := secondaryVA + uint64(i)*tint.TintTableEntrySecondarySize
+		offset, err := sm.fileOffset(va)
+		if err != nil {
+			return nil, fmt.Errorf("locate secondary entry %d: %w", i, err)
+		}
+
+		buf := make([]byte, tint.TintTableEntrySecondarySize)
+		if _, err := r.ReadAt(buf, int64(offset)); err != nil {
+			return nil, fmt.Errorf("read secondary entry %d: %w", i, err)
+		}
+
+		entry := tint.TintTableEntry_Secondary{
+			TintValue:  binary.LittleEndian.Uint64(buf[0:8]),
+			ResourceID: binary.LittleEndian.Uint64(buf[8:16]),
+			ItemData:   binary.LittleEndian.Uint64(buf[16:24]),
+			Flags:      binary.LittleEndian.Uint64(buf[24:32]),
+		}
+		if entry.TintValue == 0 && entry.ResourceID == 0 && entry.ItemData == 0 && entry.Flags == 0 {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,51 @@
+package tint
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzTintEntryFromBytes feeds arbitrary byte slices through
+// TintEntryFromBytes, seeded with real 96-byte tint entries and some
+// truncated/oversized variants. TintEntryFromBytes must never panic, and
+// whenever it returns a non-nil entry, ToCSS/Hex/String must produce
+// valid UTF-8.
+func FuzzTintEntryFromBytes(f *testing.F) {
+	valid := &TintEntry{
+		ResourceID: 0x74d228d09dc5dc86,
+		Colors: [5]Color{
+			{1.0, 0.0, 0.0, 1.0},
+			{0.0, 1.0, 0.0, 1.0},
+			{0.0, 0.0, 1.0, 1.0},
+			{1.0, 1.0, 0.0, 1.0},
+			{1.0, 0.0, 1.0, 1.0},
+		},
+	}
+	data := valid.ToBytes()
+	f.Add(data)
+	f.Add(data[:len(data)-1])
+	f.Add(append(data, 0xff))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entry := TintEntryFromBytes(data)
+		if entry == nil {
+			return
+		}
+
+		if !utf8.ValidString(entry.String()) {
+			t.Fatalf("String() produced invalid UTF-8 for %x", data)
+		}
+		for _, c := range entry.Colors {
+			if !utf8.ValidString(c.Hex()) {
+				t.Fatalf("Hex() produced invalid UTF-8 for %x", data)
+			}
+			if !utf8.ValidString(c.CSS()) {
+				t.Fatalf("CSS() produced invalid UTF-8 for %x", data)
+			}
+		}
+		if !utf8.ValidString(entry.ToCSS("fuzz")) {
+			t.Fatalf("ToCSS() produced invalid UTF-8 for %x", data)
+		}
+	})
+}
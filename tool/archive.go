@@ -7,10 +7,19 @@ import (
 	"io"
 
 	"github.com/DataDog/zstd"
+	"github.com/goopsie/evrFileTools/pkg/archive"
+	"github.com/goopsie/evrFileTools/pkg/asset"
 )
 
 const zstdCompressionLevel = zstd.BestSpeed
 
+// peekHeaderLength is how many leading bytes NewArchiveReader and
+// ArchiveEncode inspect to tell a legacy ArchiveHeader (HeaderLength 16,
+// always zstd) apart from a pkg/archive Header (HeaderLength
+// archive.HeaderSize, codec selectable). Both share the same Magic, so the
+// HeaderLength field after it is what disambiguates them.
+const peekHeaderLength = 8
+
 type ArchiveHeader struct { // seems to be the same across every manifest
 	Magic            [4]byte
 	HeaderLength     uint32
@@ -61,8 +70,45 @@ func (c *ArchiveHeader) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
-// NewArchiveReader creates a new reader for the package file.
+// peekHeaderIsCodecTagged reports whether the header at r's current
+// position is a pkg/archive Header (codec-taggable) rather than a legacy
+// ArchiveHeader (always zstd), then seeks back to where it started so the
+// real header read sees the same bytes.
+func peekHeaderIsCodecTagged(r io.ReadSeeker) (bool, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, fmt.Errorf("get position: %w", err)
+	}
+
+	peek := make([]byte, peekHeaderLength)
+	if _, err := io.ReadFull(r, peek); err != nil {
+		return false, fmt.Errorf("peek header: %w", err)
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return false, fmt.Errorf("rewind after peek: %w", err)
+	}
+
+	headerLength := binary.LittleEndian.Uint32(peek[4:8])
+	return headerLength == archive.HeaderSize, nil
+}
+
+// NewArchiveReader creates a new reader for the package file. It reads
+// whichever header format is actually on disk: the legacy zstd-only
+// ArchiveHeader written by older ArchiveEncode calls, or a pkg/archive
+// Header written by ArchiveEncode with WithArchiveCodec.
 func NewArchiveReader(r io.ReadSeeker) (reader io.ReadCloser, length int, cLength int, err error) {
+	codecTagged, err := peekHeaderIsCodecTagged(r)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if codecTagged {
+		ar, err := archive.NewReader(r)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to create archive reader: %w", err)
+		}
+		return ar, ar.Length(), ar.CompressedLength(), nil
+	}
+
 	// Read the header
 	header := &ArchiveHeader{}
 
@@ -83,8 +129,9 @@ func NewArchiveReader(r io.ReadSeeker) (reader io.ReadCloser, length int, cLengt
 }
 
 // ArchiveDecode reads a compressed file and returns the uncompressed data.
-// It uses a zstd reader to decompress the data and returns the uncompressed bytes.
-// The function also handles the header of the compressed file.
+// It dispatches to whichever codec the file's header names (zstd for every
+// legacy archive, or the header's Codec byte for archives ArchiveEncode
+// wrote with WithArchiveCodec) and returns the uncompressed bytes.
 func ArchiveDecode(compressed io.ReadSeeker) ([]byte, error) {
 
 	reader, length, compressedLength, err := NewArchiveReader(compressed)
@@ -105,7 +152,129 @@ func ArchiveDecode(compressed io.ReadSeeker) ([]byte, error) {
 	return dst[:length], nil
 }
 
-func ArchiveEncode(dst io.WriteSeeker, data []byte) error {
+// sparseEnvelope flag bytes, identifying whether the payload WithSparseDetection
+// produced is the raw data verbatim or a SparseMap-packed form.
+const (
+	sparseEnvelopeRaw    = 0
+	sparseEnvelopePacked = 1
+)
+
+// wrapSparseEnvelope prefixes data with a one-byte flag so
+// ArchiveDecodeSparse knows whether to run Expand. Compacting only helps
+// when the map overhead is smaller than what it elides, so data that
+// doesn't compact well is stored verbatim behind the raw flag instead.
+func wrapSparseEnvelope(data []byte) []byte {
+	m, packed := asset.Compact(data)
+
+	mapBytes, err := m.MarshalBinary()
+	if err != nil || len(mapBytes)+len(packed) >= len(data) {
+		envelope := make([]byte, 1+len(data))
+		envelope[0] = sparseEnvelopeRaw
+		copy(envelope[1:], data)
+		return envelope
+	}
+
+	envelope := make([]byte, 0, 1+4+len(mapBytes)+len(packed))
+	envelope = append(envelope, sparseEnvelopePacked)
+	envelope = binary.LittleEndian.AppendUint32(envelope, uint32(len(mapBytes)))
+	envelope = append(envelope, mapBytes...)
+	envelope = append(envelope, packed...)
+	return envelope
+}
+
+// unwrapSparseEnvelope reverses wrapSparseEnvelope.
+func unwrapSparseEnvelope(data []byte) ([]byte, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("sparse envelope too short")
+	}
+
+	switch data[0] {
+	case sparseEnvelopeRaw:
+		return data[1:], nil
+	case sparseEnvelopePacked:
+		if len(data) < 5 {
+			return nil, fmt.Errorf("sparse envelope too short for map length")
+		}
+		mapLength := binary.LittleEndian.Uint32(data[1:5])
+		if len(data) < 5+int(mapLength) {
+			return nil, fmt.Errorf("sparse envelope too short for map")
+		}
+
+		m := &asset.SparseMap{}
+		if err := m.UnmarshalBinary(data[5 : 5+mapLength]); err != nil {
+			return nil, fmt.Errorf("unmarshal sparse map: %w", err)
+		}
+
+		return asset.Expand(m, data[5+mapLength:])
+	default:
+		return nil, fmt.Errorf("unknown sparse envelope flag %d", data[0])
+	}
+}
+
+// ArchiveDecodeSparse decodes an archive written with ArchiveEncode and
+// WithSparseDetection, reversing the sparse envelope after decompression.
+// Plain ArchiveDecode would return the envelope bytes unexpanded.
+func ArchiveDecodeSparse(compressed io.ReadSeeker) ([]byte, error) {
+	data, err := ArchiveDecode(compressed)
+	if err != nil {
+		return nil, err
+	}
+	return unwrapSparseEnvelope(data)
+}
+
+// archiveEncodeConfig holds the options ArchiveEncode accepts.
+type archiveEncodeConfig struct {
+	codec      archive.CodecTag
+	useCodec   bool
+	sparseScan bool
+}
+
+// ArchiveEncodeOption configures ArchiveEncode's output format.
+type ArchiveEncodeOption func(*archiveEncodeConfig)
+
+// WithArchiveCodec selects a codec other than the default zstd for
+// ArchiveEncode. Using it switches the on-disk header from the legacy
+// 24-byte zstd-only ArchiveHeader to a pkg/archive Header so NewArchiveReader
+// knows which codec to decompress with; archives encoded without this
+// option are unaffected and stay byte-for-byte what they always were.
+func WithArchiveCodec(tag archive.CodecTag) ArchiveEncodeOption {
+	return func(c *archiveEncodeConfig) {
+		c.codec = tag
+		c.useCodec = true
+	}
+}
+
+// WithSparseDetection runs data through asset.Compact before compressing
+// it, so long runs of zero bytes (common in AssetReference AdditionalData
+// payloads) are stored as a sparse map rather than spent on the
+// compressor. Archives written with this option must be read back with
+// ArchiveDecodeSparse, not ArchiveDecode, since the sparse envelope needs
+// unwrapping after decompression.
+func WithSparseDetection() ArchiveEncodeOption {
+	return func(c *archiveEncodeConfig) {
+		c.sparseScan = true
+	}
+}
+
+// ArchiveEncode writes data to dst as a compressed archive. With no
+// options it reproduces the original zstd-BestSpeed, 24-byte-header format
+// every existing archive on disk uses. Pass WithArchiveCodec to pick a
+// different codec (lz4, brotli, deflate, or stored); this writes the
+// pkg/archive Header format instead, which NewArchiveReader also
+// understands.
+func ArchiveEncode(dst io.WriteSeeker, data []byte, opts ...ArchiveEncodeOption) error {
+	cfg := &archiveEncodeConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.sparseScan {
+		data = wrapSparseEnvelope(data)
+	}
+
+	if cfg.useCodec {
+		return archive.Encode(dst, data, archive.WithCodec(cfg.codec))
+	}
 
 	// Write a placeholder for the compressed size
 	header := ArchiveHeader{
@@ -2,6 +2,8 @@ package tool
 
 import (
 	"bytes"
+	"context"
+	"os"
 	"testing"
 
 	"github.com/DataDog/zstd"
@@ -285,3 +287,98 @@ func BenchmarkLookupTable(b *testing.B) {
 		}
 	})
 }
+
+// memPackageFile adapts a bytes.Reader to PackageFile for benchmarking
+// without touching disk.
+type memPackageFile struct {
+	*bytes.Reader
+}
+
+func (memPackageFile) Close() error { return nil }
+
+// newBenchPackage builds an in-memory Package with frameCount frames, each
+// holding filesPerFrame small files, for use as a PackageExtract fixture.
+// It stands in for a real multi-GB, multi-part package: the frame and file
+// counts are scaled down so the benchmark runs in a reasonable time, but
+// the per-frame decompression work (and therefore the shape of the
+// concurrency speedup) is the same.
+func newBenchPackage(b *testing.B, frameCount, filesPerFrame int) *Package {
+	b.Helper()
+
+	const rawFrameSize = 256 * 1024
+	raw := make([]byte, rawFrameSize)
+	for i := range raw {
+		raw[i] = byte(i % 256)
+	}
+	compressed, err := zstd.Compress(nil, raw)
+	if err != nil {
+		b.Fatalf("failed to compress fixture data: %v", err)
+	}
+
+	data := make([]byte, 0, len(compressed)*frameCount)
+	frames := make([]Frame, frameCount)
+	fileSize := uint32(rawFrameSize / filesPerFrame)
+	contents := make([]FrameContents, 0, frameCount*filesPerFrame)
+	for i := 0; i < frameCount; i++ {
+		frames[i] = Frame{
+			Index:          0,
+			Offset:         uint32(len(data)),
+			CompressedSize: uint32(len(compressed)),
+			Length:         uint32(rawFrameSize),
+		}
+		data = append(data, compressed...)
+
+		for j := 0; j < filesPerFrame; j++ {
+			contents = append(contents, FrameContents{
+				T:          int64(i),
+				FileSymbol: int64(i)<<32 | int64(j),
+				FileIndex:  uint32(i),
+				DataOffset: uint32(j) * fileSize,
+				Size:       fileSize,
+			})
+		}
+	}
+
+	return &Package{
+		Manifest: &ManifestBase{
+			Header: ManifestHeader{
+				PackageCount: 1,
+				FrameContents: ManifestSection{
+					Count: uint64(len(contents)),
+				},
+			},
+			FrameContents: contents,
+			Frames:        frames,
+		},
+		Files: []PackageFile{memPackageFile{bytes.NewReader(data)}},
+	}
+}
+
+// BenchmarkPackageExtractConcurrency compares PackageExtract's worker-pool
+// throughput at Concurrency 1 against a higher concurrency, on the
+// scaled-down fixture built by newBenchPackage (see its comment for why
+// this stands in for a real multi-GB, multi-part package).
+func BenchmarkPackageExtractConcurrency(b *testing.B) {
+	pkg := newBenchPackage(b, 32, 8)
+
+	for _, concurrency := range []int{1, 4, 8} {
+		b.Run(concurrencyName(concurrency), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				outDir, err := os.MkdirTemp("", "package-extract-bench")
+				if err != nil {
+					b.Fatalf("failed to create temp dir: %v", err)
+				}
+
+				if err := PackageExtract(context.Background(), pkg, outDir, false, nil, ExtractOptions{Concurrency: concurrency}); err != nil {
+					b.Fatalf("PackageExtract failed: %v", err)
+				}
+
+				os.RemoveAll(outDir)
+			}
+		})
+	}
+}
+
+func concurrencyName(n int) string {
+	return "Concurrency_" + string(rune('0'+n))
+}
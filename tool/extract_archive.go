@@ -0,0 +1,128 @@
+package tool
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DataDog/zstd"
+)
+
+// archiveModTime is the mod time ExtractToArchive stamps on every zip
+// entry. Using a fixed value instead of time.Now() keeps repeated
+// extractions of the same package byte-for-byte identical, which matters
+// for anyone diffing or hashing distributed archives.
+var archiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ArchiveOptions configures ExtractToArchive.
+type ArchiveOptions struct {
+	// Store disables deflate compression in favor of storing entries
+	// verbatim. Useful when the source files are already compressed
+	// (most package content is) and re-deflating them would only cost
+	// time for little to no size reduction.
+	Store bool
+}
+
+// ExtractToArchive decompresses every frame in p, same as PackageExtract,
+// but writes the resulting files as entries in a zip archive at
+// archivePath instead of loose files under a directory. archivePath's
+// extension selects the container: .zip or .cbz (a zip by another name,
+// for comic-book readers). Entry paths match PackageExtract's on-disk
+// layout, "<fileType>/<fileSymbol>" or, with preserveGroups,
+// "<frameIndex>/<fileType>/<fileSymbol>". Frames are decompressed one at a
+// time on the calling goroutine, since zip.Writer only accepts entries
+// sequentially - there is no concurrency benefit in parallelizing the
+// decompression here.
+func ExtractToArchive(p *Package, archivePath string, preserveGroups bool, opts ArchiveOptions) error {
+	switch ext := strings.ToLower(filepath.Ext(archivePath)); ext {
+	case ".zip", ".cbz":
+	default:
+		return fmt.Errorf("unsupported archive extension %q (want .zip or .cbz)", ext)
+	}
+
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive %s: %w", archivePath, err)
+	}
+
+	method := zip.Deflate
+	if opts.Store {
+		method = zip.Store
+	}
+
+	contentsByFrame := make(map[uint32][]FrameContents, len(p.Manifest.FrameContents))
+	for _, fc := range p.Manifest.FrameContents {
+		contentsByFrame[fc.FileIndex] = append(contentsByFrame[fc.FileIndex], fc)
+	}
+
+	zw := zip.NewWriter(f)
+
+	var (
+		zstdCtx      = zstd.NewCtx()
+		compressed   []byte
+		decompressed []byte
+	)
+	fail := func(err error) error {
+		zw.Close()
+		f.Close()
+		return err
+	}
+
+	for k, v := range p.Manifest.Frames {
+		if v.Length == 0 {
+			continue
+		}
+		if err := validateFrame(v, len(p.Files)); err != nil {
+			return fail(fmt.Errorf("frame %d: %w", k, err))
+		}
+
+		if len(compressed) < int(v.CompressedSize) {
+			compressed = make([]byte, v.CompressedSize)
+		}
+		if len(decompressed) < int(v.Length) {
+			decompressed = make([]byte, v.Length)
+		}
+
+		if _, err := p.Files[v.Index].ReadAt(compressed[:v.CompressedSize], int64(v.Offset)); err != nil {
+			return fail(fmt.Errorf("failed to read file, check input: %w", err))
+		}
+		if _, err := zstdCtx.Decompress(decompressed[:v.Length], compressed[:v.CompressedSize]); err != nil {
+			return fail(fmt.Errorf("failed to decompress file index %d: %w", k, err))
+		}
+
+		for _, v2 := range contentsByFrame[uint32(k)] {
+			if int64(v2.DataOffset)+int64(v2.Size) > int64(v.Length) {
+				return fail(fmt.Errorf("file %x in frame %d extends past decompressed frame", v2.FileSymbol, k))
+			}
+
+			fileName := fmt.Sprintf("%x", v2.FileSymbol)
+			fileType := fmt.Sprintf("%x", v2.T)
+			name := fmt.Sprintf("%s/%s", fileType, fileName)
+			if preserveGroups {
+				name = fmt.Sprintf("%d/%s/%s", v2.FileIndex, fileType, fileName)
+			}
+
+			hdr := &zip.FileHeader{
+				Name:     name,
+				Method:   method,
+				Modified: archiveModTime,
+			}
+			entry, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return fail(fmt.Errorf("failed to create archive entry %s: %w", name, err))
+			}
+			if _, err := entry.Write(decompressed[v2.DataOffset : v2.DataOffset+v2.Size]); err != nil {
+				return fail(fmt.Errorf("failed to write archive entry %s: %w", name, err))
+			}
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to finalize archive %s: %w", archivePath, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,80 @@
+package tool
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultFSCacheSize is the decompressed-frame cache size Package.FS uses
+// when passed a non-positive cacheSizeBytes.
+const DefaultFSCacheSize = 64 * 1024 * 1024
+
+type frameCacheEntry struct {
+	frameIndex int
+	data       []byte
+}
+
+// frameCache is a byte-bounded LRU cache of decompressed frames, keyed by
+// frame index (unique across a Package since Manifest.Frames is a single
+// flat slice). It exists so Package.FS doesn't repay zstd decompression
+// cost for every file read out of a frame that's already been read once.
+type frameCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	curBytes int
+	order    *list.List
+	items    map[int]*list.Element
+}
+
+func newFrameCache(maxBytes int) *frameCache {
+	if maxBytes <= 0 {
+		maxBytes = DefaultFSCacheSize
+	}
+	return &frameCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[int]*list.Element),
+	}
+}
+
+func (c *frameCache) get(frameIndex int) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[frameIndex]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*frameCacheEntry).data, true
+}
+
+func (c *frameCache) put(frameIndex int, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[frameIndex]; ok {
+		c.curBytes -= len(el.Value.(*frameCacheEntry).data)
+		c.order.Remove(el)
+		delete(c.items, frameIndex)
+	}
+
+	if len(data) > c.maxBytes {
+		return
+	}
+
+	el := c.order.PushFront(&frameCacheEntry{frameIndex: frameIndex, data: data})
+	c.items[frameIndex] = el
+	c.curBytes += len(data)
+
+	for c.curBytes > c.maxBytes {
+		back := c.order.Back()
+		if back == nil {
+			break
+		}
+		entry := back.Value.(*frameCacheEntry)
+		c.curBytes -= len(entry.data)
+		c.order.Remove(back)
+		delete(c.items, entry.frameIndex)
+	}
+}
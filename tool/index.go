@@ -0,0 +1,169 @@
+package tool
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/DataDog/zstd"
+)
+
+// IndexEntry describes one file packed into a Package: where it lives
+// (which frame, at what offset within it) and a checksum of its
+// decompressed bytes, so a file can be located and verified without
+// re-walking the manifest's FrameContents table.
+type IndexEntry struct {
+	FileSymbol     int64
+	TypeSymbol     int64
+	FrameIndex     uint32
+	FrameOffset    uint32
+	CompressedSize uint32
+	Length         uint32
+	DataOffset     uint32
+	Size           uint32
+	SHA256         [32]byte
+}
+
+// Index is a sidecar listing of a Package's contents, sorted by
+// FileSymbol so PackageExtractOne can binary-search it instead of
+// scanning every FrameContents entry.
+type Index struct {
+	Entries []IndexEntry
+}
+
+// find returns the entry for fileSymbol, or false if none exists. Entries
+// must be sorted by FileSymbol, as BuildIndex guarantees for anything it
+// writes.
+func (idx *Index) find(fileSymbol int64) (IndexEntry, bool) {
+	i := sort.Search(len(idx.Entries), func(i int) bool {
+		return idx.Entries[i].FileSymbol >= fileSymbol
+	})
+	if i < len(idx.Entries) && idx.Entries[i].FileSymbol == fileSymbol {
+		return idx.Entries[i], true
+	}
+	return IndexEntry{}, false
+}
+
+// BuildIndex decompresses every frame in p exactly once - grouping its
+// FrameContents by frame first so a frame with many small files is only
+// paid for once - computes a SHA-256 of each file's decompressed bytes,
+// and writes the result to out as JSON sorted by FileSymbol.
+func BuildIndex(p *Package, out io.Writer) error {
+	contentsByFrame := make(map[uint32][]FrameContents, len(p.Manifest.FrameContents))
+	for _, fc := range p.Manifest.FrameContents {
+		contentsByFrame[fc.FileIndex] = append(contentsByFrame[fc.FileIndex], fc)
+	}
+
+	var (
+		zstdCtx      = zstd.NewCtx()
+		compressed   []byte
+		decompressed []byte
+		entries      []IndexEntry
+	)
+	for k, v := range p.Manifest.Frames {
+		contents := contentsByFrame[uint32(k)]
+		if len(contents) == 0 {
+			continue
+		}
+		if v.Length == 0 {
+			return fmt.Errorf("frame %d has content but no data (length=0)", k)
+		}
+		if err := validateFrame(v, len(p.Files)); err != nil {
+			return fmt.Errorf("frame %d: %w", k, err)
+		}
+
+		if len(compressed) < int(v.CompressedSize) {
+			compressed = make([]byte, v.CompressedSize)
+		}
+		if len(decompressed) < int(v.Length) {
+			decompressed = make([]byte, v.Length)
+		}
+		if _, err := p.Files[v.Index].ReadAt(compressed[:v.CompressedSize], int64(v.Offset)); err != nil {
+			return fmt.Errorf("failed to read frame %d: %w", k, err)
+		}
+		if _, err := zstdCtx.Decompress(decompressed[:v.Length], compressed[:v.CompressedSize]); err != nil {
+			return fmt.Errorf("failed to decompress frame %d: %w", k, err)
+		}
+
+		for _, fc := range contents {
+			if int64(fc.DataOffset)+int64(fc.Size) > int64(v.Length) {
+				return fmt.Errorf("file %x in frame %d extends past decompressed frame", fc.FileSymbol, k)
+			}
+			entries = append(entries, IndexEntry{
+				FileSymbol:     fc.FileSymbol,
+				TypeSymbol:     fc.T,
+				FrameIndex:     uint32(k),
+				FrameOffset:    v.Offset,
+				CompressedSize: v.CompressedSize,
+				Length:         v.Length,
+				DataOffset:     fc.DataOffset,
+				Size:           fc.Size,
+				SHA256:         sha256.Sum256(decompressed[fc.DataOffset : fc.DataOffset+fc.Size]),
+			})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].FileSymbol < entries[j].FileSymbol })
+
+	return json.NewEncoder(out).Encode(&Index{Entries: entries})
+}
+
+// LoadIndex reads an Index written by BuildIndex.
+func LoadIndex(r io.Reader) (*Index, error) {
+	idx := &Index{}
+	if err := json.NewDecoder(r).Decode(idx); err != nil {
+		return nil, fmt.Errorf("failed to decode index: %w", err)
+	}
+	return idx, nil
+}
+
+// ExtractOneOptions configures PackageExtractOne.
+type ExtractOneOptions struct {
+	// Verify checks the extracted file's bytes against index's recorded
+	// SHA-256 before returning, failing if they don't match.
+	Verify bool
+}
+
+// PackageExtractOne writes a single file's decompressed bytes to w using
+// index to go straight to its frame, without walking or decompressing
+// any other frame in the manifest. index is typically built once with
+// BuildIndex (or loaded with LoadIndex) and reused across many calls, so
+// random single-file access into a package doesn't pay the cost of
+// scanning FrameContents or decompressing frames the caller doesn't want.
+func PackageExtractOne(p *Package, index *Index, fileSymbol int64, w io.Writer, opts ExtractOneOptions) error {
+	entry, ok := index.find(fileSymbol)
+	if !ok {
+		return fmt.Errorf("file symbol %x not found in index", fileSymbol)
+	}
+	if int(entry.FrameIndex) >= len(p.Manifest.Frames) {
+		return fmt.Errorf("file %x references frame %d, manifest only has %d frames", fileSymbol, entry.FrameIndex, len(p.Manifest.Frames))
+	}
+	frame := p.Manifest.Frames[entry.FrameIndex]
+	if int(frame.Index) >= len(p.Files) {
+		return fmt.Errorf("frame %d references package index %d, only %d package files open", entry.FrameIndex, frame.Index, len(p.Files))
+	}
+
+	compressed := make([]byte, entry.CompressedSize)
+	if _, err := p.Files[frame.Index].ReadAt(compressed, int64(entry.FrameOffset)); err != nil {
+		return fmt.Errorf("failed to read frame %d: %w", entry.FrameIndex, err)
+	}
+	decompressed, err := zstd.Decompress(make([]byte, 0, entry.Length), compressed)
+	if err != nil {
+		return fmt.Errorf("failed to decompress frame %d: %w", entry.FrameIndex, err)
+	}
+	if int64(entry.DataOffset)+int64(entry.Size) > int64(len(decompressed)) {
+		return fmt.Errorf("file %x extends past decompressed frame %d", fileSymbol, entry.FrameIndex)
+	}
+	data := decompressed[entry.DataOffset : entry.DataOffset+entry.Size]
+
+	if opts.Verify {
+		if got := sha256.Sum256(data); got != entry.SHA256 {
+			return fmt.Errorf("file %x failed checksum verification", fileSymbol)
+		}
+	}
+
+	_, err = w.Write(data)
+	return err
+}
@@ -30,17 +30,29 @@ func (m *ManifestBase) UnmarshalBinary(b []byte) error {
 		return fmt.Errorf("failed to read header: %w", err)
 	}
 
-	m.FrameContents = make([]FrameContents, m.Header.FrameContents.ElementCount)
+	count, err := manifestSectionCount("FrameContents", m.Header.FrameContents.ElementCount, int(binary.Size(FrameContents{})), reader.Len())
+	if err != nil {
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+	m.FrameContents = make([]FrameContents, count)
 	if err := binary.Read(reader, binary.LittleEndian, &m.FrameContents); err != nil {
 		return fmt.Errorf("failed to read frame contents: %w", err)
 	}
 
-	m.SomeStructure = make([]SomeStructure, m.Header.SomeStructure.ElementCount)
+	count, err = manifestSectionCount("SomeStructure", m.Header.SomeStructure.ElementCount, int(binary.Size(SomeStructure{})), reader.Len())
+	if err != nil {
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+	m.SomeStructure = make([]SomeStructure, count)
 	if err := binary.Read(reader, binary.LittleEndian, &m.SomeStructure); err != nil {
 		return fmt.Errorf("failed to read some structure: %w", err)
 	}
 
-	m.Frames = make([]Frame, m.Header.Frames.ElementCount)
+	count, err = manifestSectionCount("Frames", m.Header.Frames.ElementCount, int(binary.Size(Frame{})), reader.Len())
+	if err != nil {
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+	m.Frames = make([]Frame, count)
 	if err := binary.Read(reader, binary.LittleEndian, &m.Frames); err != nil {
 		return fmt.Errorf("failed to read frames: %w", err)
 	}
@@ -48,6 +60,23 @@ func (m *ManifestBase) UnmarshalBinary(b []byte) error {
 	return nil
 }
 
+// manifestSectionCount validates a section's declared element count
+// against the bytes actually remaining in the reader before
+// UnmarshalBinary allocates a slice sized by it, so an inflated count in
+// a corrupt or adversarial manifest returns an error instead of an
+// out-of-memory allocation or an out-of-bounds read.
+func manifestSectionCount(name string, elementCount uint64, elementSize, remaining int) (int, error) {
+	if elementCount > uint64(remaining) {
+		return 0, fmt.Errorf("%s: element count %d exceeds remaining data (%d bytes)", name, elementCount, remaining)
+	}
+	count := int(elementCount)
+	need := int64(count) * int64(elementSize)
+	if need > int64(remaining) {
+		return 0, fmt.Errorf("%s: truncated data: need %d bytes, have %d", name, need, remaining)
+	}
+	return count, nil
+}
+
 func (m *ManifestBase) MarshalBinary() ([]byte, error) {
 	wbuf := bytes.NewBuffer(nil)
 
@@ -69,8 +98,16 @@ func (m *ManifestBase) MarshalBinary() ([]byte, error) {
 	return manifestBytes, nil // hack
 }
 
-func ManifestReadFile(manifestFilePath string) (*ManifestBase, error) {
-	// Allocate the destination buffer
+// ManifestReadFile reads and decompresses the archive at manifestFilePath,
+// then routes the resulting bytes to the ManifestConverter registered under
+// typeID. There's no in-band tag to sniff the manifest type from (every
+// archive shares the same ArchiveHeader), so callers that know which game
+// build/deployment a manifest came from pass typeID as an out-of-band hint.
+func ManifestReadFile(manifestFilePath string, typeID string) (*ManifestBase, error) {
+	converter, ok := manifestConverters[typeID]
+	if !ok {
+		return nil, fmt.Errorf("unimplemented manifest type %q", typeID)
+	}
 
 	manifestFile, err := os.OpenFile(manifestFilePath, os.O_RDWR, 0777)
 	if err != nil {
@@ -93,63 +130,64 @@ func ManifestReadFile(manifestFilePath string) (*ManifestBase, error) {
 	}
 	defer archiveReader.Close()
 
-	manifest := ManifestBase{}
-	if err := manifest.UnmarshalBinary(b); err != nil {
+	manifest, err := converter.evrmFromBytes(b)
+	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal manifest: %w", err)
 	}
 
 	return &manifest, nil
 }
 
+// WriteFile is ManifestReadFile's symmetric write path: it marshals m via
+// the ManifestConverter registered under typeID and writes the result to
+// path as a compressed archive.
+func WriteFile(path string, m *ManifestBase, typeID string) error {
+	converter, ok := manifestConverters[typeID]
+	if !ok {
+		return fmt.Errorf("unimplemented manifest type %q", typeID)
+	}
+
+	data, err := converter.bytesFromEvrm(*m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	manifestFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest file: %w", err)
+	}
+	defer manifestFile.Close()
+
+	if err := ArchiveEncode(manifestFile, data); err != nil {
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+
+	return nil
+}
+
 // end evrManifest definition
 
 // note: i have a sneaking suspicion that there's only one manifest version.
 // the ones i've looked at so far can either be extracted by 5932408047-LE2 or 5932408047-EVR
 // i think i remember being told this but i need to do more research
 
-// every manifest version will be defined in it's own file
-// each file should have functions to convert from evrManifest to it's type, and vice versa
-// each file should also have a function to read and write itself to []byte
+// every manifest version is defined in its own file (manifest_<typeID>.go,
+// dashes/dots folded to underscores) and registers itself with Register in
+// an init func, so adding a new type never means touching this file.
 
-type manifestConverter interface {
+// ManifestConverter converts between the on-disk bytes of one manifest
+// type and the in-memory ManifestBase every type shares.
+type ManifestConverter interface {
 	evrmFromBytes(data []byte) (ManifestBase, error)
 	bytesFromEvrm(m ManifestBase) ([]byte, error)
 }
 
-/*
-// this should take given manifestType and manifest []byte data, and call the appropriate function for that type, and return the result
-func MarshalManifest(data []byte, manifestType string) (EvrManifest, error) {
-	var converter manifestConverter
-
-	// switch based on manifestType
-	switch manifestType {
-	case "5932408047-LE2":
-		converter = manifest_5932408047_LE2{}
-	case "5932408047-EVR":
-		converter = Manifest5932408047{}
-	case "5868485946-EVR":
-		converter = manifest_5868485946_EVR{}
-	default:
-		return EvrManifest{}, errors.New("unimplemented manifest type")
-	}
-
-	return converter.evrmFromBytes(data)
-}
+// manifestConverters holds every type registered via Register, keyed by
+// typeID (e.g. "5932408047-LE2").
+var manifestConverters = map[string]ManifestConverter{}
 
-func UnmarshalManifest(m EvrManifest, manifestType string) ([]byte, error) {
-	switch manifestType {
-	case "5932408047-LE2":
-		m5932408047_LE2 := manifest_5932408047_LE2{}
-		return m5932408047_LE2.bytesFromEvrm(m)
-	case "5932408047-EVR":
-		m5932408047_EVR := Manifest5932408047{}
-		return m5932408047_EVR.bytesFromEvrm(m)
-	//case "5868485946-EVR":
-	//	m5868485946_EVR := manifest_5868485946_EVR{}
-	//	return m5868485946_EVR.bytesFromEvrm(m)
-	default:
-		return nil, errors.New("unimplemented manifest type")
-	}
+// Register makes a manifest type available to ManifestReadFile and
+// WriteFile under typeID. Converters call this from an init func.
+func Register(typeID string, c ManifestConverter) {
+	manifestConverters[typeID] = c
 }
-
-*/
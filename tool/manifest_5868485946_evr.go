@@ -0,0 +1,28 @@
+package tool
+
+import "fmt"
+
+// manifest5868485946EVR is the 5868485946-EVR manifest type. No samples
+// from this deployment have shown a layout difference from ManifestBase
+// yet, so it converts the same way as the 5932408047 types for now.
+type manifest5868485946EVR struct{}
+
+func init() {
+	Register("5868485946-EVR", manifest5868485946EVR{})
+}
+
+func (manifest5868485946EVR) evrmFromBytes(data []byte) (ManifestBase, error) {
+	var m ManifestBase
+	if err := m.UnmarshalBinary(data); err != nil {
+		return ManifestBase{}, fmt.Errorf("5868485946-EVR: %w", err)
+	}
+	return m, nil
+}
+
+func (manifest5868485946EVR) bytesFromEvrm(m ManifestBase) ([]byte, error) {
+	data, err := m.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("5868485946-EVR: %w", err)
+	}
+	return data, nil
+}
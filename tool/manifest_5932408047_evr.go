@@ -0,0 +1,28 @@
+package tool
+
+import "fmt"
+
+// manifest5932408047EVR is the 5932408047-EVR manifest type. Like
+// 5932408047-LE2, every sample we've looked at decodes cleanly as
+// ManifestBase; kept as its own converter in case that stops holding.
+type manifest5932408047EVR struct{}
+
+func init() {
+	Register("5932408047-EVR", manifest5932408047EVR{})
+}
+
+func (manifest5932408047EVR) evrmFromBytes(data []byte) (ManifestBase, error) {
+	var m ManifestBase
+	if err := m.UnmarshalBinary(data); err != nil {
+		return ManifestBase{}, fmt.Errorf("5932408047-EVR: %w", err)
+	}
+	return m, nil
+}
+
+func (manifest5932408047EVR) bytesFromEvrm(m ManifestBase) ([]byte, error) {
+	data, err := m.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("5932408047-EVR: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,29 @@
+package tool
+
+import "fmt"
+
+// manifest5932408047LE2 is the 5932408047-LE2 manifest type. As far as
+// we've found it shares ManifestBase's on-disk layout exactly; it's split
+// out into its own converter rather than aliased to 5932408047-EVR so a
+// divergence discovered later only needs a change here.
+type manifest5932408047LE2 struct{}
+
+func init() {
+	Register("5932408047-LE2", manifest5932408047LE2{})
+}
+
+func (manifest5932408047LE2) evrmFromBytes(data []byte) (ManifestBase, error) {
+	var m ManifestBase
+	if err := m.UnmarshalBinary(data); err != nil {
+		return ManifestBase{}, fmt.Errorf("5932408047-LE2: %w", err)
+	}
+	return m, nil
+}
+
+func (manifest5932408047LE2) bytesFromEvrm(m ManifestBase) ([]byte, error) {
+	data, err := m.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("5932408047-LE2: %w", err)
+	}
+	return data, nil
+}
@@ -3,6 +3,7 @@ package tool
 import (
 	"bytes"
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -45,7 +46,7 @@ func TestManifestUnmarshalBinary(t *testing.T) {
 	t.Run("Unmarshal Valid Manifest", func(t *testing.T) {
 		manifestFilePath := "/mnt/c/Users/User/source/repos/EchoRelay9/_local/newnakama/echovr-newnakama/_data/5932408047/rad15/win10/manifests/2b47aab238f60515"
 
-		manifest, err := ManifestReadFile(manifestFilePath)
+		manifest, err := ManifestReadFile(manifestFilePath, "5932408047-EVR")
 		if err != nil {
 			t.Fatalf("Failed to read manifest file: %v", err)
 		}
@@ -54,3 +55,58 @@ func TestManifestUnmarshalBinary(t *testing.T) {
 	})
 
 }
+
+func TestManifestConverterRoundTrip(t *testing.T) {
+	original := ManifestBase{
+		Header: ManifestHeader{
+			PackageCount:  2,
+			FrameContents: ManifestSection{ElementCount: 1},
+			SomeStructure: ManifestSection{ElementCount: 1},
+			Frames:        ManifestSection{ElementCount: 1},
+		},
+		FrameContents: []FrameContents{
+			{T: 1, FileSymbol: 2, FileIndex: 3, DataOffset: 4, Size: 5, SomeAlignment: 1},
+		},
+		SomeStructure: []SomeStructure{
+			{T: 1, FileSymbol: 2, Unk1: 3, Unk2: 4, AssetType: 5},
+		},
+		Frames: []Frame{
+			{Index: 0, Offset: 0, CompressedSize: 64, Length: 128},
+		},
+	}
+
+	for _, typeID := range []string{"5932408047-LE2", "5932408047-EVR", "5868485946-EVR"} {
+		t.Run(typeID, func(t *testing.T) {
+			converter, ok := manifestConverters[typeID]
+			if !ok {
+				t.Fatalf("no converter registered for %q", typeID)
+			}
+
+			data, err := converter.bytesFromEvrm(original)
+			if err != nil {
+				t.Fatalf("bytesFromEvrm: %v", err)
+			}
+
+			got, err := converter.evrmFromBytes(data)
+			if err != nil {
+				t.Fatalf("evrmFromBytes: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, original) {
+				t.Errorf("round trip mismatch:\ngot:  %+v\nwant: %+v", got, original)
+			}
+		})
+	}
+}
+
+func TestManifestReadFileUnregisteredType(t *testing.T) {
+	if _, err := ManifestReadFile("/does/not/matter", "unknown-type"); err == nil {
+		t.Fatal("expected error for unregistered manifest type")
+	}
+}
+
+func TestWriteFileUnregisteredType(t *testing.T) {
+	if err := WriteFile("/tmp/does-not-matter", &ManifestBase{}, "unknown-type"); err == nil {
+		t.Fatal("expected error for unregistered manifest type")
+	}
+}
@@ -0,0 +1,44 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrUnsupportedPackageFormat is returned by Open when path doesn't match
+// any package layout it knows how to open.
+var ErrUnsupportedPackageFormat = errors.New("tool: unsupported package format")
+
+// Open opens the package file(s) backing manifest, given a single path
+// into the set, detecting from what's actually on disk whether that path
+// names a standalone single-file package or the first part of a
+// multi-part set - rather than requiring the caller to already know
+// which layout applies, the way PackageOpenMultiPart's "_0" suffix
+// convention does. A manifest is still required: unlike a self-describing
+// container format (zip, tar), nothing in a package file names its own
+// frame layout, so there's no format to sniff that would let Open derive
+// one from path alone.
+//
+// Dispatch rule: if manifest names exactly one package file and path
+// exists as given, Open reads it directly. Otherwise, if path+"_0"
+// exists, Open defers to PackageOpenMultiPart. If neither exists, Open
+// returns ErrUnsupportedPackageFormat.
+func Open(ctx context.Context, manifest *ManifestBase, path string) (*Package, error) {
+	if manifest.PackageCount() == 1 {
+		if _, err := os.Stat(path); err == nil {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open package file %s: %w", path, err)
+			}
+			return &Package{Manifest: manifest, Files: []PackageFile{f}}, nil
+		}
+	}
+
+	if _, err := os.Stat(path + "_0"); err == nil {
+		return PackageOpenMultiPart(ctx, manifest, path)
+	}
+
+	return nil, fmt.Errorf("%w: no package file found at %s or %s_0", ErrUnsupportedPackageFormat, path, path)
+}
@@ -1,12 +1,16 @@
 package tool
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/DataDog/zstd"
 )
@@ -92,7 +96,10 @@ type Package struct {
 	Files    []PackageFile
 }
 
-func PackageOpenMultiPart(manifest *ManifestBase, path string) (*Package, error) {
+// PackageOpenMultiPart opens every package file manifest expects at path,
+// checking ctx.Err() between opens so a caller can abort before working
+// through a package set with an unreasonable number of parts.
+func PackageOpenMultiPart(ctx context.Context, manifest *ManifestBase, path string) (*Package, error) {
 
 	var (
 		err      error
@@ -105,9 +112,15 @@ func PackageOpenMultiPart(manifest *ManifestBase, path string) (*Package, error)
 	)
 
 	for i := range manifest.PackageCount() {
+		if err := ctx.Err(); err != nil {
+			resource.closeOpened()
+			return nil, err
+		}
+
 		path := filepath.Join(dirPath, fmt.Sprintf("%s_%d", stem, i))
 		resource.Files[i], err = os.Open(path)
 		if err != nil {
+			resource.closeOpened()
 			return nil, fmt.Errorf("failed to open package file %s: %w", path, err)
 		}
 	}
@@ -115,68 +128,183 @@ func PackageOpenMultiPart(manifest *ManifestBase, path string) (*Package, error)
 	return resource, nil
 }
 
-func PackageExtract(p *Package, outputDir string, preserveGroups bool) error {
+// closeOpened closes every file PackageOpenMultiPart has opened so far,
+// used to clean up after a cancellation or a later-part open failure.
+func (p *Package) closeOpened() {
+	for _, f := range p.Files {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
+
+// validateFrame checks a Frame's fields against the number of package
+// files actually open before anything indexes into them, so a crafted or
+// corrupt manifest returns an error instead of panicking with an
+// out-of-range package index or a negative/overflowing slice bound.
+func validateFrame(v Frame, packageCount int) error {
+	if int(v.Index) >= packageCount {
+		return fmt.Errorf("references package index %d, only %d package files open", v.Index, packageCount)
+	}
+	if v.CompressedSize == 0 {
+		return fmt.Errorf("compressed size is 0")
+	}
+	return nil
+}
+
+// ExtractOptions configures PackageExtract.
+type ExtractOptions struct {
+	// Concurrency is how many frames PackageExtract decompresses in
+	// parallel, each on its own goroutine with its own zstd context and
+	// scratch buffers. A non-positive value defaults to runtime.NumCPU().
+	Concurrency int
+}
+
+// PackageExtract decompresses every frame in p and writes out each of its
+// FrameContents entries under outputDir. Frames are processed by a pool of
+// opts.Concurrency worker goroutines; since reads go through each
+// PackageFile's ReaderAt rather than Seek+Read, workers never contend over
+// a shared read position. It honors ctx: each worker checks ctx.Err()
+// before taking on a new frame, and progress (if non-nil) is called once
+// per file written, synchronized across workers, with how many files have
+// been written so far, the total file count, the frame index that file
+// came from, and the file's symbol. The first error encountered by any
+// worker is returned once every worker has finished.
+func PackageExtract(ctx context.Context, p *Package, outputDir string, preserveGroups bool, progress func(current, total int, frameIndex int, fileSymbol int64), opts ExtractOptions) error {
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	contentsByFrame := make(map[uint32][]FrameContents, len(p.Manifest.FrameContents))
+	for _, fc := range p.Manifest.FrameContents {
+		contentsByFrame[fc.FileIndex] = append(contentsByFrame[fc.FileIndex], fc)
+	}
 
 	var (
-		totalFilesWritten = 0
-		zstdCtx           = zstd.NewCtx()
-		compressed        = make([]byte, 32*1024*1024)
-		decompressed      = make([]byte, 32*1024*1024)
-	)
-	for k, v := range p.Manifest.Frames {
-		activeFile := p.Files[v.Index]
+		totalFiles        = int(p.Manifest.Header.FrameContents.Count)
+		totalFilesWritten int64
 
-		if v.Length == 0 {
-			continue
-		}
-		if v.CompressedSize == 0 {
-			return fmt.Errorf("compressed size is 0 for file index %d", k)
-		}
+		progressMu sync.Mutex
 
-		if _, err := activeFile.Seek(int64(v.Offset), 0); err != nil {
-			return fmt.Errorf("failed to seek to offset %d: %w", v.Offset, err)
+		errMu    sync.Mutex
+		firstErr error
+	)
+	setErr := func(err error) {
+		errMu.Lock()
+		defer errMu.Unlock()
+		if firstErr == nil {
+			firstErr = err
 		}
+	}
 
-		if len(compressed) < int(v.CompressedSize) {
-			compressed = make([]byte, v.CompressedSize)
-		}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 
-		if len(decompressed) < int(v.Length) {
-			decompressed = make([]byte, v.Length)
-		}
+			var (
+				zstdCtx      = zstd.NewCtx()
+				compressed   []byte
+				decompressed []byte
+			)
+			for k := range jobs {
+				if err := ctx.Err(); err != nil {
+					setErr(err)
+					continue
+				}
 
-		if _, err := activeFile.Read(compressed[:v.Length]); err != nil {
-			return fmt.Errorf("failed to read file, check input: %w", err)
-		}
+				v := p.Manifest.Frames[k]
+				if v.Length == 0 {
+					continue
+				}
+				if err := validateFrame(v, len(p.Files)); err != nil {
+					setErr(fmt.Errorf("frame %d: %w", k, err))
+					continue
+				}
 
-		fmt.Printf("Decompressing and extracting files contained in file index %d, %d/%d\n", k, totalFilesWritten, p.Manifest.Header.FrameContents.Count)
-		if _, err := zstdCtx.Decompress(decompressed[:v.Length], compressed[:v.CompressedSize]); err != nil {
-			fmt.Println("failed to decompress file, check input")
-		}
+				if len(compressed) < int(v.CompressedSize) {
+					compressed = make([]byte, v.CompressedSize)
+				}
+				if len(decompressed) < int(v.Length) {
+					decompressed = make([]byte, v.Length)
+				}
 
-		for _, v2 := range p.Manifest.FrameContents {
-			if v2.FileIndex != uint32(k) {
-				continue
-			}
-			fileName := fmt.Sprintf("%x", v2.FileSymbol)
-			fileType := fmt.Sprintf("%x", v2.T)
-			basePath := fmt.Sprintf("%s/%s", outputDir, fileType)
-			if preserveGroups {
-				basePath = fmt.Sprintf("%s/%d/%s", outputDir, v2.FileIndex, fileType)
-			}
-			os.MkdirAll(basePath, 0777)
-			file, err := os.OpenFile(fmt.Sprintf("%s/%s", basePath, fileName), os.O_RDWR|os.O_CREATE, 0777)
-			if err != nil {
-				fmt.Println(err)
-				continue
+				if _, err := p.Files[v.Index].ReadAt(compressed[:v.CompressedSize], int64(v.Offset)); err != nil {
+					setErr(fmt.Errorf("failed to read file, check input: %w", err))
+					continue
+				}
+
+				if _, err := zstdCtx.Decompress(decompressed[:v.Length], compressed[:v.CompressedSize]); err != nil {
+					setErr(fmt.Errorf("failed to decompress file index %d: %w", k, err))
+					continue
+				}
+
+				for _, v2 := range contentsByFrame[uint32(k)] {
+					if err := ctx.Err(); err != nil {
+						setErr(err)
+						break
+					}
+					if int64(v2.DataOffset)+int64(v2.Size) > int64(v.Length) {
+						setErr(fmt.Errorf("file %x in frame %d extends past decompressed frame", v2.FileSymbol, k))
+						continue
+					}
+
+					fileName := fmt.Sprintf("%x", v2.FileSymbol)
+					fileType := fmt.Sprintf("%x", v2.T)
+					basePath := fmt.Sprintf("%s/%s", outputDir, fileType)
+					if preserveGroups {
+						basePath = fmt.Sprintf("%s/%d/%s", outputDir, v2.FileIndex, fileType)
+					}
+					if err := os.MkdirAll(basePath, 0777); err != nil {
+						setErr(fmt.Errorf("failed to create dir %s: %w", basePath, err))
+						continue
+					}
+					filePath := fmt.Sprintf("%s/%s", basePath, fileName)
+					file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0777)
+					if err != nil {
+						setErr(fmt.Errorf("failed to open output file %s: %w", filePath, err))
+						continue
+					}
+
+					_, writeErr := file.Write(decompressed[v2.DataOffset : v2.DataOffset+v2.Size])
+					closeErr := file.Close()
+					if writeErr != nil {
+						setErr(fmt.Errorf("failed to write output file %s: %w", filePath, writeErr))
+						continue
+					}
+					if closeErr != nil {
+						setErr(fmt.Errorf("failed to close output file %s: %w", filePath, closeErr))
+						continue
+					}
+
+					n := atomic.AddInt64(&totalFilesWritten, 1)
+					if progress != nil {
+						progressMu.Lock()
+						progress(int(n), totalFiles, k, v2.FileSymbol)
+						progressMu.Unlock()
+					}
+				}
 			}
+		}()
+	}
 
-			file.Write(decompressed[v2.DataOffset : v2.DataOffset+v2.Size])
-			file.Close()
-			totalFilesWritten++
+feed:
+	for k := range p.Manifest.Frames {
+		select {
+		case jobs <- k:
+		case <-ctx.Done():
+			setErr(ctx.Err())
+			break feed
 		}
 	}
-	return nil
+	close(jobs)
+	wg.Wait()
+
+	return firstErr
 }
 
 func Int64Hex(v int64) string {
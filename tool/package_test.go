@@ -1,6 +1,7 @@
 package tool
 
 import (
+	"context"
 	"testing"
 )
 
@@ -8,18 +9,18 @@ func TestPackageExtract(t *testing.T) {
 	t.Run("Unmarshal Valid Manifest", func(t *testing.T) {
 		manifestFilePath := "/mnt/c/Users/User/source/repos/EchoRelay9/_local/newnakama/echovr-newnakama/_data/5932408047/rad15/win10/manifests/2b47aab238f60515"
 
-		manifest, err := ManifestReadFile(manifestFilePath)
+		manifest, err := ManifestReadFile(manifestFilePath, "5932408047-EVR")
 		if err != nil {
 			t.Fatalf("Failed to read manifest file: %v", err)
 		}
 
 		path := "/mnt/c/Users/User/source/repos/EchoRelay9/_local/newnakama/echovr-newnakama/_data/5932408047/rad15/win10/packages/2b47aab238f60515"
-		resource, err := PackageOpenMultiPart(manifest, path)
+		resource, err := PackageOpenMultiPart(context.Background(), manifest, path)
 		if err != nil {
 			t.Fatalf("Failed to open package files: %v", err)
 		}
 
-		err = PackageExtract(resource, "/tmp/output", false)
+		err = PackageExtract(context.Background(), resource, "/tmp/output", false, nil, ExtractOptions{})
 		if err != nil {
 			t.Fatalf("Failed to extract package files: %v", err)
 		}
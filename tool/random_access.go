@@ -0,0 +1,125 @@
+package tool
+
+import (
+	"fmt"
+
+	"github.com/goopsie/evrFileTools/pkg/archive"
+)
+
+// DefaultRandomAccessCacheSize is the decompressed-frame cache size
+// RandomAccessArchive uses when it isn't given WithRandomAccessCacheSize.
+const DefaultRandomAccessCacheSize = 64 * 1024 * 1024
+
+// randomAccessConfig holds the options NewRandomAccessArchive accepts.
+type randomAccessConfig struct {
+	codec         archive.CodecTag
+	maxCacheBytes int
+}
+
+// RandomAccessOption configures a RandomAccessArchive.
+type RandomAccessOption func(*randomAccessConfig)
+
+// WithRandomAccessCodec selects the codec the package's frames were
+// compressed with. It defaults to archive.CodecZstd, which is what every
+// PackageExtract-written package uses today.
+func WithRandomAccessCodec(tag archive.CodecTag) RandomAccessOption {
+	return func(c *randomAccessConfig) {
+		c.codec = tag
+	}
+}
+
+// WithRandomAccessCacheSize bounds the total decompressed bytes
+// RandomAccessArchive keeps cached. It defaults to
+// DefaultRandomAccessCacheSize.
+func WithRandomAccessCacheSize(maxBytes int) RandomAccessOption {
+	return func(c *randomAccessConfig) {
+		c.maxCacheBytes = maxBytes
+	}
+}
+
+// RandomAccessArchive decodes individual frames out of an already-open
+// Package on demand, so a caller that only wants a handful of files out of
+// a manifest with hundreds of frames doesn't pay to decompress the rest.
+// Decompressed frames are kept in a byte-bounded LRU cache shared across
+// ReadFrame and ReadFile calls.
+type RandomAccessArchive struct {
+	pkg     *Package
+	readers []*archive.RandomReader
+	cache   *randomAccessCache
+}
+
+// NewRandomAccessArchive wraps pkg for on-demand frame and file decoding.
+func NewRandomAccessArchive(pkg *Package, opts ...RandomAccessOption) (*RandomAccessArchive, error) {
+	cfg := &randomAccessConfig{
+		codec:         archive.CodecZstd,
+		maxCacheBytes: DefaultRandomAccessCacheSize,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	readers := make([]*archive.RandomReader, len(pkg.Files))
+	for i, f := range pkg.Files {
+		r, err := archive.NewRandomReader(f, cfg.codec)
+		if err != nil {
+			return nil, fmt.Errorf("package file %d: %w", i, err)
+		}
+		readers[i] = r
+	}
+
+	return &RandomAccessArchive{
+		pkg:     pkg,
+		readers: readers,
+		cache:   newRandomAccessCache(cfg.maxCacheBytes),
+	}, nil
+}
+
+// ReadFrame decompresses and returns the frame at frameIndex, serving it
+// from the cache if it's already been read.
+func (a *RandomAccessArchive) ReadFrame(frameIndex uint32) ([]byte, error) {
+	if data, ok := a.cache.get(frameIndex); ok {
+		return data, nil
+	}
+
+	if int(frameIndex) >= len(a.pkg.Manifest.Frames) {
+		return nil, fmt.Errorf("frame index %d out of range (%d frames)", frameIndex, len(a.pkg.Manifest.Frames))
+	}
+	frame := a.pkg.Manifest.Frames[frameIndex]
+	if int(frame.Index) >= len(a.readers) {
+		return nil, fmt.Errorf("frame %d references package index %d, only %d package files open", frameIndex, frame.Index, len(a.readers))
+	}
+
+	data, err := a.readers[frame.Index].ReadFrame(archive.FrameLocation{
+		Offset:           int64(frame.Offset),
+		CompressedLength: int64(frame.CompressedSize),
+		Length:           int64(frame.Length),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read frame %d: %w", frameIndex, err)
+	}
+
+	a.cache.put(frameIndex, data)
+	return data, nil
+}
+
+// ReadFile locates typeSymbol/fileSymbol in the manifest's FrameContents
+// table, decompresses its frame via ReadFrame (so repeated lookups into
+// the same frame are cheap), and returns just that file's bytes.
+func (a *RandomAccessArchive) ReadFile(typeSymbol, fileSymbol int64) ([]byte, error) {
+	for _, fc := range a.pkg.Manifest.FrameContents {
+		if fc.T != typeSymbol || fc.FileSymbol != fileSymbol {
+			continue
+		}
+
+		frameData, err := a.ReadFrame(fc.FileIndex)
+		if err != nil {
+			return nil, err
+		}
+		end := int64(fc.DataOffset) + int64(fc.Size)
+		if end > int64(len(frameData)) {
+			return nil, fmt.Errorf("file %x/%x extends past decompressed frame %d", typeSymbol, fileSymbol, fc.FileIndex)
+		}
+		return frameData[fc.DataOffset:end], nil
+	}
+	return nil, fmt.Errorf("no file with type %x symbol %x in manifest", typeSymbol, fileSymbol)
+}
@@ -0,0 +1,75 @@
+package tool
+
+import (
+	"container/list"
+	"sync"
+)
+
+type randomAccessCacheEntry struct {
+	key  uint32
+	data []byte
+}
+
+// randomAccessCache is a byte-bounded LRU cache of decompressed frames,
+// safe for concurrent use by multiple goroutines sharing one
+// RandomAccessArchive.
+type randomAccessCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	curBytes int
+	order    *list.List
+	items    map[uint32]*list.Element
+}
+
+func newRandomAccessCache(maxBytes int) *randomAccessCache {
+	if maxBytes <= 0 {
+		maxBytes = DefaultRandomAccessCacheSize
+	}
+	return &randomAccessCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[uint32]*list.Element),
+	}
+}
+
+func (c *randomAccessCache) get(frameIndex uint32) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[frameIndex]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*randomAccessCacheEntry).data, true
+}
+
+func (c *randomAccessCache) put(frameIndex uint32, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[frameIndex]; ok {
+		c.curBytes -= len(el.Value.(*randomAccessCacheEntry).data)
+		c.order.Remove(el)
+		delete(c.items, frameIndex)
+	}
+
+	if len(data) > c.maxBytes {
+		return
+	}
+
+	el := c.order.PushFront(&randomAccessCacheEntry{key: frameIndex, data: data})
+	c.items[frameIndex] = el
+	c.curBytes += len(data)
+
+	for c.curBytes > c.maxBytes {
+		back := c.order.Back()
+		if back == nil {
+			break
+		}
+		entry := back.Value.(*randomAccessCacheEntry)
+		c.curBytes -= len(entry.data)
+		c.order.Remove(back)
+		delete(c.items, entry.key)
+	}
+}